@@ -29,13 +29,37 @@ import (
 )
 
 var (
-	url     string
-	detach  bool
-	version bool
+	url             string
+	endpointsFile   string
+	endpointsScheme string
+	caPath          string
+	certPath        string
+	keyPath         string
+	context         string
+	timeout         string
+	idleTimeout     string
+	connTimeout     string
+	noColor         bool
+	raw             bool
+	detach          bool
+	trace           bool
+	version         bool
 )
 
 func init() {
-	flag.StringVarP(&url, "pd", "u", "http://127.0.0.1:2379", "The pd address")
+	flag.StringVarP(&url, "pd", "u", "http://127.0.0.1:2379", "The pd address, multiple addresses are separated by commas")
+	flag.StringVar(&endpointsFile, "endpoints-file", "", "The file of newline or comma separated pd addresses")
+	flag.StringVar(&endpointsScheme, "endpoints-scheme", "", "The scheme of the pd addresses without one, http or https")
+	flag.StringVar(&caPath, "cacert", "", "The path of file that contains list of trusted SSL CAs")
+	flag.StringVar(&certPath, "cert", "", "The path of file that contains X509 certificate in PEM format")
+	flag.StringVar(&keyPath, "key", "", "The path of file that contains X509 key in PEM format")
+	flag.StringVar(&context, "context", "", "The name of the context in ~/.pd/config")
+	flag.StringVar(&timeout, "timeout", "", "The timeout of each request to pd")
+	flag.StringVar(&connTimeout, "connect-timeout", "", "The timeout of connecting to pd")
+	flag.StringVar(&idleTimeout, "idle-conn-timeout", "", "The timeout of the idle connections to pd")
+	flag.BoolVar(&noColor, "no-color", false, "Disable the colors of the output")
+	flag.BoolVar(&raw, "raw", false, "Write the response bodies verbatim")
+	flag.BoolVar(&trace, "trace", false, "Write the requests to pd and the responses to stderr, only for the command with '-d'")
 	flag.BoolVarP(&detach, "detach", "d", false, "Run pdctl without readline")
 	flag.BoolVarP(&version, "version", "V", false, "print version information and exit")
 }
@@ -81,8 +105,7 @@ func main() {
 		input = strings.Split(strings.TrimSpace(string(b[:])), " ")
 	}
 	if detach {
-		pdctl.Start(append(os.Args[1:], input...))
-		return
+		os.Exit(pdctl.Start(append(os.Args[1:], input...)))
 	}
 	loop()
 }
@@ -114,7 +137,43 @@ func loop() {
 			os.Exit(0)
 		}
 		args := strings.Split(strings.TrimSpace(line), " ")
-		args = append(args, "-u", url)
+		// The address given by the context is used if '-u' is not set.
+		if flag.CommandLine.Changed("pd") {
+			args = append(args, "-u", url)
+		}
+		if context != "" {
+			args = append(args, "--context", context)
+		}
+		if timeout != "" {
+			args = append(args, "--timeout", timeout)
+		}
+		if connTimeout != "" {
+			args = append(args, "--connect-timeout", connTimeout)
+		}
+		if idleTimeout != "" {
+			args = append(args, "--idle-conn-timeout", idleTimeout)
+		}
+		if noColor {
+			args = append(args, "--no-color")
+		}
+		if raw {
+			args = append(args, "--raw")
+		}
+		if endpointsFile != "" {
+			args = append(args, "--endpoints-file", endpointsFile)
+		}
+		if endpointsScheme != "" {
+			args = append(args, "--endpoints-scheme", endpointsScheme)
+		}
+		if caPath != "" {
+			args = append(args, "--cacert", caPath)
+		}
+		if certPath != "" {
+			args = append(args, "--cert", certPath)
+		}
+		if keyPath != "" {
+			args = append(args, "--key", keyPath)
+		}
 		pdctl.Start(args)
 	}
 }
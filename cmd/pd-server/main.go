@@ -45,6 +45,12 @@ func main() {
 		log.Fatalf("parse cmd flags error: %s\n", err)
 	}
 
+	// Validate before initializing the logger, which falls back to the
+	// default level silently and opens the log file lazily.
+	if err = cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
 	err = logutil.InitLogger(&cfg.Log)
 	if err != nil {
 		log.Fatalf("initalize logger error: %s\n", err)
@@ -14,6 +14,7 @@
 package pd
 
 import (
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -61,8 +62,15 @@ type tsoRequest struct {
 
 const (
 	pdTimeout             = 3 * time.Second
+	defaultConnectTimeout = 3 * time.Second
 	maxMergeTSORequests   = 10000
 	maxInitClusterRetries = 100
+	// The backoff of retrying an endpoint starts from endpointRetryBackoff and
+	// doubles up to maxEndpointRetryBackoff, and all the retries of finding
+	// the leader stop after maxEndpointRetryTime.
+	endpointRetryBackoff    = 100 * time.Millisecond
+	maxEndpointRetryBackoff = time.Second
+	maxEndpointRetryTime    = 5 * time.Second
 )
 
 var (
@@ -75,9 +83,13 @@ var (
 )
 
 type client struct {
-	urls        []string
-	clusterID   uint64
-	tsoRequests chan *tsoRequest
+	urls           []string
+	clusterID      uint64
+	tsoRequests    chan *tsoRequest
+	connectTimeout time.Duration
+	// endpointRetries is the number of retries of an endpoint before the
+	// next endpoint is tried.
+	endpointRetries int
 
 	connMu struct {
 		sync.RWMutex
@@ -93,17 +105,44 @@ type client struct {
 	cancel context.CancelFunc
 }
 
+// ClientOption configures the client created by NewClient.
+type ClientOption func(c *client)
+
+// WithConnectTimeout sets the timeout of connecting to a PD endpoint, it is
+// 3s by default. An unreachable endpoint fails after the timeout instead of
+// the TCP timeout of the OS, so the next endpoint can be tried.
+func WithConnectTimeout(timeout time.Duration) ClientOption {
+	return func(c *client) {
+		c.connectTimeout = timeout
+	}
+}
+
+// WithEndpointRetries sets the number of retries of an endpoint with a short
+// exponential backoff when finding the leader, so a transient failure does not
+// fall through to the next endpoint at once, or fail the client if there is
+// only one endpoint. It is 0 by default, and the retries of all endpoints stop
+// after 5s.
+func WithEndpointRetries(retries int) ClientOption {
+	return func(c *client) {
+		c.endpointRetries = retries
+	}
+}
+
 // NewClient creates a PD client.
-func NewClient(pdAddrs []string) (Client, error) {
+func NewClient(pdAddrs []string, opts ...ClientOption) (Client, error) {
 	log.Infof("[pd] create pd client with endpoints %v", pdAddrs)
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &client{
-		urls:          addrsToUrls(pdAddrs),
-		tsoRequests:   make(chan *tsoRequest, maxMergeTSORequests),
-		tsDeadlineCh:  make(chan deadline, 1),
-		checkLeaderCh: make(chan struct{}, 1),
-		ctx:           ctx,
-		cancel:        cancel,
+		urls:           addrsToUrls(pdAddrs),
+		tsoRequests:    make(chan *tsoRequest, maxMergeTSORequests),
+		connectTimeout: defaultConnectTimeout,
+		tsDeadlineCh:   make(chan deadline, 1),
+		checkLeaderCh:  make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 	c.connMu.clientConns = make(map[string]*grpc.ClientConn)
 
@@ -148,9 +187,18 @@ func (c *client) initClusterID() error {
 func (c *client) updateLeader() error {
 	ctx, cancel := context.WithCancel(c.ctx)
 	defer cancel()
+	deadline := time.Now().Add(maxEndpointRetryTime)
 	for _, u := range c.urls {
-		members, err := c.getMembers(ctx, u)
-		if err != nil || members.GetLeader() == nil || len(members.GetLeader().GetClientUrls()) == 0 {
+		var members *pdpb.GetMembersResponse
+		err := c.retryEndpoint(ctx, deadline, func() error {
+			var err error
+			members, err = c.getMembers(ctx, u)
+			if err == nil && (members.GetLeader() == nil || len(members.GetLeader().GetClientUrls()) == 0) {
+				err = errors.Errorf("no leader in %s", u)
+			}
+			return err
+		})
+		if err != nil {
 			continue
 		}
 		if err = c.switchLeader(members.GetLeader().GetClientUrls()); err != nil {
@@ -161,6 +209,30 @@ func (c *client) updateLeader() error {
 	return errors.Errorf("failed to get leader from %v", c.urls)
 }
 
+// retryEndpoint calls f until it succeeds or it has been retried
+// endpointRetries times, the backoff before a retry doubles each time. It does
+// not retry if the backoff would pass the deadline.
+func (c *client) retryEndpoint(ctx context.Context, deadline time.Time, f func() error) error {
+	err := f()
+	backoff := endpointRetryBackoff
+	for i := 0; i < c.endpointRetries && err != nil; i++ {
+		if time.Now().Add(backoff).After(deadline) {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		}
+		log.Warnf("[pd] retry the endpoint after error: %v", err)
+		if backoff *= 2; backoff > maxEndpointRetryBackoff {
+			backoff = maxEndpointRetryBackoff
+		}
+		err = f()
+	}
+	return err
+}
+
 func (c *client) getMembers(ctx context.Context, url string) (*pdpb.GetMembersResponse, error) {
 	cc, err := c.getOrCreateGRPCConn(url)
 	if err != nil {
@@ -204,7 +276,7 @@ func (c *client) getOrCreateGRPCConn(addr string) (*grpc.ClientConn, error) {
 		return conn, nil
 	}
 
-	cc, err := grpc.Dial(strings.TrimPrefix(addr, "http://"), grpc.WithInsecure()) // TODO: Support HTTPS.
+	cc, err := grpc.Dial(strings.TrimPrefix(addr, "http://"), grpc.WithInsecure(), grpc.WithDialer(c.dial)) // TODO: Support HTTPS.
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -220,6 +292,20 @@ func (c *client) getOrCreateGRPCConn(addr string) (*grpc.ClientConn, error) {
 	return cc, nil
 }
 
+// dial connects to a "host:port" or "unix://path" address within the connect
+// timeout, or the timeout given by gRPC if it is shorter.
+func (c *client) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	if timeout <= 0 || timeout > c.connectTimeout {
+		timeout = c.connectTimeout
+	}
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix://") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix://")
+	}
+	// The error is not traced, gRPC checks if it is temporary.
+	return net.DialTimeout(network, addr, timeout)
+}
+
 func (c *client) leaderLoop() {
 	defer c.wg.Done()
 
@@ -276,8 +362,15 @@ func (c *client) tsLoop() {
 	defer loopCancel()
 
 	var requests []*tsoRequest
+	// The requests which are kept to be retried, or not sent yet, are
+	// finished when the loop exits, so their callers do not wait for them.
+	defer func() {
+		c.finishTSORequest(requests, 0, 0, errors.Trace(errClosing))
+	}()
 	var stream pdpb.PD_TsoClient
 	var cancel context.CancelFunc
+	// retried is set if the requests are kept to be retried on a new stream.
+	var retried bool
 
 	for {
 		var err error
@@ -290,6 +383,8 @@ func (c *client) tsLoop() {
 				log.Errorf("[pd] create tso stream error: %v", err)
 				c.scheduleCheckLeader()
 				cancel()
+				c.finishTSORequest(requests, 0, 0, errors.Trace(err))
+				requests, retried = requests[:0], false
 				c.revokeTSORequest(err)
 				select {
 				case <-time.After(time.Second):
@@ -300,30 +395,43 @@ func (c *client) tsLoop() {
 			}
 		}
 
-		select {
-		case first := <-c.tsoRequests:
-			requests = append(requests, first)
-			pending := len(c.tsoRequests)
-			for i := 0; i < pending; i++ {
-				requests = append(requests, <-c.tsoRequests)
-			}
-			done := make(chan struct{})
-			dl := deadline{
-				timer:  time.After(pdTimeout),
-				done:   done,
-				cancel: cancel,
-			}
+		if len(requests) == 0 {
 			select {
-			case c.tsDeadlineCh <- dl:
+			case first := <-c.tsoRequests:
+				requests = append(requests, first)
 			case <-loopCtx.Done():
 				return
 			}
-			err = c.processTSORequests(stream, requests)
-			close(done)
-			requests = requests[:0]
+		}
+		pending := len(c.tsoRequests)
+		for i := 0; i < pending; i++ {
+			requests = append(requests, <-c.tsoRequests)
+		}
+		done := make(chan struct{})
+		dl := deadline{
+			timer:  time.After(pdTimeout),
+			done:   done,
+			cancel: cancel,
+		}
+		select {
+		case c.tsDeadlineCh <- dl:
 		case <-loopCtx.Done():
 			return
 		}
+		err = c.processTSORequests(stream, requests)
+		close(done)
+		if errors.Cause(err) == errTSOLength && !retried {
+			// The responses of the stream are out of sync with the
+			// requests, such as a stale response is left in it. The stream
+			// is dropped and the requests are retried once on a new one.
+			log.Warnf("[pd] tso stream is out of sync, retry %d requests on a new stream", len(requests))
+			retried = true
+		} else {
+			if errors.Cause(err) == errTSOLength {
+				c.finishTSORequest(requests, 0, 0, errors.Trace(err))
+			}
+			requests, retried = requests[:0], false
+		}
 
 		if err != nil {
 			log.Errorf("[pd] getTS error: %v", err)
@@ -334,6 +442,9 @@ func (c *client) tsLoop() {
 	}
 }
 
+// processTSORequests gets the timestamps of the requests on the stream. The
+// requests are finished, except that errTSOLength is returned and the caller
+// decides whether to retry them.
 func (c *client) processTSORequests(stream pdpb.PD_TsoClient, requests []*tsoRequest) error {
 	start := time.Now()
 	req := &pdpb.TsoRequest{
@@ -350,12 +461,8 @@ func (c *client) processTSORequests(stream pdpb.PD_TsoClient, requests []*tsoReq
 		return errors.Trace(err)
 	}
 	requestDuration.WithLabelValues("tso").Observe(time.Since(start).Seconds())
-	if err == nil && resp.GetCount() != uint32(len(requests)) {
-		err = errTSOLength
-	}
-	if err != nil {
-		c.finishTSORequest(requests, 0, 0, errors.Trace(err))
-		return errors.Trace(err)
+	if resp.GetCount() != uint32(len(requests)) {
+		return errors.Trace(errTSOLength)
 	}
 
 	physical, logical := resp.GetTimestamp().GetPhysical(), resp.GetTimestamp().GetLogical()
@@ -14,12 +14,16 @@
 package pd
 
 import (
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -219,3 +223,191 @@ func (s *testClientSuite) TestGetStore(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(n, IsNil)
 }
+
+var _ = Suite(&testClientDialSuite{})
+
+type testClientDialSuite struct{}
+
+func (s *testClientDialSuite) TestDialTimeout(c *C) {
+	// The connect timeout is used if gRPC gives no shorter one.
+	cli := &client{connectTimeout: time.Nanosecond}
+	_, err := cli.dial("127.0.0.1:2379", time.Hour)
+	c.Assert(err, NotNil)
+	c.Assert(err.(net.Error).Timeout(), IsTrue)
+
+	// 10.255.255.1 is not routable, the dial fails after the connect timeout
+	// instead of the TCP timeout.
+	cli = &client{connectTimeout: 200 * time.Millisecond}
+	start := time.Now()
+	conn, err := cli.dial("10.255.255.1:2379", 0)
+	if err == nil {
+		conn.Close()
+		c.Skip("10.255.255.1 is reachable in this network")
+	}
+	c.Assert(time.Since(start), Less, 2*time.Second)
+}
+
+func (s *testClientDialSuite) TestDialUnix(c *C) {
+	dir, err := ioutil.TempDir("", "pd_client_dial")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pd.sock")
+	l, err := net.Listen("unix", path)
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	cli := &client{connectTimeout: time.Second}
+	conn, err := cli.dial("unix://"+path, 0)
+	c.Assert(err, IsNil)
+	c.Assert(conn.Close(), IsNil)
+}
+
+func (s *testClientDialSuite) TestRetryEndpoint(c *C) {
+	ctx := context.Background()
+	var calls int
+	failTwice := func() error {
+		if calls++; calls <= 2 {
+			return errors.New("transient")
+		}
+		return nil
+	}
+
+	// No retry by default.
+	cli := &client{}
+	c.Assert(cli.retryEndpoint(ctx, time.Now().Add(time.Minute), failTwice), NotNil)
+	c.Assert(calls, Equals, 1)
+
+	calls = 0
+	cli = &client{endpointRetries: 3}
+	start := time.Now()
+	c.Assert(cli.retryEndpoint(ctx, time.Now().Add(time.Minute), failTwice), IsNil)
+	c.Assert(calls, Equals, 3)
+	c.Assert(time.Since(start) >= endpointRetryBackoff*3, IsTrue)
+
+	// The fast path does not wait.
+	calls = 2
+	start = time.Now()
+	c.Assert(cli.retryEndpoint(ctx, time.Now().Add(time.Minute), failTwice), IsNil)
+	c.Assert(time.Since(start), Less, endpointRetryBackoff)
+
+	// The retries stop at the deadline.
+	calls = -10
+	c.Assert(cli.retryEndpoint(ctx, time.Now().Add(endpointRetryBackoff*2), failTwice), NotNil)
+	c.Assert(calls, Equals, -8)
+}
+
+// desyncTSOServer is a PD which only serves tso, the first response of the
+// first desync streams has a wrong count as if it is a stale one.
+type desyncTSOServer struct {
+	pdpb.PDServer
+
+	mu      sync.Mutex
+	streams int
+	desync  int
+}
+
+func (s *desyncTSOServer) Tso(stream pdpb.PD_TsoServer) error {
+	s.mu.Lock()
+	s.streams++
+	stale := s.streams <= s.desync
+	s.mu.Unlock()
+
+	var logical int64
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		logical += int64(req.GetCount())
+		count := req.GetCount()
+		if stale {
+			count, stale = count+1, false
+		}
+		resp := &pdpb.TsoResponse{Count: count, Timestamp: &pdpb.Timestamp{Physical: 1, Logical: logical}}
+		if err = stream.Send(resp); err != nil {
+			return nil
+		}
+	}
+}
+
+func (s *desyncTSOServer) getStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streams
+}
+
+// newDesyncTSOClient returns a client of a desyncTSOServer, the deadlines of
+// the tso requests are not taken if cancelLoop is false.
+func newDesyncTSOClient(c *C, desync int, cancelLoop bool) (*client, *desyncTSOServer, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	srv := &desyncTSOServer{desync: desync}
+	gs := grpc.NewServer()
+	pdpb.RegisterPDServer(gs, srv)
+	go gs.Serve(l)
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cli := &client{
+		tsoRequests:   make(chan *tsoRequest, maxMergeTSORequests),
+		tsDeadlineCh:  make(chan deadline, 1),
+		checkLeaderCh: make(chan struct{}, 1),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	cli.connMu.clientConns = map[string]*grpc.ClientConn{"pd": conn}
+	cli.connMu.leader = "pd"
+	cli.wg.Add(1)
+	go cli.tsLoop()
+	if cancelLoop {
+		cli.wg.Add(1)
+		go cli.tsCancelLoop()
+	}
+	return cli, srv, func() {
+		cancel()
+		cli.wg.Wait()
+		conn.Close()
+		gs.Stop()
+	}
+}
+
+func (s *testClientDialSuite) TestTSODesync(c *C) {
+	// The requests are retried once on a new stream.
+	cli, srv, cleanup := newDesyncTSOClient(c, 1, true)
+	defer cleanup()
+	physical, logical, err := cli.GetTS(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(physical, Equals, int64(1))
+	c.Assert(logical, Equals, int64(1))
+	c.Assert(srv.getStreams(), Equals, 2)
+
+	// They fail if the new stream is out of sync too, and the next requests
+	// are on another stream.
+	cli, srv, cleanup = newDesyncTSOClient(c, 2, true)
+	defer cleanup()
+	_, _, err = cli.GetTS(context.Background())
+	c.Assert(errors.Cause(err), Equals, errTSOLength)
+	_, _, err = cli.GetTS(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(srv.getStreams(), Equals, 3)
+}
+
+func (s *testClientDialSuite) TestTSOCloseRetried(c *C) {
+	// The retry waits for its deadline to be taken, which never happens, so
+	// the request is still kept when the client is closed.
+	cli, _, cleanup := newDesyncTSOClient(c, 1, false)
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := cli.GetTS(context.Background())
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cleanup()
+	select {
+	case err := <-errCh:
+		c.Assert(errors.Cause(err), Equals, errClosing)
+	case <-time.After(time.Second):
+		c.Fatal("the retried request is not finished")
+	}
+}
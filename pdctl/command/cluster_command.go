@@ -35,8 +35,8 @@ func NewClusterCommand() *cobra.Command {
 func showClusterCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, clusterPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get the cluster information: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get the cluster information: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
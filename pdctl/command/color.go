@@ -0,0 +1,50 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// ANSI color codes of the foreground.
+const (
+	colorRed    = 31
+	colorGreen  = 32
+	colorYellow = 33
+)
+
+// isTerminal is replaced in tests.
+var isTerminal = func(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether the output can be colorized, colors are
+// disabled by '--no-color' or if stdout is not a terminal.
+func colorEnabled(cmd *cobra.Command) bool {
+	if noColor, _ := cmd.Flags().GetBool("no-color"); noColor {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+func colorize(cmd *cobra.Command, s string, color int) string {
+	if !colorEnabled(cmd) {
+		return s
+	}
+	return fmt.Sprintf("\033[%dm%s\033[0m", color, s)
+}
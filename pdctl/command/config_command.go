@@ -14,19 +14,29 @@
 package command
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/juju/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPrefix    = "pd/api/v1/config"
-	schedulePrefix  = "pd/api/v1/config/schedule"
-	replicatePrefix = "pd/api/v1/config/replicate"
+	configPrefix        = "pd/api/v1/config"
+	configDefaultPrefix = "pd/api/v1/config/default"
+	configResetPrefix   = "pd/api/v1/config/reset/%s"
+	schedulePrefix      = "pd/api/v1/config/schedule"
+	replicatePrefix     = "pd/api/v1/config/replicate"
 )
 
 // NewConfigCommand return a config subcommand of rootCmd
@@ -36,7 +46,14 @@ func NewConfigCommand() *cobra.Command {
 		Short: "tune pd configs",
 	}
 	conf.AddCommand(NewShowConfigCommand())
+	conf.AddCommand(NewGetConfigCommand())
 	conf.AddCommand(NewSetConfigCommand())
+	conf.AddCommand(NewDefaultConfigCommand())
+	conf.AddCommand(NewDiffConfigCommand())
+	conf.AddCommand(NewResetConfigCommand())
+	conf.AddCommand(NewSetReplicasCommand())
+	conf.AddCommand(NewDumpConfigCommand())
+	conf.AddCommand(NewRestoreConfigCommand())
 	return conf
 }
 
@@ -61,6 +78,46 @@ func NewShowAllConfigCommand() *cobra.Command {
 	return sc
 }
 
+// NewDefaultConfigCommand return a default subcommand of configCmd
+func NewDefaultConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "default",
+		Short: "show the default schedule config of PD",
+		Run:   showDefaultConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewDiffConfigCommand return a diff subcommand of configCmd
+func NewDiffConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "diff",
+		Short: "show the schedule config which is changed from the default",
+		Run:   diffConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewResetConfigCommand return a reset subcommand of configCmd
+func NewResetConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "reset <option>",
+		Short: "set the option back to its default value",
+		Run:   resetConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewGetConfigCommand return a get subcommand of configCmd
+func NewGetConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "get <key>",
+		Short: "show the value of a config key, nested keys are joined by '.'",
+		Run:   getConfigCommandFunc,
+	}
+	return sc
+}
+
 // NewSetConfigCommand return a set subcommand of configCmd
 func NewSetConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
@@ -71,22 +128,202 @@ func NewSetConfigCommand() *cobra.Command {
 	return sc
 }
 
+// NewSetReplicasCommand return a set-replicas subcommand of configCmd
+func NewSetReplicasCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "set-replicas <n>",
+		Short: "set max-replicas, the number of replicas of each region",
+		Run:   setReplicasCommandFunc,
+	}
+	return sc
+}
+
+// NewDumpConfigCommand return a dump subcommand of configCmd
+func NewDumpConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "dump <file>",
+		Short: "dump the schedule and replication config, the schedulers and the store labels to a JSON file",
+		Run:   dumpConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewRestoreConfigCommand return a restore subcommand of configCmd
+func NewRestoreConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "restore the config dumped to the JSON file, the parts which no longer apply are skipped",
+		Run:   restoreConfigCommandFunc,
+	}
+	return sc
+}
+
 func showConfigCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, schedulePrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get config: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get config: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 func showAllConfigCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, configPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get config: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get config: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func showDefaultConfigCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, configDefaultPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get default config: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func diffConfigCommandFunc(cmd *cobra.Command, args []string) {
+	var def, cur map[string]interface{}
+	if err := getConfigJSON(cmd, configDefaultPrefix, &def); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get default config: %s\n", err)
+		return
+	}
+	if err := getConfigJSON(cmd, schedulePrefix, &cur); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get config: %s\n", err)
+		return
+	}
+	diffs := diffConfig("", def, cur)
+	if len(diffs) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No config is changed from the default")
+		return
+	}
+	for _, diff := range diffs {
+		fmt.Fprintln(cmd.OutOrStdout(), diff)
+	}
+}
+
+func getConfigJSON(cmd *cobra.Command, prefix string, v interface{}) error {
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(r), v)
+}
+
+// diffConfig returns the keys whose values are different from the default,
+// formatted as "key: old -> new". Nested keys are joined by '.'.
+func diffConfig(prefix string, def, cur map[string]interface{}) []string {
+	keys := make(map[string]struct{})
+	for k := range def {
+		keys[k] = struct{}{}
+	}
+	for k := range cur {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var diffs []string
+	for _, k := range sorted {
+		d, dok := def[k]
+		c, cok := cur[k]
+		dm, dIsMap := d.(map[string]interface{})
+		cm, cIsMap := c.(map[string]interface{})
+		if dIsMap && cIsMap {
+			diffs = append(diffs, diffConfig(prefix+k+".", dm, cm)...)
+			continue
+		}
+		if dok && cok && reflect.DeepEqual(d, c) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("%s%s: %s -> %s", prefix, k, formatConfigValue(d, dok), formatConfigValue(c, cok)))
+	}
+	return diffs
+}
+
+func formatConfigValue(v interface{}, ok bool) string {
+	if !ok {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// configSections are searched for a key which is not at the top level, they
+// have the keys accepted by 'config set'.
+var configSections = []string{"schedule", "replication"}
+
+// convertName converts a snake_case name to the hyphenated name of a config
+// key, such as max_replicas to max-replicas.
+func convertName(name string) string {
+	return strings.Replace(name, "_", "-", -1)
+}
+
+// getConfigValue returns the value of key in the config, nested keys are
+// joined by '.'.
+func getConfigValue(config map[string]interface{}, key string) (interface{}, bool) {
+	path := strings.Split(convertName(key), ".")
+	if _, ok := config[path[0]]; !ok && len(path) == 1 {
+		for _, section := range configSections {
+			if m, ok := config[section].(map[string]interface{}); ok {
+				if v, ok := m[path[0]]; ok {
+					return v, true
+				}
+			}
+		}
+		return nil, false
+	}
+	var v interface{} = config
+	for _, name := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func getConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: config get <key>")
+		return
+	}
+	r, err := doRequest(cmd, configPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get config: %s\n", err)
+		return
+	}
+	// The numbers are kept as they are, such as a large uint64.
+	var config map[string]interface{}
+	d := json.NewDecoder(strings.NewReader(r))
+	d.UseNumber()
+	if err = d.Decode(&config); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse config: %s\n", err)
+		return
+	}
+	v, ok := getConfigValue(config, args[0])
+	if !ok {
+		fmt.Fprintf(cmd.OutOrStdout(), "Unknown config key %q\n", args[0])
+		exitCode = 1
 		return
 	}
-	fmt.Println(r)
+	if s, ok := v.(string); ok {
+		fmt.Fprintln(cmd.OutOrStdout(), s)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), formatConfigValue(v, true))
 }
 
 func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
@@ -97,28 +334,335 @@ func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
 		val = value
 	}
 	data[key] = val
-	reqData, err := json.Marshal(data)
-	req, err := getRequest(cmd, path, http.MethodPost, "application/json", bytes.NewBuffer(reqData))
+	_, err = doPostJSON(cmd, path, data)
+	return err
+}
+
+func setConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+	opt, val := args[0], args[1]
+	err := postConfigDataWithPath(cmd, opt, val, configPrefix)
 	if err != nil {
-		return err
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set config: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func resetConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: config reset <option>")
+		return
 	}
-	_, err = dail(req)
+	r, err := doRequest(cmd, fmt.Sprintf(configResetPrefix, url.PathEscape(args[0])), http.MethodPost)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to reset config: %s\n", err)
+		return
+	}
+	var value map[string]interface{}
+	if err = json.Unmarshal([]byte(r), &value); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to reset config: %s\n", err)
+		return
+	}
+	v, ok := value[args[0]]
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! %s: %s\n", args[0], formatConfigValue(v, ok))
+}
+
+func setReplicasCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: config set-replicas <n>")
+		return
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "max-replicas should be a positive integer")
+		return
+	}
+	if n%2 == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Warning: %d replicas tolerate no more failures than %d, an odd number is preferred\n", n, n-1)
+	}
+	var old struct {
+		MaxReplicas int `json:"max-replicas"`
+	}
+	if err = getConfigJSON(cmd, replicatePrefix, &old); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get config: %s\n", err)
+		return
+	}
+	if err = postConfigDataWithPath(cmd, "max-replicas", args[0], configPrefix); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set config: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! max-replicas: %d -> %d\n", old.MaxReplicas, n)
+}
+
+// configBundle is the scheduling state dumped by 'config dump'.
+type configBundle struct {
+	Schedule    map[string]interface{}       `json:"schedule"`
+	Replication map[string]interface{}       `json:"replication"`
+	Schedulers  []string                     `json:"schedulers"`
+	StoreLabels map[uint64]map[string]string `json:"store_labels"`
+}
+
+// getConfigNumberJSON is like getConfigJSON, but keeps the numbers as they
+// are, such as a large uint64.
+func getConfigNumberJSON(cmd *cobra.Command, prefix string, v interface{}) error {
+	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
 		return err
 	}
-	return nil
+	return decodeNumberJSON(strings.NewReader(r), v)
 }
 
-func setConfigCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 2 {
-		fmt.Println(cmd.UsageString())
+func decodeNumberJSON(r io.Reader, v interface{}) error {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return errors.Trace(d.Decode(v))
+}
+
+func dumpConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: config dump <file>")
 		return
 	}
-	opt, val := args[0], args[1]
-	err := postConfigDataWithPath(cmd, opt, val, configPrefix)
+	var (
+		bundle configBundle
+		err    error
+	)
+	if err = getConfigNumberJSON(cmd, schedulePrefix, &bundle.Schedule); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get schedule config: %s\n", err)
+		exitCode = 1
+		return
+	}
+	if err = getConfigNumberJSON(cmd, replicatePrefix, &bundle.Replication); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get replication config: %s\n", err)
+		exitCode = 1
+		return
+	}
+	if err = getConfigJSON(cmd, schedulersPrefix, &bundle.Schedulers); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get schedulers: %s\n", err)
+		exitCode = 1
+		return
+	}
+	if bundle.StoreLabels, err = getStoreLabels(cmd); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		exitCode = 1
+		return
+	}
+	sort.Strings(bundle.Schedulers)
+
+	data, err := json.MarshalIndent(&bundle, "", "  ")
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to encode config: %s\n", err)
+		exitCode = 1
+		return
+	}
+	if err = ioutil.WriteFile(args[0], data, 0644); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to write config: %s\n", err)
+		exitCode = 1
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "schedule: %d keys\n", len(bundle.Schedule))
+	fmt.Fprintf(cmd.OutOrStdout(), "replication: %d keys\n", len(bundle.Replication))
+	fmt.Fprintf(cmd.OutOrStdout(), "schedulers: %d\n", len(bundle.Schedulers))
+	fmt.Fprintf(cmd.OutOrStdout(), "store labels: %d stores\n", len(bundle.StoreLabels))
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! The config is dumped to %s\n", args[0])
+}
+
+// restoreSummary counts the restored items of a section of configBundle.
+type restoreSummary struct {
+	changed, unchanged, skipped, failed int
+}
+
+func (s *restoreSummary) String() string {
+	str := fmt.Sprintf("%d changed, %d unchanged, %d skipped", s.changed, s.unchanged, s.skipped)
+	if s.failed > 0 {
+		str += fmt.Sprintf(", %d failed", s.failed)
+	}
+	return str
+}
+
+func restoreConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: config restore <file>")
+		return
+	}
+	f, err := os.Open(args[0])
 	if err != nil {
-		fmt.Printf("Failed to set config: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to read config: %s\n", err)
+		exitCode = 1
 		return
 	}
-	fmt.Println("Success!")
+	defer f.Close()
+	var bundle configBundle
+	if err = decodeNumberJSON(f, &bundle); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse config: %s\n", err)
+		exitCode = 1
+		return
+	}
+	// The stores are needed to tell whether the store labels and the
+	// schedulers of a store still apply.
+	labels, err := getStoreLabels(cmd)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		exitCode = 1
+		return
+	}
+
+	out := cmd.OutOrStdout()
+	summaries := []struct {
+		name    string
+		summary *restoreSummary
+	}{
+		{"schedule", restoreConfigSection(cmd, "schedule", schedulePrefix, bundle.Schedule)},
+		{"replication", restoreConfigSection(cmd, "replication", replicatePrefix, bundle.Replication)},
+		{"schedulers", restoreSchedulers(cmd, bundle.Schedulers, labels)},
+		{"store labels", restoreStoreLabels(cmd, bundle.StoreLabels, labels)},
+	}
+	for _, s := range summaries {
+		if s.summary == nil {
+			exitCode = 1
+			continue
+		}
+		if s.summary.failed > 0 {
+			exitCode = 1
+		}
+		fmt.Fprintf(out, "%s: %s\n", s.name, s.summary)
+	}
+}
+
+// restoreConfigSection sets the keys of the config section which are changed,
+// the keys unknown to the pd are skipped. It returns nil if the section can
+// not be restored at all.
+func restoreConfigSection(cmd *cobra.Command, name, prefix string, config map[string]interface{}) *restoreSummary {
+	out := cmd.OutOrStdout()
+	var current map[string]interface{}
+	if err := getConfigNumberJSON(cmd, prefix, &current); err != nil {
+		fmt.Fprintf(out, "Failed to get %s config: %s\n", name, err)
+		return nil
+	}
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	summary := &restoreSummary{}
+	changed := make(map[string]interface{})
+	for _, k := range keys {
+		cur, ok := current[k]
+		if !ok {
+			fmt.Fprintf(out, "Skip %s config %s: unknown key\n", name, k)
+			summary.skipped++
+			continue
+		}
+		if formatConfigValue(cur, true) == formatConfigValue(config[k], true) {
+			summary.unchanged++
+			continue
+		}
+		changed[k] = config[k]
+	}
+	if len(changed) == 0 {
+		return summary
+	}
+	if _, err := doPostJSON(cmd, prefix, changed); err != nil {
+		fmt.Fprintf(out, "Failed to restore %s config: %s\n", name, err)
+		summary.failed += len(changed)
+		return summary
+	}
+	summary.changed += len(changed)
+	return summary
+}
+
+// storeSchedulers are the schedulers added for a store, they are named as
+// "<name>-<store_id>".
+var storeSchedulers = []string{"grant-leader-scheduler", "evict-leader-scheduler"}
+
+// restoreSchedulers adds the schedulers which are missing, the ones for the
+// stores which are not found are skipped. The schedulers which are not in
+// the dump are not removed.
+func restoreSchedulers(cmd *cobra.Command, schedulers []string, stores map[uint64]map[string]string) *restoreSummary {
+	out := cmd.OutOrStdout()
+	var current []string
+	if err := getConfigJSON(cmd, schedulersPrefix, &current); err != nil {
+		fmt.Fprintf(out, "Failed to get schedulers: %s\n", err)
+		return nil
+	}
+	exists := make(map[string]bool, len(current))
+	for _, name := range current {
+		exists[name] = true
+	}
+
+	summary := &restoreSummary{}
+	for _, name := range schedulers {
+		if exists[name] {
+			summary.unchanged++
+			continue
+		}
+		input := map[string]interface{}{"name": name}
+		for _, s := range storeSchedulers {
+			if !strings.HasPrefix(name, s+"-") {
+				continue
+			}
+			storeID, err := strconv.ParseUint(strings.TrimPrefix(name, s+"-"), 10, 64)
+			if err != nil {
+				break
+			}
+			input["name"], input["store_id"] = s, storeID
+			break
+		}
+		if storeID, ok := input["store_id"].(uint64); ok {
+			if _, ok := stores[storeID]; !ok {
+				fmt.Fprintf(out, "Skip scheduler %s: store %d is not found\n", name, storeID)
+				summary.skipped++
+				continue
+			}
+		}
+		if _, err := doPostJSON(cmd, schedulersPrefix, input); err != nil {
+			fmt.Fprintf(out, "Failed to add scheduler %s: %s\n", name, err)
+			summary.failed++
+			continue
+		}
+		summary.changed++
+	}
+	return summary
+}
+
+// restoreStoreLabels replaces the labels of the stores which are changed, the
+// stores which are not found are skipped.
+func restoreStoreLabels(cmd *cobra.Command, labels, current map[uint64]map[string]string) *restoreSummary {
+	out := cmd.OutOrStdout()
+	ids := make([]uint64, 0, len(labels))
+	for id := range labels {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+
+	summary := &restoreSummary{}
+	for _, id := range ids {
+		cur, ok := current[id]
+		if !ok {
+			fmt.Fprintf(out, "Skip the labels of store %d: store is not found\n", id)
+			summary.skipped++
+			continue
+		}
+		if len(cur) == len(labels[id]) && (len(cur) == 0 || reflect.DeepEqual(cur, labels[id])) {
+			summary.unchanged++
+			continue
+		}
+		input := make(map[string]interface{}, len(labels[id]))
+		for k, v := range labels[id] {
+			input[k] = v
+		}
+		prefix := fmt.Sprintf(path.Join(storePrefix, "label"), strconv.FormatUint(id, 10)) + "?replace=true"
+		if _, err := doPostJSON(cmd, prefix, input); err != nil {
+			fmt.Fprintf(out, "Failed to restore the labels of store %d: %s\n", id, err)
+			summary.failed++
+			continue
+		}
+		summary.changed++
+	}
+	return summary
 }
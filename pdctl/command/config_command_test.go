@@ -0,0 +1,254 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestDiffConfig(c *C) {
+	var def, cur map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(`{
+  "max-snapshot-count": 3,
+  "max-store-down-time": "1h0m0s",
+  "location-labels": ["zone"],
+  "nested": {"a": 1, "b": {"c": "x"}},
+  "removed": true
+}`), &def), IsNil)
+	c.Assert(json.Unmarshal([]byte(`{
+  "max-snapshot-count": 5,
+  "max-store-down-time": "1h0m0s",
+  "location-labels": ["zone", "rack"],
+  "nested": {"a": 1, "b": {"c": "y"}},
+  "added": 1
+}`), &cur), IsNil)
+
+	c.Assert(diffConfig("", def, cur), DeepEquals, []string{
+		`added: <none> -> 1`,
+		`location-labels: ["zone"] -> ["zone","rack"]`,
+		`max-snapshot-count: 3 -> 5`,
+		`nested.b.c: "x" -> "y"`,
+		`removed: true -> <none>`,
+	})
+	c.Assert(diffConfig("", def, def), HasLen, 0)
+}
+
+func (s *testConfigSuite) TestGetConfig(c *C) {
+	origin := exitCode
+	defer func() { exitCode = origin }()
+	server := newTestServer(false, `{
+  "name": "pd",
+  "lease": 3,
+  "log": {"level": "info", "file": {"filename": ""}},
+  "schedule": {"max-snapshot-count": 3, "max-store-down-time": "1h0m0s"},
+  "replication": {"max-replicas": 3, "location-labels": ["zone", "rack"]}
+}`)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	for key, value := range map[string]string{
+		"name":      "pd",
+		"lease":     "3",
+		"log.level": "info",
+		"log.file":  `{"filename":""}`,
+		// The keys of 'config set' are found in the sections.
+		"max-store-down-time":         "1h0m0s",
+		"location_labels":             `["zone","rack"]`,
+		"replication.max_replicas":    "3",
+		"schedule.max-snapshot-count": "3",
+	} {
+		out.Reset()
+		exitCode = 0
+		getConfigCommandFunc(cmd, []string{key})
+		c.Assert(out.String(), Equals, value+"\n", Commentf("key %s", key))
+		c.Assert(exitCode, Equals, 0)
+	}
+
+	for _, key := range []string{"unknown", "log.unknown", "name.sub", "schedule.max-replicas"} {
+		out.Reset()
+		exitCode = 0
+		getConfigCommandFunc(cmd, []string{key})
+		c.Assert(out.String(), Equals, fmt.Sprintf("Unknown config key %q\n", key))
+		c.Assert(exitCode, Equals, 1)
+	}
+}
+
+func (s *testConfigSuite) TestResetConfig(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		if r.URL.Path != "/pd/api/v1/config/reset/region-schedule-limit" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `"unknown config key"`)
+			return
+		}
+		fmt.Fprint(w, `{"region-schedule-limit": 12}`)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	resetConfigCommandFunc(cmd, []string{"region-schedule-limit"})
+	c.Assert(out.String(), Equals, "Success! region-schedule-limit: 12\n")
+
+	out.Reset()
+	resetConfigCommandFunc(cmd, []string{"bogus-key"})
+	c.Assert(out.String(), Matches, `Failed to reset config: \[400\] "unknown config key"\n`)
+
+	out.Reset()
+	resetConfigCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Usage: config reset <option>\n")
+}
+
+func (s *testConfigSuite) TestSetReplicas(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/config/replicate":
+			fmt.Fprint(w, `{"max-replicas": 3, "location-labels": ""}`)
+		case "/pd/api/v1/config":
+			c.Assert(r.Method, Equals, http.MethodPost)
+			c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	setReplicasCommandFunc(cmd, []string{"5"})
+	c.Assert(out.String(), Equals, "Success! max-replicas: 3 -> 5\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"max-replicas": float64(5)})
+
+	out.Reset()
+	setReplicasCommandFunc(cmd, []string{"4"})
+	c.Assert(out.String(), Equals, "Warning: 4 replicas tolerate no more failures than 3, an odd number is preferred\n"+
+		"Success! max-replicas: 3 -> 4\n")
+
+	for _, arg := range []string{"0", "-1", "three"} {
+		out.Reset()
+		input = nil
+		setReplicasCommandFunc(cmd, []string{arg})
+		c.Assert(out.String(), Equals, "max-replicas should be a positive integer\n")
+		c.Assert(input, IsNil)
+	}
+}
+
+func (s *testConfigSuite) TestDumpRestoreConfig(c *C) {
+	origin := exitCode
+	defer func() { exitCode = origin }()
+	exitCode = 0
+	schedule := `{"max-snapshot-count": 3, "leader-schedule-limit": 64}`
+	schedulers := `["evict-leader-scheduler-2", "balance-leader-scheduler"]`
+	posts := make(map[string][]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var input map[string]interface{}
+			c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+			posts[r.URL.String()] = append(posts[r.URL.String()], input)
+			return
+		}
+		switch r.URL.Path {
+		case "/pd/api/v1/config/schedule":
+			fmt.Fprint(w, schedule)
+		case "/pd/api/v1/config/replicate":
+			fmt.Fprint(w, `{"max-replicas": 3, "location-labels": "zone"}`)
+		case "/pd/api/v1/schedulers":
+			fmt.Fprint(w, schedulers)
+		case "/pd/api/v1/stores":
+			fmt.Fprint(w, `{"count": 2, "stores": [{"store": {"id": 1, "labels": [{"key": "zone", "value": "z1"}]}}, {"store": {"id": 2}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	dir, err := ioutil.TempDir("", "pdctl_config")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.json")
+	dumpConfigCommandFunc(cmd, []string{file})
+	c.Assert(out.String(), Equals, "schedule: 2 keys\nreplication: 2 keys\nschedulers: 2\nstore labels: 2 stores\n"+
+		"Success! The config is dumped to "+file+"\n")
+	var bundle map[string]interface{}
+	data, err := ioutil.ReadFile(file)
+	c.Assert(err, IsNil)
+	c.Assert(json.Unmarshal(data, &bundle), IsNil)
+	c.Assert(bundle["schedulers"], DeepEquals, []interface{}{"balance-leader-scheduler", "evict-leader-scheduler-2"})
+	c.Assert(bundle["store_labels"], DeepEquals, map[string]interface{}{
+		"1": map[string]interface{}{"zone": "z1"},
+		"2": map[string]interface{}{},
+	})
+
+	// Nothing is changed by restoring the dump.
+	out.Reset()
+	restoreConfigCommandFunc(cmd, []string{file})
+	c.Assert(out.String(), Equals, "schedule: 0 changed, 2 unchanged, 0 skipped\n"+
+		"replication: 0 changed, 2 unchanged, 0 skipped\n"+
+		"schedulers: 0 changed, 2 unchanged, 0 skipped\n"+
+		"store labels: 0 changed, 2 unchanged, 0 skipped\n")
+	c.Assert(posts, HasLen, 0)
+	c.Assert(exitCode, Equals, 0)
+
+	schedule = `{"max-snapshot-count": 5, "leader-schedule-limit": 64}`
+	schedulers = `["balance-leader-scheduler"]`
+	c.Assert(ioutil.WriteFile(file, []byte(`{
+	  "schedule": {"max-snapshot-count": 3, "leader-schedule-limit": 64, "unknown-key": 1},
+	  "replication": {"max-replicas": 3},
+	  "schedulers": ["balance-leader-scheduler", "evict-leader-scheduler-2", "grant-leader-scheduler-5", "shuffle-leader-scheduler"],
+	  "store_labels": {"1": {"zone": "z1"}, "2": {"zone": "z2"}, "5": {}}
+	}`), 0644), IsNil)
+	out.Reset()
+	restoreConfigCommandFunc(cmd, []string{file})
+	c.Assert(out.String(), Equals, "Skip schedule config unknown-key: unknown key\n"+
+		"Skip scheduler grant-leader-scheduler-5: store 5 is not found\n"+
+		"Skip the labels of store 5: store is not found\n"+
+		"schedule: 1 changed, 1 unchanged, 1 skipped\n"+
+		"replication: 0 changed, 1 unchanged, 0 skipped\n"+
+		"schedulers: 2 changed, 1 unchanged, 1 skipped\n"+
+		"store labels: 1 changed, 1 unchanged, 1 skipped\n")
+	c.Assert(posts, DeepEquals, map[string][]map[string]interface{}{
+		"/pd/api/v1/config/schedule": {{"max-snapshot-count": float64(3)}},
+		"/pd/api/v1/schedulers": {
+			{"name": "evict-leader-scheduler", "store_id": float64(2)},
+			{"name": "shuffle-leader-scheduler"},
+		},
+		"/pd/api/v1/store/2/label?replace=true": {{"zone": "z2"}},
+	})
+
+	out.Reset()
+	restoreConfigCommandFunc(cmd, []string{filepath.Join(dir, "missing.json")})
+	c.Assert(out.String(), Matches, "Failed to read config: .*\n")
+	c.Assert(exitCode, Equals, 1)
+}
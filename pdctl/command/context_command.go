@@ -0,0 +1,189 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+	"github.com/spf13/cobra"
+)
+
+// ContextFlags are the flags which can be given by a context, they are reset
+// before each command so a context is applied again.
+var ContextFlags = []string{"pd", "cacert", "cert", "key", "timeout"}
+
+// contextConfig is the content of the pdctl config file, such as:
+//
+//	current-context = "prod"
+//
+//	[contexts.prod]
+//	endpoints = ["https://10.0.1.1:2379", "https://10.0.1.2:2379"]
+//	cacert = "/path/to/ca.pem"
+//	timeout = "5s"
+type contextConfig struct {
+	CurrentContext string                   `toml:"current-context"`
+	Contexts       map[string]*contextEntry `toml:"contexts"`
+}
+
+// contextEntry is a named set of connection settings.
+type contextEntry struct {
+	Endpoints []string `toml:"endpoints"`
+	CAPath    string   `toml:"cacert,omitempty"`
+	CertPath  string   `toml:"cert,omitempty"`
+	KeyPath   string   `toml:"key,omitempty"`
+	Timeout   string   `toml:"timeout,omitempty"`
+}
+
+// NewContextCommand returns a context subcommand of rootCmd
+func NewContextCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "context <subcommand>",
+		Short: "manage the named contexts in " + contextConfigPath(),
+	}
+	c.AddCommand(&cobra.Command{
+		Use:   "use <name>",
+		Short: "set the default context",
+		Run:   useContextCommandFunc,
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "list the contexts, the default one is marked by '*'",
+		Run:   listContextCommandFunc,
+	})
+	return c
+}
+
+func useContextCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: context use <name>")
+		return
+	}
+	cfg, err := loadContextConfig()
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to load contexts: %s\n", err)
+		return
+	}
+	if _, ok := cfg.Contexts[args[0]]; !ok {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to use context: context %q is not found\n", args[0])
+		return
+	}
+	cfg.CurrentContext = args[0]
+	if err = saveContextConfig(cfg); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to save contexts: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func listContextCommandFunc(cmd *cobra.Command, args []string) {
+	cfg, err := loadContextConfig()
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to load contexts: %s\n", err)
+		return
+	}
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mark := " "
+		if name == cfg.CurrentContext {
+			mark = "*"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\t%s\n", mark, name, strings.Join(cfg.Contexts[name].Endpoints, ","))
+	}
+}
+
+// ApplyContext sets the flags which are not given on the command line from
+// the context named by '--context', or the default context if it is empty.
+func ApplyContext(cmd *cobra.Command) error {
+	name, _ := cmd.Flags().GetString("context")
+	cfg, err := loadContextConfig()
+	if err != nil {
+		if name == "" {
+			// The config file is optional without '--context'.
+			return nil
+		}
+		return errors.Errorf("Failed to load contexts: %s", err)
+	}
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return errors.Errorf("context %q is not found in %s", name, contextConfigPath())
+	}
+	if ctx.Timeout != "" {
+		if _, err = time.ParseDuration(ctx.Timeout); err != nil {
+			return errors.Errorf("invalid timeout of context %q: %s", name, err)
+		}
+	}
+
+	values := map[string]string{
+		"pd":      strings.Join(ctx.Endpoints, ","),
+		"cacert":  ctx.CAPath,
+		"cert":    ctx.CertPath,
+		"key":     ctx.KeyPath,
+		"timeout": ctx.Timeout,
+	}
+	for _, flag := range ContextFlags {
+		if values[flag] == "" || cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err = cmd.Flags().Set(flag, values[flag]); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func contextConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".pd", "config")
+}
+
+func loadContextConfig() (*contextConfig, error) {
+	cfg := &contextConfig{}
+	if _, err := toml.DecodeFile(contextConfigPath(), cfg); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cfg, nil
+}
+
+func saveContextConfig(cfg *contextConfig) error {
+	path := contextConfigPath()
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = toml.NewEncoder(f).Encode(cfg); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	if err = f.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp, path))
+}
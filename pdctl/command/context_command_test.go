@@ -0,0 +1,111 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testContextSuite{})
+
+type testContextSuite struct {
+	home    string
+	oldHome string
+}
+
+const testContextConfig = `
+current-context = "a"
+
+[contexts.a]
+endpoints = ["http://a1:2379", "http://a2:2379"]
+timeout = "3s"
+
+[contexts.b]
+endpoints = ["https://b:2379"]
+cacert = "/b/ca.pem"
+`
+
+func (s *testContextSuite) SetUpTest(c *C) {
+	var err error
+	s.home, err = ioutil.TempDir("", "test_context")
+	c.Assert(err, IsNil)
+	s.oldHome = os.Getenv("HOME")
+	os.Setenv("HOME", s.home)
+}
+
+func (s *testContextSuite) TearDownTest(c *C) {
+	os.Setenv("HOME", s.oldHome)
+	os.RemoveAll(s.home)
+}
+
+func (s *testContextSuite) writeConfig(c *C, data string) {
+	c.Assert(os.MkdirAll(filepath.Join(s.home, ".pd"), 0700), IsNil)
+	c.Assert(ioutil.WriteFile(contextConfigPath(), []byte(data), 0600), IsNil)
+}
+
+func (s *testContextSuite) TestApplyContext(c *C) {
+	// No config file and no context is fine.
+	cmd := newTestCommand("http://127.0.0.1:2379", "")
+	c.Assert(ApplyContext(cmd), IsNil)
+	c.Assert(cmd.Flags().Set("context", "a"), IsNil)
+	c.Assert(ApplyContext(cmd), NotNil)
+
+	s.writeConfig(c, testContextConfig)
+
+	// The default context is used.
+	cmd = newTestCommand("http://127.0.0.1:2379", "")
+	c.Assert(ApplyContext(cmd), IsNil)
+	endpoints, err := getEndpoints(cmd)
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, DeepEquals, []string{"http://a1:2379", "http://a2:2379"})
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	c.Assert(err, IsNil)
+	c.Assert(timeout, Equals, 3*time.Second)
+
+	// The flags override the context.
+	cmd = newTestCommand("http://127.0.0.1:2379", "")
+	c.Assert(cmd.Flags().Set("context", "b"), IsNil)
+	c.Assert(cmd.Flags().Set("pd", "http://c:2379"), IsNil)
+	c.Assert(ApplyContext(cmd), IsNil)
+	pd, _ := cmd.Flags().GetString("pd")
+	c.Assert(pd, Equals, "http://c:2379")
+	caPath, _ := cmd.Flags().GetString("cacert")
+	c.Assert(caPath, Equals, "/b/ca.pem")
+
+	cmd = newTestCommand("http://127.0.0.1:2379", "")
+	c.Assert(cmd.Flags().Set("context", "unknown"), IsNil)
+	c.Assert(ApplyContext(cmd), ErrorMatches, ".*not found.*")
+}
+
+func (s *testContextSuite) TestUseContext(c *C) {
+	s.writeConfig(c, testContextConfig)
+
+	cmd := newTestCommand("", "")
+	useContextCommandFunc(cmd, []string{"unknown"})
+	cfg, err := loadContextConfig()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.CurrentContext, Equals, "a")
+
+	useContextCommandFunc(cmd, []string{"b"})
+	cfg, err = loadContextConfig()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.CurrentContext, Equals, "b")
+	c.Assert(cfg.Contexts, HasLen, 2)
+	c.Assert(cfg.Contexts["b"].CAPath, Equals, "/b/ca.pem")
+}
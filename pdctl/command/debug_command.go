@@ -0,0 +1,70 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+var slowRequestsPrefix = "pd/api/v1/debug/slow-requests"
+
+// NewDebugCommand return a debug subcommand of rootCmd
+func NewDebugCommand() *cobra.Command {
+	d := &cobra.Command{
+		Use:   "debug <subcommand>",
+		Short: "show the debug information of pd",
+	}
+	d.AddCommand(NewSlowRequestsDebugCommand())
+	return d
+}
+
+// NewSlowRequestsDebugCommand return a slow-requests subcommand of debugCmd
+func NewSlowRequestsDebugCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "slow-requests [--since <duration>] [--op kvget|txn]",
+		Short: "show the latest slow etcd requests of pd",
+		Run:   showSlowRequestsCommandFunc,
+	}
+	s.Flags().String("since", "", "only show the requests in the duration, such as 5m")
+	s.Flags().String("op", "", "only show the requests of the operation, kvget or txn")
+	return s
+}
+
+func showSlowRequestsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: debug slow-requests [--since <duration>] [--op kvget|txn]")
+		return
+	}
+	query := url.Values{}
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		query.Set("since", since)
+	}
+	if op, _ := cmd.Flags().GetString("op"); op != "" {
+		query.Set("op", op)
+	}
+	prefix := slowRequestsPrefix
+	if len(query) != 0 {
+		prefix += "?" + query.Encode()
+	}
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get slow requests: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
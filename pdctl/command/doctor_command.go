@@ -0,0 +1,326 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/spf13/cobra"
+)
+
+const etcdDBSizePrefix = "pd/api/v1/members/etcd/db"
+
+// doctorPingTimeout bounds each ping if '--timeout' is not set, an unreachable
+// address should not block the other checks.
+const doctorPingTimeout = 3 * time.Second
+
+// The etcd backend size is warned at 80% of the quota, etcd rejects writes
+// after it exceeds the quota.
+const etcdDBSizeWarnRatio = 0.8
+
+// The status of a check.
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+type checkResult struct {
+	status  string
+	message string
+	// hint tells how to fix a warning or a failure.
+	hint string
+}
+
+type doctorCheck struct {
+	name string
+	run  func(cmd *cobra.Command) *checkResult
+}
+
+var doctorChecks = []doctorCheck{
+	{"endpoints", checkEndpoints},
+	{"leader", checkLeader},
+	{"members", checkMembers},
+	{"stores", checkDownStores},
+	{"regions", checkMissingPeers},
+	{"etcd db", checkEtcdDBSize},
+}
+
+// NewDoctorCommand return a doctor subcommand of rootCmd
+func NewDoctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "check the health of the cluster and show the problems with hints",
+		Run:   doctorCommandFunc,
+	}
+}
+
+func doctorCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+	results := make([]*checkResult, 0, len(doctorChecks))
+	for _, check := range doctorChecks {
+		results = append(results, check.run(cmd))
+	}
+	if printDoctorReport(cmd, cmd.OutOrStdout(), results) {
+		exitCode = 1
+	}
+}
+
+// printDoctorReport prints a line for each check and a summary, it returns
+// true if any check fails.
+func printDoctorReport(cmd *cobra.Command, out io.Writer, results []*checkResult) bool {
+	counts := make(map[string]int)
+	for i, res := range results {
+		counts[res.status]++
+		status := res.status
+		switch status {
+		case checkPass:
+			status = colorize(cmd, status, colorGreen)
+		case checkWarn:
+			status = colorize(cmd, status, colorYellow)
+		default:
+			status = colorize(cmd, status, colorRed)
+		}
+		// Error responses usually end with a newline.
+		fmt.Fprintf(out, "%s  %s: %s\n", status, doctorChecks[i].name, strings.TrimSpace(res.message))
+		if res.status != checkPass && res.hint != "" {
+			fmt.Fprintf(out, "      hint: %s\n", res.hint)
+		}
+	}
+	fmt.Fprintf(out, "%d passed, %d warnings, %d failed\n", counts[checkPass], counts[checkWarn], counts[checkFail])
+	return counts[checkFail] > 0
+}
+
+// pingEndpoint pings the pd of the address rather than the pd endpoints.
+func pingEndpoint(cmd *cobra.Command, endpoint string) error {
+	client, err := getHTTPClient(cmd, endpoint)
+	if err != nil {
+		return err
+	}
+	if client.Timeout == 0 {
+		c := *client
+		c.Timeout = doctorPingTimeout
+		client = &c
+	}
+	req, err := getRequest(endpoint, pingPrefix, http.MethodGet, "", nil)
+	if err != nil {
+		return err
+	}
+	_, err = dail(client, req)
+	return err
+}
+
+func getJSON(cmd *cobra.Command, prefix string, v interface{}) error {
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	return errors.Trace(json.Unmarshal([]byte(r), v))
+}
+
+func checkEndpoints(cmd *cobra.Command) *checkResult {
+	endpoints, err := getEndpoints(cmd)
+	if err != nil {
+		return &checkResult{status: checkFail, message: err.Error(), hint: "check '-u' and '--endpoints-file'"}
+	}
+	var unreachable []string
+	for _, endpoint := range endpoints {
+		if err = pingEndpoint(cmd, endpoint); err != nil {
+			unreachable = append(unreachable, endpoint)
+		}
+	}
+	if len(unreachable) == len(endpoints) {
+		return &checkResult{
+			status:  checkFail,
+			message: fmt.Sprintf("none of %s is reachable: %s", strings.Join(endpoints, ", "), err),
+			hint:    "check the addresses, the network and whether pd-server is running",
+		}
+	}
+	if len(unreachable) > 0 {
+		return &checkResult{
+			status:  checkWarn,
+			message: fmt.Sprintf("%d of %d endpoints are unreachable: %s", len(unreachable), len(endpoints), strings.Join(unreachable, ", ")),
+			hint:    "remove the stale addresses from '-u' or '--endpoints-file', or check the pd-servers",
+		}
+	}
+	return &checkResult{status: checkPass, message: fmt.Sprintf("%d of %d endpoints are reachable", len(endpoints), len(endpoints))}
+}
+
+func checkLeader(cmd *cobra.Command) *checkResult {
+	leader := &memberInfo{}
+	if err := getJSON(cmd, leaderMemberPrefix, leader); err != nil || leader.Name == "" {
+		message := "no leader"
+		if err != nil {
+			message = fmt.Sprintf("failed to get the leader: %s", err)
+		}
+		return &checkResult{
+			status:  checkFail,
+			message: message,
+			hint:    "a majority of the members must be up to elect a leader, check the logs of pd-server",
+		}
+	}
+	return &checkResult{status: checkPass, message: fmt.Sprintf("%s is the leader", leader.Name)}
+}
+
+func checkMembers(cmd *cobra.Command) *checkResult {
+	members := &membersInfo{}
+	if err := getJSON(cmd, membersPrefix, members); err != nil {
+		return &checkResult{status: checkFail, message: fmt.Sprintf("failed to get the members: %s", err)}
+	}
+	var unhealthy []string
+	for _, m := range members.Members {
+		if len(m.ClientUrls) == 0 || !isMemberHealthy(cmd, strings.TrimSuffix(m.ClientUrls[0], "/")) {
+			unhealthy = append(unhealthy, m.Name)
+		}
+	}
+	total := len(members.Members)
+	if len(unhealthy) == 0 {
+		return &checkResult{status: checkPass, message: fmt.Sprintf("%d of %d members are healthy", total, total)}
+	}
+	res := &checkResult{
+		status:  checkWarn,
+		message: fmt.Sprintf("%d of %d members are unhealthy: %s", len(unhealthy), total, strings.Join(unhealthy, ", ")),
+		hint:    "restart the unhealthy members, or remove them with 'member delete' and add new ones",
+	}
+	// The cluster is unavailable if a majority is lost.
+	if len(unhealthy)*2 >= total {
+		res.status = checkFail
+	}
+	return res
+}
+
+func checkDownStores(cmd *cobra.Command) *checkResult {
+	var stores struct {
+		Stores []struct {
+			Store struct {
+				ID        uint64 `json:"id"`
+				StateName string `json:"state_name"`
+			} `json:"store"`
+		} `json:"stores"`
+	}
+	if err := getJSON(cmd, storesPrefix, &stores); err != nil {
+		return &checkResult{status: checkFail, message: fmt.Sprintf("failed to get the stores: %s", err)}
+	}
+	var down []string
+	for _, s := range stores.Stores {
+		if s.Store.StateName == "Down" {
+			down = append(down, strconv.FormatUint(s.Store.ID, 10))
+		}
+	}
+	if len(down) > 0 {
+		return &checkResult{
+			status:  checkFail,
+			message: fmt.Sprintf("%d of %d stores are down: %s", len(down), len(stores.Stores), strings.Join(down, ", ")),
+			hint:    "restart tikv-server on the stores, or delete them with 'store delete' if they are lost",
+		}
+	}
+	return &checkResult{status: checkPass, message: fmt.Sprintf("no store is down in %d stores", len(stores.Stores))}
+}
+
+func checkMissingPeers(cmd *cobra.Command) *checkResult {
+	var replication struct {
+		MaxReplicas int `json:"max-replicas"`
+	}
+	if err := getJSON(cmd, replicatePrefix, &replication); err != nil {
+		return &checkResult{status: checkFail, message: fmt.Sprintf("failed to get the replication config: %s", err)}
+	}
+	var regions struct {
+		Regions []*metapb.Region `json:"regions"`
+	}
+	if err := getJSON(cmd, regionsPrefix, &regions); err != nil {
+		return &checkResult{status: checkFail, message: fmt.Sprintf("failed to get the regions: %s", err)}
+	}
+	var missing int
+	for _, region := range regions.Regions {
+		if len(region.GetPeers()) < replication.MaxReplicas {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return &checkResult{
+			status:  checkWarn,
+			message: fmt.Sprintf("%d of %d regions have less than %d peers", missing, len(regions.Regions), replication.MaxReplicas),
+			hint:    "pd adds the missing peers, check 'operator show' and the stores if it does not recover",
+		}
+	}
+	return &checkResult{status: checkPass, message: fmt.Sprintf("all %d regions have %d peers", len(regions.Regions), replication.MaxReplicas)}
+}
+
+func checkEtcdDBSize(cmd *cobra.Command) *checkResult {
+	var info struct {
+		Quota   int64 `json:"quota"`
+		Members []struct {
+			Name   string `json:"name"`
+			DBSize int64  `json:"db_size"`
+			Error  string `json:"error"`
+		} `json:"members"`
+	}
+	if err := getJSON(cmd, etcdDBSizePrefix, &info); err != nil {
+		return &checkResult{status: checkFail, message: fmt.Sprintf("failed to get the etcd db size: %s", err)}
+	}
+	res := &checkResult{status: checkPass}
+	var max int64
+	var problems []string
+	for _, m := range info.Members {
+		if m.Error != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", m.Name, m.Error))
+			res.status = maxCheckStatus(res.status, checkWarn)
+			continue
+		}
+		if m.DBSize > max {
+			max = m.DBSize
+		}
+		if info.Quota == 0 {
+			continue
+		}
+		if ratio := float64(m.DBSize) / float64(info.Quota); ratio >= 1 {
+			problems = append(problems, fmt.Sprintf("%s exceeds the quota", m.Name))
+			res.status = checkFail
+		} else if ratio >= etcdDBSizeWarnRatio {
+			problems = append(problems, fmt.Sprintf("%s uses %.0f%% of the quota", m.Name, ratio*100))
+			res.status = maxCheckStatus(res.status, checkWarn)
+		}
+	}
+	quota := "no quota"
+	if info.Quota > 0 {
+		quota = fmt.Sprintf("quota %d bytes", info.Quota)
+	}
+	res.message = fmt.Sprintf("max db size %d bytes, %s", max, quota)
+	if len(problems) > 0 {
+		res.message += ", " + strings.Join(problems, ", ")
+		res.hint = "compact and defragment etcd, or raise quota-backend-bytes in the config of pd-server"
+	}
+	return res
+}
+
+func maxCheckStatus(a, b string) string {
+	if a == checkFail || b == checkFail {
+		return checkFail
+	}
+	if a == checkWarn || b == checkWarn {
+		return checkWarn
+	}
+	return checkPass
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testDoctorSuite{})
+
+type testDoctorSuite struct{}
+
+type mockDoctorCluster struct {
+	url       string
+	stores    string
+	regions   string
+	dbSize    int64
+	noLeader  bool
+	unhealthy bool
+}
+
+func (m *mockDoctorCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/pd/ping":
+	case "/health":
+		fmt.Fprintf(w, `{"health": "%v"}`, !m.unhealthy)
+	case "/pd/api/v1/leader":
+		if m.noLeader {
+			http.Error(w, "no leader", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"name": "pd1"}`)
+	case "/pd/api/v1/members":
+		fmt.Fprintf(w, `{"members": [{"name": "pd1", "client_urls": ["%s"]}]}`, m.url)
+	case "/pd/api/v1/stores":
+		fmt.Fprint(w, m.stores)
+	case "/pd/api/v1/config/replicate":
+		fmt.Fprint(w, `{"max-replicas": 3}`)
+	case "/pd/api/v1/regions":
+		fmt.Fprint(w, m.regions)
+	case "/pd/api/v1/members/etcd/db":
+		fmt.Fprintf(w, `{"quota": 100, "members": [{"name": "pd1", "db_size": %d}]}`, m.dbSize)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testDoctorSuite) TestDoctor(c *C) {
+	cluster := &mockDoctorCluster{
+		stores:  `{"count": 1, "stores": [{"store": {"id": 1, "state_name": "Up"}}]}`,
+		regions: `{"count": 1, "regions": [{"id": 2, "peers": [{"id": 3}, {"id": 4}, {"id": 5}]}]}`,
+		dbSize:  10,
+	}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	cluster.url = server.URL
+
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	defer ResetExitCode()
+
+	doctorCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "PASS  endpoints: 1 of 1 endpoints are reachable\n"+
+		"PASS  leader: pd1 is the leader\n"+
+		"PASS  members: 1 of 1 members are healthy\n"+
+		"PASS  stores: no store is down in 1 stores\n"+
+		"PASS  regions: all 1 regions have 3 peers\n"+
+		"PASS  etcd db: max db size 10 bytes, quota 100 bytes\n"+
+		"6 passed, 0 warnings, 0 failed\n")
+	c.Assert(ExitCode(), Equals, 0)
+
+	cluster.stores = `{"count": 2, "stores": [{"store": {"id": 1, "state_name": "Up"}}, {"store": {"id": 2, "state_name": "Down"}}]}`
+	cluster.regions = `{"count": 1, "regions": [{"id": 2, "peers": [{"id": 3}, {"id": 4}]}]}`
+	cluster.dbSize = 90
+	cluster.noLeader = true
+	cluster.unhealthy = true
+	out.Reset()
+	doctorCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "PASS  endpoints: 1 of 1 endpoints are reachable\n"+
+		"FAIL  leader: failed to get the leader: [500] no leader\n"+
+		"      hint: a majority of the members must be up to elect a leader, check the logs of pd-server\n"+
+		"FAIL  members: 1 of 1 members are unhealthy: pd1\n"+
+		"      hint: restart the unhealthy members, or remove them with 'member delete' and add new ones\n"+
+		"FAIL  stores: 1 of 2 stores are down: 2\n"+
+		"      hint: restart tikv-server on the stores, or delete them with 'store delete' if they are lost\n"+
+		"WARN  regions: 1 of 1 regions have less than 3 peers\n"+
+		"      hint: pd adds the missing peers, check 'operator show' and the stores if it does not recover\n"+
+		"WARN  etcd db: max db size 90 bytes, quota 100 bytes, pd1 uses 90% of the quota\n"+
+		"      hint: compact and defragment etcd, or raise quota-backend-bytes in the config of pd-server\n"+
+		"1 passed, 2 warnings, 3 failed\n")
+	c.Assert(ExitCode(), Equals, 1)
+}
+
+func (s *testDoctorSuite) TestUnreachableEndpoints(c *C) {
+	server := httptest.NewServer(&mockDoctorCluster{})
+	closedServer := httptest.NewServer(&mockDoctorCluster{})
+	closedServer.Close()
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL+","+closedServer.URL, "")
+	res := checkEndpoints(cmd)
+	c.Assert(res.status, Equals, checkWarn)
+	c.Assert(res.message, Equals, "1 of 2 endpoints are unreachable: "+closedServer.URL)
+
+	cmd = newTestCommand(closedServer.URL, "")
+	c.Assert(checkEndpoints(cmd).status, Equals, checkFail)
+}
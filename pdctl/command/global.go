@@ -15,53 +15,260 @@ package command
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/juju/errors"
 	"github.com/pingcap/pd/pd-client"
+	"github.com/pingcap/pd/pkg/apiutil"
 	"github.com/spf13/cobra"
 )
 
 var (
-	pdClient   pd.Client
-	dailClient = &http.Client{}
+	pdClient pd.Client
 
 	pingPrefix     = "pd/ping"
 	errInvalidAddr = errors.New("Invalid pd address, Cannot get connect to it")
+	errNotModified = errors.New("Not modified since the last request")
+
+	// etags caches the ETag of each url, it is only used in a watch session
+	// so the unchanged responses are not rendered again.
+	etags map[string]string
+
+	// exitCode is the exit code of the last command.
+	exitCode int
 )
 
-func getRequest(cmd *cobra.Command, prefix string, method string, bodyType string, body io.Reader) (*http.Request, error) {
+// ExitCode returns the exit code of the last command, it is non-zero if the
+// command failed in a way which scripts should notice.
+func ExitCode() int {
+	return exitCode
+}
+
+// ResetExitCode resets the exit code before running a command.
+func ResetExitCode() {
+	exitCode = 0
+}
+
+func getRequest(endpoint string, prefix string, method string, bodyType string, body io.Reader) (*http.Request, error) {
 	if method == "" {
 		method = http.MethodGet
 	}
-	url := getAddressFromCmd(cmd, prefix)
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequest(method, endpointURL(endpoint, prefix), body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", bodyType)
+	if etag, ok := etags[req.URL.String()]; ok && method == http.MethodGet {
+		req.Header.Set("If-None-Match", etag)
+	}
 	return req, err
 }
 
-func dail(req *http.Request) (string, error) {
+// unixSocketHost is the host in the URLs of the requests to a unix socket
+// endpoint, the connection is dialed to the socket whatever the host is.
+const unixSocketHost = "unix"
+
+// endpointURL returns the URL of path on the endpoint, the URL of a unix
+// socket endpoint is a http URL since the socket is dialed by the client.
+func endpointURL(endpoint string, path string) string {
+	if strings.HasPrefix(endpoint, "unix://") {
+		endpoint = "http://" + unixSocketHost
+	}
+	return fmt.Sprintf("%s/%s", endpoint, path)
+}
+
+// getHTTPClient returns the client to connect the endpoint, the TLS flags are
+// only used by https endpoints, so http and https endpoints can be mixed. The
+// requests of the client are traced if '--trace' is set.
+func getHTTPClient(cmd *cobra.Command, endpoint string) (*http.Client, error) {
+	client, err := newHTTPClient(cmd, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return traceClient(cmd, client), nil
+}
+
+func newHTTPClient(cmd *cobra.Command, endpoint string) (*http.Client, error) {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	var cfg transportConfig
+	cfg.idle, _ = cmd.Flags().GetDuration("idle-conn-timeout")
+	cfg.connect, _ = cmd.Flags().GetDuration("connect-timeout")
+	if strings.HasPrefix(endpoint, "unix://") {
+		t, err := getTransport(endpoint, cfg, func() (*http.Transport, error) {
+			return apiutil.NewUnixTransport(strings.TrimPrefix(endpoint, "unix://")), nil
+		})
+		return &http.Client{Transport: t, Timeout: timeout}, err
+	}
+	caPath, _ := cmd.Flags().GetString("cacert")
+	certPath, _ := cmd.Flags().GetString("cert")
+	keyPath, _ := cmd.Flags().GetString("key")
+	if !strings.HasPrefix(endpoint, "https://") || (caPath == "" && certPath == "" && keyPath == "") {
+		t, err := getTransport("", cfg, func() (*http.Transport, error) { return newDefaultTransport(), nil })
+		return &http.Client{Transport: t, Timeout: timeout}, err
+	}
+
+	key := strings.Join([]string{"https", caPath, certPath, keyPath}, ",")
+	t, err := getTransport(key, cfg, func() (*http.Transport, error) {
+		tlsConfig, err := newTLSConfig(caPath, certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Transport{TLSClientConfig: tlsConfig}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
+
+func newTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if caPath != "" {
+		ca, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return nil, errors.Errorf("Failed to read CA file: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("Failed to parse CA file %s", caPath)
+		}
+	}
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, errors.Errorf("Failed to load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// newDefaultTransport returns a transport with the same settings as
+// http.DefaultTransport, whose idle connections are never closed.
+func newDefaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// reapableTransport records when it is used last, so its idle connections
+// can be closed if it is not used any more.
+type reapableTransport struct {
+	*http.Transport
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+func (t *reapableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.lastUsed = time.Now()
+	t.mu.Unlock()
+	return t.Transport.RoundTrip(req)
+}
+
+func (t *reapableTransport) idleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Since(t.lastUsed)
+}
+
+var (
+	// transports are shared by the requests to the same kind of endpoints,
+	// so the connections are reused by the commands of a session. They are
+	// keyed by the unix socket or the TLS files, and the transportConfig.
+	transports   = make(map[string]*reapableTransport)
+	transportsMu sync.Mutex
+)
+
+// transportConfig holds the timeouts of a transport, 0 means no timeout.
+type transportConfig struct {
+	// idle is the timeout of the idle connections.
+	idle time.Duration
+	// connect bounds the dial and the TLS handshake of a connection, so a
+	// dead endpoint fails fast whatever the timeout of the request is.
+	connect time.Duration
+}
+
+// getTransport returns the transport of key, it is created by newTransport
+// with the timeouts of cfg if there is not one.
+func getTransport(key string, cfg transportConfig, newTransport func() (*http.Transport, error)) (*reapableTransport, error) {
+	key = fmt.Sprintf("%s/%s/%s", key, cfg.idle, cfg.connect)
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if t, ok := transports[key]; ok {
+		return t, nil
+	}
+	t, err := newTransport()
+	if err != nil {
+		return nil, err
+	}
+	t.IdleConnTimeout = cfg.idle
+	if cfg.connect > 0 {
+		t.DialContext = dialWithTimeout(t.DialContext, cfg.connect)
+		t.TLSHandshakeTimeout = cfg.connect
+	}
+	transports[key] = &reapableTransport{Transport: t, lastUsed: time.Now()}
+	return transports[key], nil
+}
+
+// dialWithTimeout returns a dial function which fails if dial does not
+// connect within timeout, a nil dial is the one of net.Dialer.
+func dialWithTimeout(dial func(ctx context.Context, network, addr string) (net.Conn, error),
+	timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, addr)
+	}
+}
+
+// reapIdleConnections closes the idle connections of the transports which
+// are not used for idle, such as the ones to an endpoint which is failed
+// over in a watch session.
+func reapIdleConnections(idle time.Duration) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	for _, t := range transports {
+		if t.idleFor() >= idle {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+func dail(client *http.Client, req *http.Request) (string, error) {
 	var res string
-	reps, err := dailClient.Do(req)
+	body, err := dailStream(client, req)
 	if err != nil {
 		return res, err
 	}
-	defer reps.Body.Close()
-	if reps.StatusCode != http.StatusOK {
-		return res, genResponseError(reps)
-	}
+	defer body.Close()
 
-	r, err := ioutil.ReadAll(reps.Body)
+	r, err := ioutil.ReadAll(body)
 	if err != nil {
 		return res, err
 	}
@@ -69,38 +276,190 @@ func dail(req *http.Request) (string, error) {
 	return res, nil
 }
 
+// dailStream sends the request and returns the body of a successful response,
+// the caller must close it.
+func dailStream(client *http.Client, req *http.Request) (io.ReadCloser, error) {
+	reps, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if reps.StatusCode == http.StatusNotModified {
+		reps.Body.Close()
+		return nil, errNotModified
+	}
+	if reps.StatusCode != http.StatusOK {
+		defer reps.Body.Close()
+		return nil, genResponseError(reps)
+	}
+	if etag := reps.Header.Get("ETag"); etags != nil && etag != "" {
+		etags[req.URL.String()] = etag
+	}
+	return reps.Body, nil
+}
+
 func doRequest(cmd *cobra.Command, prefix string, method string) (string, error) {
-	req, err := getRequest(cmd, prefix, method, "", nil)
+	return doRequestWithBody(cmd, prefix, method, "", nil)
+}
+
+// doRequestWithBody sends the request to the pd endpoints in order, and fails
+// over to the next one as shouldFailover decides.
+func doRequestWithBody(cmd *cobra.Command, prefix string, method string, bodyType string, body []byte) (string, error) {
+	endpoints, err := getEndpoints(cmd)
 	if err != nil {
 		return "", err
 	}
-	return dail(req)
+	for _, endpoint := range endpoints {
+		var req *http.Request
+		req, err = getRequest(endpoint, prefix, method, bodyType, bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		var client *http.Client
+		client, err = getHTTPClient(cmd, endpoint)
+		if err != nil {
+			return "", err
+		}
+		var res string
+		res, err = dail(client, req)
+		if shouldFailover(method, err) {
+			continue
+		}
+		return res, err
+	}
+	return "", err
+}
+
+// doRequestStream is like doRequest, but returns the response body without
+// reading it, so a large response can be copied to the output directly. The
+// caller must close the body.
+func doRequestStream(cmd *cobra.Command, prefix string, method string) (io.ReadCloser, error) {
+	endpoints, err := getEndpoints(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints {
+		var req *http.Request
+		req, err = getRequest(endpoint, prefix, method, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		var client *http.Client
+		client, err = getHTTPClient(cmd, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		var body io.ReadCloser
+		body, err = dailStream(client, req)
+		if shouldFailover(method, err) {
+			continue
+		}
+		return body, err
+	}
+	return nil, err
+}
+
+// shouldFailover reports whether a request should be sent to the next
+// endpoint after err. It is if the endpoint cannot be connected, or if a GET
+// request gets a 5xx status, such as from a leader which is stopping. The
+// other requests are not sent again after a 5xx status, since they may have
+// been applied.
+func shouldFailover(method string, err error) bool {
+	if _, ok := err.(*url.Error); ok {
+		return true
+	}
+	e, ok := err.(*responseError)
+	return ok && e.code >= http.StatusInternalServerError && (method == "" || method == http.MethodGet)
+}
+
+// watchCommand runs the command every '--watch' interval until it is
+// interrupted, it returns false if '--watch' is not set. A command should skip
+// rendering if its request returns errNotModified.
+func watchCommand(cmd *cobra.Command, args []string, run func(*cobra.Command, []string)) bool {
+	interval, _ := cmd.Flags().GetDuration("watch")
+	if interval <= 0 || etags != nil {
+		return false
+	}
+	etags = make(map[string]string)
+	for {
+		run(cmd, args)
+		// The polls are more than an interval apart, so a transport which
+		// is idle for an interval is not used by the last poll.
+		reapIdleConnections(interval)
+		time.Sleep(interval)
+	}
+}
+
+// responseError is the error of a response whose status is not 200.
+type responseError struct {
+	code int
+	body []byte
+}
+
+// Error returns the status and the message, which is taken from the body if
+// it is {"error": "...", "code": status}.
+func (e *responseError) Error() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(e.body, &body) == nil && body.Error != "" {
+		return fmt.Sprintf("[%d] %s", e.code, body.Error)
+	}
+	return fmt.Sprintf("[%d] %s", e.code, e.body)
 }
 
+// genResponseError returns the error of a failed response.
 func genResponseError(r *http.Response) error {
 	res, _ := ioutil.ReadAll(r.Body)
-	return errors.Errorf("[%d] %s", r.StatusCode, res)
+	return &responseError{code: r.StatusCode, body: res}
+}
+
+// printResponseError prints the status and the body of a failed response as
+// "[code]: body", so the error text of the server is shown as it is. The
+// other errors are printed as they are.
+func printResponseError(cmd *cobra.Command, err error) {
+	if e, ok := err.(*responseError); ok {
+		fmt.Fprintf(cmd.OutOrStdout(), "[%d]: %s\n", e.code, e.body)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), err)
 }
 
 // InitPDClient initialize pd client from cmd
 func InitPDClient(cmd *cobra.Command) error {
-	addr, err := cmd.Flags().GetString("pd")
-	if err != nil {
+	if _, err := getEndpoints(cmd); err != nil {
 		return err
 	}
 	log.SetOutput(ioutil.Discard)
 	if pdClient != nil {
 		return nil
 	}
-	err = validPDAddr(addr)
+	var err error
+	pdClient, err = newPDClient(cmd)
+	return err
+}
+
+// newPDClient creates a pd client with its own connection to the endpoints
+// of cmd.
+func newPDClient(cmd *cobra.Command) (pd.Client, error) {
+	endpoints, err := getEndpoints(cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	pdClient, err = pd.NewClient([]string{addr})
-	if err != nil {
-		return err
+	// Only reachable endpoints are used, pd client will discover the others.
+	var valid []string
+	for _, endpoint := range endpoints {
+		if err = validPDAddr(cmd, endpoint); err == nil {
+			valid = append(valid, endpoint)
+		}
 	}
-	return nil
+	if len(valid) == 0 {
+		return nil, err
+	}
+	connectTimeout, _ := cmd.Flags().GetDuration("connect-timeout")
+	if connectTimeout > 0 {
+		return pd.NewClient(valid, pd.WithConnectTimeout(connectTimeout))
+	}
+	return pd.NewClient(valid)
 }
 
 func getClient() (pd.Client, error) {
@@ -110,39 +469,130 @@ func getClient() (pd.Client, error) {
 	return pdClient, nil
 }
 
-func getAddressFromCmd(cmd *cobra.Command, prefix string) string {
+// getEndpoints returns the pd addresses given by the comma separated '-u'
+// flag, followed by the ones read from '--endpoints-file'.
+func getEndpoints(cmd *cobra.Command) ([]string, error) {
 	p, err := cmd.Flags().GetString("pd")
 	if err != nil {
-		fmt.Println("Get pd address error,should set flag with '-u'")
+		fmt.Fprintln(cmd.OutOrStdout(), "Get pd address error,should set flag with '-u'")
 		os.Exit(1)
 	}
+	addrs := splitEndpoints(p)
 
-	u, err := url.Parse(p)
+	if file, _ := cmd.Flags().GetString("endpoints-file"); file != "" {
+		fileAddrs, err := readEndpointsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, fileAddrs...)
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("No pd address is given, should set flag with '-u' or '--endpoints-file'")
+	}
+
+	scheme, err := getEndpointsScheme(cmd)
 	if err != nil {
-		fmt.Println("address is wrong format,should like 'http://127.0.0.1:2379'")
+		return nil, err
 	}
-	if u.Scheme == "" {
-		u.Scheme = "http"
+	endpoints := make([]string, 0, len(addrs))
+	seen := make(map[string]struct{})
+	for _, addr := range addrs {
+		endpoint, err := normalizeEndpoint(addr, scheme)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[endpoint]; ok {
+			continue
+		}
+		seen[endpoint] = struct{}{}
+		endpoints = append(endpoints, endpoint)
 	}
-	s := fmt.Sprintf("%s/%s", u, prefix)
-	return s
+	return endpoints, nil
 }
 
-func printResponseError(r *http.Response) {
-	fmt.Printf("[%d]:", r.StatusCode)
-	io.Copy(os.Stdout, r.Body)
+// getEndpointsScheme returns the scheme of the addresses without one, it is
+// '--endpoints-scheme' if set, otherwise https if '--cacert' is set or http.
+func getEndpointsScheme(cmd *cobra.Command) (string, error) {
+	scheme, _ := cmd.Flags().GetString("endpoints-scheme")
+	switch scheme {
+	case "http", "https":
+		return scheme, nil
+	case "":
+		if caPath, _ := cmd.Flags().GetString("cacert"); caPath != "" {
+			return "https", nil
+		}
+		return "http", nil
+	default:
+		return "", errors.Errorf("Invalid endpoints scheme %q, it should be http or https", scheme)
+	}
 }
 
-func validPDAddr(pd string) error {
-	u, err := url.Parse(pd)
+// normalizeEndpoint returns the address as "scheme://host:port", the scheme
+// is added if the address is a bare "host:port". A unix socket is given as
+// "unix:///path/to/sock".
+func normalizeEndpoint(addr string, scheme string) (string, error) {
+	if !strings.Contains(addr, "://") {
+		addr = scheme + "://" + addr
+	}
+	u, err := url.Parse(addr)
 	if err != nil {
-		return err
+		return "", errors.Errorf("address %q is wrong format,should like 'http://127.0.0.1:2379'", addr)
+	}
+	if u.Scheme == "unix" {
+		if u.Host != "" || u.Path == "" {
+			return "", errors.Errorf("address %q is wrong format, should like 'unix:///path/to/sock'", addr)
+		}
+		return "unix://" + u.Path, nil
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.Errorf("address %q is wrong format, the scheme should be http, https or unix", addr)
+	}
+	if u.Host == "" {
+		return "", errors.Errorf("address %q is wrong format, the host is missing", addr)
 	}
-	if u.Scheme == "" {
-		u.Scheme = "http"
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", errors.Errorf("address %q is wrong format, it should only have a scheme, host and port", addr)
 	}
-	addr := u.String()
-	reps, err := http.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// readEndpointsFile reads the newline or comma separated pd addresses from
+// file, blank lines and lines starting with '#' are ignored.
+func readEndpointsFile(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Errorf("Failed to read endpoints file: %s", err)
+	}
+	var addrs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, splitEndpoints(line)...)
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Errorf("No pd address is found in endpoints file %s", file)
+	}
+	return addrs, nil
+}
+
+func splitEndpoints(s string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func validPDAddr(cmd *cobra.Command, addr string) error {
+	client, err := getHTTPClient(cmd, addr)
+	if err != nil {
+		return err
+	}
+	reps, err := client.Get(endpointURL(addr, pingPrefix))
 	if err != nil {
 		return err
 	}
@@ -154,24 +604,127 @@ func validPDAddr(pd string) error {
 	return nil
 }
 
-func postJSON(cmd *cobra.Command, prefix string, input map[string]interface{}) {
-	data, err := json.Marshal(input)
-	if err != nil {
-		fmt.Println(err)
+// rawOutput reports whether '--raw' is set, it takes precedence over the
+// other output options of a command.
+func rawOutput(cmd *cobra.Command) bool {
+	raw, _ := cmd.Flags().GetBool("raw")
+	return raw
+}
+
+// checkEmptyResponse reports an empty body of a successful response to stderr
+// and sets a non-zero exit code, an empty body is not valid output of any
+// command and usually means a misconfigured endpoint. It returns false if
+// the body is not empty or '--raw' is set.
+func checkEmptyResponse(cmd *cobra.Command, r string) bool {
+	if rawOutput(cmd) || strings.TrimSpace(r) != "" {
+		return false
+	}
+	fmt.Fprintln(cmd.OutOrStderr(), "(empty response)")
+	exitCode = 1
+	return true
+}
+
+// printResponse writes the response body of a request, it is written
+// verbatim without a trailing newline if '--raw' is set.
+func printResponse(cmd *cobra.Command, r string) {
+	if checkEmptyResponse(cmd, r) {
 		return
 	}
+	if rawOutput(cmd) {
+		fmt.Fprint(cmd.OutOrStdout(), r)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), r)
+}
+
+// printNDJSON writes each element of the array named key in the JSON object
+// read from r as a line of compact JSON, the elements are decoded one by one
+// so a large response is not buffered. The whole object is written as one
+// line if key is empty.
+func printNDJSON(out io.Writer, r io.Reader, key string) error {
+	dec := json.NewDecoder(r)
+	if key == "" {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return errors.Trace(err)
+		}
+		return writeJSONLine(out, v)
+	}
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if name, _ := t.(string); name != key {
+			var skip json.RawMessage
+			if err = dec.Decode(&skip); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		t, err = dec.Token()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// The array is null if there is no element.
+		if t == nil {
+			continue
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			return errors.Errorf("%s is not an array", key)
+		}
+		for dec.More() {
+			var v json.RawMessage
+			if err = dec.Decode(&v); err != nil {
+				return errors.Trace(err)
+			}
+			if err = writeJSONLine(out, v); err != nil {
+				return err
+			}
+		}
+		if err = expectJSONDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectJSONDelim(dec, '}')
+}
 
-	url := getAddressFromCmd(cmd, prefix)
-	r, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
 	if err != nil {
-		fmt.Println(err)
-		return
+		return errors.Trace(err)
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return errors.Errorf("unexpected %v, expect %v", t, delim)
+	}
+	return nil
+}
+
+func writeJSONLine(out io.Writer, v json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, v); err != nil {
+		return errors.Trace(err)
+	}
+	buf.WriteByte('\n')
+	_, err := out.Write(buf.Bytes())
+	return errors.Trace(err)
+}
+
+func postJSON(cmd *cobra.Command, prefix string, input map[string]interface{}) {
+	if _, err := doPostJSON(cmd, prefix, input); err != nil {
+		printResponseError(cmd, err)
 	}
-	defer r.Body.Close()
+}
 
-	if r.StatusCode != http.StatusOK {
-		printResponseError(r)
+func doPostJSON(cmd *cobra.Command, prefix string, input map[string]interface{}) (string, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
 	}
+	return doRequestWithBody(cmd, prefix, http.MethodPost, "application/json", data)
 }
 
 // UsageTemplate will used to generate a help information
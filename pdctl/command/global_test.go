@@ -0,0 +1,375 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/spf13/cobra"
+)
+
+func TestCommand(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testGlobalSuite{})
+
+type testGlobalSuite struct{}
+
+func newTestCommand(endpoints string, caPath string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("pd", endpoints, "")
+	cmd.Flags().String("endpoints-file", "", "")
+	cmd.Flags().String("cacert", caPath, "")
+	cmd.Flags().String("cert", "", "")
+	cmd.Flags().String("key", "", "")
+	cmd.Flags().String("context", "", "")
+	cmd.Flags().Duration("timeout", 0, "")
+	cmd.Flags().Bool("no-color", false, "")
+	return cmd
+}
+
+func newTestServer(tls bool, body string) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+	if tls {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func (s *testGlobalSuite) TestMixedEndpoints(c *C) {
+	httpServer := newTestServer(false, "http")
+	defer httpServer.Close()
+	httpsServer := newTestServer(true, "https")
+	defer httpsServer.Close()
+	closedHTTPServer := newTestServer(false, "")
+	closedHTTPServer.Close()
+	closedHTTPSServer := newTestServer(true, "")
+	closedHTTPSServer.Close()
+
+	ca, err := ioutil.TempFile("", "test_ca")
+	c.Assert(err, IsNil)
+	defer os.Remove(ca.Name())
+	cert := httpsServer.TLS.Certificates[0].Certificate[0]
+	c.Assert(pem.Encode(ca, &pem.Block{Type: "CERTIFICATE", Bytes: cert}), IsNil)
+	c.Assert(ca.Close(), IsNil)
+
+	// Fail over from a http endpoint to a https endpoint.
+	cmd := newTestCommand(closedHTTPServer.URL+","+httpsServer.URL, ca.Name())
+	res, err := doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, "https")
+
+	// Fail over from a https endpoint to a http endpoint.
+	cmd = newTestCommand(closedHTTPSServer.URL+","+httpServer.URL, ca.Name())
+	res, err = doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, "http")
+
+	// The https endpoint is not trusted without the CA.
+	cmd = newTestCommand(httpsServer.URL, "")
+	_, err = doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, NotNil)
+}
+
+func (s *testGlobalSuite) TestReapIdleConnections(c *C) {
+	var mu sync.Mutex
+	conns := make(map[string]http.ConnState)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		conns[conn.RemoteAddr().String()] = state
+	}
+	server.Start()
+	defer server.Close()
+	states := func() map[http.ConnState]int {
+		mu.Lock()
+		defer mu.Unlock()
+		ret := make(map[http.ConnState]int)
+		for _, state := range conns {
+			ret[state]++
+		}
+		return ret
+	}
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Duration("idle-conn-timeout", time.Hour, "")
+	// The requests share the transport, so the connection is reused.
+	for i := 0; i < 3; i++ {
+		_, err := doRequest(cmd, "", http.MethodGet)
+		c.Assert(err, IsNil)
+	}
+	client, err := getHTTPClient(cmd, server.URL)
+	c.Assert(err, IsNil)
+	t := client.Transport.(*reapableTransport)
+	c.Assert(t.IdleConnTimeout, Equals, time.Hour)
+	c.Assert(states(), DeepEquals, map[http.ConnState]int{http.StateIdle: 1})
+
+	// The connection is kept if the transport is used recently.
+	reapIdleConnections(time.Minute)
+	c.Assert(states(), DeepEquals, map[http.ConnState]int{http.StateIdle: 1})
+	time.Sleep(10 * time.Millisecond)
+	reapIdleConnections(time.Millisecond)
+	for i := 0; i < 100 && states()[http.StateClosed] == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(states(), DeepEquals, map[http.ConnState]int{http.StateClosed: 1})
+}
+
+func (s *testGlobalSuite) TestConnectTimeout(c *C) {
+	hang := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	start := time.Now()
+	_, err := dialWithTimeout(hang, 10*time.Millisecond)(context.Background(), "tcp", "127.0.0.1:2379")
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start), Less, time.Second)
+
+	cmd := newTestCommand("", "")
+	cmd.Flags().Duration("connect-timeout", time.Second, "")
+	for _, endpoint := range []string{"http://127.0.0.1:2379", "unix:///tmp/pd.sock"} {
+		client, err := getHTTPClient(cmd, endpoint)
+		c.Assert(err, IsNil)
+		t := client.Transport.(*reapableTransport)
+		c.Assert(t.TLSHandshakeTimeout, Equals, time.Second)
+		c.Assert(t.DialContext, NotNil)
+	}
+	// The transports without the connect timeout are not changed.
+	client, err := getHTTPClient(newTestCommand("", ""), "http://127.0.0.1:2379")
+	c.Assert(err, IsNil)
+	c.Assert(client.Transport.(*reapableTransport).TLSHandshakeTimeout, Equals, 10*time.Second)
+}
+
+func (s *testGlobalSuite) TestETag(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		if r.Header.Get("If-None-Match") == `"1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "body")
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+
+	// ETags are not used out of a watch session.
+	for i := 0; i < 2; i++ {
+		res, err := doRequest(cmd, "", http.MethodGet)
+		c.Assert(err, IsNil)
+		c.Assert(res, Equals, "body")
+	}
+
+	etags = make(map[string]string)
+	defer func() { etags = nil }()
+	res, err := doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, "body")
+	_, err = doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, Equals, errNotModified)
+}
+
+func (s *testGlobalSuite) TestFailoverOnServerError(c *C) {
+	var posts int
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+		}
+		http.Error(w, `{"error": "no leader"}`, http.StatusInternalServerError)
+	}))
+	defer errServer.Close()
+	server := newTestServer(false, "body")
+	defer server.Close()
+	cmd := newTestCommand(errServer.URL+","+server.URL, "")
+
+	// A GET request is sent to the next endpoint after a 5xx status.
+	res, err := doRequest(cmd, "", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, "body")
+
+	// The other requests are not, since they may have been applied.
+	_, err = doPostJSON(cmd, "", map[string]interface{}{})
+	c.Assert(err, ErrorMatches, `\[500\] no leader`)
+	c.Assert(posts, Equals, 1)
+
+	// The failed response is printed as "[code]: body".
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	postJSON(cmd, "", map[string]interface{}{})
+	c.Assert(out.String(), Equals, "[500]: {\"error\": \"no leader\"}\n\n")
+}
+
+func (s *testGlobalSuite) TestRequestStream(c *C) {
+	server := newTestServer(false, "body")
+	defer server.Close()
+	closedServer := newTestServer(false, "")
+	closedServer.Close()
+
+	// Fail over to the next endpoint.
+	cmd := newTestCommand(closedServer.URL+","+server.URL, "")
+	body, err := doRequestStream(cmd, "", http.MethodGet)
+	c.Assert(err, IsNil)
+	res, err := ioutil.ReadAll(body)
+	c.Assert(err, IsNil)
+	c.Assert(string(res), Equals, "body")
+	c.Assert(body.Close(), IsNil)
+
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", http.StatusInternalServerError)
+	}))
+	defer errServer.Close()
+	cmd = newTestCommand(errServer.URL, "")
+	body, err = doRequestStream(cmd, "", http.MethodGet)
+	c.Assert(err, NotNil)
+	c.Assert(body, IsNil)
+}
+
+func (s *testGlobalSuite) TestPrintResponse(c *C) {
+	cmd := newTestCommand("", "")
+	cmd.Flags().Bool("raw", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	printResponse(cmd, "{\n  \"count\": 1\n}")
+	c.Assert(out.String(), Equals, "{\n  \"count\": 1\n}\n")
+
+	out.Reset()
+	defer ResetExitCode()
+	printResponse(cmd, "")
+	c.Assert(out.String(), Equals, "(empty response)\n")
+	c.Assert(ExitCode(), Equals, 1)
+
+	// The raw output is left untouched.
+	out.Reset()
+	ResetExitCode()
+	c.Assert(cmd.Flags().Set("raw", "true"), IsNil)
+	printResponse(cmd, "{\n  \"count\": 1\n}")
+	c.Assert(out.String(), Equals, "{\n  \"count\": 1\n}")
+	out.Reset()
+	printResponse(cmd, "")
+	c.Assert(out.String(), Equals, "")
+	c.Assert(ExitCode(), Equals, 0)
+}
+
+func (s *testGlobalSuite) TestPrintNDJSON(c *C) {
+	var out bytes.Buffer
+	body := `{"count": 2, "regions": [{"id": 1, "peers": [{"id": 2}]}, {"id": 3}], "next": "x"}`
+	c.Assert(printNDJSON(&out, strings.NewReader(body), "regions"), IsNil)
+	c.Assert(out.String(), Equals, "{\"id\":1,\"peers\":[{\"id\":2}]}\n{\"id\":3}\n")
+
+	out.Reset()
+	c.Assert(printNDJSON(&out, strings.NewReader(`{"count": 0, "regions": null}`), "regions"), IsNil)
+	c.Assert(out.String(), Equals, "")
+
+	out.Reset()
+	c.Assert(printNDJSON(&out, strings.NewReader("{\n  \"id\": 1\n}"), ""), IsNil)
+	c.Assert(out.String(), Equals, "{\"id\":1}\n")
+
+	c.Assert(printNDJSON(&out, strings.NewReader(`{"regions": 1}`), "regions"), ErrorMatches, "regions is not an array")
+	c.Assert(printNDJSON(&out, strings.NewReader(`[]`), "regions"), NotNil)
+	c.Assert(errors.Cause(printNDJSON(&out, strings.NewReader(""), "regions")), Equals, io.EOF)
+}
+
+func (s *testGlobalSuite) TestNormalizeEndpoints(c *C) {
+	cmd := newTestCommand("pd1:2379,http://pd2:2379/,https://pd3:2379", "")
+	cmd.Flags().String("endpoints-scheme", "", "")
+	endpoints, err := getEndpoints(cmd)
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, DeepEquals, []string{"http://pd1:2379", "http://pd2:2379", "https://pd3:2379"})
+
+	// The bare addresses are https if the CA is set.
+	c.Assert(cmd.Flags().Set("cacert", "ca.pem"), IsNil)
+	endpoints, err = getEndpoints(cmd)
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, DeepEquals, []string{"https://pd1:2379", "http://pd2:2379", "https://pd3:2379"})
+
+	// '--endpoints-scheme' overrides the CA.
+	c.Assert(cmd.Flags().Set("endpoints-scheme", "http"), IsNil)
+	endpoints, err = getEndpoints(cmd)
+	c.Assert(err, IsNil)
+	c.Assert(endpoints, DeepEquals, []string{"http://pd1:2379", "http://pd2:2379", "https://pd3:2379"})
+	c.Assert(cmd.Flags().Set("endpoints-scheme", "grpc"), IsNil)
+	_, err = getEndpoints(cmd)
+	c.Assert(err, ErrorMatches, `Invalid endpoints scheme "grpc".*`)
+
+	endpoint, err := normalizeEndpoint("unix:///tmp/pd.sock", "http")
+	c.Assert(err, IsNil)
+	c.Assert(endpoint, Equals, "unix:///tmp/pd.sock")
+
+	for _, addr := range []string{"pd1:2379/pd", "http://pd1:2379?a=b", "ftp://pd1:2379", "http://", "unix://pd.sock", "unix://"} {
+		_, err = normalizeEndpoint(addr, "http")
+		c.Assert(err, ErrorMatches, `address ".*" is wrong format.*`, Commentf("address %s", addr))
+	}
+}
+
+func (s *testGlobalSuite) TestUnixSocketEndpoint(c *C) {
+	dir, err := ioutil.TempDir("", "pdctl_unix")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pd.sock")
+	l, err := net.Listen("unix", path)
+	c.Assert(err, IsNil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	server.Listener.Close()
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	cmd := newTestCommand("unix://"+path, "")
+	r, err := doRequest(cmd, "pd/api/v1/stores", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(r, Equals, "/pd/api/v1/stores")
+	c.Assert(validPDAddr(cmd, "unix://"+path), IsNil)
+
+	// Fail over from a missing socket to the next endpoint.
+	httpServer := newTestServer(false, "http")
+	defer httpServer.Close()
+	cmd = newTestCommand(fmt.Sprintf("unix://%s/missing.sock,%s", dir, httpServer.URL), "")
+	r, err = doRequest(cmd, "pd/api/v1/stores", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(r, Equals, "http")
+}
+
+func (s *testGlobalSuite) TestResponseError(c *C) {
+	for _, body := range []string{`{"error":"invalid store ID 100, not found","code":500}`, "invalid store ID 100, not found"} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, body)
+		}))
+		_, err := doRequest(newTestCommand(server.URL, ""), "pd/api/v1/store/100", http.MethodGet)
+		server.Close()
+		c.Assert(err, ErrorMatches, `\[500\] invalid store ID 100, not found\n?`)
+	}
+}
@@ -49,10 +49,10 @@ func NewHotRegionCommand() *cobra.Command {
 func showHotRegionsCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, hotRegionsPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get hotspot: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get hotspot: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 // NewHotStoreCommand return a hot stores subcommand of hotSpotCmd
@@ -68,8 +68,8 @@ func NewHotStoreCommand() *cobra.Command {
 func showHotStoresCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, hotStoresPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get hotspot: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get hotspot: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
@@ -14,29 +14,69 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
 	"github.com/spf13/cobra"
 )
 
 var (
 	membersPrefix      = "pd/api/v1/members"
+	etcdMembersPrefix  = "pd/api/v1/members/etcd"
 	leaderMemberPrefix = "pd/api/v1/leader"
+
+	// Served by the embedded etcd on every member's client urls.
+	etcdHealthPath    = "health"
+	etcdSelfStatsPath = "v2/stats/self"
+
+	// leaderTransferTimeout is how long 'leader transfer' waits for the new
+	// leader, it is the TTL of the next leader directive of pd.
+	leaderTransferTimeout = 10 * time.Second
+	// leaderCheckInterval is how often the leader is checked during the
+	// wait, it is replaced in tests.
+	leaderCheckInterval = 500 * time.Millisecond
 )
 
 // NewMemberCommand return a member subcommand of rootCmd
 func NewMemberCommand() *cobra.Command {
 	m := &cobra.Command{
-		Use:   "member [leader|delete]",
+		Use:   "member [leader|delete|update|campaign|best|etcd-endpoints]",
 		Short: "show the pd member status",
 		Run:   showMemberCommandFunc,
 	}
 	m.AddCommand(NewLeaderMemberCommand())
 	m.AddCommand(NewDeleteMemberCommand())
+	m.AddCommand(NewUpdateMemberCommand())
+	m.AddCommand(NewCampaignMemberCommand())
+	m.AddCommand(NewBestMemberCommand())
+	m.AddCommand(NewEtcdEndpointsMemberCommand())
 	return m
 }
 
+// NewEtcdEndpointsMemberCommand return a etcd-endpoints subcommand of memberCmd
+func NewEtcdEndpointsMemberCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "etcd-endpoints",
+		Short: "show the etcd client and peer urls of each member",
+		Run:   showEtcdEndpointsCommandFunc,
+	}
+}
+
+// NewBestMemberCommand return a best subcommand of memberCmd
+func NewBestMemberCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "best",
+		Short: "show the recommended member endpoint to send requests to",
+		Run:   bestMemberCommandFunc,
+	}
+}
+
 // NewDeleteMemberCommand return a delete subcommand of memberCmd
 func NewDeleteMemberCommand() *cobra.Command {
 	d := &cobra.Command{
@@ -56,6 +96,29 @@ func NewDeleteMemberCommand() *cobra.Command {
 	return d
 }
 
+// NewUpdateMemberCommand return a update subcommand of memberCmd
+func NewUpdateMemberCommand() *cobra.Command {
+	u := &cobra.Command{
+		Use:   "update <member_name> --peer-urls <url>[,<url>...]",
+		Short: "update the peer urls of a member",
+		Run:   updateMemberCommandFunc,
+	}
+	u.Flags().String("peer-urls", "", "the new comma-separated peer urls")
+	return u
+}
+
+// NewCampaignMemberCommand return a campaign subcommand of memberCmd
+func NewCampaignMemberCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "campaign <member_name> --enable|--disable",
+		Short: "allow or disallow a member to campaign leader",
+		Run:   campaignMemberCommandFunc,
+	}
+	c.Flags().Bool("enable", false, "allow the member to campaign leader")
+	c.Flags().Bool("disable", false, "disallow the member to campaign leader, it resigns if it is the leader")
+	return c
+}
+
 // NewLeaderMemberCommand return a leader subcommand of memberCmd
 func NewLeaderMemberCommand() *cobra.Command {
 	d := &cobra.Command{
@@ -83,69 +146,303 @@ func NewLeaderMemberCommand() *cobra.Command {
 func showMemberCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get pd members: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get pd members: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
+}
+
+type etcdMemberInfo struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	ClientUrls []string `json:"client_urls"`
+	PeerUrls   []string `json:"peer_urls"`
+}
+
+func showEtcdEndpointsCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, etcdMembersPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get etcd members: %s\n", err)
+		return
+	}
+	members := make(map[string][]*etcdMemberInfo)
+	if err = json.Unmarshal([]byte(r), &members); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse etcd members: %s\n", err)
+		return
+	}
+	// The ids are in hex, the same as etcdctl.
+	for _, m := range members["members"] {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s (id: %x)\n", m.Name, m.MemberID)
+		fmt.Fprintf(cmd.OutOrStdout(), "  client urls: %s\n", strings.Join(m.ClientUrls, ","))
+		fmt.Fprintf(cmd.OutOrStdout(), "  peer urls: %s\n", strings.Join(m.PeerUrls, ","))
+	}
 }
 
 func deleteMemberByNameCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: member delete <member_name>")
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: member delete <member_name>")
 		return
 	}
 	prefix := membersPrefix + "/name/" + args[0]
 	_, err := doRequest(cmd, prefix, http.MethodDelete)
 	if err != nil {
-		fmt.Printf("Failed to delete member %s: %s\n", args[0], err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to delete member %s: %s\n", args[0], err)
 		return
 	}
-	fmt.Println("Success!")
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
 }
 
 func deleteMemberByIDCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: member delete id <member_id>")
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: member delete id <member_id>")
 		return
 	}
 	prefix := membersPrefix + "/id/" + args[0]
 	_, err := doRequest(cmd, prefix, http.MethodDelete)
 	if err != nil {
-		fmt.Printf("Failed to delete member %s: %s\n", args[0], err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to delete member %s: %s\n", args[0], err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func updateMemberCommandFunc(cmd *cobra.Command, args []string) {
+	peerUrls, _ := cmd.Flags().GetString("peer-urls")
+	if len(args) != 1 || peerUrls == "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: member update <member_name> --peer-urls <url>[,<url>...]")
+		return
+	}
+	urls, err := server.ParseUrls(peerUrls)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse peer urls: %s\n", err)
+		return
+	}
+	input := make([]string, 0, len(urls))
+	for _, u := range urls {
+		input = append(input, u.String())
+	}
+	prefix := membersPrefix + "/name/" + args[0]
+	if _, err = doPostJSON(cmd, prefix, map[string]interface{}{"peer_urls": input}); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to update member %s: %s\n", args[0], err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+	// Show the members to confirm the change.
+	showEtcdEndpointsCommandFunc(cmd, nil)
+}
+
+func campaignMemberCommandFunc(cmd *cobra.Command, args []string) {
+	enable, _ := cmd.Flags().GetBool("enable")
+	disable, _ := cmd.Flags().GetBool("disable")
+	if len(args) != 1 || enable == disable {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: member campaign <member_name> --enable|--disable")
+		return
+	}
+	prefix := membersPrefix + "/name/" + args[0] + "/campaign"
+	if _, err := doPostJSON(cmd, prefix, map[string]interface{}{"enable": enable}); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set campaign of member %s: %s\n", args[0], err)
 		return
 	}
-	fmt.Println("Success!")
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
 }
 
 func getLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, leaderMemberPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get the leader of pd members: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get the leader of pd members: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 func resignLeaderCommandFunc(cmd *cobra.Command, args []string) {
 	prefix := leaderMemberPrefix + "/resign"
 	_, err := doRequest(cmd, prefix, http.MethodPost)
 	if err != nil {
-		fmt.Printf("Failed to resign: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to resign: %s\n", err)
 		return
 	}
-	fmt.Println("Success!")
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
 }
 
 func transferPDLeaderCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: leader transfer <member_name>")
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: leader transfer <member_name>")
 		return
 	}
-	prefix := leaderMemberPrefix + "/transfer/" + args[0]
-	_, err := doRequest(cmd, prefix, http.MethodPost)
+	name := args[0]
+	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to trasfer leadership: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get pd members: %s\n", err)
+		return
+	}
+	members := &membersInfo{}
+	if err = json.Unmarshal([]byte(r), members); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse pd members: %s\n", err)
+		return
+	}
+	var target *memberInfo
+	for _, m := range members.Members {
+		if m.Name == name {
+			target = m
+		}
+	}
+	if target == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leadership: member %s not found\n", name)
 		return
 	}
-	fmt.Println("Success!")
+	// The next leader must be able to campaign, or no one is the leader until
+	// the directive expires.
+	if len(target.ClientUrls) == 0 || !isMemberHealthy(cmd, strings.TrimSuffix(target.ClientUrls[0], "/")) {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leadership: member %s is not healthy\n", name)
+		return
+	}
+	leader, err := getLeaderName(cmd)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get the leader of pd members: %s\n", err)
+		return
+	}
+	if leader == name {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is already the leader\n", name)
+		return
+	}
+
+	prefix := leaderMemberPrefix + "/transfer/" + name
+	if _, err = doRequest(cmd, prefix, http.MethodPost); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leadership: %s\n", err)
+		return
+	}
+	leader, err = waitLeaderChange(cmd, leader, leaderTransferTimeout)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to wait for the new leader: %s\n", err)
+		return
+	}
+	if leader != name {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leadership: %s is the leader instead of %s\n", leader, name)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! The leader is %s\n", leader)
+}
+
+// getLeaderName returns the name of the pd leader, it is empty if there is
+// no leader.
+func getLeaderName(cmd *cobra.Command) (string, error) {
+	r, err := doRequest(cmd, leaderMemberPrefix, http.MethodGet)
+	if err != nil {
+		return "", err
+	}
+	leader := &memberInfo{}
+	if err = json.Unmarshal([]byte(r), leader); err != nil {
+		return "", err
+	}
+	return leader.Name, nil
+}
+
+// waitLeaderChange waits until there is a leader other than old, the errors
+// during the election are ignored until the timeout.
+func waitLeaderChange(cmd *cobra.Command, old string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		leader, err := getLeaderName(cmd)
+		if err == nil && leader != "" && leader != old {
+			return leader, nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = errors.Errorf("the leader is not changed in %s", timeout)
+			}
+			return "", err
+		}
+		time.Sleep(leaderCheckInterval)
+	}
+}
+
+type memberInfo struct {
+	Name       string   `json:"name"`
+	ClientUrls []string `json:"client_urls"`
+}
+
+type membersInfo struct {
+	Members []*memberInfo `json:"members"`
+}
+
+func bestMemberCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get pd members: %s\n", err)
+		return
+	}
+	members := &membersInfo{}
+	if err = json.Unmarshal([]byte(r), members); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse pd members: %s\n", err)
+		return
+	}
+
+	// Prefer the healthy etcd leader, then fall back to any healthy member.
+	var best string
+	for _, m := range members.Members {
+		if len(m.ClientUrls) == 0 {
+			continue
+		}
+		endpoint := strings.TrimSuffix(m.ClientUrls[0], "/")
+		if !isMemberHealthy(cmd, endpoint) {
+			continue
+		}
+		if isEtcdLeader(cmd, endpoint) {
+			best = endpoint
+			break
+		}
+		if best == "" {
+			best = endpoint
+		}
+	}
+	if best == "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Failed to find a healthy member")
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), best)
+}
+
+func getMemberPath(cmd *cobra.Command, endpoint string, path string) ([]byte, error) {
+	client, err := getHTTPClient(cmd, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Get(endpointURL(endpoint, path))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, genResponseError(resp)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func isMemberHealthy(cmd *cobra.Command, endpoint string) bool {
+	body, err := getMemberPath(cmd, endpoint, etcdHealthPath)
+	if err != nil {
+		return false
+	}
+	var health struct {
+		Health string `json:"health"`
+	}
+	if err = json.Unmarshal(body, &health); err != nil {
+		return false
+	}
+	return health.Health == "true"
+}
+
+func isEtcdLeader(cmd *cobra.Command, endpoint string) bool {
+	body, err := getMemberPath(cmd, endpoint, etcdSelfStatsPath)
+	if err != nil {
+		return false
+	}
+	var stats struct {
+		State string `json:"state"`
+	}
+	if err = json.Unmarshal(body, &stats); err != nil {
+		return false
+	}
+	return stats.State == "StateLeader"
 }
@@ -0,0 +1,155 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testMemberSuite{})
+
+type testMemberSuite struct{}
+
+func (s *testMemberSuite) TestUpdateMember(c *C) {
+	var input map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/members/name/pd1":
+			c.Assert(r.Method, Equals, http.MethodPost)
+			c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+			fmt.Fprint(w, `"updated, pd: pd1"`)
+		case "/pd/api/v1/members/etcd":
+			fmt.Fprint(w, `{"members": [{"name": "pd1", "member_id": 26, "client_urls": ["http://10.0.1.1:2379"], "peer_urls": ["http://10.0.1.1:2380"]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("peer-urls", "", "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	c.Assert(cmd.Flags().Set("peer-urls", "http://10.0.1.1:2380"), IsNil)
+	updateMemberCommandFunc(cmd, []string{"pd1"})
+	c.Assert(input, DeepEquals, map[string][]string{"peer_urls": {"http://10.0.1.1:2380"}})
+	c.Assert(out.String(), Equals, "Success!\n"+
+		"pd1 (id: 1a)\n"+
+		"  client urls: http://10.0.1.1:2379\n"+
+		"  peer urls: http://10.0.1.1:2380\n")
+
+	// Invalid urls are not sent.
+	input = nil
+	out.Reset()
+	c.Assert(cmd.Flags().Set("peer-urls", "10.0.1.1:2380"), IsNil)
+	updateMemberCommandFunc(cmd, []string{"pd1"})
+	c.Assert(input, IsNil)
+	c.Assert(out.String(), Matches, "Failed to parse peer urls: .*\n")
+}
+
+func (s *testMemberSuite) TestCampaignMember(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/members/name/pd1/campaign")
+		c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		fmt.Fprint(w, `"updated, pd: pd1"`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("enable", false, "")
+	cmd.Flags().Bool("disable", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	// Exactly one of the flags is required.
+	campaignMemberCommandFunc(cmd, []string{"pd1"})
+	c.Assert(out.String(), Equals, "Usage: member campaign <member_name> --enable|--disable\n")
+	c.Assert(input, IsNil)
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("disable", "true"), IsNil)
+	campaignMemberCommandFunc(cmd, []string{"pd1"})
+	c.Assert(out.String(), Equals, "Success!\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"enable": false})
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("disable", "false"), IsNil)
+	c.Assert(cmd.Flags().Set("enable", "true"), IsNil)
+	campaignMemberCommandFunc(cmd, []string{"pd1"})
+	c.Assert(out.String(), Equals, "Success!\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"enable": true})
+}
+
+func (s *testMemberSuite) TestTransferLeader(c *C) {
+	var (
+		mu          sync.Mutex
+		leader      = "pd1"
+		transferred []string
+	)
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/pd/api/v1/members":
+			fmt.Fprintf(w, `{"members": [{"name": "pd1", "client_urls": ["%s"]}, {"name": "pd2", "client_urls": ["%s/"]}, {"name": "pd3"}]}`, serverURL, serverURL)
+		case r.URL.Path == "/health":
+			fmt.Fprint(w, `{"health": "true"}`)
+		case r.URL.Path == "/pd/api/v1/leader":
+			fmt.Fprintf(w, `{"name": "%s"}`, leader)
+		case strings.HasPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/"):
+			c.Assert(r.Method, Equals, http.MethodPost)
+			leader = path.Base(r.URL.Path)
+			transferred = append(transferred, leader)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL = server.URL
+	defer func(interval time.Duration) { leaderCheckInterval = interval }(leaderCheckInterval)
+	leaderCheckInterval = time.Millisecond
+
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	transferPDLeaderCommandFunc(cmd, []string{"pd2"})
+	c.Assert(out.String(), Equals, "Success! The leader is pd2\n")
+
+	for _, t := range []struct {
+		name   string
+		output string
+	}{
+		{"pd2", "pd2 is already the leader\n"},
+		{"pd3", "Failed to transfer leadership: member pd3 is not healthy\n"},
+		{"pd4", "Failed to transfer leadership: member pd4 not found\n"},
+	} {
+		out.Reset()
+		transferPDLeaderCommandFunc(cmd, []string{t.name})
+		c.Assert(out.String(), Equals, t.output)
+	}
+	c.Assert(transferred, DeepEquals, []string{"pd2"})
+}
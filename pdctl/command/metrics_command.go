@@ -0,0 +1,56 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Served by the embedded etcd with all metrics of pd.
+const metricsPrefix = "metrics"
+
+// NewMetricsCommand return a metrics subcommand of rootCmd
+func NewMetricsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metrics [<substring>]",
+		Short: "show the prometheus metrics of pd, only the lines containing the substring if it is given",
+		Run:   showMetricsCommandFunc,
+	}
+	return cmd
+}
+
+func showMetricsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: metrics [<substring>]")
+		return
+	}
+	r, err := doRequest(cmd, metricsPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get metrics: %s\n", err)
+		return
+	}
+	if len(args) == 0 {
+		fmt.Fprint(cmd.OutOrStdout(), r)
+		return
+	}
+	for _, line := range strings.Split(r, "\n") {
+		if strings.Contains(line, args[0]) {
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testMetricsSuite{})
+
+type testMetricsSuite struct{}
+
+func (s *testMetricsSuite) TestShowMetrics(c *C) {
+	metrics := "# HELP pd_txn_txns_count Counter of txns.\n" +
+		"# TYPE pd_txn_txns_count counter\n" +
+		"pd_txn_txns_count{result=\"success\"} 12\n" +
+		"pd_server_tso{type=\"save\"} 81\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/metrics")
+		fmt.Fprint(w, metrics)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	showMetricsCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, metrics)
+
+	out.Reset()
+	showMetricsCommandFunc(cmd, []string{"txn"})
+	c.Assert(out.String(), Equals, ""+
+		"# HELP pd_txn_txns_count Counter of txns.\n"+
+		"# TYPE pd_txn_txns_count counter\n"+
+		"pd_txn_txns_count{result=\"success\"} 12\n")
+}
@@ -14,7 +14,9 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -41,10 +43,12 @@ func NewOperatorCommand() *cobra.Command {
 // NewShowOperatorCommand returns a command to show operators.
 func NewShowOperatorCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "show [kind]",
+		Use:   "show [kind] [--progress] [--explain]",
 		Short: "show operators",
 		Run:   showOperatorCommandFunc,
 	}
+	c.Flags().Bool("progress", false, "only show the progress and the estimated remaining time of operators")
+	c.Flags().Bool("explain", false, "only show the reason why each operator is created")
 	return c
 }
 
@@ -55,16 +59,82 @@ func showOperatorCommandFunc(cmd *cobra.Command, args []string) {
 	} else if len(args) == 1 {
 		path = fmt.Sprintf("%s?kind=%s", operatorsPrefix, args[0])
 	} else {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	r, err := doRequest(cmd, path, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
-	fmt.Println(r)
+	if progress, _ := cmd.Flags().GetBool("progress"); progress && !rawOutput(cmd) {
+		if err = printOperatorProgress(cmd.OutOrStdout(), r); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse operators: %s\n", err)
+		}
+		return
+	}
+	if explain, _ := cmd.Flags().GetBool("explain"); explain && !rawOutput(cmd) {
+		if err = printOperatorReasons(cmd.OutOrStdout(), r); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse operators: %s\n", err)
+		}
+		return
+	}
+	printResponse(cmd, r)
+}
+
+type operatorProgressInfo struct {
+	Name   string `json:"name"`
+	Region struct {
+		ID uint64 `json:"id"`
+	} `json:"region"`
+	State              string            `json:"state"`
+	Index              int               `json:"index"`
+	Ops                []json.RawMessage `json:"ops"`
+	Progress           float64           `json:"progress"`
+	EstimatedRemaining *string           `json:"estimated_remaining"`
+}
+
+// printOperatorProgress prints a line for each operator, such as
+// "region 1 region_operator running: 1/3 steps (33%), 2m0s remaining".
+func printOperatorProgress(out io.Writer, r string) error {
+	var ops []*operatorProgressInfo
+	if err := json.Unmarshal([]byte(r), &ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		remaining := "unknown"
+		if op.EstimatedRemaining != nil {
+			remaining = *op.EstimatedRemaining
+		}
+		fmt.Fprintf(out, "region %d %s %s: %d/%d steps (%.0f%%), %s remaining\n", op.Region.ID, op.Name, op.State,
+			op.Index, len(op.Ops), op.Progress*100, remaining)
+	}
+	return nil
+}
+
+// printOperatorReasons prints a line for each operator, such as
+// "region 1 balance-leader: store 1 has 10 leaders ...". The operators added
+// by 'operator add' have no reason.
+func printOperatorReasons(out io.Writer, r string) error {
+	var ops []*struct {
+		Name   string `json:"name"`
+		Region struct {
+			ID uint64 `json:"id"`
+		} `json:"region"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(r), &ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		reason := op.Reason
+		if reason == "" {
+			reason = "no reason recorded"
+		}
+		fmt.Fprintf(out, "region %d %s: %s\n", op.Region.ID, op.Name, reason)
+	}
+	return nil
 }
 
 // NewAddOperatorCommand returns a command to add operators.
@@ -76,6 +146,8 @@ func NewAddOperatorCommand() *cobra.Command {
 	c.AddCommand(NewTransferLeaderCommand())
 	c.AddCommand(NewTransferRegionCommand())
 	c.AddCommand(NewTransferPeerCommand())
+	c.AddCommand(NewAddPeerCommand())
+	c.AddCommand(NewRemovePeerCommand())
 	return c
 }
 
@@ -91,13 +163,13 @@ func NewTransferLeaderCommand() *cobra.Command {
 
 func transferLeaderCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 2 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 
@@ -120,13 +192,13 @@ func NewTransferRegionCommand() *cobra.Command {
 
 func transferRegionCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) <= 2 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 
@@ -149,13 +221,13 @@ func NewTransferPeerCommand() *cobra.Command {
 
 func transferPeerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 3 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	ids, err := parseUint64s(args)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 
@@ -167,6 +239,49 @@ func transferPeerCommandFunc(cmd *cobra.Command, args []string) {
 	postJSON(cmd, operatorsPrefix, input)
 }
 
+// NewAddPeerCommand returns a command to add region peer.
+func NewAddPeerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add-peer <region_id> <to_store_id>",
+		Short: "add a region peer on specified store",
+		Run:   changePeerCommandFunc,
+	}
+	return c
+}
+
+// NewRemovePeerCommand returns a command to remove region peer.
+func NewRemovePeerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "remove-peer <region_id> <from_store_id>",
+		Short: "remove a region peer on specified store",
+		Run:   changePeerCommandFunc,
+	}
+	return c
+}
+
+func changePeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["store_id"] = ids[1]
+	if _, err = doPostJSON(cmd, operatorsPrefix, input); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to add operator: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! The operator is added to region %d\n", ids[0])
+}
+
 // NewRemoveOperatorCommand returns a command to remove operators.
 func NewRemoveOperatorCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -179,14 +294,14 @@ func NewRemoveOperatorCommand() *cobra.Command {
 
 func removeOperatorCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	path := operatorsPrefix + "/" + args[0]
 	_, err := doRequest(cmd, path, http.MethodDelete)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 }
@@ -0,0 +1,77 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testOperatorSuite{})
+
+type testOperatorSuite struct{}
+
+func (s *testOperatorSuite) TestOperatorProgress(c *C) {
+	r := `[
+		{"name": "region_operator", "region": {"id": 1}, "state": "running", "index": 1,
+		 "ops": [{}, {}, {}], "progress": 0.3333, "estimated_remaining": "2m0s"},
+		{"name": "admin_operator", "region": {"id": 2}, "state": "waiting", "index": 0,
+		 "ops": [{}], "progress": 0, "estimated_remaining": null}
+	]`
+	var out bytes.Buffer
+	c.Assert(printOperatorProgress(&out, r), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"region 1 region_operator running: 1/3 steps (33%), 2m0s remaining\n"+
+		"region 2 admin_operator waiting: 0/1 steps (0%), unknown remaining\n")
+}
+
+func (s *testOperatorSuite) TestOperatorReasons(c *C) {
+	r := `[
+		{"name": "region_operator", "region": {"id": 1}, "reason": "region has 2 replicas, 3 expected"},
+		{"name": "admin_operator", "region": {"id": 2}}
+	]`
+	var out bytes.Buffer
+	c.Assert(printOperatorReasons(&out, r), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"region 1 region_operator: region has 2 replicas, 3 expected\n"+
+		"region 2 admin_operator: no reason recorded\n")
+	c.Assert(printOperatorReasons(&out, "{}"), NotNil)
+}
+
+func (s *testOperatorSuite) TestChangePeer(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		if input["store_id"].(float64) == 1 {
+			http.Error(w, `{"error": "region already has peer in store 1", "code": 400}`, http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Use = "add-peer"
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	changePeerCommandFunc(cmd, []string{"2", "3"})
+	c.Assert(input, DeepEquals, map[string]interface{}{"name": "add-peer", "region_id": float64(2), "store_id": float64(3)})
+	c.Assert(out.String(), Equals, "Success! The operator is added to region 2\n")
+
+	out.Reset()
+	changePeerCommandFunc(cmd, []string{"2", "1"})
+	c.Assert(out.String(), Equals, "Failed to add operator: [400] region already has peer in store 1\n")
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/pingcap/pd/server"
+	"github.com/spf13/cobra"
+)
+
+// NewParseURLsCommand returns a parse-urls command.
+func NewParseURLsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parse-urls <url>[,<url>...]",
+		Short: "validate the comma-separated urls",
+		Run:   parseURLsCommandFunc,
+	}
+	return cmd
+}
+
+func parseURLsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: parse-urls <url>[,<url>...]")
+		return
+	}
+	urls, err := server.ParseUrls(args[0])
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse urls: %s\n", err)
+		return
+	}
+	for _, u := range urls {
+		fmt.Fprintf(cmd.OutOrStdout(), "scheme: %s, host: %s\n", u.Scheme, u.Host)
+	}
+}
@@ -35,9 +35,9 @@ func showPingCommandFunc(cmd *cobra.Command, args []string) {
 	start := time.Now()
 	_, err := doRequest(cmd, pingPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 	elapsed := time.Since(start)
-	fmt.Println("time:", elapsed)
+	fmt.Fprintln(cmd.OutOrStdout(), "time:", elapsed)
 }
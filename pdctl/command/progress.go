@@ -0,0 +1,92 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+const progressBarWidth = 30
+
+// progressBar shows the progress of a wait on a single terminal line. If
+// stdout is not a terminal, the message is printed on a new line only when it
+// is changed, so the output is still readable in logs. The bar does not handle
+// Ctrl-C, pd-ctl exits on its own signal handler, which starts a new line.
+type progressBar struct {
+	sync.Mutex
+	out     io.Writer
+	tty     bool
+	last    string
+	stopped bool
+}
+
+// newProgressBar returns the progress bar of the command if '--progress-bar'
+// is set, or nil otherwise.
+func newProgressBar(cmd *cobra.Command, out io.Writer) *progressBar {
+	if enabled, _ := cmd.Flags().GetBool("progress-bar"); !enabled {
+		return nil
+	}
+	return &progressBar{out: out, tty: isTerminal(os.Stdout)}
+}
+
+// update shows the ratio of done in [0, 1] with the message.
+func (p *progressBar) update(ratio float64, msg string) {
+	p.Lock()
+	defer p.Unlock()
+	if p.stopped {
+		return
+	}
+	if ratio < 0 {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+	if !p.tty {
+		line := fmt.Sprintf("%3.0f%% %s", ratio*100, msg)
+		if line != p.last {
+			fmt.Fprintln(p.out, line)
+			p.last = line
+		}
+		return
+	}
+	filled := int(ratio * progressBarWidth)
+	line := fmt.Sprintf("[%s%s] %3.0f%% %s", strings.Repeat("#", filled),
+		strings.Repeat("-", progressBarWidth-filled), ratio*100, msg)
+	// Pad with spaces to overwrite a longer last line.
+	pad := ""
+	if n := len(p.last) - len(line); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+	fmt.Fprintf(p.out, "\r%s%s", line, pad)
+	p.last = line
+}
+
+// stop ends the line of the bar, it is safe to call more than once.
+func (p *progressBar) stop() {
+	p.Lock()
+	defer p.Unlock()
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	if p.tty && p.last != "" {
+		fmt.Fprintln(p.out)
+	}
+}
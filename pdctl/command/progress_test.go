@@ -0,0 +1,84 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testProgressSuite{})
+
+type testProgressSuite struct{}
+
+func (s *testProgressSuite) TestProgressBar(c *C) {
+	var out bytes.Buffer
+	bar := &progressBar{out: &out, tty: true}
+	bar.update(0.5, "store 1: 10 regions left")
+	bar.update(1.5, "done")
+	bar.stop()
+	bar.stop()
+	c.Assert(out.String(), Equals, "\r["+strings.Repeat("#", 15)+strings.Repeat("-", 15)+"]  50% store 1: 10 regions left"+
+		"\r["+strings.Repeat("#", 30)+"] 100% done"+strings.Repeat(" ", 20)+"\n")
+
+	// Updates after stop are ignored.
+	out.Reset()
+	bar.update(1, "done")
+	c.Assert(out.String(), Equals, "")
+
+	// The unchanged lines are not printed again without a terminal.
+	bar = &progressBar{out: &out}
+	bar.update(0, "waiting")
+	bar.update(0, "waiting")
+	bar.update(-1, "waiting")
+	bar.update(0.333, "running")
+	bar.stop()
+	c.Assert(out.String(), Equals, "  0% waiting\n 33% running\n")
+}
+
+func (s *testProgressSuite) TestWaitRegionOperator(c *C) {
+	origin := regionOperatorCheckInterval
+	defer func() { regionOperatorCheckInterval = origin }()
+	regionOperatorCheckInterval = time.Millisecond
+
+	index := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/operators/3")
+		if index > 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `"operator not found"`)
+			return
+		}
+		fmt.Fprintf(w, `{"name": "scatter-region", "state": "running", "index": %d, "ops": [{}, {}], "progress": %f}`, index/2, float64(index/2)/2)
+		index++
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+
+	var out bytes.Buffer
+	c.Assert(waitRegionOperator(cmd, &out, 3, 0), IsNil)
+	c.Assert(out.String(), Equals, "  0% region 3 scatter-region running: 0/2 steps\n"+
+		" 50% region 3 scatter-region running: 1/2 steps\n"+
+		"100% region 3: finished\n")
+
+	index = 0
+	out.Reset()
+	c.Assert(waitRegionOperator(cmd, &out, 3, 2*time.Millisecond), ErrorMatches, "Timed out .*")
+}
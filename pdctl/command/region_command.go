@@ -14,20 +14,38 @@
 package command
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	gh "github.com/dustin/go-humanize"
+	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/spf13/cobra"
 )
 
 var (
-	regionsPrefix   = "pd/api/v1/regions"
-	regionIDPrefix  = "pd/api/v1/region/id"
-	regionKeyPrefix = "pd/api/v1/region/key"
+	regionsPrefix                = "pd/api/v1/regions"
+	regionsCountPrefix           = "pd/api/v1/regions/count"
+	regionsSiblingPrefix         = "pd/api/v1/regions/sibling/%s"
+	regionsMergeCandidatesPrefix = "pd/api/v1/regions/merge-candidates?limit=%d"
+	regionsLeaderDistPrefix      = "pd/api/v1/regions/leader-distribution?start_key=%s&end_key=%s"
+	regionsScatterPrefix         = "pd/api/v1/regions/scatter"
+	regionsAcceleratePrefix      = "pd/api/v1/regions/accelerate-schedule"
+	regionsCheckPrefix           = "pd/api/v1/regions/check"
+	regionIDPrefix               = "pd/api/v1/region/id"
+	regionKeyPrefix              = "pd/api/v1/region/key"
 )
 
 type regionInfo struct {
@@ -35,6 +53,21 @@ type regionInfo struct {
 	Leader *metapb.Peer   `json:"leader"`
 }
 
+// regionReplicasInfo is the peer state of a region, the region fields are
+// inlined in the response of regionIDPrefix.
+type regionReplicasInfo struct {
+	ID           uint64            `json:"id"`
+	Peers        []*metapb.Peer    `json:"peers"`
+	Leader       *metapb.Peer      `json:"Leader"`
+	DownPeers    []*pdpb.PeerStats `json:"DownPeers"`
+	PendingPeers []*metapb.Peer    `json:"PendingPeers"`
+}
+
+type regionSiblingsInfo struct {
+	Prev *metapb.Region `json:"prev"`
+	Next *metapb.Region `json:"next"`
+}
+
 // NewRegionCommand return a region subcommand of rootCmd
 func NewRegionCommand() *cobra.Command {
 	r := &cobra.Command{
@@ -43,25 +76,668 @@ func NewRegionCommand() *cobra.Command {
 		Run:   showRegionCommandFunc,
 	}
 	r.AddCommand(NewRegionWithKeyCommand())
+	r.AddCommand(NewRegionSiblingCommand())
+	r.AddCommand(NewRegionMergeCandidatesCommand())
+	r.AddCommand(NewRegionDistributionCommand())
+	r.AddCommand(NewRegionLeaderDistributionCommand())
+	r.AddCommand(NewRegionScatterCommand())
+	r.AddCommand(NewRegionScatterRangeCommand())
+	r.AddCommand(NewRegionAccelerateScheduleCommand())
+	r.AddCommand(NewRegionTransferLeaderCommand())
+	r.AddCommand(NewRegionReplicasCommand())
+	r.AddCommand(NewRegionHistoryCommand())
+	r.AddCommand(NewRegionCheckCommand())
+	r.Flags().Bool("count-only", false, "only show the count of regions")
+	r.Flags().Duration("watch", 0, "show the regions again every interval if they are changed")
+	r.Flags().String("format", regionFormatJSON, "the output format, one of json, ndjson")
 	return r
 }
 
+// The output formats of the region command, each region is one line of
+// ndjson so the output can be processed incrementally.
+const (
+	regionFormatJSON   = "json"
+	regionFormatNDJSON = "ndjson"
+)
+
 func showRegionCommandFunc(cmd *cobra.Command, args []string) {
+	if watchCommand(cmd, args, showRegionCommandFunc) {
+		return
+	}
 	var prefix string
 	prefix = regionsPrefix
 	if len(args) == 1 {
 		if _, err := strconv.Atoi(args[0]); err != nil {
-			fmt.Println("region_id should be a number")
+			fmt.Fprintln(cmd.OutOrStdout(), "region_id should be a number")
 			return
 		}
 		prefix = regionIDPrefix + "/" + args[0]
 	}
-	r, err := doRequest(cmd, prefix, http.MethodGet)
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" || rawOutput(cmd) {
+		format = regionFormatJSON
+	}
+	if format != regionFormatJSON && format != regionFormatNDJSON {
+		fmt.Fprintf(cmd.OutOrStdout(), "Unknown format %q, it should be one of json, ndjson\n", format)
+		return
+	}
+	countOnly, _ := cmd.Flags().GetBool("count-only")
+	if countOnly && format == regionFormatNDJSON {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region --format=ndjson [<region_id>]")
+		return
+	}
+	if countOnly {
+		showRegionCountCommandFunc(cmd, args)
+		return
+	}
+	// All regions may be a large response, so it is not buffered.
+	body, err := doRequestStream(cmd, prefix, http.MethodGet)
+	if err == errNotModified {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region: %s\n", err)
+		return
+	}
+	defer body.Close()
+	if format == regionFormatNDJSON {
+		key := "regions"
+		if len(args) == 1 {
+			key = ""
+		}
+		err = printNDJSON(cmd.OutOrStdout(), body, key)
+		if errors.Cause(err) == io.EOF {
+			checkEmptyResponse(cmd, "")
+		} else if err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region: %s\n", err)
+		}
+		return
+	}
+	n, err := io.Copy(cmd.OutOrStdout(), body)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "\nFailed to get region: %s\n", err)
+		return
+	}
+	if n == 0 && checkEmptyResponse(cmd, "") {
+		return
+	}
+	if !rawOutput(cmd) {
+		fmt.Fprintln(cmd.OutOrStdout())
+	}
+}
+
+func showRegionCountCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region --count-only")
+		return
+	}
+	r, err := doRequest(cmd, regionsCountPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region count: %s\n", err)
+		return
+	}
+	var info struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse region count: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), info.Count)
+}
+
+// NewRegionSiblingCommand returns a sibling subcommand of regionCmd.
+func NewRegionSiblingCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "sibling <region_id>",
+		Short: "show the previous and next regions of the region by key order",
+		Run:   showRegionSiblingCommandFunc,
+	}
+	return r
+}
+
+func showRegionSiblingCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region sibling <region_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_id should be a number")
+		return
+	}
+	r, err := doRequest(cmd, fmt.Sprintf(regionsSiblingPrefix, args[0]), http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region siblings: %s\n", err)
+		return
+	}
+	siblings := &regionSiblingsInfo{}
+	if err = json.Unmarshal([]byte(r), siblings); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse region siblings: %s\n", err)
+		return
+	}
+	printSiblingRegion(cmd.OutOrStdout(), "prev", siblings.Prev)
+	printSiblingRegion(cmd.OutOrStdout(), "next", siblings.Next)
+}
+
+func printSiblingRegion(out io.Writer, name string, region *metapb.Region) {
+	if region == nil {
+		fmt.Fprintf(out, "%s: none\n", name)
+		return
+	}
+	fmt.Fprintf(out, "%s: id: %d, start_key: %q, end_key: %q\n", name, region.GetId(), region.GetStartKey(), region.GetEndKey())
+}
+
+// NewRegionScatterCommand returns a scatter subcommand of regionCmd.
+func NewRegionScatterCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "scatter <region_id> [--wait [--progress-bar]]",
+		Short: "move the peers of the region to the stores with less regions",
+		Run:   scatterRegionCommandFunc,
+	}
+	r.Flags().Bool("wait", false, "wait until the operator of the region is finished, until '--timeout' if it is set")
+	r.Flags().Bool("progress-bar", false, "show the progress of '--wait' in a bar if stdout is a terminal")
+	return r
+}
+
+func scatterRegionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region scatter <region_id>")
+		return
+	}
+	regionID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_id should be a number")
+		return
+	}
+	count, ok := postScatterRegions(cmd, map[string]interface{}{"region_id": regionID})
+	if wait, _ := cmd.Flags().GetBool("wait"); !wait || !ok || count == 0 {
+		return
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if err = waitRegionOperator(cmd, cmd.OutOrStdout(), regionID, timeout); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		exitCode = 1
+	}
+}
+
+// regionOperatorCheckInterval is the interval of checking whether the operator
+// of a region is finished, the steps of an operator usually take seconds.
+var regionOperatorCheckInterval = time.Second
+
+// waitRegionOperator waits until the operator of the region is finished and
+// shows its steps, in a progress bar if '--progress-bar' is set. A zero
+// timeout waits forever.
+func waitRegionOperator(cmd *cobra.Command, out io.Writer, regionID uint64, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	bar := newProgressBar(cmd, out)
+	if bar == nil {
+		// Without the bar, the steps are printed when they are changed.
+		bar = &progressBar{out: out}
+	}
+	defer bar.stop()
+	prefix := fmt.Sprintf("%s/%d", operatorsPrefix, regionID)
+	for {
+		r, err := doRequest(cmd, prefix, http.MethodGet)
+		// The operator is removed after it is finished.
+		if err != nil && strings.Contains(err.Error(), "operator not found") {
+			bar.update(1, fmt.Sprintf("region %d: finished", regionID))
+			return nil
+		}
+		if err != nil {
+			return errors.Errorf("Failed to get the operator of region %d: %s", regionID, err)
+		}
+		var op operatorProgressInfo
+		if err = json.Unmarshal([]byte(r), &op); err != nil {
+			return errors.Errorf("Failed to parse the operator of region %d: %s", regionID, err)
+		}
+		bar.update(op.Progress, fmt.Sprintf("region %d %s %s: %d/%d steps", regionID, op.Name, op.State, op.Index, len(op.Ops)))
+		switch op.State {
+		case "finished":
+			return nil
+		case "timeout", "replaced":
+			return errors.Errorf("The operator of region %d is %s", regionID, op.State)
+		}
+
+		if !deadline.IsZero() && time.Now().Add(regionOperatorCheckInterval).After(deadline) {
+			return errors.Errorf("Timed out waiting for the operator of region %d", regionID)
+		}
+		time.Sleep(regionOperatorCheckInterval)
+	}
+}
+
+// NewRegionTransferLeaderCommand returns a transfer-leader subcommand of
+// regionCmd, it adds the same operator as 'operator add transfer-leader'.
+func NewRegionTransferLeaderCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "transfer-leader <region_id> <store_id>",
+		Short: "transfer the leader of the region to the store, the store must have a peer of the region",
+		Run:   transferRegionLeaderCommandFunc,
+	}
+	return r
+}
+
+func transferRegionLeaderCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region transfer-leader <region_id> <store_id>")
+		return
+	}
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_id and store_id should be numbers")
+		return
+	}
+	regionID, storeID := ids[0], ids[1]
+	r, err := doRequest(cmd, regionIDPrefix+"/"+args[0], http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region: %s\n", err)
+		return
+	}
+	// The region fields are inlined in the response, it is null if the
+	// region is not found.
+	var region metapb.Region
+	if err = json.Unmarshal([]byte(r), &region); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse region: %s\n", err)
+		return
+	}
+	if region.GetId() == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leader: region %d is not found\n", regionID)
+		return
+	}
+	if !hasPeerInStore(&region, storeID) {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leader: region %d has no peer in store %d\n", regionID, storeID)
+		return
+	}
+	input := map[string]interface{}{
+		"name":        "transfer-leader",
+		"region_id":   regionID,
+		"to_store_id": storeID,
+	}
+	if _, err = doPostJSON(cmd, operatorsPrefix, input); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer leader: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func hasPeerInStore(region *metapb.Region, storeID uint64) bool {
+	for _, peer := range region.GetPeers() {
+		if peer.GetStoreId() == storeID {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRegionReplicasCommand returns a replicas subcommand of regionCmd.
+func NewRegionReplicasCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "replicas <region_id>",
+		Short: "show the store, role and state of each peer of the region",
+		Run:   showRegionReplicasCommandFunc,
+	}
+	return r
+}
+
+func showRegionReplicasCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region replicas <region_id>")
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_id should be a number")
+		return
+	}
+	region, err := doRequest(cmd, regionIDPrefix+"/"+args[0], http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get region: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	stores, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		return
+	}
+	if err = printRegionReplicas(cmd.OutOrStdout(), region, stores); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to show replicas: %s\n", err)
+	}
+}
+
+// printRegionReplicas prints a table of the peers of the region, the store
+// addresses are looked up in the response of storesPrefix.
+func printRegionReplicas(out io.Writer, region string, stores string) error {
+	var info regionReplicasInfo
+	if err := json.Unmarshal([]byte(region), &info); err != nil {
+		return errors.Trace(err)
+	}
+	if info.ID == 0 {
+		return errors.New("region is not found")
+	}
+	var list struct {
+		Stores []*storeTableInfo `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(stores), &list); err != nil {
+		return errors.Trace(err)
+	}
+	addrs := make(map[uint64]string, len(list.Stores))
+	for _, s := range list.Stores {
+		addrs[s.Store.ID] = s.Store.Address
+	}
+	downSeconds := make(map[uint64]uint64, len(info.DownPeers))
+	for _, stats := range info.DownPeers {
+		downSeconds[stats.GetPeer().GetId()] = stats.GetDownSeconds()
+	}
+	pending := make(map[uint64]bool, len(info.PendingPeers))
+	for _, peer := range info.PendingPeers {
+		pending[peer.GetId()] = true
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PEER\tSTORE\tADDRESS\tROLE\tSTATE")
+	for _, peer := range info.Peers {
+		addr, ok := addrs[peer.GetStoreId()]
+		if !ok {
+			addr = "-"
+		}
+		role := "follower"
+		if peer.GetId() == info.Leader.GetId() {
+			role = "leader"
+		}
+		state := "ok"
+		if seconds, ok := downSeconds[peer.GetId()]; ok {
+			state = fmt.Sprintf("down (%ds)", seconds)
+		} else if pending[peer.GetId()] {
+			state = "pending"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", peer.GetId(), peer.GetStoreId(), addr, role, state)
+	}
+	return w.Flush()
+}
+
+// NewRegionHistoryCommand returns a history subcommand of regionCmd.
+func NewRegionHistoryCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "history <region_id>",
+		Short: "show the recent splits, merges, peer and leader changes of the region, it needs region-history-limit to be set",
+		Run:   showRegionHistoryCommandFunc,
+	}
+	return r
+}
+
+func showRegionHistoryCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region history <region_id>")
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_id should be a number")
+		return
+	}
+	r, err := doRequest(cmd, regionIDPrefix+"/"+args[0]+"/history", http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region history: %s\n", err)
+		return
+	}
+	if rawOutput(cmd) {
+		printResponse(cmd, r)
+		return
+	}
+	if err = printRegionHistory(cmd.OutOrStdout(), r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to show region history: %s\n", err)
+	}
+}
+
+// printRegionHistory prints a table of the region events from the oldest to
+// the latest.
+func printRegionHistory(out io.Writer, r string) error {
+	var events []struct {
+		Time   time.Time `json:"time"`
+		Type   string    `json:"type"`
+		Detail string    `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(r), &events); err != nil {
+		return errors.Trace(err)
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No events")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tDETAIL")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Time.Format("2006-01-02 15:04:05.000"), e.Type, e.Detail)
+	}
+	return w.Flush()
+}
+
+// regionCheckInput is where the confirmation of 'region check --fix' is read,
+// it is replaced in tests.
+var regionCheckInput io.Reader = os.Stdin
+
+// NewRegionCheckCommand returns a check subcommand of regionCmd.
+func NewRegionCheckCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "check [--fix [--yes]]",
+		Short: "show the regions which have down peers or miss peers, and repair them with '--fix'",
+		Run:   checkRegionsCommandFunc,
+	}
+	r.Flags().Bool("fix", false, "add an operator to repair each region which has an anomaly")
+	r.Flags().Bool("yes", false, "fix the regions without confirmation")
+	return r
+}
+
+// regionAnomaly is a problem of a region reported by regionsCheckPrefix.
+type regionAnomaly struct {
+	RegionID uint64 `json:"region_id"`
+	Type     string `json:"type"`
+	Detail   string `json:"detail"`
+}
+
+func checkRegionsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region check [--fix [--yes]]")
+		return
+	}
+	r, err := doRequest(cmd, regionsCheckPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to check regions: %s\n", err)
+		return
+	}
+	var anomalies []*regionAnomaly
+	if err = json.Unmarshal([]byte(r), &anomalies); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse the anomalies: %s\n", err)
+		return
+	}
+	out := cmd.OutOrStdout()
+	if len(anomalies) == 0 {
+		fmt.Fprintln(out, "No anomaly is found")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REGION\tANOMALY\tDETAIL")
+	var regionIDs []uint64
+	for i, a := range anomalies {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", a.RegionID, a.Type, a.Detail)
+		// The anomalies of a region are listed together.
+		if i == 0 || anomalies[i-1].RegionID != a.RegionID {
+			regionIDs = append(regionIDs, a.RegionID)
+		}
+	}
+	w.Flush()
+
+	if fix, _ := cmd.Flags().GetBool("fix"); !fix {
+		return
+	}
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		fmt.Fprintf(out, "Fix %d regions? [y/N] ", len(regionIDs))
+		answer, _ := bufio.NewReader(regionCheckInput).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Fprintln(out, "Canceled")
+			return
+		}
+	}
+	fixRegions(cmd, regionIDs)
+}
+
+// fixRegions asks pd to add an operator to repair each region, and prints the
+// operators and a summary.
+func fixRegions(cmd *cobra.Command, regionIDs []uint64) {
+	out := cmd.OutOrStdout()
+	var fixed, skipped, failed int
+	for _, id := range regionIDs {
+		prefix := fmt.Sprintf("%s/%d/fix", regionIDPrefix, id)
+		r, err := doRequestWithBody(cmd, prefix, http.MethodPost, "", nil)
+		if err != nil {
+			fmt.Fprintf(out, "region %d: failed, %s\n", id, strings.TrimSpace(err.Error()))
+			failed++
+			continue
+		}
+		if strings.TrimSpace(r) == "null" {
+			fmt.Fprintf(out, "region %d: no operator is needed\n", id)
+			skipped++
+			continue
+		}
+		if err = printOperatorSteps(out, "["+r+"]", ""); err != nil {
+			fmt.Fprintf(out, "region %d: failed to parse the operator, %s\n", id, err)
+			failed++
+			continue
+		}
+		fixed++
+	}
+	fmt.Fprintf(out, "%d fixed, %d skipped, %d failed\n", fixed, skipped, failed)
+	if failed > 0 {
+		exitCode = 1
+	}
+}
+
+// NewRegionScatterRangeCommand returns a scatter-range subcommand of regionCmd.
+func NewRegionScatterRangeCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "scatter-range <start_key> <end_key>",
+		Short: "move the peers of the regions in the range to the stores with less regions, the keys are in hex",
+		Run:   scatterRangeCommandFunc,
+	}
+	return r
+}
+
+func scatterRangeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region scatter-range <start_key> <end_key>")
+		return
+	}
+	for _, key := range args {
+		if _, err := hex.DecodeString(key); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Invalid hex key %q: %s\n", key, err)
+			return
+		}
+	}
+	postScatterRegions(cmd, map[string]interface{}{"start_key": args[0], "end_key": args[1]})
+}
+
+// postScatterRegions prints the result of scattering the regions, it returns
+// the number of added operators and whether it succeeds.
+func postScatterRegions(cmd *cobra.Command, input map[string]interface{}) (int, bool) {
+	r, err := doPostJSON(cmd, regionsScatterPrefix, input)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to scatter regions: %s\n", err)
+		return 0, false
+	}
+	var info struct {
+		OperatorCount int `json:"operator_count"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse response: %s\n", err)
+		return 0, false
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! %d operators are added\n", info.OperatorCount)
+	return info.OperatorCount, true
+}
+
+// NewRegionAccelerateScheduleCommand returns an accelerate-schedule subcommand
+// of regionCmd.
+func NewRegionAccelerateScheduleCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "accelerate-schedule <start_key> <end_key>",
+		Short: "move the peers of the regions in the range before the other regions for a while, the keys are in hex",
+		Run:   accelerateScheduleCommandFunc,
+	}
+	return r
+}
+
+func accelerateScheduleCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region accelerate-schedule <start_key> <end_key>")
+		return
+	}
+	for _, key := range args {
+		if _, err := hex.DecodeString(key); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Invalid hex key %q: %s\n", key, err)
+			return
+		}
+	}
+	r, err := doPostJSON(cmd, regionsAcceleratePrefix, map[string]interface{}{"start_key": args[0], "end_key": args[1]})
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to accelerate schedule: %s\n", err)
+		return
+	}
+	var info struct {
+		Count int `json:"count"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse response: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! The scheduling of %d regions is accelerated\n", info.Count)
+}
+
+// NewRegionMergeCandidatesCommand returns a merge-candidates subcommand of regionCmd.
+func NewRegionMergeCandidatesCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "merge-candidates [--limit <n>]",
+		Short: "show the adjacent regions which pass the merge checks",
+		Run:   showRegionMergeCandidatesCommandFunc,
+	}
+	r.Flags().Int("limit", 10, "the number of sample candidates to show")
+	r.Flags().Uint64("max-size", 0, "the max size of the regions to merge in MB, it is not supported since the heartbeats do not report region sizes")
+	return r
+}
+
+func showRegionMergeCandidatesCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+	// The candidates can not be filtered by size, so the flag is rejected
+	// instead of being ignored.
+	if cmd.Flags().Changed("max-size") {
+		fmt.Fprintln(cmd.OutOrStdout(), "--max-size is not supported, the region size is not reported by heartbeats")
+		exitCode = 1
+		return
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	r, err := doRequest(cmd, fmt.Sprintf(regionsMergeCandidatesPrefix, limit), http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get merge candidates: %s\n", err)
+		return
+	}
+	var info struct {
+		Count      int `json:"count"`
+		Candidates []struct {
+			SourceID uint64 `json:"source_region_id"`
+			TargetID uint64 `json:"target_region_id"`
+		} `json:"candidates"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse merge candidates: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "count: %d\n", info.Count)
+	for _, candidate := range info.Candidates {
+		fmt.Fprintf(cmd.OutOrStdout(), "region %d -> region %d\n", candidate.SourceID, candidate.TargetID)
+	}
 }
 
 // NewRegionWithKeyCommand return a region with key subcommand of regionCmd
@@ -77,7 +753,7 @@ func NewRegionWithKeyCommand() *cobra.Command {
 
 func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
@@ -93,21 +769,21 @@ func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 	case "pb", "proto", "protobuf":
 		key, err = decodeProtobufText(args[0])
 		if err != nil {
-			fmt.Println("Error: ", err)
+			fmt.Fprintln(cmd.OutOrStdout(), "Error: ", err)
 			return
 		}
 	default:
-		fmt.Println("Error: unknown format")
+		fmt.Fprintln(cmd.OutOrStdout(), "Error: unknown format")
 		return
 	}
 	// TODO: Deal with path escaped
 	prefix := regionKeyPrefix + "/" + key
 	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get region: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get region: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 
 }
 
@@ -132,3 +808,187 @@ func decodeProtobufText(text string) (string, error) {
 	}
 	return string(buf), nil
 }
+
+// NewRegionDistributionCommand returns a distribution subcommand of regionCmd.
+func NewRegionDistributionCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "distribution [--metric leader|region|size]",
+		Short: "show the histogram of the leaders, regions or used size of the stores",
+		Run:   showRegionDistributionCommandFunc,
+	}
+	r.Flags().String("metric", "region", "the metric of stores, one of leader, region and size")
+	return r
+}
+
+// NewRegionLeaderDistributionCommand returns a leader-distribution subcommand
+// of regionCmd.
+func NewRegionLeaderDistributionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "leader-distribution <start_key> <end_key>",
+		Short: "show the leaders of the regions in the range on each store, the keys are in hex",
+		Run:   showRegionLeaderDistributionCommandFunc,
+	}
+}
+
+func showRegionLeaderDistributionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region leader-distribution <start_key> <end_key>")
+		return
+	}
+	for _, key := range args {
+		if _, err := hex.DecodeString(key); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Invalid hex key %q: %s\n", key, err)
+			return
+		}
+	}
+	r, err := doRequest(cmd, fmt.Sprintf(regionsLeaderDistPrefix, args[0], args[1]), http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get leader distribution: %s\n", err)
+		return
+	}
+	if rawOutput(cmd) {
+		printResponse(cmd, r)
+		return
+	}
+	if err = printLeaderDistribution(cmd.OutOrStdout(), r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to show leader distribution: %s\n", err)
+	}
+}
+
+// printLeaderDistribution prints the leader count and its share of the
+// regions on each store, the stores without leaders are shown too.
+func printLeaderDistribution(out io.Writer, r string) error {
+	var info struct {
+		RegionCount int `json:"region_count"`
+		Stores      []struct {
+			StoreID     uint64 `json:"store_id"`
+			Address     string `json:"address"`
+			LeaderCount int    `json:"leader_count"`
+		} `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return errors.Trace(err)
+	}
+
+	fmt.Fprintf(out, "regions: %d\n", info.RegionCount)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STORE\tADDRESS\tLEADERS\tRATIO")
+	for _, s := range info.Stores {
+		ratio := 0.0
+		if info.RegionCount > 0 {
+			ratio = float64(s.LeaderCount) * 100 / float64(info.RegionCount)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.1f%%\n", s.StoreID, s.Address, s.LeaderCount, ratio)
+	}
+	return w.Flush()
+}
+
+const (
+	maxDistributionBuckets = 10
+	maxDistributionBarLen  = 40
+)
+
+func showRegionDistributionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: region distribution [--metric leader|region|size]")
+		return
+	}
+	metric, _ := cmd.Flags().GetString("metric")
+	r, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		return
+	}
+	if err = printDistribution(cmd.OutOrStdout(), metric, r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to show distribution: %s\n", err)
+	}
+}
+
+// printDistribution prints the histogram of the metric over the stores which
+// are not tombstone, followed by the mean and standard deviation. The size is
+// the used space of a store, since region sizes are not reported.
+func printDistribution(out io.Writer, metric string, r string) error {
+	var info struct {
+		Stores []*storeTableInfo `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return errors.Trace(err)
+	}
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	var values []uint64
+	for _, s := range info.Stores {
+		if s.Store.StateName == metapb.StoreState_Tombstone.String() {
+			continue
+		}
+		switch metric {
+		case "leader":
+			values = append(values, uint64(s.Status.LeaderCount))
+		case "region":
+			values = append(values, uint64(s.Status.RegionCount))
+		case "size":
+			capacity, err := gh.ParseBytes(s.Status.Capacity)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			available, err := gh.ParseBytes(s.Status.Available)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if available > capacity {
+				available = capacity
+			}
+			values = append(values, capacity-available)
+			format = func(v float64) string { return gh.IBytes(uint64(v)) }
+		default:
+			return errors.Errorf("unknown metric %q", metric)
+		}
+	}
+	if len(values) == 0 {
+		return errors.New("no store is found")
+	}
+
+	min, max := values[0], values[0]
+	var sum float64
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(values)))
+
+	// Buckets are in the same width, and cover [min, max].
+	n := uint64(len(values))
+	if n > maxDistributionBuckets {
+		n = maxDistributionBuckets
+	}
+	span := max - min + 1
+	width := (span + n - 1) / n
+	counts := make([]int, (span+width-1)/width)
+	maxCount := 0
+	for _, v := range values {
+		i := (v - min) / width
+		counts[i]++
+		if counts[i] > maxCount {
+			maxCount = counts[i]
+		}
+	}
+
+	fmt.Fprintf(out, "metric: %s, stores: %d, mean: %s, stddev: %s\n", metric, len(values), format(math.Floor(mean*100)/100), format(math.Floor(stddev*100)/100))
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, count := range counts {
+		lo := min + uint64(i)*width
+		bar := strings.Repeat("#", (count*maxDistributionBarLen+maxCount-1)/maxCount)
+		fmt.Fprintf(w, "%s - %s\t%d\t%s\n", format(float64(lo)), format(float64(lo+width-1)), count, bar)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,337 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRegionSuite{})
+
+type testRegionSuite struct{}
+
+func (s *testRegionSuite) TestDistribution(c *C) {
+	var out bytes.Buffer
+	c.Assert(printDistribution(&out, "region", testStores), IsNil)
+	c.Assert(out.String(), Equals, "metric: region, stores: 2, mean: 33, stddev: 3\n"+
+		"30 - 33  1  ########################################\n"+
+		"34 - 37  1  ########################################\n")
+
+	out.Reset()
+	c.Assert(printDistribution(&out, "size", testStores), IsNil)
+	c.Assert(out.String(), Matches, "metric: size, stores: 2, mean: 69 GiB, stddev: 29 GiB\n(?s:.*)")
+
+	const stores = `{"stores": [
+	  {"store": {"state_name": "Up"}, "status": {"leader_count": 1}},
+	  {"store": {"state_name": "Up"}, "status": {"leader_count": 2}},
+	  {"store": {"state_name": "Up"}, "status": {"leader_count": 10}},
+	  {"store": {"state_name": "Tombstone"}, "status": {"leader_count": 100}}
+	]}`
+	out.Reset()
+	c.Assert(printDistribution(&out, "leader", stores), IsNil)
+	c.Assert(out.String(), Equals, "metric: leader, stores: 3, mean: 4.33, stddev: 4.02\n"+
+		"1 - 4   2  ########################################\n"+
+		"5 - 8   0  \n"+
+		"9 - 12  1  ####################\n")
+
+	c.Assert(printDistribution(&out, "unknown", stores), NotNil)
+}
+
+func (s *testRegionSuite) TestLeaderDistribution(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/regions/leader-distribution")
+		c.Assert(r.URL.Query().Get("start_key"), Equals, "7480")
+		c.Assert(r.URL.Query().Get("end_key"), Equals, "7481")
+		fmt.Fprint(w, `{"region_count": 8, "stores": [
+		  {"store_id": 1, "address": "127.0.0.1:20160", "leader_count": 7},
+		  {"store_id": 2, "address": "127.0.0.1:20161", "leader_count": 1},
+		  {"store_id": 3, "address": "127.0.0.1:20162", "leader_count": 0}
+		]}`)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	showRegionLeaderDistributionCommandFunc(cmd, []string{"7480", "7481"})
+	c.Assert(out.String(), Equals, "regions: 8\n"+
+		"STORE  ADDRESS          LEADERS  RATIO\n"+
+		"1      127.0.0.1:20160  7        87.5%\n"+
+		"2      127.0.0.1:20161  1        12.5%\n"+
+		"3      127.0.0.1:20162  0        0.0%\n")
+
+	out.Reset()
+	showRegionLeaderDistributionCommandFunc(cmd, []string{"7480", "zz"})
+	c.Assert(out.String(), Matches, `Invalid hex key "zz": .*\n`)
+
+	out.Reset()
+	c.Assert(printLeaderDistribution(&out, `{"region_count": 0, "stores": [{"store_id": 1, "address": "a"}]}`), IsNil)
+	c.Assert(out.String(), Equals, "regions: 0\nSTORE  ADDRESS  LEADERS  RATIO\n1      a        0        0.0%\n")
+}
+
+func (s *testRegionSuite) TestShowRegionNDJSON(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pd/api/v1/region/id/3" {
+			fmt.Fprint(w, "{\n  \"id\": 3\n}")
+			return
+		}
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/regions")
+		fmt.Fprint(w, `{"count": 2, "regions": [{"id": 2, "start_key": ""}, {"id": 3}]}`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("format", "", "")
+	cmd.Flags().Bool("count-only", false, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	c.Assert(cmd.Flags().Set("format", regionFormatNDJSON), IsNil)
+	showRegionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "{\"id\":2,\"start_key\":\"\"}\n{\"id\":3}\n")
+
+	out.Reset()
+	showRegionCommandFunc(cmd, []string{"3"})
+	c.Assert(out.String(), Equals, "{\"id\":3}\n")
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("format", "yaml"), IsNil)
+	showRegionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Unknown format \"yaml\", it should be one of json, ndjson\n")
+}
+
+func (s *testRegionSuite) TestScatterRange(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		fmt.Fprint(w, `{"operator_count": 3}`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	scatterRangeCommandFunc(cmd, []string{"7480", "7490"})
+	c.Assert(out.String(), Equals, "Success! 3 operators are added\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"start_key": "7480", "end_key": "7490"})
+
+	out.Reset()
+	input = nil
+	scatterRangeCommandFunc(cmd, []string{"7480", "xx"})
+	c.Assert(out.String(), Matches, "Invalid hex key \"xx\".*\n")
+	c.Assert(input, IsNil)
+}
+
+func (s *testRegionSuite) TestAccelerateSchedule(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/regions/accelerate-schedule")
+		c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		fmt.Fprint(w, `{"count": 12}`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	accelerateScheduleCommandFunc(cmd, []string{"7480", "7490"})
+	c.Assert(out.String(), Equals, "Success! The scheduling of 12 regions is accelerated\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"start_key": "7480", "end_key": "7490"})
+
+	out.Reset()
+	input = nil
+	accelerateScheduleCommandFunc(cmd, []string{"xx", "7490"})
+	c.Assert(out.String(), Matches, "Invalid hex key \"xx\".*\n")
+	c.Assert(input, IsNil)
+}
+
+func (s *testRegionSuite) TestMergeCandidates(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/regions/merge-candidates")
+		c.Assert(r.URL.Query().Get("limit"), Equals, "10")
+		fmt.Fprint(w, `{"count": 3, "candidates": [{"source_region_id": 2, "target_region_id": 3}]}`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Int("limit", 10, "")
+	cmd.Flags().Uint64("max-size", 0, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	showRegionMergeCandidatesCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "count: 3\nregion 2 -> region 3\n")
+
+	// The size is not silently ignored.
+	origin := exitCode
+	defer func() { exitCode = origin }()
+	exitCode = 0
+	out.Reset()
+	c.Assert(cmd.Flags().Set("max-size", "20"), IsNil)
+	showRegionMergeCandidatesCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "--max-size is not supported, the region size is not reported by heartbeats\n")
+	c.Assert(exitCode, Equals, 1)
+}
+
+func (s *testRegionSuite) TestTransferLeader(c *C) {
+	var input map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/region/id/1":
+			fmt.Fprint(w, `{"id": 1, "peers": [{"id": 2, "store_id": 1}, {"id": 3, "store_id": 2}], "Leader": {"id": 2, "store_id": 1}}`)
+		case "/pd/api/v1/region/id/2":
+			fmt.Fprint(w, "null")
+		case "/pd/api/v1/operators":
+			c.Assert(json.NewDecoder(r.Body).Decode(&input), IsNil)
+		}
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	transferRegionLeaderCommandFunc(cmd, []string{"1", "2"})
+	c.Assert(out.String(), Equals, "Success!\n")
+	c.Assert(input, DeepEquals, map[string]interface{}{"name": "transfer-leader", "region_id": float64(1), "to_store_id": float64(2)})
+
+	out.Reset()
+	input = nil
+	transferRegionLeaderCommandFunc(cmd, []string{"1", "3"})
+	c.Assert(out.String(), Equals, "Failed to transfer leader: region 1 has no peer in store 3\n")
+	c.Assert(input, IsNil)
+
+	out.Reset()
+	transferRegionLeaderCommandFunc(cmd, []string{"2", "1"})
+	c.Assert(out.String(), Equals, "Failed to transfer leader: region 2 is not found\n")
+	c.Assert(input, IsNil)
+}
+
+func (s *testRegionSuite) TestRegionReplicas(c *C) {
+	region := `{"id": 1, "peers": [{"id": 2, "store_id": 1}, {"id": 3, "store_id": 2}, {"id": 4, "store_id": 5}],
+		"Leader": {"id": 2, "store_id": 1},
+		"DownPeers": [{"peer": {"id": 3, "store_id": 2}, "down_seconds": 120}],
+		"PendingPeers": [{"id": 4, "store_id": 5}]}`
+	var out bytes.Buffer
+	c.Assert(printRegionReplicas(&out, region, testStores), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"PEER  STORE  ADDRESS          ROLE      STATE\n"+
+		"2     1      127.0.0.1:20160  leader    ok\n"+
+		"3     2      127.0.0.1:20161  follower  down (120s)\n"+
+		"4     5      -                follower  pending\n")
+
+	c.Assert(printRegionReplicas(&out, "null", testStores), ErrorMatches, "region is not found")
+}
+
+func (s *testRegionSuite) TestRegionHistory(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/region/id/2/history")
+		fmt.Fprint(w, `[{"time": "2017-09-01T15:04:05.123+08:00", "type": "create", "detail": "range [61, 62), peers [3(store 1)]"},
+		  {"time": "2017-09-01T15:04:06+08:00", "type": "leader-change", "detail": "leader 3(store 1)"}]`)
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	showRegionHistoryCommandFunc(cmd, []string{"2"})
+	c.Assert(out.String(), Equals, ""+
+		"TIME                     EVENT          DETAIL\n"+
+		"2017-09-01 15:04:05.123  create         range [61, 62), peers [3(store 1)]\n"+
+		"2017-09-01 15:04:06.000  leader-change  leader 3(store 1)\n")
+
+	out.Reset()
+	showRegionHistoryCommandFunc(cmd, []string{"x"})
+	c.Assert(out.String(), Equals, "region_id should be a number\n")
+
+	out.Reset()
+	c.Assert(printRegionHistory(out, "[]"), IsNil)
+	c.Assert(out.String(), Equals, "No events\n")
+}
+
+func (s *testRegionSuite) TestRegionCheck(c *C) {
+	origin, originInput := exitCode, regionCheckInput
+	defer func() { exitCode, regionCheckInput = origin, originInput }()
+	var fixed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/regions/check":
+			fmt.Fprint(w, `[{"region_id": 2, "type": "down-peer", "detail": "peer 4 on store 2 is down for 60s"},
+			  {"region_id": 2, "type": "miss-peer", "detail": "region has 2 replicas, 3 expected"},
+			  {"region_id": 3, "type": "miss-peer", "detail": "region has 1 replicas, 3 expected"},
+			  {"region_id": 4, "type": "miss-peer", "detail": "region has 2 replicas, 3 expected"}]`)
+			return
+		}
+		c.Assert(r.Method, Equals, http.MethodPost)
+		fixed = append(fixed, r.URL.Path)
+		switch r.URL.Path {
+		case "/pd/api/v1/region/id/2/fix":
+			fmt.Fprint(w, `{"region": {"id": 2}, "ops": [{"name": "add_peer", "change_peer": {"peer": {"store_id": 3}}},
+			  {"name": "remove_peer", "change_peer": {"peer": {"store_id": 2}}}]}`)
+		case "/pd/api/v1/region/id/3/fix":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, `"no store to add a replica of region 3"`)
+		default:
+			fmt.Fprint(w, "null")
+		}
+	}))
+	defer server.Close()
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("fix", false, "")
+	cmd.Flags().Bool("yes", false, "")
+	out := &bytes.Buffer{}
+	cmd.SetOutput(out)
+	report := "" +
+		"REGION  ANOMALY    DETAIL\n" +
+		"2       down-peer  peer 4 on store 2 is down for 60s\n" +
+		"2       miss-peer  region has 2 replicas, 3 expected\n" +
+		"3       miss-peer  region has 1 replicas, 3 expected\n" +
+		"4       miss-peer  region has 2 replicas, 3 expected\n"
+	checkRegionsCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, report)
+	c.Assert(fixed, HasLen, 0)
+
+	// Nothing is fixed unless it is confirmed.
+	out.Reset()
+	c.Assert(cmd.Flags().Set("fix", "true"), IsNil)
+	regionCheckInput = strings.NewReader("n\n")
+	checkRegionsCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, report+"Fix 3 regions? [y/N] Canceled\n")
+	c.Assert(fixed, HasLen, 0)
+
+	exitCode = 0
+	out.Reset()
+	regionCheckInput = strings.NewReader("y\n")
+	checkRegionsCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, report+"Fix 3 regions? [y/N] "+
+		"region 2: add peer on store 3, remove peer on store 2\n"+
+		"region 3: failed, [500] \"no store to add a replica of region 3\"\n"+
+		"region 4: no operator is needed\n"+
+		"1 fixed, 1 skipped, 1 failed\n")
+	c.Assert(fixed, DeepEquals, []string{"/pd/api/v1/region/id/2/fix", "/pd/api/v1/region/id/3/fix", "/pd/api/v1/region/id/4/fix"})
+	c.Assert(exitCode, Equals, 1)
+
+	// '--yes' skips the confirmation.
+	out.Reset()
+	fixed = nil
+	c.Assert(cmd.Flags().Set("yes", "true"), IsNil)
+	regionCheckInput = strings.NewReader("")
+	checkRegionsCommandFunc(cmd, nil)
+	c.Assert(fixed, HasLen, 3)
+	c.Assert(strings.HasPrefix(out.String(), report+"region 2: "), IsTrue)
+}
@@ -14,7 +14,9 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -23,6 +25,7 @@ import (
 
 var (
 	schedulersPrefix = "pd/api/v1/schedulers"
+	slowStorePrefix  = "pd/api/v1/schedulers/evict-slow-store-scheduler"
 )
 
 // NewSchedulerCommand returns a scheduler command.
@@ -40,25 +43,100 @@ func NewSchedulerCommand() *cobra.Command {
 // NewShowSchedulerCommand returns a command to show schedulers.
 func NewShowSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "show",
+		Use:   "show [--explain]",
 		Short: "show schedulers",
 		Run:   showSchedulerCommandFunc,
 	}
+	c.Flags().Bool("explain", false, "show the last decision of each scheduler")
 	return c
 }
 
 func showSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 0 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+	if explain, _ := cmd.Flags().GetBool("explain"); explain {
+		showSchedulerDecisions(cmd)
 		return
 	}
 
 	r, err := doRequest(cmd, schedulersPrefix, http.MethodGet)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return
+	}
+	printResponse(cmd, r)
+	// The raw output is only the response, so it is still valid JSON.
+	if rawOutput(cmd) {
+		return
+	}
+
+	var schedulers []string
+	if err = json.Unmarshal([]byte(r), &schedulers); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
-	fmt.Println(r)
+	for _, name := range schedulers {
+		if name == "evict-slow-store-scheduler" {
+			showSlowStore(cmd)
+		}
+	}
+}
+
+func showSchedulerDecisions(cmd *cobra.Command) {
+	r, err := doRequest(cmd, schedulersPrefix+"?explain=true", http.MethodGet)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return
+	}
+	if rawOutput(cmd) {
+		printResponse(cmd, r)
+		return
+	}
+	if err = printSchedulerDecisions(cmd.OutOrStdout(), r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse the decisions: %s\n", err)
+	}
+}
+
+// printSchedulerDecisions prints the last decision of each scheduler, such as
+// "balance-leader-scheduler: transfer the leader of region 1: ...".
+func printSchedulerDecisions(out io.Writer, r string) error {
+	var decisions []*struct {
+		Name     string `json:"name"`
+		Decision string `json:"decision"`
+	}
+	if err := json.Unmarshal([]byte(r), &decisions); err != nil {
+		return err
+	}
+	for _, d := range decisions {
+		decision := d.Decision
+		if decision == "" {
+			decision = "no decision yet"
+		}
+		fmt.Fprintf(out, "%s: %s\n", d.Name, decision)
+	}
+	return nil
+}
+
+func showSlowStore(cmd *cobra.Command) {
+	r, err := doRequest(cmd, slowStorePrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return
+	}
+	var info struct {
+		StoreID uint64 `json:"store_id"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return
+	}
+	if info.StoreID == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "evict-slow-store-scheduler target store: none")
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "evict-slow-store-scheduler target store: %d\n", info.StoreID)
 }
 
 // NewAddSchedulerCommand returns a command to add scheduler.
@@ -71,6 +149,7 @@ func NewAddSchedulerCommand() *cobra.Command {
 	c.AddCommand(NewEvictLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleRegionSchedulerCommand())
+	c.AddCommand(NewEvictSlowStoreSchedulerCommand())
 	return c
 }
 
@@ -96,13 +175,13 @@ func NewEvictLeaderSchedulerCommand() *cobra.Command {
 
 func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
 	storeID, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 
@@ -132,9 +211,19 @@ func NewShuffleRegionSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewEvictSlowStoreSchedulerCommand returns a command to add a evict-slow-store-scheduler.
+func NewEvictSlowStoreSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "evict-slow-store-scheduler",
+		Short: "add a scheduler to evict leaders from a busy store",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
 func addSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 0 {
-		fmt.Println(cmd.UsageString())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
 		return
 	}
 
@@ -155,14 +244,14 @@ func NewRemoveSchedulerCommand() *cobra.Command {
 
 func removeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println(cmd.Usage())
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.Usage())
 		return
 	}
 
 	path := schedulersPrefix + "/" + args[0]
 	_, err := doRequest(cmd, path, http.MethodDelete)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(cmd.OutOrStdout(), err)
 		return
 	}
 }
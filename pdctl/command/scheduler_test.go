@@ -0,0 +1,59 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSchedulerSuite{})
+
+type testSchedulerSuite struct{}
+
+func (s *testSchedulerSuite) TestSchedulerDecisions(c *C) {
+	server := newTestServer(false, `[{"name": "balance-leader-scheduler", "decision": "no leader to transfer between the stores"},
+		{"name": "grant-leader-scheduler-1", "decision": ""}]`)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("raw", false, "")
+	cmd.Flags().Bool("explain", true, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	showSchedulerCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, ""+
+		"balance-leader-scheduler: no leader to transfer between the stores\n"+
+		"grant-leader-scheduler-1: no decision yet\n")
+}
+
+func (s *testSchedulerSuite) TestShowSchedulersRaw(c *C) {
+	body := `["evict-slow-store-scheduler"]`
+	server := newTestServer(false, body)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("raw", true, "")
+	cmd.Flags().Bool("explain", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	// The slow store is not shown with '--raw'.
+	showSchedulerCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, body)
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("explain", "true"), IsNil)
+	showSchedulerCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, body)
+}
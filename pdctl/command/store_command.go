@@ -14,19 +14,52 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
+	gh "github.com/dustin/go-humanize"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"github.com/spf13/cobra"
 )
 
 var (
 	storesPrefix = "pd/api/v1/stores"
 	storePrefix  = "pd/api/v1/store/%s"
+
+	storesHeartbeatPrefix   = "pd/api/v1/stores/heartbeat"
+	storesSnapshotsPrefix   = "pd/api/v1/stores/snapshots"
+	balancePreviewPrefix    = "pd/api/v1/operators/balance-preview?limit=%d"
+	storeFixPlacementPrefix = "pd/api/v1/store/%s/fix-placement?dry_run=%t"
+
+	// storeDrainCheckInterval is how often 'store delete --wait' and 'store
+	// transfer-leaders --wait' check the store, it is replaced in tests.
+	storeDrainCheckInterval = 5 * time.Second
+	errStoreDrainTimeout    = errors.New("Timed out waiting for the store to be drained")
+	errStoreLeadersTimeout  = errors.New("Timed out waiting for the store to have no leader")
+	// errStoreNotFound is the error of the stores in the file of 'store
+	// import' which are not in the cluster, they are skipped, not failed.
+	errStoreNotFound = errors.New("not found")
 )
 
+type storesInfo struct {
+	Count  int               `json:"count"`
+	Stores []json.RawMessage `json:"stores"`
+}
+
 // NewStoreCommand return a store subcommand of rootCmd
 func NewStoreCommand() *cobra.Command {
 	s := &cobra.Command{
@@ -36,74 +69,1655 @@ func NewStoreCommand() *cobra.Command {
 	}
 	s.AddCommand(NewDeleteStoreCommand())
 	s.AddCommand(NewLabelStoreCommand())
+	s.AddCommand(NewRemoveLabelStoreCommand())
+	s.AddCommand(NewAnnotateStoreCommand())
+	s.AddCommand(NewStoreDetailsCommand())
+	s.AddCommand(NewSetStoreAddressCommand())
+	s.AddCommand(NewSetPreferredLeaderCommand())
+	s.AddCommand(NewSetStoreWeightCommand())
+	s.AddCommand(NewStoreAutoWeightCommand())
+	s.AddCommand(NewStoreGRPCStatusCommand())
+	s.AddCommand(NewRelocateStoreCommand())
+	s.AddCommand(NewTransferStoreLeadersCommand())
+	s.AddCommand(NewSetStoreStatusCommand())
+	s.AddCommand(NewTouchStoreCommand())
+	s.AddCommand(NewStoreHeartbeatCommand())
+	s.AddCommand(NewStoreSnapshotsCommand())
+	s.AddCommand(NewStoreBalancePreviewCommand())
+	s.AddCommand(NewStoreFixPlacementCommand())
+	s.AddCommand(NewStorePendingPeersCommand())
+	s.AddCommand(NewBusyStoresCommand())
+	s.AddCommand(NewNearFullStoresCommand())
+	s.AddCommand(NewExportStoreLabelsCommand())
+	s.AddCommand(NewImportStoreLabelsCommand())
+	s.Flags().Bool("count-only", false, "only show the count of stores")
+	s.Flags().String("sort", "", "sort stores by one of "+strings.Join(storeSortKeys, ", ")+", prefix with '-' for descending")
+	s.Flags().Bool("table", false, "show the stores in a table, the same as '--format=table'")
+	s.Flags().String("format", "json", "the output format, one of "+strings.Join(storeFormats, ", "))
+	s.Flags().Duration("watch", 0, "show the stores again every interval if they are changed")
 	return s
 }
 
 // NewDeleteStoreCommand return a  delete subcommand of storeCmd
 func NewDeleteStoreCommand() *cobra.Command {
 	d := &cobra.Command{
-		Use:   "delete <store_id>",
+		Use:   "delete <store_id> [--wait [--progress-bar]]",
 		Short: "delete the store",
 		Run:   deleteStoreCommandFunc,
 	}
+	d.Flags().Bool("wait", false, "wait until all regions are moved out of the store, until '--timeout' if it is set")
+	d.Flags().Bool("progress-bar", false, "show the progress of '--wait' in a bar if stdout is a terminal")
 	return d
 }
 
 // NewLabelStoreCommand returns a label subcommand of storeCmd.
 func NewLabelStoreCommand() *cobra.Command {
 	l := &cobra.Command{
-		Use:   "label <store_id> <key> <value>",
-		Short: "set a store's label value",
+		Use:   "label <store_id> <key> <value> [--replace]",
+		Short: "set a store's label value, an empty value removes the label",
 		Run:   labelStoreCommandFunc,
 	}
+	l.Flags().Bool("replace", false, "clear all existing labels before setting the label")
 	return l
 }
 
+// NewRemoveLabelStoreCommand returns a remove-label subcommand of storeCmd.
+func NewRemoveLabelStoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove-label <store_id> <key> [<key>...]",
+		Short: "remove labels from a store",
+		Run:   removeLabelStoreCommandFunc,
+	}
+}
+
+// NewAnnotateStoreCommand returns an annotate subcommand of storeCmd.
+func NewAnnotateStoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "annotate <store_id> <note>",
+		Short: "set a note of the store for other operators, an empty note clears it",
+		Run:   annotateStoreCommandFunc,
+	}
+}
+
+// NewSetStoreAddressCommand returns a set-address subcommand of storeCmd.
+func NewSetStoreAddressCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-address <store_id> <address>",
+		Short: "change the address of the store, such as after the store host is renamed",
+		Run:   setStoreAddressCommandFunc,
+	}
+}
+
+// NewSetPreferredLeaderCommand returns a set-preferred-leader subcommand of storeCmd.
+func NewSetPreferredLeaderCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-preferred-leader <store_id> <true|false>",
+		Short: "set whether the store is preferred to host more leaders",
+		Run:   setPreferredLeaderCommandFunc,
+	}
+}
+
+// NewSetStoreWeightCommand returns a weight subcommand of storeCmd.
+func NewSetStoreWeightCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "weight <store_id> <leader_weight> <region_weight>",
+		Short: "set the weights of the store, the leaders and regions are balanced in proportion to them",
+		Run:   setStoreWeightCommandFunc,
+	}
+}
+
+// NewStoreAutoWeightCommand returns an auto-weight subcommand of storeCmd.
+func NewStoreAutoWeightCommand() *cobra.Command {
+	a := &cobra.Command{
+		Use:   "auto-weight [--by capacity|available] [--dry-run]",
+		Short: "set the weights of all stores in proportion to their capacity or available size",
+		Run:   storeAutoWeightCommandFunc,
+	}
+	a.Flags().String("by", "capacity", "the metric to compute the weights by, one of capacity, available")
+	a.Flags().Bool("dry-run", false, "only show the weights, without setting them")
+	return a
+}
+
+// NewStoreDetailsCommand returns a details subcommand of storeCmd.
+func NewStoreDetailsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "details <store_id>",
+		Short: "show the metadata, status and note of the store as a readable report",
+		Run:   showStoreDetailsCommandFunc,
+	}
+}
+
+// NewStoreGRPCStatusCommand returns a grpc-status subcommand of storeCmd.
+func NewStoreGRPCStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "grpc-status <store_id>",
+		Short: "check if pd can connect to the store's address",
+		Run:   showStoreGRPCStatusCommandFunc,
+	}
+}
+
+// NewStorePendingPeersCommand returns a pending-peers subcommand of storeCmd.
+func NewStorePendingPeersCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pending-peers <store_id>",
+		Short: "show the regions which have a pending peer on the store",
+		Run:   showStorePendingPeersCommandFunc,
+	}
+}
+
+// NewBusyStoresCommand returns a busy subcommand of storeCmd.
+func NewBusyStoresCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "busy",
+		Short: "show the stores which report they are busy",
+		Run:   showBusyStoresCommandFunc,
+	}
+}
+
+// NewNearFullStoresCommand returns a near-full subcommand of storeCmd.
+func NewNearFullStoresCommand() *cobra.Command {
+	n := &cobra.Command{
+		Use:   "near-full [--threshold <ratio>]",
+		Short: "show the stores whose used ratio exceeds the threshold",
+		Run:   showNearFullStoresCommandFunc,
+	}
+	n.Flags().Float64("threshold", 0.8, "the used ratio of the capacity, between 0 and 1")
+	return n
+}
+
+// NewRelocateStoreCommand returns a relocate subcommand of storeCmd.
+func NewRelocateStoreCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "relocate <store_id> --to <store_id>[,<store_id>...]",
+		Short: "move all regions of the store to the specified stores",
+		Run:   relocateStoreCommandFunc,
+	}
+	r.Flags().String("to", "", "the target store ids, separated by commas")
+	return r
+}
+
+// NewTransferStoreLeadersCommand returns a transfer-leaders subcommand of storeCmd.
+func NewTransferStoreLeadersCommand() *cobra.Command {
+	t := &cobra.Command{
+		Use:   "transfer-leaders <store_id> [--wait]",
+		Short: "transfer the leaders of all regions led by the store to its healthy followers",
+		Run:   transferStoreLeadersCommandFunc,
+	}
+	t.Flags().Bool("wait", false, "wait until the store has no leader, until '--timeout' if it is set")
+	return t
+}
+
+// NewSetStoreStatusCommand returns a hidden set-status subcommand of storeCmd.
+// It only works when pd-server is started with --enable-test-api.
+func NewSetStoreStatusCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:    "set-status <store_id> [--capacity <bytes>] [--available <bytes>] [--region-count <count>]",
+		Short:  "override the reported status of a store, only for tests",
+		Hidden: true,
+		Run:    setStoreStatusCommandFunc,
+	}
+	s.Flags().Uint64("capacity", 0, "the capacity of the store in bytes")
+	s.Flags().Uint64("available", 0, "the available size of the store in bytes")
+	s.Flags().Int("region-count", 0, "the region count of the store")
+	return s
+}
+
+// NewTouchStoreCommand returns a hidden touch subcommand of storeCmd. It only
+// works when pd-server is started with --enable-test-api.
+func NewTouchStoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "touch <store_id>",
+		Short:  "mark the store as just heartbeated, only for tests",
+		Hidden: true,
+		Run:    touchStoreCommandFunc,
+	}
+}
+
+// NewStoreHeartbeatCommand returns a heartbeat subcommand of storeCmd.
+func NewStoreHeartbeatCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "heartbeat",
+		Short: "show the seconds since the last heartbeat of stores, the stalest first",
+		Run:   showStoreHeartbeatCommandFunc,
+	}
+}
+
+// NewStoreSnapshotsCommand returns a snapshots subcommand of storeCmd.
+func NewStoreSnapshotsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "snapshots [<store_id>]",
+		Short: "show the snapshot counts in the last heartbeats of stores, the busiest first",
+		Run:   showStoreSnapshotsCommandFunc,
+	}
+}
+
+// NewStoreBalancePreviewCommand returns a balance-preview subcommand of storeCmd.
+func NewStoreBalancePreviewCommand() *cobra.Command {
+	b := &cobra.Command{
+		Use:   "balance-preview [--limit <n>]",
+		Short: "show the moves the balance schedulers would make now, without making them",
+		Run:   showBalancePreviewCommandFunc,
+	}
+	b.Flags().Int("limit", 10, "the max number of moves of each balance scheduler")
+	return b
+}
+
+// NewStoreFixPlacementCommand returns a fix-placement subcommand of storeCmd.
+func NewStoreFixPlacementCommand() *cobra.Command {
+	f := &cobra.Command{
+		Use:   "fix-placement <store_id> [--dry-run]",
+		Short: "move the peers of the store which break the location labels to better placed stores",
+		Run:   fixStorePlacementCommandFunc,
+	}
+	f.Flags().Bool("dry-run", false, "only show the moves, without making them")
+	return f
+}
+
+// NewExportStoreLabelsCommand returns an export subcommand of storeCmd.
+func NewExportStoreLabelsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <file>",
+		Short: "export the labels of all stores to a JSON file",
+		Run:   exportStoreLabelsCommandFunc,
+	}
+}
+
+// NewImportStoreLabelsCommand returns an import subcommand of storeCmd.
+func NewImportStoreLabelsCommand() *cobra.Command {
+	i := &cobra.Command{
+		Use:   "import <file> [--parallel <n>] [--qps <n>]",
+		Short: "replace the labels of stores with the ones exported to the JSON file",
+		Run:   importStoreLabelsCommandFunc,
+	}
+	i.Flags().Int("parallel", 8, "the number of stores to apply labels at the same time")
+	i.Flags().Int("qps", 0, "the max number of stores to apply labels per second, 0 means no limit")
+	return i
+}
+
 func showStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if watchCommand(cmd, args, showStoreCommandFunc) {
+		return
+	}
 	var prefix string
 	prefix = storesPrefix
 	if len(args) == 1 {
 		if _, err := strconv.Atoi(args[0]); err != nil {
-			fmt.Println("store_id should be a number")
+			fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
 			return
 		}
 		prefix = fmt.Sprintf(storePrefix, args[0])
 	}
+	countOnly, _ := cmd.Flags().GetBool("count-only")
+	if countOnly && len(args) == 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store --count-only")
+		return
+	}
+	sortKey, _ := cmd.Flags().GetString("sort")
+	if sortKey != "" && len(args) == 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store --sort <key>")
+		return
+	}
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		format = storeFormatJSON
+	}
+	if !isStoreFormat(format) {
+		fmt.Fprintf(cmd.OutOrStdout(), "Unknown format %q, it should be one of %s\n", format, strings.Join(storeFormats, ", "))
+		return
+	}
+	if table, _ := cmd.Flags().GetBool("table"); table {
+		format = storeFormatTable
+	}
+	raw := rawOutput(cmd)
+	if raw {
+		format = storeFormatJSON
+	}
+	if format == storeFormatTable && (countOnly || len(args) == 1) {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store --format=table")
+		return
+	}
+	if format == storeFormatCompact && countOnly {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store --format=compact [<store_id>]")
+		return
+	}
+	if format == storeFormatNDJSON && countOnly {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store --format=ndjson [<store_id>]")
+		return
+	}
 	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err == errNotModified {
+		return
+	}
 	if err != nil {
-		fmt.Printf("Failed to get store: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get store: %s\n", err)
+		return
+	}
+	if raw {
+		printResponse(cmd, r)
+		return
+	}
+	if checkEmptyResponse(cmd, r) {
+		return
+	}
+	if countOnly {
+		var stores storesInfo
+		if err := json.Unmarshal([]byte(r), &stores); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse stores: %s\n", err)
+			return
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), len(stores.Stores))
 		return
 	}
-	fmt.Println(r)
+	if sortKey != "" {
+		sorted, err := sortStores(r, sortKey)
+		if err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to sort stores: %s\n", err)
+			return
+		}
+		r = sorted
+	}
+	switch format {
+	case storeFormatTable:
+		err = printStoreTable(cmd, cmd.OutOrStdout(), r)
+	case storeFormatCompact:
+		err = printStoreCompact(cmd.OutOrStdout(), r, len(args) == 1)
+	case storeFormatNDJSON:
+		key := "stores"
+		if len(args) == 1 {
+			key = ""
+		}
+		err = printNDJSON(cmd.OutOrStdout(), strings.NewReader(r), key)
+	default:
+		printResponse(cmd, r)
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse stores: %s\n", err)
+	}
 }
 
-func deleteStoreCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		fmt.Println("Usage: store delete <store_id>")
+// The output formats of the store command.
+const (
+	storeFormatJSON    = "json"
+	storeFormatTable   = "table"
+	storeFormatCompact = "compact"
+	storeFormatNDJSON  = "ndjson"
+)
+
+var storeFormats = []string{storeFormatJSON, storeFormatTable, storeFormatCompact, storeFormatNDJSON}
+
+func isStoreFormat(format string) bool {
+	for _, f := range storeFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+type storeTableInfo struct {
+	Store struct {
+		ID        uint64 `json:"id"`
+		Address   string `json:"address"`
+		StateName string `json:"state_name"`
+	} `json:"store"`
+	Status struct {
+		Capacity    string `json:"capacity"`
+		Available   string `json:"available"`
+		LeaderCount int    `json:"leader_count"`
+		RegionCount int    `json:"region_count"`
+		IsBusy      bool   `json:"is_busy"`
+	} `json:"status"`
+}
+
+// yesNo shows a flag of a store, such as is_busy.
+func yesNo(flag bool) string {
+	if flag {
+		return "yes"
+	}
+	return "no"
+}
+
+var storeStateColors = map[string]int{
+	"Up":        colorGreen,
+	"Offline":   colorYellow,
+	"Down":      colorRed,
+	"Tombstone": colorRed,
+}
+
+// printStoreTable prints the stores in a table. The state is the last column,
+// so its color does not break the alignment.
+func printStoreTable(cmd *cobra.Command, out io.Writer, r string) error {
+	var info struct {
+		Stores []*storeTableInfo `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tADDRESS\tCAPACITY\tAVAILABLE\tLEADERS\tREGIONS\tBUSY\tSTATE")
+	for _, s := range info.Stores {
+		state := s.Store.StateName
+		if color, ok := storeStateColors[state]; ok {
+			state = colorize(cmd, state, color)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", s.Store.ID, s.Store.Address, s.Status.Capacity,
+			s.Status.Available, s.Status.LeaderCount, s.Status.RegionCount, yesNo(s.Status.IsBusy), state)
+	}
+	return w.Flush()
+}
+
+// printStoreCompact prints one store per line, such as
+// "1 127.0.0.1:20160 Up regions=36 leaders=12", which is easy to grep. A
+// busy store ends with "busy". The response is of a single store if single is
+// set.
+func printStoreCompact(out io.Writer, r string, single bool) error {
+	var info struct {
+		Stores []*storeTableInfo `json:"stores"`
+	}
+	if single {
+		store := &storeTableInfo{}
+		if err := json.Unmarshal([]byte(r), store); err != nil {
+			return err
+		}
+		info.Stores = []*storeTableInfo{store}
+	} else if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return err
+	}
+	for _, s := range info.Stores {
+		busy := ""
+		if s.Status.IsBusy {
+			busy = " busy"
+		}
+		fmt.Fprintf(out, "%d %s %s regions=%d leaders=%d%s\n", s.Store.ID, s.Store.Address, s.Store.StateName,
+			s.Status.RegionCount, s.Status.LeaderCount, busy)
+	}
+	return nil
+}
+
+// printBusyStores prints the count of the busy stores and a line for each of
+// them, such as "store 1: 127.0.0.1:20160".
+func printBusyStores(out io.Writer, r string) error {
+	var info struct {
+		Stores []*storeTableInfo `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return err
+	}
+	var busy []*storeTableInfo
+	for _, s := range info.Stores {
+		if s.Status.IsBusy {
+			busy = append(busy, s)
+		}
+	}
+	fmt.Fprintf(out, "count: %d\n", len(busy))
+	for _, s := range busy {
+		fmt.Fprintf(out, "store %d: %s\n", s.Store.ID, s.Store.Address)
+	}
+	return nil
+}
+
+func showBusyStoresCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store busy")
 		return
 	}
-	if _, err := strconv.Atoi(args[0]); err != nil {
-		fmt.Println("store_id should be a number")
+	r, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
 		return
 	}
-	prefix := fmt.Sprintf(storePrefix, args[0])
-	_, err := doRequest(cmd, prefix, http.MethodDelete)
+	if checkEmptyResponse(cmd, r) {
+		return
+	}
+	if err = printBusyStores(cmd.OutOrStdout(), r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse stores: %s\n", err)
+	}
+}
+
+// storeUsageInfo holds the fields of a listed store to compute its used ratio.
+type storeUsageInfo struct {
+	Store struct {
+		ID        uint64 `json:"id"`
+		Address   string `json:"address"`
+		StateName string `json:"state_name"`
+	} `json:"store"`
+	Status struct {
+		Capacity  typeutil.ByteSize `json:"capacity"`
+		Available typeutil.ByteSize `json:"available"`
+	} `json:"status"`
+}
+
+func (s *storeUsageInfo) usedRatio() float64 {
+	return 1 - float64(s.Status.Available)/float64(s.Status.Capacity)
+}
+
+// printNearFullStores prints the stores whose used ratio exceeds threshold,
+// the fullest first, and returns how many they are. Tombstone stores and
+// the stores which do not report their capacity are skipped.
+func printNearFullStores(out io.Writer, r string, threshold float64) (int, error) {
+	var info struct {
+		Stores []*storeUsageInfo `json:"stores"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return 0, err
+	}
+	var full []*storeUsageInfo
+	for _, s := range info.Stores {
+		if s.Store.StateName == "Tombstone" || s.Status.Capacity == 0 {
+			continue
+		}
+		if s.usedRatio() > threshold {
+			full = append(full, s)
+		}
+	}
+	sort.Slice(full, func(i, j int) bool { return full[i].usedRatio() > full[j].usedRatio() })
+
+	fmt.Fprintf(out, "count: %d\n", len(full))
+	if len(full) == 0 {
+		return 0, nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tADDRESS\tCAPACITY\tAVAILABLE\tUSED")
+	for _, s := range full {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.1f%%\n", s.Store.ID, s.Store.Address, gh.IBytes(uint64(s.Status.Capacity)),
+			gh.IBytes(uint64(s.Status.Available)), s.usedRatio()*100)
+	}
+	return len(full), w.Flush()
+}
+
+// nearFullErrorExitCode is the exit code of store near-full if it fails, 1
+// means some stores exceed the threshold.
+const nearFullErrorExitCode = 2
+
+func showNearFullStoresCommandFunc(cmd *cobra.Command, args []string) {
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	if len(args) != 0 || threshold <= 0 || threshold > 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store near-full [--threshold <ratio>], the ratio is in (0, 1]")
+		exitCode = nearFullErrorExitCode
+		return
+	}
+	r, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		exitCode = nearFullErrorExitCode
+		return
+	}
+	if checkEmptyResponse(cmd, r) {
+		exitCode = nearFullErrorExitCode
+		return
+	}
+	n, err := printNearFullStores(cmd.OutOrStdout(), r, threshold)
 	if err != nil {
-		fmt.Printf("Failed to delete store %s: %s\n", args[0], err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse stores: %s\n", err)
+		exitCode = nearFullErrorExitCode
 		return
 	}
-	fmt.Println("Success!")
+	if n > 0 {
+		exitCode = 1
+	}
 }
 
-func labelStoreCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 3 {
-		fmt.Println("Usage: store label <store_id> <key> <value>")
+type storeHeartbeatAge struct {
+	StoreID      uint64 `json:"store_id"`
+	HeartbeatAge *int64 `json:"heartbeat_age_seconds"`
+}
+
+type storeHeartbeatAges []*storeHeartbeatAge
+
+func (s storeHeartbeatAges) Len() int      { return len(s) }
+func (s storeHeartbeatAges) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// Less puts the stalest store first, stores without heartbeat are the stalest.
+func (s storeHeartbeatAges) Less(i, j int) bool {
+	if s[i].HeartbeatAge == nil || s[j].HeartbeatAge == nil {
+		return s[i].HeartbeatAge == nil && s[j].HeartbeatAge != nil
+	}
+	return *s[i].HeartbeatAge > *s[j].HeartbeatAge
+}
+
+func showStoreHeartbeatCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, storesHeartbeatPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get store heartbeats: %s\n", err)
 		return
 	}
-	if _, err := strconv.Atoi(args[0]); err != nil {
-		fmt.Println("store_id should be a number")
+	var info struct {
+		Stores storeHeartbeatAges `json:"stores"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store heartbeats: %s\n", err)
 		return
 	}
-	prefix := fmt.Sprintf(path.Join(storePrefix, "label"), args[0])
-	postJSON(cmd, prefix, map[string]interface{}{args[1]: args[2]})
+	sort.Stable(info.Stores)
+	for _, store := range info.Stores {
+		if store.HeartbeatAge == nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "store %d: never\n", store.StoreID)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "store %d: %ds\n", store.StoreID, *store.HeartbeatAge)
+	}
+}
+
+type storeSnapshotInfo struct {
+	StoreID            uint64 `json:"store_id"`
+	SendingSnapCount   uint32 `json:"sending_snap_count"`
+	ReceivingSnapCount uint32 `json:"receiving_snap_count"`
+	ApplyingSnapCount  uint32 `json:"applying_snap_count"`
+}
+
+func (s *storeSnapshotInfo) total() uint32 {
+	return s.SendingSnapCount + s.ReceivingSnapCount + s.ApplyingSnapCount
+}
+
+func showStoreSnapshotsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store snapshots [<store_id>]")
+		return
+	}
+	prefix := storesSnapshotsPrefix
+	if len(args) == 1 {
+		if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+			fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+			return
+		}
+		prefix = fmt.Sprintf(storePrefix, args[0]) + "/snapshots"
+	}
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get store snapshots: %s\n", err)
+		return
+	}
+	if err = printStoreSnapshots(cmd.OutOrStdout(), r, len(args) == 0); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store snapshots: %s\n", err)
+	}
+}
+
+// printStoreSnapshots prints the snapshot counts of a store, or of all stores
+// with the total if summary is set. The stores with the most snapshots are
+// printed first.
+func printStoreSnapshots(out io.Writer, r string, summary bool) error {
+	var info struct {
+		Stores []*storeSnapshotInfo `json:"stores"`
+		Total  *storeSnapshotInfo   `json:"total"`
+	}
+	if summary {
+		if err := json.Unmarshal([]byte(r), &info); err != nil {
+			return err
+		}
+	} else {
+		store := &storeSnapshotInfo{}
+		if err := json.Unmarshal([]byte(r), store); err != nil {
+			return err
+		}
+		info.Stores = []*storeSnapshotInfo{store}
+	}
+	sort.SliceStable(info.Stores, func(i, j int) bool {
+		return info.Stores[i].total() > info.Stores[j].total()
+	})
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STORE\tSENDING\tRECEIVING\tAPPLYING")
+	for _, s := range info.Stores {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\n", s.StoreID, s.SendingSnapCount, s.ReceivingSnapCount, s.ApplyingSnapCount)
+	}
+	if info.Total != nil {
+		t := info.Total
+		fmt.Fprintf(w, "total\t%d\t%d\t%d\n", t.SendingSnapCount, t.ReceivingSnapCount, t.ApplyingSnapCount)
+	}
+	return w.Flush()
+}
+
+func showBalancePreviewCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), cmd.UsageString())
+		return
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	r, err := doRequest(cmd, fmt.Sprintf(balancePreviewPrefix, limit), http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get balance preview: %s\n", err)
+		return
+	}
+	if err = printBalancePreview(cmd.OutOrStdout(), r); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse balance preview: %s\n", err)
+	}
+}
+
+func fixStorePlacementCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store fix-placement <store_id> [--dry-run]")
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	prefix := fmt.Sprintf(storeFixPlacementPrefix, args[0], dryRun)
+	r, err := doRequestWithBody(cmd, prefix, http.MethodPost, "", nil)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to fix placement of store %s: %s\n", args[0], err)
+		return
+	}
+	if err = printOperatorSteps(cmd.OutOrStdout(), r, "The peers of the store are placed well, no move is needed"); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse operators: %s\n", err)
+	}
+}
+
+type previewPeer struct {
+	StoreID uint64 `json:"store_id"`
+}
+
+// previewStep is a step of a region operator, only the fields of the used
+// kinds of steps are set.
+type previewStep struct {
+	Name       string       `json:"name"`
+	OldLeader  *previewPeer `json:"old_leader"`
+	NewLeader  *previewPeer `json:"new_leader"`
+	ChangePeer *struct {
+		Peer *previewPeer `json:"peer"`
+	} `json:"change_peer"`
+}
+
+func (s *previewStep) String() string {
+	switch s.Name {
+	case "transfer_leader":
+		if s.OldLeader == nil || s.NewLeader == nil {
+			break
+		}
+		return fmt.Sprintf("transfer leader from store %d to store %d", s.OldLeader.StoreID, s.NewLeader.StoreID)
+	case "add_peer", "remove_peer":
+		if s.ChangePeer != nil && s.ChangePeer.Peer != nil {
+			return fmt.Sprintf("%s on store %d", strings.Replace(s.Name, "_", " ", 1), s.ChangePeer.Peer.StoreID)
+		}
+	}
+	return s.Name
+}
+
+func printBalancePreview(out io.Writer, r string) error {
+	return printOperatorSteps(out, r, "The stores are balanced, no move is needed")
+}
+
+// printOperatorSteps prints a line of the steps of each operator, such as
+// "region 2: add peer on store 4, remove peer on store 1", or the message if
+// there is no operator.
+func printOperatorSteps(out io.Writer, r string, empty string) error {
+	var ops []struct {
+		Region struct {
+			ID uint64 `json:"id"`
+		} `json:"region"`
+		Ops []*previewStep `json:"ops"`
+	}
+	if err := json.Unmarshal([]byte(r), &ops); err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		fmt.Fprintln(out, empty)
+		return nil
+	}
+	for _, op := range ops {
+		steps := make([]string, 0, len(op.Ops))
+		for _, step := range op.Ops {
+			steps = append(steps, step.String())
+		}
+		fmt.Fprintf(out, "region %d: %s\n", op.Region.ID, strings.Join(steps, ", "))
+	}
+	return nil
+}
+
+var storeSortKeys = []string{"id", "region_count", "leader_count", "available"}
+
+// storeSortInfo holds the fields of a listed store that can be sorted on.
+type storeSortInfo struct {
+	Store struct {
+		ID uint64 `json:"id"`
+	} `json:"store"`
+	Status struct {
+		LeaderCount int               `json:"leader_count"`
+		RegionCount int               `json:"region_count"`
+		Available   typeutil.ByteSize `json:"available"`
+	} `json:"status"`
+}
+
+type storeSorter struct {
+	stores []json.RawMessage
+	infos  []*storeSortInfo
+	less   func(a, b *storeSortInfo) bool
+}
+
+func (s *storeSorter) Len() int           { return len(s.stores) }
+func (s *storeSorter) Less(i, j int) bool { return s.less(s.infos[i], s.infos[j]) }
+func (s *storeSorter) Swap(i, j int) {
+	s.stores[i], s.stores[j] = s.stores[j], s.stores[i]
+	s.infos[i], s.infos[j] = s.infos[j], s.infos[i]
+}
+
+// sortStores sorts the stores listed in r by key, which is descending if it
+// is prefixed with '-'.
+func sortStores(r string, key string) (string, error) {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b *storeSortInfo) bool
+	switch key {
+	case "id":
+		less = func(a, b *storeSortInfo) bool { return a.Store.ID < b.Store.ID }
+	case "region_count":
+		less = func(a, b *storeSortInfo) bool { return a.Status.RegionCount < b.Status.RegionCount }
+	case "leader_count":
+		less = func(a, b *storeSortInfo) bool { return a.Status.LeaderCount < b.Status.LeaderCount }
+	case "available":
+		less = func(a, b *storeSortInfo) bool { return a.Status.Available < b.Status.Available }
+	default:
+		return "", errors.Errorf("invalid sort key %q, should be one of %s", key, strings.Join(storeSortKeys, ", "))
+	}
+	if desc {
+		asc := less
+		less = func(a, b *storeSortInfo) bool { return asc(b, a) }
+	}
+
+	var stores storesInfo
+	if err := json.Unmarshal([]byte(r), &stores); err != nil {
+		return "", errors.Trace(err)
+	}
+	sorter := &storeSorter{stores: stores.Stores, less: less}
+	for _, store := range stores.Stores {
+		info := &storeSortInfo{}
+		if err := json.Unmarshal(store, info); err != nil {
+			return "", errors.Trace(err)
+		}
+		sorter.infos = append(sorter.infos, info)
+	}
+	sort.Stable(sorter)
+
+	data, err := json.MarshalIndent(stores, "", "  ")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return string(data), nil
+}
+
+func deleteStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store delete <store_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0])
+	_, err := doRequest(cmd, prefix, http.MethodDelete)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to delete store %s: %s\n", args[0], err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+
+	if wait, _ := cmd.Flags().GetBool("wait"); wait {
+		// The store is still deleted if the waiting is interrupted.
+		fmt.Fprintf(cmd.OutOrStdout(), "Waiting for store %s to be drained, press Ctrl-C to stop waiting\n", args[0])
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if err = waitStoreDrained(cmd, cmd.OutOrStdout(), args[0], timeout); err != nil {
+			fmt.Fprintln(cmd.OutOrStdout(), err)
+			exitCode = 1
+			return
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Store %s is drained\n", args[0])
+	}
+}
+
+// waitStoreDrained waits until the store has no region or is tombstone, and
+// prints the region count on every check, or updates the progress bar if
+// '--progress-bar' is set. A zero timeout waits forever.
+func waitStoreDrained(cmd *cobra.Command, out io.Writer, storeID string, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	bar := newProgressBar(cmd, out)
+	if bar != nil {
+		defer bar.stop()
+	}
+	// The progress is relative to the region count of the first check.
+	var total int
+	prefix := fmt.Sprintf(storePrefix, storeID)
+	for {
+		r, err := doRequest(cmd, prefix, http.MethodGet)
+		if err != nil {
+			return errors.Errorf("Failed to get store %s: %s", storeID, err)
+		}
+		var info struct {
+			Store struct {
+				StateName string `json:"state_name"`
+			} `json:"store"`
+			Status struct {
+				RegionCount int `json:"region_count"`
+			} `json:"status"`
+		}
+		if err = json.Unmarshal([]byte(r), &info); err != nil {
+			return errors.Errorf("Failed to parse store %s: %s", storeID, err)
+		}
+		drained := info.Status.RegionCount == 0 || info.Store.StateName == metapb.StoreState_Tombstone.String()
+		if bar != nil {
+			if total == 0 {
+				total = info.Status.RegionCount
+			}
+			ratio := 1.0
+			if !drained && total > 0 {
+				ratio = float64(total-info.Status.RegionCount) / float64(total)
+			}
+			bar.update(ratio, fmt.Sprintf("store %s: %d regions left, state: %s", storeID, info.Status.RegionCount, info.Store.StateName))
+		}
+		if drained {
+			return nil
+		}
+		if bar == nil {
+			fmt.Fprintf(out, "store %s: %d regions left, state: %s\n", storeID, info.Status.RegionCount, info.Store.StateName)
+		}
+
+		if !deadline.IsZero() && time.Now().Add(storeDrainCheckInterval).After(deadline) {
+			return errStoreDrainTimeout
+		}
+		time.Sleep(storeDrainCheckInterval)
+	}
+}
+
+func labelStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store label <store_id> <key> <value> [--replace]")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "label"), args[0])
+	if replace, _ := cmd.Flags().GetBool("replace"); replace {
+		prefix += "?replace=true"
+	}
+	// null is sent as a JSON null, which removes the label like "".
+	var value interface{} = args[2]
+	if args[2] == "null" {
+		value = nil
+	}
+	postJSON(cmd, prefix, map[string]interface{}{args[1]: value})
+}
+
+func annotateStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store annotate <store_id> <note>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	// The note is not required to be quoted, and the interactive mode splits
+	// the line by spaces, so the quotes are trimmed here.
+	note := strings.Join(args[1:], " ")
+	if len(note) >= 2 && strings.HasPrefix(note, `"`) && strings.HasSuffix(note, `"`) {
+		note = note[1 : len(note)-1]
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "note"), args[0])
+	if _, err := doPostJSON(cmd, prefix, map[string]interface{}{"note": note}); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to annotate store: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func setPreferredLeaderCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store set-preferred-leader <store_id> <true|false>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	preferred, err := strconv.ParseBool(args[1])
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store set-preferred-leader <store_id> <true|false>")
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "preferred-leader"), args[0])
+	if _, err := doPostJSON(cmd, prefix, map[string]interface{}{"preferred": preferred}); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set the preferred leader store: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func setStoreWeightCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store weight <store_id> <leader_weight> <region_weight>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	leader, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || leader <= 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "leader_weight should be a number greater than 0")
+		return
+	}
+	region, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || region <= 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "region_weight should be a number greater than 0")
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "weight"), args[0])
+	if _, err := doPostJSON(cmd, prefix, map[string]interface{}{"leader": leader, "region": region}); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set the store weight: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+// storeAutoWeight is the weight computed for a store.
+type storeAutoWeight struct {
+	store  *storeUsageInfo
+	metric typeutil.ByteSize
+	weight float64
+}
+
+// computeStoreWeights returns the weights of the stores in proportion to the
+// capacity or available size, the mean weight is 1. Tombstone stores and the
+// stores whose metric is 0 are skipped.
+func computeStoreWeights(stores []*storeUsageInfo, by string) []*storeAutoWeight {
+	var (
+		weights []*storeAutoWeight
+		total   float64
+	)
+	for _, s := range stores {
+		metric := s.Status.Capacity
+		if by == "available" {
+			metric = s.Status.Available
+		}
+		if s.Store.StateName == "Tombstone" || metric == 0 {
+			continue
+		}
+		weights = append(weights, &storeAutoWeight{store: s, metric: metric})
+		total += float64(metric)
+	}
+	for _, w := range weights {
+		// The weights are rounded to keep them readable.
+		w.weight = math.Floor(float64(w.metric)/total*float64(len(weights))*100+0.5) / 100
+		if w.weight == 0 {
+			w.weight = 0.01
+		}
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i].store.Store.ID < weights[j].store.Store.ID })
+	return weights
+}
+
+func storeAutoWeightCommandFunc(cmd *cobra.Command, args []string) {
+	by, _ := cmd.Flags().GetString("by")
+	if len(args) != 0 || (by != "capacity" && by != "available") {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store auto-weight [--by capacity|available] [--dry-run]")
+		return
+	}
+	r, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		return
+	}
+	var info struct {
+		Stores []*storeUsageInfo `json:"stores"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse stores: %s\n", err)
+		return
+	}
+	weights := computeStoreWeights(info.Stores, by)
+	if len(weights) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No store reports its %s\n", by)
+		return
+	}
+
+	out := cmd.OutOrStdout()
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tADDRESS\t%s\tWEIGHT\n", strings.ToUpper(by))
+	for _, sw := range weights {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.2f\n", sw.store.Store.ID, sw.store.Store.Address, gh.IBytes(uint64(sw.metric)), sw.weight)
+	}
+	w.Flush()
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Fprintln(out, "Dry run, the weights are not set")
+		return
+	}
+	failed := 0
+	for _, sw := range weights {
+		// The region score is already divided by the capacity, so the region
+		// weight is left at 1 to not count the capacity twice.
+		region := sw.weight
+		if by == "capacity" {
+			region = 1
+		}
+		prefix := fmt.Sprintf(path.Join(storePrefix, "weight"), strconv.FormatUint(sw.store.Store.ID, 10))
+		if _, err := doPostJSON(cmd, prefix, map[string]interface{}{"leader": sw.weight, "region": region}); err != nil {
+			fmt.Fprintf(out, "Failed to set the weight of store %d: %s\n", sw.store.Store.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(out, "%d of %d stores failed\n", failed, len(weights))
+		exitCode = 1
+		return
+	}
+	fmt.Fprintf(out, "Success! The weights of %d stores are set\n", len(weights))
+}
+
+func setStoreAddressCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store set-address <store_id> <address>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0])
+	r, err := doPostJSON(cmd, prefix, map[string]interface{}{"address": args[1]})
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set store address: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func showStoreDetailsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store details <store_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0])
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get store: %s\n", err)
+		return
+	}
+	if checkEmptyResponse(cmd, r) {
+		return
+	}
+	if err = printStoreDetails(cmd, cmd.OutOrStdout(), r, time.Now()); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store: %s\n", err)
+	}
+}
+
+type storeDetails struct {
+	Store struct {
+		ID        uint64               `json:"id"`
+		Address   string               `json:"address"`
+		StateName string               `json:"state_name"`
+		Labels    []*metapb.StoreLabel `json:"labels"`
+	} `json:"store"`
+	Status struct {
+		Capacity        string    `json:"capacity"`
+		Available       string    `json:"available"`
+		LeaderCount     int       `json:"leader_count"`
+		RegionCount     int       `json:"region_count"`
+		IsBusy          bool      `json:"is_busy"`
+		LastHeartbeatTS time.Time `json:"last_heartbeat_ts"`
+	} `json:"status"`
+	Note            string `json:"note"`
+	PreferredLeader bool   `json:"preferred_leader"`
+}
+
+// printStoreDetails prints a field of the store per line, the heartbeat age
+// is relative to now.
+func printStoreDetails(cmd *cobra.Command, out io.Writer, r string, now time.Time) error {
+	var info storeDetails
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		return err
+	}
+	labels := make([]string, 0, len(info.Store.Labels))
+	for _, l := range info.Store.Labels {
+		labels = append(labels, l.GetKey()+"="+l.GetValue())
+	}
+	state := info.Store.StateName
+	if color, ok := storeStateColors[state]; ok {
+		state = colorize(cmd, state, color)
+	}
+	heartbeat := "never"
+	if !info.Status.LastHeartbeatTS.IsZero() {
+		age := now.Sub(info.Status.LastHeartbeatTS) / time.Second * time.Second
+		heartbeat = fmt.Sprintf("%s ago", age)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", info.Store.ID)
+	fmt.Fprintf(w, "Address:\t%s\n", info.Store.Address)
+	fmt.Fprintf(w, "State:\t%s\n", state)
+	fmt.Fprintf(w, "Labels:\t%s\n", strings.Join(labels, ", "))
+	fmt.Fprintf(w, "Capacity:\t%s\n", info.Status.Capacity)
+	fmt.Fprintf(w, "Available:\t%s\n", info.Status.Available)
+	fmt.Fprintf(w, "Regions:\t%d\n", info.Status.RegionCount)
+	fmt.Fprintf(w, "Leaders:\t%d\n", info.Status.LeaderCount)
+	fmt.Fprintf(w, "Busy:\t%s\n", yesNo(info.Status.IsBusy))
+	fmt.Fprintf(w, "Last heartbeat:\t%s\n", heartbeat)
+	if info.PreferredLeader {
+		fmt.Fprintf(w, "Preferred leader:\t%s\n", yesNo(info.PreferredLeader))
+	}
+	if info.Note != "" {
+		fmt.Fprintf(w, "Note:\t%s\n", info.Note)
+	}
+	return w.Flush()
+}
+
+func showStoreGRPCStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store grpc-status <store_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "grpc-status"), args[0])
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to probe store: %s\n", err)
+		return
+	}
+	var info struct {
+		Address   string `json:"address"`
+		Reachable bool   `json:"reachable"`
+		Latency   string `json:"latency"`
+		Error     string `json:"error"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store status: %s\n", err)
+		return
+	}
+	if info.Reachable {
+		fmt.Fprintf(cmd.OutOrStdout(), "store %s (%s): reachable, latency: %s\n", args[0], info.Address, info.Latency)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "store %s (%s): unreachable, latency: %s, error: %s\n", args[0], info.Address, info.Latency, info.Error)
+}
+
+func setStoreStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store set-status <store_id> [--capacity <bytes>] [--available <bytes>] [--region-count <count>]")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+
+	// Only send the fields which are set, the others are left unchanged.
+	input := make(map[string]interface{})
+	if cmd.Flags().Changed("capacity") {
+		input["capacity"], _ = cmd.Flags().GetUint64("capacity")
+	}
+	if cmd.Flags().Changed("available") {
+		input["available"], _ = cmd.Flags().GetUint64("available")
+	}
+	if cmd.Flags().Changed("region-count") {
+		input["region_count"], _ = cmd.Flags().GetInt("region-count")
+	}
+
+	prefix := fmt.Sprintf(path.Join(storePrefix, "status"), args[0])
+	if _, err := doPostJSON(cmd, prefix, input); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to set store status: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Success!")
+}
+
+func touchStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store touch <store_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(path.Join(storePrefix, "touch"), args[0])
+	r, err := doRequest(cmd, prefix, http.MethodPost)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to touch store: %s\n", err)
+		return
+	}
+	var age storeHeartbeatAge
+	if err = json.Unmarshal([]byte(r), &age); err != nil || age.HeartbeatAge == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse the heartbeat age: %s\n", r)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! store %d: last heartbeat %ds ago\n", age.StoreID, *age.HeartbeatAge)
+}
+
+// getStoreLabels returns the labels of all stores, keyed by store id.
+func getStoreLabels(cmd *cobra.Command) (map[uint64]map[string]string, error) {
+	r, err := doRequest(cmd, storesPrefix, http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	var stores storesInfo
+	if err = json.Unmarshal([]byte(r), &stores); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	labels := make(map[uint64]map[string]string, len(stores.Stores))
+	for _, data := range stores.Stores {
+		var info struct {
+			Store *metapb.Store `json:"store"`
+		}
+		if err = json.Unmarshal(data, &info); err != nil {
+			return nil, errors.Trace(err)
+		}
+		storeLabels := make(map[string]string)
+		for _, label := range info.Store.GetLabels() {
+			storeLabels[label.GetKey()] = label.GetValue()
+		}
+		labels[info.Store.GetId()] = storeLabels
+	}
+	return labels, nil
+}
+
+func exportStoreLabelsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store export <file>")
+		return
+	}
+	labels, err := getStoreLabels(cmd)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		return
+	}
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to encode store labels: %s\n", err)
+		return
+	}
+	if err = ioutil.WriteFile(args[0], data, 0644); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to write store labels: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! The labels of %d stores are exported\n", len(labels))
+}
+
+func importStoreLabelsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store import <file>")
+		return
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to read store labels: %s\n", err)
+		return
+	}
+	var labels map[uint64]map[string]string
+	if err = json.Unmarshal(data, &labels); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store labels: %s\n", err)
+		return
+	}
+	current, err := getStoreLabels(cmd)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get stores: %s\n", err)
+		return
+	}
+
+	ids := make([]uint64, 0, len(labels))
+	for id := range labels {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	qps, _ := cmd.Flags().GetInt("qps")
+	errs := applyStoreLabels(ids, parallel, qps, func(id uint64) error {
+		if _, ok := current[id]; !ok {
+			return errStoreNotFound
+		}
+		input := make(map[string]interface{}, len(labels[id]))
+		for k, v := range labels[id] {
+			input[k] = v
+		}
+		prefix := fmt.Sprintf(path.Join(storePrefix, "label"), strconv.FormatUint(id, 10)) + "?replace=true"
+		_, err := doPostJSON(cmd, prefix, input)
+		return err
+	})
+
+	var applied, skipped, failed int
+	for i, id := range ids {
+		switch errs[i] {
+		case nil:
+			applied++
+		case errStoreNotFound:
+			fmt.Fprintf(cmd.OutOrStdout(), "Skip store %d: %s\n", id, errs[i])
+			skipped++
+		default:
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to apply store %d: %s\n", id, strings.TrimSpace(errs[i].Error()))
+			failed++
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%d applied, %d skipped, %d failed\n", applied, skipped, failed)
+	if failed > 0 {
+		exitCode = 1
+	}
+}
+
+// applyStoreLabels runs apply for the stores with at most parallel workers, and
+// at most qps stores per second if qps is positive. It returns the error of
+// each store, a failed store does not stop the others.
+func applyStoreLabels(ids []uint64, parallel int, qps int, apply func(uint64) error) []error {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	var tick <-chan time.Time
+	if qps > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(qps))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	errs := make([]error, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = apply(ids[i])
+			}
+		}()
+	}
+	for i := range ids {
+		if tick != nil {
+			<-tick
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func removeLabelStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store remove-label <store_id> <key> [<key>...]")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0])
+	for _, key := range args[1:] {
+		if _, err := doRequest(cmd, path.Join(prefix, "label", url.PathEscape(key)), http.MethodDelete); err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to remove label %s: %s\n", key, err)
+			return
+		}
+	}
+
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get store: %s\n", err)
+		return
+	}
+	var info struct {
+		Store *metapb.Store `json:"store"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse store: %s\n", err)
+		return
+	}
+	labels := make(map[string]string)
+	for _, label := range info.Store.GetLabels() {
+		labels[label.GetKey()] = label.GetValue()
+	}
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to encode labels: %s\n", err)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(data))
+}
+
+func showStorePendingPeersCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store pending-peers <store_id>")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/regions?check=pending-peer"
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get pending peers: %s\n", err)
+		return
+	}
+	var regions struct {
+		Count   int              `json:"count"`
+		Regions []*metapb.Region `json:"regions"`
+	}
+	if err = json.Unmarshal([]byte(r), &regions); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse pending peers: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "count: %d\n", regions.Count)
+	for _, region := range regions.Regions {
+		fmt.Fprintf(cmd.OutOrStdout(), "region %d: start_key: %q, end_key: %q\n", region.GetId(), region.GetStartKey(), region.GetEndKey())
+	}
+}
+
+func relocateStoreCommandFunc(cmd *cobra.Command, args []string) {
+	to, _ := cmd.Flags().GetString("to")
+	if len(args) != 1 || to == "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store relocate <store_id> --to <store_id>[,<store_id>...]")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+	targets, err := parseUint64s(strings.Split(to, ","))
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "target store ids should be numbers")
+		return
+	}
+
+	prefix := fmt.Sprintf(path.Join(storePrefix, "relocate"), args[0])
+	r, err := doPostJSON(cmd, prefix, map[string]interface{}{"to_store_ids": targets})
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to relocate store %s: %s\n", args[0], err)
+		return
+	}
+	var info struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse response: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! %d regions are scheduled\n", info.Count)
+}
+
+func transferStoreLeadersCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: store transfer-leaders <store_id> [--wait]")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), "store_id should be a number")
+		return
+	}
+
+	prefix := fmt.Sprintf(path.Join(storePrefix, "transfer-leaders"), args[0])
+	r, err := doRequest(cmd, prefix, http.MethodPost)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to transfer the leaders of store %s: %s\n", args[0], err)
+		return
+	}
+	var info struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse response: %s\n", err)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Success! %d regions are scheduled\n", info.Count)
+
+	if wait, _ := cmd.Flags().GetBool("wait"); wait {
+		fmt.Fprintf(cmd.OutOrStdout(), "Waiting for store %s to have no leader, press Ctrl-C to stop waiting\n", args[0])
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if err = waitStoreLeadersTransferred(cmd, cmd.OutOrStdout(), args[0], timeout); err != nil {
+			fmt.Fprintln(cmd.OutOrStdout(), err)
+			exitCode = 1
+			return
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Store %s has no leader\n", args[0])
+	}
+}
+
+// waitStoreLeadersTransferred waits until the store has no leader, and prints
+// the leader count on every check. A zero timeout waits forever.
+func waitStoreLeadersTransferred(cmd *cobra.Command, out io.Writer, storeID string, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	prefix := fmt.Sprintf(storePrefix, storeID)
+	for {
+		r, err := doRequest(cmd, prefix, http.MethodGet)
+		if err != nil {
+			return errors.Errorf("Failed to get store %s: %s", storeID, err)
+		}
+		var info struct {
+			Status struct {
+				LeaderCount int `json:"leader_count"`
+			} `json:"status"`
+		}
+		if err = json.Unmarshal([]byte(r), &info); err != nil {
+			return errors.Errorf("Failed to parse store %s: %s", storeID, err)
+		}
+		if info.Status.LeaderCount == 0 {
+			return nil
+		}
+		fmt.Fprintf(out, "store %s: %d leaders left\n", storeID, info.Status.LeaderCount)
+
+		if !deadline.IsZero() && time.Now().Add(storeDrainCheckInterval).After(deadline) {
+			return errStoreLeadersTimeout
+		}
+		time.Sleep(storeDrainCheckInterval)
+	}
 }
@@ -0,0 +1,732 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testStoreSuite{})
+
+type testStoreSuite struct{}
+
+const testStores = `{
+  "count": 2,
+  "stores": [
+    {
+      "store": {"id": 1, "address": "127.0.0.1:20160", "state_name": "Up"},
+      "status": {"capacity": "100 GiB", "available": "60 GiB", "leader_count": 12, "region_count": 36}
+    },
+    {
+      "store": {"id": 2, "address": "127.0.0.1:20161", "state_name": "Down"},
+      "status": {"capacity": "100 GiB", "available": "1.5 GiB", "leader_count": 0, "region_count": 30, "is_busy": true}
+    }
+  ]
+}`
+
+func (s *testStoreSuite) TestStoreTable(c *C) {
+	origin := isTerminal
+	defer func() { isTerminal = origin }()
+	terminal := true
+	isTerminal = func(*os.File) bool { return terminal }
+
+	expected := "ID  ADDRESS          CAPACITY  AVAILABLE  LEADERS  REGIONS  BUSY  STATE\n" +
+		"1   127.0.0.1:20160  100 GiB   60 GiB     12       36       no    \033[32mUp\033[0m\n" +
+		"2   127.0.0.1:20161  100 GiB   1.5 GiB    0        30       yes   \033[31mDown\033[0m\n"
+	cmd := newTestCommand("", "")
+	var out bytes.Buffer
+	c.Assert(printStoreTable(cmd, &out, testStores), IsNil)
+	c.Assert(out.String(), Equals, expected)
+
+	// Colors are disabled by '--no-color' or if stdout is not a terminal.
+	plain := "ID  ADDRESS          CAPACITY  AVAILABLE  LEADERS  REGIONS  BUSY  STATE\n" +
+		"1   127.0.0.1:20160  100 GiB   60 GiB     12       36       no    Up\n" +
+		"2   127.0.0.1:20161  100 GiB   1.5 GiB    0        30       yes   Down\n"
+	c.Assert(cmd.Flags().Set("no-color", "true"), IsNil)
+	out.Reset()
+	c.Assert(printStoreTable(cmd, &out, testStores), IsNil)
+	c.Assert(out.String(), Equals, plain)
+
+	cmd = newTestCommand("", "")
+	terminal = false
+	out.Reset()
+	c.Assert(printStoreTable(cmd, &out, testStores), IsNil)
+	c.Assert(out.String(), Equals, plain)
+}
+
+func (s *testStoreSuite) TestStoreCompact(c *C) {
+	var out bytes.Buffer
+	c.Assert(printStoreCompact(&out, testStores, false), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"1 127.0.0.1:20160 Up regions=36 leaders=12\n"+
+		"2 127.0.0.1:20161 Down regions=30 leaders=0 busy\n")
+
+	single := `{"store": {"id": 3, "address": "127.0.0.1:20162", "state_name": "Offline"}, "status": {"leader_count": 1, "region_count": 2}}`
+	out.Reset()
+	c.Assert(printStoreCompact(&out, single, true), IsNil)
+	c.Assert(out.String(), Equals, "3 127.0.0.1:20162 Offline regions=2 leaders=1\n")
+}
+
+func (s *testStoreSuite) TestLabelStore(c *C) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1/label")
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		body = string(b)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("replace", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	labelStoreCommandFunc(cmd, []string{"1", "zone", "cn"})
+	c.Assert(body, Equals, `{"zone":"cn"}`)
+	labelStoreCommandFunc(cmd, []string{"1", "zone", "null"})
+	c.Assert(body, Equals, `{"zone":null}`)
+	c.Assert(out.String(), Equals, "")
+}
+
+func (s *testStoreSuite) TestSetPreferredLeader(c *C) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1/preferred-leader")
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		body = string(b)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	setPreferredLeaderCommandFunc(cmd, []string{"1", "true"})
+	c.Assert(out.String(), Equals, "Success!\n")
+	c.Assert(body, Equals, `{"preferred":true}`)
+
+	out.Reset()
+	setPreferredLeaderCommandFunc(cmd, []string{"1", "yes"})
+	c.Assert(out.String(), Equals, "Usage: store set-preferred-leader <store_id> <true|false>\n")
+}
+
+func (s *testStoreSuite) TestSetStoreWeight(c *C) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1/weight")
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		body = string(b)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	setStoreWeightCommandFunc(cmd, []string{"1", "2", "0.5"})
+	c.Assert(out.String(), Equals, "Success!\n")
+	c.Assert(body, Equals, `{"leader":2,"region":0.5}`)
+
+	out.Reset()
+	setStoreWeightCommandFunc(cmd, []string{"1", "0", "1"})
+	c.Assert(out.String(), Equals, "leader_weight should be a number greater than 0\n")
+}
+
+func (s *testStoreSuite) TestStoreAutoWeight(c *C) {
+	origin := exitCode
+	defer func() { exitCode = origin }()
+	var (
+		mu     sync.Mutex
+		bodies = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, testStores)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		mu.Lock()
+		bodies[r.URL.Path] = string(b)
+		mu.Unlock()
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("by", "capacity", "")
+	cmd.Flags().Bool("dry-run", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	exitCode = 0
+	c.Assert(cmd.Flags().Set("by", "available"), IsNil)
+	c.Assert(cmd.Flags().Set("dry-run", "true"), IsNil)
+	storeAutoWeightCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "ID  ADDRESS          AVAILABLE  WEIGHT\n"+
+		"1   127.0.0.1:20160  60 GiB     1.95\n"+
+		"2   127.0.0.1:20161  1.5 GiB    0.05\n"+
+		"Dry run, the weights are not set\n")
+	c.Assert(bodies, HasLen, 0)
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("by", "capacity"), IsNil)
+	c.Assert(cmd.Flags().Set("dry-run", "false"), IsNil)
+	storeAutoWeightCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "ID  ADDRESS          CAPACITY  WEIGHT\n"+
+		"1   127.0.0.1:20160  100 GiB   1.00\n"+
+		"2   127.0.0.1:20161  100 GiB   1.00\n"+
+		"Success! The weights of 2 stores are set\n")
+	c.Assert(bodies, DeepEquals, map[string]string{
+		"/pd/api/v1/store/1/weight": `{"leader":1,"region":1}`,
+		"/pd/api/v1/store/2/weight": `{"leader":1,"region":1}`,
+	})
+	c.Assert(exitCode, Equals, 0)
+
+	// The region weight is only set by the available size.
+	out.Reset()
+	c.Assert(cmd.Flags().Set("by", "available"), IsNil)
+	storeAutoWeightCommandFunc(cmd, nil)
+	c.Assert(bodies, DeepEquals, map[string]string{
+		"/pd/api/v1/store/1/weight": `{"leader":1.95,"region":1.95}`,
+		"/pd/api/v1/store/2/weight": `{"leader":0.05,"region":0.05}`,
+	})
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("by", "used"), IsNil)
+	storeAutoWeightCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Usage: store auto-weight [--by capacity|available] [--dry-run]\n")
+
+	// Tombstone stores and the stores without the metric are skipped.
+	tombstone, empty := &storeUsageInfo{}, &storeUsageInfo{}
+	tombstone.Store.StateName, tombstone.Status.Capacity = "Tombstone", 100
+	c.Assert(computeStoreWeights([]*storeUsageInfo{tombstone, empty}, "capacity"), HasLen, 0)
+}
+
+func (s *testStoreSuite) TestBusyStores(c *C) {
+	server := newTestServer(false, testStores)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	showBusyStoresCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "count: 1\nstore 2: 127.0.0.1:20161\n")
+
+	out.Reset()
+	showBusyStoresCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Equals, "Usage: store busy\n")
+}
+
+func (s *testStoreSuite) TestNearFullStores(c *C) {
+	origin := exitCode
+	defer func() { exitCode = origin }()
+	server := newTestServer(false, testStores)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Float64("threshold", 0.8, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	exitCode = 0
+	showNearFullStoresCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "count: 1\n"+
+		"ID  ADDRESS          CAPACITY  AVAILABLE  USED\n"+
+		"2   127.0.0.1:20161  100 GiB   1.5 GiB    98.5%\n")
+	c.Assert(exitCode, Equals, 1)
+
+	// The fullest store is the first.
+	out.Reset()
+	c.Assert(cmd.Flags().Set("threshold", "0.3"), IsNil)
+	showNearFullStoresCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "count: 2\n"+
+		"ID  ADDRESS          CAPACITY  AVAILABLE  USED\n"+
+		"2   127.0.0.1:20161  100 GiB   1.5 GiB    98.5%\n"+
+		"1   127.0.0.1:20160  100 GiB   60 GiB     40.0%\n")
+
+	exitCode = 0
+	out.Reset()
+	c.Assert(cmd.Flags().Set("threshold", "0.99"), IsNil)
+	showNearFullStoresCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "count: 0\n")
+	c.Assert(exitCode, Equals, 0)
+
+	exitCode = 0
+	out.Reset()
+	c.Assert(cmd.Flags().Set("threshold", "80"), IsNil)
+	showNearFullStoresCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Usage: store near-full [--threshold <ratio>], the ratio is in (0, 1]\n")
+	c.Assert(exitCode, Equals, nearFullErrorExitCode)
+
+	// Failing to check the stores is not taken as no store is near full.
+	check := func(url string) {
+		exitCode = 0
+		cmd := newTestCommand(url, "")
+		cmd.Flags().Float64("threshold", 0.8, "")
+		cmd.SetOutput(ioutil.Discard)
+		showNearFullStoresCommandFunc(cmd, nil)
+		c.Assert(exitCode, Equals, nearFullErrorExitCode, Commentf("url %s", url))
+	}
+	for _, body := range []string{"", "not json"} {
+		badServer := newTestServer(false, body)
+		check(badServer.URL)
+		badServer.Close()
+	}
+	downServer := newTestServer(false, "")
+	downServer.Close()
+	check(downServer.URL)
+
+	// Tombstone stores and the stores without capacity are skipped.
+	out.Reset()
+	n, err := printNearFullStores(&out, `{"stores": [
+	  {"store": {"id": 1, "state_name": "Tombstone"}, "status": {"capacity": "1 GiB", "available": "0 B"}},
+	  {"store": {"id": 2, "state_name": "Up"}, "status": {}}
+	]}`, 0.8)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+	c.Assert(out.String(), Equals, "count: 0\n")
+}
+
+func (s *testStoreSuite) TestTransferStoreLeaders(c *C) {
+	origin, originExitCode := storeDrainCheckInterval, exitCode
+	defer func() { storeDrainCheckInterval, exitCode = origin, originExitCode }()
+	storeDrainCheckInterval = time.Millisecond
+
+	leaderCount := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/store/1/transfer-leaders":
+			c.Assert(r.Method, Equals, http.MethodPost)
+			fmt.Fprint(w, `{"count": 2}`)
+		case "/pd/api/v1/store/1":
+			fmt.Fprintf(w, `{"store": {"id": 1}, "status": {"leader_count": %d}}`, leaderCount)
+			if leaderCount > 0 {
+				leaderCount--
+			}
+		default:
+			c.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("wait", true, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	transferStoreLeadersCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Equals, "Success! 2 regions are scheduled\n"+
+		"Waiting for store 1 to have no leader, press Ctrl-C to stop waiting\n"+
+		"store 1: 2 leaders left\n"+
+		"store 1: 1 leaders left\n"+
+		"Store 1 has no leader\n")
+
+	leaderCount = 100
+	out.Reset()
+	c.Assert(waitStoreLeadersTransferred(cmd, &out, "1", 10*time.Millisecond), Equals, errStoreLeadersTimeout)
+
+	// The timeout is reported by the exit code, so the interactive mode goes on.
+	exitCode = 0
+	out.Reset()
+	c.Assert(cmd.Flags().Set("timeout", "50ms"), IsNil)
+	transferStoreLeadersCommandFunc(cmd, []string{"1"})
+	c.Assert(strings.HasSuffix(out.String(), errStoreLeadersTimeout.Error()+"\n"), IsTrue)
+	c.Assert(exitCode, Equals, 1)
+	c.Assert(cmd.Flags().Set("timeout", "0"), IsNil)
+
+	out.Reset()
+	transferStoreLeadersCommandFunc(cmd, []string{"a"})
+	c.Assert(out.String(), Equals, "store_id should be a number\n")
+}
+
+func (s *testStoreSuite) TestDeleteStoreWait(c *C) {
+	origin, originExitCode := storeDrainCheckInterval, exitCode
+	defer func() { storeDrainCheckInterval, exitCode = origin, originExitCode }()
+	storeDrainCheckInterval = time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1")
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"store": {"state_name": "Offline"}, "status": {"region_count": 100}}`)
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("wait", true, "")
+	c.Assert(cmd.Flags().Set("timeout", "50ms"), IsNil)
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	// The timeout is reported by the exit code, so the interactive mode goes on.
+	exitCode = 0
+	deleteStoreCommandFunc(cmd, []string{"1"})
+	c.Assert(strings.HasPrefix(out.String(), "Success!\nWaiting for store 1 to be drained"), IsTrue)
+	c.Assert(strings.HasSuffix(out.String(), errStoreDrainTimeout.Error()+"\n"), IsTrue)
+	c.Assert(exitCode, Equals, 1)
+}
+
+func (s *testStoreSuite) TestWaitStoreDrained(c *C) {
+	origin := storeDrainCheckInterval
+	defer func() { storeDrainCheckInterval = origin }()
+	storeDrainCheckInterval = time.Millisecond
+
+	regionCount := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1")
+		fmt.Fprintf(w, `{"store": {"state_name": "Offline"}, "status": {"region_count": %d}}`, regionCount)
+		if regionCount > 0 {
+			regionCount--
+		}
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+
+	var out bytes.Buffer
+	c.Assert(waitStoreDrained(cmd, &out, "1", 0), IsNil)
+	c.Assert(out.String(), Equals, "store 1: 2 regions left, state: Offline\n"+
+		"store 1: 1 regions left, state: Offline\n")
+
+	regionCount = 100
+	out.Reset()
+	c.Assert(waitStoreDrained(cmd, &out, "1", 10*time.Millisecond), Equals, errStoreDrainTimeout)
+
+	// The progress is printed on new lines if stdout is not a terminal.
+	regionCount = 4
+	out.Reset()
+	cmd.Flags().Bool("progress-bar", true, "")
+	c.Assert(waitStoreDrained(cmd, &out, "1", 0), IsNil)
+	c.Assert(out.String(), Equals, "  0% store 1: 4 regions left, state: Offline\n"+
+		" 25% store 1: 3 regions left, state: Offline\n"+
+		" 50% store 1: 2 regions left, state: Offline\n"+
+		" 75% store 1: 1 regions left, state: Offline\n"+
+		"100% store 1: 0 regions left, state: Offline\n")
+}
+
+func (s *testStoreSuite) TestShowStoreOutput(c *C) {
+	server := newTestServer(false, testStores)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	showStoreCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, testStores+"\n")
+
+	out.Reset()
+	showStoreCommandFunc(cmd, []string{"a"})
+	c.Assert(out.String(), Equals, "store_id should be a number\n")
+}
+
+func (s *testStoreSuite) TestShowStoreNDJSON(c *C) {
+	server := newTestServer(false, testStores)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("format", "", "")
+	cmd.Flags().Bool("count-only", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	c.Assert(cmd.Flags().Set("format", storeFormatNDJSON), IsNil)
+	showStoreCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, `{"store":{"id":1,"address":"127.0.0.1:20160","state_name":"Up"},`+
+		`"status":{"capacity":"100 GiB","available":"60 GiB","leader_count":12,"region_count":36}}`+"\n"+
+		`{"store":{"id":2,"address":"127.0.0.1:20161","state_name":"Down"},`+
+		`"status":{"capacity":"100 GiB","available":"1.5 GiB","leader_count":0,"region_count":30,"is_busy":true}}`+"\n")
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("count-only", "true"), IsNil)
+	showStoreCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Usage: store --format=ndjson [<store_id>]\n")
+}
+
+func (s *testStoreSuite) TestStoreDetails(c *C) {
+	now := time.Date(2017, 9, 1, 12, 0, 0, 0, time.UTC)
+	r := `{
+  "store": {"id": 1, "address": "127.0.0.1:20160", "state_name": "Up",
+    "labels": [{"key": "zone", "value": "z1"}, {"key": "host", "value": "h1"}]},
+  "status": {"capacity": "100 GiB", "available": "60 GiB", "leader_count": 12, "region_count": 36,
+    "last_heartbeat_ts": "2017-09-01T11:59:54.5Z"},
+  "note": "disk replaced"
+}`
+	cmd := newTestCommand("", "")
+	var out bytes.Buffer
+	c.Assert(printStoreDetails(cmd, &out, r, now), IsNil)
+	c.Assert(out.String(), Equals, "ID:              1\n"+
+		"Address:         127.0.0.1:20160\n"+
+		"State:           Up\n"+
+		"Labels:          zone=z1, host=h1\n"+
+		"Capacity:        100 GiB\n"+
+		"Available:       60 GiB\n"+
+		"Regions:         36\n"+
+		"Leaders:         12\n"+
+		"Busy:            no\n"+
+		"Last heartbeat:  5s ago\n"+
+		"Note:            disk replaced\n")
+
+	out.Reset()
+	c.Assert(printStoreDetails(cmd, &out, `{"store": {"id": 3}, "status": {}, "preferred_leader": true}`, now), IsNil)
+	c.Assert(out.String(), Matches, "(?s).*\nPreferred leader:  yes\n")
+
+	out.Reset()
+	c.Assert(printStoreDetails(cmd, &out, `{"store": {"id": 2, "state_name": "Down"}, "status": {}}`, now), IsNil)
+	c.Assert(out.String(), Matches, "(?s).*Labels:          \n.*Last heartbeat:  never\n")
+
+	server := newTestServer(false, r)
+	defer server.Close()
+	cmd = newTestCommand(server.URL, "")
+	cmd.SetOutput(&out)
+	out.Reset()
+	showStoreDetailsCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Matches, "ID:              1\n(?s).*Note:            disk replaced\n")
+	out.Reset()
+	showStoreDetailsCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Usage: store details <store_id>\n")
+}
+
+func (s *testStoreSuite) TestShowStoreEmptyResponse(c *C) {
+	server := newTestServer(false, "")
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("format", "", "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	defer ResetExitCode()
+
+	for _, format := range []string{storeFormatJSON, storeFormatTable} {
+		out.Reset()
+		ResetExitCode()
+		c.Assert(cmd.Flags().Set("format", format), IsNil)
+		showStoreCommandFunc(cmd, nil)
+		c.Assert(out.String(), Equals, "(empty response)\n")
+		c.Assert(ExitCode(), Equals, 1)
+	}
+}
+
+func (s *testStoreSuite) TestShowStoreRaw(c *C) {
+	server := newTestServer(false, testStores)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("format", "", "")
+	cmd.Flags().Bool("raw", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	// '--raw' takes precedence over '--format'.
+	c.Assert(cmd.Flags().Set("format", storeFormatCompact), IsNil)
+	c.Assert(cmd.Flags().Set("raw", "true"), IsNil)
+	showStoreCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, testStores)
+}
+
+func (s *testStoreSuite) TestStoreGRPCStatus(c *C) {
+	server := newTestServer(false, `{"store_id": 1, "address": "127.0.0.1:20160", "reachable": false, "latency": "1ms", "error": "connection refused"}`)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	showStoreGRPCStatusCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Equals, "store 1 (127.0.0.1:20160): unreachable, latency: 1ms, error: connection refused\n")
+}
+
+func (s *testStoreSuite) TestApplyStoreLabels(c *C) {
+	ids := []uint64{1, 2, 3, 4, 5, 6}
+	var mu sync.Mutex
+	var running, maxRunning int
+	errs := applyStoreLabels(ids, 2, 0, func(id uint64) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if id%3 == 0 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	c.Assert(maxRunning <= 2, IsTrue)
+	for i, id := range ids {
+		if id%3 == 0 {
+			c.Assert(errs[i], NotNil)
+		} else {
+			c.Assert(errs[i], IsNil)
+		}
+	}
+
+	start := time.Now()
+	errs = applyStoreLabels(ids, 8, 200, func(uint64) error { return nil })
+	c.Assert(errs, HasLen, len(ids))
+	c.Assert(time.Since(start) >= 25*time.Millisecond, IsTrue)
+}
+
+func (s *testStoreSuite) TestImportStoreLabels(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, testStores)
+		case strings.HasPrefix(r.URL.Path, "/pd/api/v1/store/2/"):
+			http.Error(w, "no leader", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	f, err := ioutil.TempFile("", "store_labels")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"1": {"zone": "z1"}, "2": {"zone": "z2"}, "3": {"zone": "z3"}}`)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Int("parallel", 1, "")
+	cmd.Flags().Int("qps", 0, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	defer ResetExitCode()
+
+	// Store 3 is not found, and store 2 fails.
+	importStoreLabelsCommandFunc(cmd, []string{f.Name()})
+	c.Assert(out.String(), Equals, ""+
+		"Failed to apply store 2: [500] no leader\n"+
+		"Skip store 3: not found\n"+
+		"1 applied, 1 skipped, 1 failed\n")
+	c.Assert(ExitCode(), Equals, 1)
+}
+
+func (s *testStoreSuite) TestStoreSnapshots(c *C) {
+	out := &bytes.Buffer{}
+	r := `{"count": 3, "stores": [
+		{"store_id": 1, "sending_snap_count": 1, "receiving_snap_count": 0, "applying_snap_count": 0},
+		{"store_id": 2, "sending_snap_count": 0, "receiving_snap_count": 5, "applying_snap_count": 2},
+		{"store_id": 3, "sending_snap_count": 0, "receiving_snap_count": 0, "applying_snap_count": 0}],
+		"total": {"store_id": 0, "sending_snap_count": 1, "receiving_snap_count": 5, "applying_snap_count": 2}}`
+	c.Assert(printStoreSnapshots(out, r, true), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"STORE  SENDING  RECEIVING  APPLYING\n"+
+		"2      0        5          2\n"+
+		"1      1        0          0\n"+
+		"3      0        0          0\n"+
+		"total  1        5          2\n")
+
+	out.Reset()
+	r = `{"store_id": 1, "sending_snap_count": 1, "receiving_snap_count": 0, "applying_snap_count": 0}`
+	c.Assert(printStoreSnapshots(out, r, false), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"STORE  SENDING  RECEIVING  APPLYING\n"+
+		"1      1        0          0\n")
+}
+
+func (s *testStoreSuite) TestBalancePreview(c *C) {
+	r := `[
+		{"name": "region_operator", "region": {"id": 1}, "ops": [
+			{"name": "transfer_leader", "old_leader": {"id": 4, "store_id": 4}, "new_leader": {"id": 1, "store_id": 1}}]},
+		{"name": "region_operator", "region": {"id": 2}, "ops": [
+			{"name": "add_peer", "change_peer": {"peer": {"id": 9, "store_id": 5}}},
+			{"name": "remove_peer", "change_peer": {"change_type": 1, "peer": {"id": 2, "store_id": 2}}}]}
+	]`
+	var out bytes.Buffer
+	c.Assert(printBalancePreview(&out, r), IsNil)
+	c.Assert(out.String(), Equals, ""+
+		"region 1: transfer leader from store 4 to store 1\n"+
+		"region 2: add peer on store 5, remove peer on store 2\n")
+
+	out.Reset()
+	c.Assert(printBalancePreview(&out, "null"), IsNil)
+	c.Assert(out.String(), Equals, "The stores are balanced, no move is needed\n")
+}
+
+func (s *testStoreSuite) TestFixStorePlacement(c *C) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/2/fix-placement")
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("dry_run") == "true" {
+			fmt.Fprint(w, `[{"name": "admin_operator", "region": {"id": 1}, "ops": [
+				{"name": "add_peer", "change_peer": {"peer": {"id": 9, "store_id": 4}}},
+				{"name": "remove_peer", "change_peer": {"change_type": 1, "peer": {"id": 3, "store_id": 2}}}]}]`)
+			return
+		}
+		fmt.Fprint(w, "[]")
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("dry-run", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	fixStorePlacementCommandFunc(cmd, []string{"2"})
+	c.Assert(query, Equals, "dry_run=false")
+	c.Assert(out.String(), Equals, "The peers of the store are placed well, no move is needed\n")
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("dry-run", "true"), IsNil)
+	fixStorePlacementCommandFunc(cmd, []string{"2"})
+	c.Assert(query, Equals, "dry_run=true")
+	c.Assert(out.String(), Equals, "region 1: add peer on store 4, remove peer on store 2\n")
+}
+
+func (s *testStoreSuite) TestSetStoreAddress(c *C) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.URL.Path, Equals, "/pd/api/v1/store/1")
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		body = string(b)
+		fmt.Fprint(w, `{"store": {"id": 1, "address": "tikv-1:20160"}}`)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	setStoreAddressCommandFunc(cmd, []string{"1", "tikv-1:20160"})
+	c.Assert(body, Equals, `{"address":"tikv-1:20160"}`)
+	c.Assert(out.String(), Equals, `{"store": {"id": 1, "address": "tikv-1:20160"}}`+"\n")
+
+	out.Reset()
+	setStoreAddressCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Equals, "Usage: store set-address <store_id> <address>\n")
+}
+
+func (s *testStoreSuite) TestTouchStore(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		if r.URL.Path != "/pd/api/v1/store/1/touch" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"store_id": 1, "heartbeat_age_seconds": 0}`)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	touchStoreCommandFunc(cmd, []string{"1"})
+	c.Assert(out.String(), Equals, "Success! store 1: last heartbeat 0s ago\n")
+
+	// The api is not registered without --enable-test-api.
+	out.Reset()
+	touchStoreCommandFunc(cmd, []string{"2"})
+	c.Assert(out.String(), Matches, "(?s)Failed to touch store: \\[404\\] .*")
+}
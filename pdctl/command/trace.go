@@ -0,0 +1,138 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// The values of these headers are not written by '--trace', so the trace can
+// be attached to a bug report.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+}
+
+// traceTransport writes each request and its response with the headers and
+// the bodies to out, the requests are prefixed by "> " and the responses by
+// "< ".
+type traceTransport struct {
+	base http.RoundTripper
+	out  io.Writer
+}
+
+// traceMu is shared by the clients, so the lines of concurrent requests are
+// not interleaved.
+var traceMu sync.Mutex
+
+// traceClient returns a copy of the client which traces its requests if
+// '--trace' is set, or the client itself otherwise.
+func traceClient(cmd *cobra.Command, client *http.Client) *http.Client {
+	if trace, _ := cmd.Flags().GetBool("trace"); !trace {
+		return client
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c := *client
+	c.Transport = &traceTransport{base: base, out: cmd.OutOrStderr()}
+	return &c
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s %s %s\n", req.Method, req.URL, req.Proto)
+	writeTraceHeaders(&buf, "> ", req.Header)
+	writeTraceBody(&buf, "> ", body)
+	t.write(&buf)
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		// The body of a stream is read here too, a trace is for debugging
+		// a single command rather than a large output.
+		body, err = readBody(&resp.Body)
+	}
+	if err != nil {
+		fmt.Fprintf(&buf, "< error: %s\n", err)
+		t.write(&buf)
+		return nil, err
+	}
+	fmt.Fprintf(&buf, "< %s %s\n", resp.Proto, resp.Status)
+	writeTraceHeaders(&buf, "< ", resp.Header)
+	writeTraceBody(&buf, "< ", body)
+	t.write(&buf)
+	return resp, nil
+}
+
+// write writes and resets buf.
+func (t *traceTransport) write(buf *bytes.Buffer) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	t.out.Write(buf.Bytes())
+	buf.Reset()
+}
+
+// readBody reads the body and replaces it with a reader of the read bytes.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func writeTraceHeaders(out io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			if redactedHeaders[http.CanonicalHeaderKey(k)] {
+				v = "[redacted]"
+			}
+			fmt.Fprintf(out, "%s%s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+func writeTraceBody(out io.Writer, prefix string, body []byte) {
+	fmt.Fprintln(out, strings.TrimSpace(prefix))
+	if len(body) == 0 {
+		return
+	}
+	for _, line := range bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n")) {
+		fmt.Fprintf(out, "%s%s\n", prefix, line)
+	}
+}
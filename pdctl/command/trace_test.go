@@ -0,0 +1,84 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTraceSuite{})
+
+type testTraceSuite struct{}
+
+func (s *testTraceSuite) TestTrace(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		w.Header().Set("X-Test", "1")
+		fmt.Fprintf(w, "{\n  \"echo\": %s\n}\n", b)
+	}))
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("trace", true, "")
+	var stdout, stderr bytes.Buffer
+	cmd.SetOutput(&stderr)
+
+	// The traced body is still read by the command.
+	r, err := doRequestWithBody(cmd, "pd/api/v1/test", http.MethodPost, "application/json", []byte(`{"a":1}`))
+	c.Assert(err, IsNil)
+	stdout.WriteString(r)
+	c.Assert(stdout.String(), Equals, "{\n  \"echo\": {\"a\":1}\n}\n")
+	c.Assert(stderr.String(), Matches, "(?s)> POST "+server.URL+"/pd/api/v1/test HTTP/1.1\n"+
+		"> Content-Type: application/json\n>\n"+`> \{"a":1\}`+"\n"+
+		"< HTTP/1.1 200 OK\n.*< X-Test: 1\n<\n"+`< \{`+"\n"+`<   "echo": \{"a":1\}`+"\n"+`< \}`+"\n")
+
+	// The credentials are not written.
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	c.Assert(err, IsNil)
+	req.Header.Set("Authorization", "Basic c2VjcmV0")
+	stderr.Reset()
+	client, err := getHTTPClient(cmd, server.URL)
+	c.Assert(err, IsNil)
+	resp, err := client.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(stderr.String(), Matches, "(?s).*> Authorization: \\[redacted\\]\n.*")
+	c.Assert(strings.Contains(stderr.String(), "c2VjcmV0"), IsFalse)
+
+	// Nothing is traced without '--trace'.
+	c.Assert(cmd.Flags().Set("trace", "false"), IsNil)
+	stderr.Reset()
+	_, err = doRequest(cmd, "pd/api/v1/test", http.MethodGet)
+	c.Assert(err, IsNil)
+	c.Assert(stderr.String(), Equals, "")
+}
+
+func (s *testTraceSuite) TestTraceError(c *C) {
+	server := newTestServer(false, "")
+	server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("trace", true, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	_, err := doRequest(cmd, "pd/api/v1/test", http.MethodGet)
+	c.Assert(err, NotNil)
+	c.Assert(out.String(), Matches, "(?s)> GET .*\n< error: .*")
+}
@@ -14,13 +14,21 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
+const tsoStatusPrefix = "pd/api/v1/tso/status"
+
 const (
 	physicalShiftBits = 18
 	logicalBits       = 0x3FFFF
@@ -33,22 +41,230 @@ func NewTSOCommand() *cobra.Command {
 		Short: "parse TSO to the system and logic time",
 		Run:   showTSOCommandFunc,
 	}
+	cmd.AddCommand(NewTSOBenchCommand())
+	cmd.AddCommand(NewTSOStatusCommand())
+	return cmd
+}
+
+// NewTSOBenchCommand returns a bench subcommand of tsoCmd.
+func NewTSOBenchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench [--count <n>] [--concurrency <n>]",
+		Short: "get TSOs from pd and show the throughput and latency",
+		Run:   benchTSOCommandFunc,
+	}
+	cmd.Flags().Int("count", 10000, "the number of TSO requests")
+	cmd.Flags().Int("concurrency", 1, "the number of connections to send the requests concurrently")
 	return cmd
 }
 
+// NewTSOStatusCommand returns a status subcommand of tsoCmd.
+func NewTSOStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "show the state of the TSO allocator of the leader",
+		Run:   showTSOStatusCommandFunc,
+	}
+}
+
 func showTSOCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
-		fmt.Println("Usage: tso <timestamp>")
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: tso <timestamp>")
 		return
 	}
 	ts, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		fmt.Printf("Failed to parse TSO: %s\n", err)
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse TSO: %s\n", err)
 		return
 	}
 	logical := ts & logicalBits
 	physical := ts >> physicalShiftBits
 	physicalTime := time.Unix(int64(physical/1000), 0)
-	fmt.Println("system: ", physicalTime)
-	fmt.Println("logic: ", logical)
+	fmt.Fprintln(cmd.OutOrStdout(), "system: ", physicalTime)
+	fmt.Fprintln(cmd.OutOrStdout(), "logic: ", logical)
+}
+
+type tsoStatus struct {
+	Physical  time.Time `json:"physical"`
+	Logical   int64     `json:"logical"`
+	SavedTime time.Time `json:"saved_time"`
+}
+
+func showTSOStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: tso status")
+		return
+	}
+	r, err := doRequest(cmd, tsoStatusPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get TSO status: %s\n", err)
+		return
+	}
+	if rawOutput(cmd) {
+		printResponse(cmd, r)
+		return
+	}
+	if checkEmptyResponse(cmd, r) {
+		return
+	}
+	status := &tsoStatus{}
+	if err = json.Unmarshal([]byte(r), status); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse TSO status: %s\n", err)
+		return
+	}
+	printTSOStatus(cmd.OutOrStdout(), status, time.Now())
+}
+
+// printTSOStatus prints the status with the time left before the lease, the
+// physical time saved in etcd, expires.
+func printTSOStatus(out io.Writer, status *tsoStatus, now time.Time) {
+	fmt.Fprintln(out, "physical: ", status.Physical)
+	fmt.Fprintln(out, "logical: ", status.Logical)
+	left := status.SavedTime.Sub(now) / time.Millisecond * time.Millisecond
+	if left > 0 {
+		fmt.Fprintf(out, "lease expiry:  %s (in %s)\n", status.SavedTime, left)
+	} else {
+		fmt.Fprintf(out, "lease expiry:  %s (expired %s ago)\n", status.SavedTime, -left)
+	}
+}
+
+// tsoClient is the part of pd.Client used by the TSO benchmark.
+type tsoClient interface {
+	GetTS(ctx context.Context) (int64, int64, error)
+}
+
+type tsoBenchResult struct {
+	count     int
+	errors    int
+	firstErr  error
+	duration  time.Duration
+	latencies []time.Duration
+}
+
+func benchTSOCommandFunc(cmd *cobra.Command, args []string) {
+	count, _ := cmd.Flags().GetInt("count")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if len(args) != 0 || count <= 0 || concurrency <= 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: tso bench [--count <n>] [--concurrency <n>], n should be positive")
+		return
+	}
+	if concurrency > count {
+		concurrency = count
+	}
+
+	// Each worker has its own connection which is dialed once and used by
+	// all its requests, the first one reuses the connection of pd-ctl.
+	client, err := getClient()
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to bench TSO: %s\n", err)
+		return
+	}
+	clients := []tsoClient{client}
+	for len(clients) < concurrency {
+		c, err := newPDClient(cmd)
+		if err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Failed to connect to pd: %s\n", err)
+			return
+		}
+		defer c.Close()
+		clients = append(clients, c)
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	res := runTSOBench(clients, count, timeout)
+	printTSOBenchResult(cmd.OutOrStdout(), res, concurrency)
+}
+
+// runTSOBench sends count TSO requests by the clients concurrently, timeout
+// limits each request if it is not zero.
+func runTSOBench(clients []tsoClient, count int, timeout time.Duration) *tsoBenchResult {
+	res := &tsoBenchResult{count: count}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	next := make(chan struct{}, count)
+	for i := 0; i < count; i++ {
+		next <- struct{}{}
+	}
+	close(next)
+
+	start := time.Now()
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client tsoClient) {
+			defer wg.Done()
+			latencies := make([]time.Duration, 0, count/len(clients)+1)
+			var failed int
+			var firstErr error
+			for range next {
+				ctx := context.Background()
+				cancel := func() {}
+				if timeout > 0 {
+					ctx, cancel = context.WithTimeout(ctx, timeout)
+				}
+				begin := time.Now()
+				_, _, err := client.GetTS(ctx)
+				cancel()
+				if err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				latencies = append(latencies, time.Since(begin))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.latencies = append(res.latencies, latencies...)
+			res.errors += failed
+			if res.firstErr == nil {
+				res.firstErr = firstErr
+			}
+		}(client)
+	}
+	wg.Wait()
+	res.duration = time.Since(start)
+	sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+	return res
+}
+
+// percentile returns the latency which p of the sorted latencies are not
+// greater than.
+func (r *tsoBenchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(r.latencies))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(r.latencies) {
+		i = len(r.latencies) - 1
+	}
+	return r.latencies[i]
+}
+
+func printTSOBenchResult(out io.Writer, r *tsoBenchResult, concurrency int) {
+	fmt.Fprintf(out, "count: %d, concurrency: %d, errors: %d\n", r.count, concurrency, r.errors)
+	if r.firstErr != nil {
+		fmt.Fprintf(out, "first error: %s\n", r.firstErr)
+	}
+	var throughput float64
+	if r.duration > 0 {
+		throughput = float64(len(r.latencies)) / r.duration.Seconds()
+	}
+	fmt.Fprintf(out, "duration: %s, throughput: %.1f req/s\n", r.duration, throughput)
+	if len(r.latencies) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, l := range r.latencies {
+		total += l
+	}
+	fmt.Fprintf(out, "latency: min %s, avg %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		r.latencies[0], total/time.Duration(len(r.latencies)),
+		r.percentile(0.5), r.percentile(0.9), r.percentile(0.99), r.latencies[len(r.latencies)-1])
 }
@@ -0,0 +1,102 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"golang.org/x/net/context"
+)
+
+var _ = Suite(&testTSOSuite{})
+
+type testTSOSuite struct{}
+
+type mockTSOClient struct {
+	sync.Mutex
+	calls int
+	// failEvery makes every n-th request fail if it is not zero.
+	failEvery int
+}
+
+func (m *mockTSOClient) GetTS(ctx context.Context) (int64, int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.calls++
+	if m.failEvery > 0 && m.calls%m.failEvery == 0 {
+		return 0, 0, errors.New("mock error")
+	}
+	return time.Now().UnixNano() / int64(time.Millisecond), int64(m.calls), nil
+}
+
+func (s *testTSOSuite) TestRunTSOBench(c *C) {
+	clients := []*mockTSOClient{{}, {}, {failEvery: 10}}
+	res := runTSOBench([]tsoClient{clients[0], clients[1], clients[2]}, 1000, time.Second)
+	var calls int
+	for _, client := range clients {
+		calls += client.calls
+	}
+	c.Assert(calls, Equals, 1000)
+	c.Assert(res.errors, Equals, clients[2].calls/10)
+	c.Assert(res.firstErr, ErrorMatches, "mock error")
+	c.Assert(res.latencies, HasLen, 1000-res.errors)
+	for i := 1; i < len(res.latencies); i++ {
+		c.Assert(res.latencies[i-1] <= res.latencies[i], IsTrue)
+	}
+}
+
+func (s *testTSOSuite) TestTSOBenchResult(c *C) {
+	res := &tsoBenchResult{count: 10, errors: 0, duration: time.Second}
+	for i := 1; i <= 10; i++ {
+		res.latencies = append(res.latencies, time.Duration(i)*time.Millisecond)
+	}
+	c.Assert(res.percentile(0.5), Equals, 5*time.Millisecond)
+	c.Assert(res.percentile(0.9), Equals, 9*time.Millisecond)
+	c.Assert(res.percentile(0.99), Equals, 10*time.Millisecond)
+
+	var out bytes.Buffer
+	printTSOBenchResult(&out, res, 2)
+	c.Assert(out.String(), Equals, "count: 10, concurrency: 2, errors: 0\n"+
+		"duration: 1s, throughput: 10.0 req/s\n"+
+		"latency: min 1ms, avg 5.5ms, p50 5ms, p90 9ms, p99 10ms, max 10ms\n")
+
+	out.Reset()
+	printTSOBenchResult(&out, &tsoBenchResult{count: 1, errors: 1, firstErr: errors.New("timeout"), duration: time.Second}, 1)
+	c.Assert(out.String(), Equals, "count: 1, concurrency: 1, errors: 1\nfirst error: timeout\nduration: 1s, throughput: 0.0 req/s\n")
+}
+
+func (s *testTSOSuite) TestShowTSOStatus(c *C) {
+	server := newTestServer(false, `{"physical":"2017-09-01T10:00:00Z","logical":12,"saved_time":"2017-09-01T10:00:03Z"}`)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().Bool("raw", false, "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	showTSOStatusCommandFunc(cmd, nil)
+	c.Assert(out.String(), Matches, "(?s)physical: .*\nlogical:  12\nlease expiry: .*\n")
+
+	now := time.Date(2017, 9, 1, 10, 0, 0, 0, time.UTC)
+	status := &tsoStatus{Physical: now, Logical: 12, SavedTime: now.Add(3 * time.Second)}
+	out.Reset()
+	printTSOStatus(&out, status, now.Add(time.Second))
+	c.Assert(out.String(), Equals, "physical:  2017-09-01 10:00:00 +0000 UTC\nlogical:  12\n"+
+		"lease expiry:  2017-09-01 10:00:03 +0000 UTC (in 2s)\n")
+	out.Reset()
+	printTSOStatus(&out, status, now.Add(5*time.Second))
+	c.Assert(out.String(), Matches, "(?s).*\\(expired 2s ago\\)\n")
+}
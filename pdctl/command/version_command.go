@@ -0,0 +1,73 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+const versionPrefix = "pd/api/v1/version"
+
+// NewVersionCommand returns a version subcommand of rootCmd.
+func NewVersionCommand() *cobra.Command {
+	v := &cobra.Command{
+		Use:   "version [--format=text|json]",
+		Short: "show the version of pd",
+		Run:   showVersionCommandFunc,
+	}
+	v.Flags().String("format", "text", "the output format, one of text, json")
+	return v
+}
+
+func showVersionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage: version [--format=text|json]")
+		return
+	}
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Unknown format %q, it should be text or json\n", format)
+		return
+	}
+	r, err := doRequest(cmd, versionPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to get version: %s\n", err)
+		return
+	}
+	if format == "json" || rawOutput(cmd) {
+		printResponse(cmd, r)
+		return
+	}
+	var info struct {
+		Version string `json:"version"`
+		GitHash string `json:"git_hash"`
+		BuildTS string `json:"build_ts"`
+	}
+	if err = json.Unmarshal([]byte(r), &info); err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Failed to parse version: %s\n", err)
+		return
+	}
+	// The fields are missing from a pd which only reports the version.
+	fmt.Fprintln(cmd.OutOrStdout(), "Release Version:", info.Version)
+	if info.GitHash != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Git Commit Hash:", info.GitHash)
+	}
+	if info.BuildTS != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "UTC Build Time: ", info.BuildTS)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testVersionSuite{})
+
+type testVersionSuite struct{}
+
+func (s *testVersionSuite) TestShowVersion(c *C) {
+	const body = `{"version": "1.0.0", "git_hash": "abc", "build_ts": "2017-09-01T15:04:05Z"}`
+	server := newTestServer(false, body)
+	defer server.Close()
+	cmd := newTestCommand(server.URL, "")
+	cmd.Flags().String("format", "text", "")
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	showVersionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Release Version: 1.0.0\nGit Commit Hash: abc\nUTC Build Time:  2017-09-01T15:04:05Z\n")
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("format", "json"), IsNil)
+	showVersionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, body+"\n")
+
+	out.Reset()
+	c.Assert(cmd.Flags().Set("format", "yaml"), IsNil)
+	showVersionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Unknown format \"yaml\", it should be text or json\n")
+
+	// An old pd only reports the version.
+	old := newTestServer(false, `{"version": "1.0.0"}`)
+	defer old.Close()
+	cmd = newTestCommand(old.URL, "")
+	cmd.Flags().String("format", "text", "")
+	cmd.SetOutput(&out)
+	out.Reset()
+	showVersionCommandFunc(cmd, nil)
+	c.Assert(out.String(), Equals, "Release Version: 1.0.0\n")
+}
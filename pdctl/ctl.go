@@ -16,14 +16,28 @@ package pdctl
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/pingcap/pd/pdctl/command"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // CommandFlags are flags that used in all Commands
 type CommandFlags struct {
-	URL string
+	URL             string
+	EndpointsFile   string
+	EndpointsScheme string
+	CAPath          string
+	CertPath        string
+	KeyPath         string
+	Context         string
+	Timeout         time.Duration
+	IdleConnTimeout time.Duration
+	ConnectTimeout  time.Duration
+	NoColor         bool
+	Raw             bool
+	Trace           bool
 }
 
 var (
@@ -35,7 +49,19 @@ var (
 )
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&commandFlags.URL, "pd", "u", "http://127.0.0.1:2379", "pd address")
+	rootCmd.PersistentFlags().StringVarP(&commandFlags.URL, "pd", "u", "http://127.0.0.1:2379", "pd address, multiple addresses are separated by commas")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CAPath, "cacert", "", "path of file that contains list of trusted SSL CAs, only used by https addresses")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CertPath, "cert", "", "path of file that contains X509 certificate in PEM format, only used by https addresses")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.KeyPath, "key", "", "path of file that contains X509 key in PEM format, only used by https addresses")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.Context, "context", "", "name of the context in ~/.pd/config, the default context is used if not set")
+	rootCmd.PersistentFlags().DurationVar(&commandFlags.Timeout, "timeout", 0, "timeout of each request to pd, 0 means no timeout")
+	rootCmd.PersistentFlags().DurationVar(&commandFlags.ConnectTimeout, "connect-timeout", 0, "timeout of connecting to pd, a pd which is not connected in time is skipped, 0 means no timeout beyond '--timeout'")
+	rootCmd.PersistentFlags().DurationVar(&commandFlags.IdleConnTimeout, "idle-conn-timeout", 90*time.Second, "close the connections to pd which are idle for the timeout, 0 means they are kept")
+	rootCmd.PersistentFlags().BoolVar(&commandFlags.NoColor, "no-color", false, "disable the colors of the output, they are also disabled if stdout is not a terminal")
+	rootCmd.PersistentFlags().BoolVar(&commandFlags.Raw, "raw", false, "write the response bodies verbatim, it takes precedence over the other output flags")
+	rootCmd.PersistentFlags().BoolVar(&commandFlags.Trace, "trace", false, "write the requests to pd and the responses with their headers and bodies to stderr")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.EndpointsFile, "endpoints-file", "", "file of newline or comma separated pd addresses, merged with '-u'")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.EndpointsScheme, "endpoints-scheme", "", "scheme of the pd addresses without one, http or https, it is https if '--cacert' is set and http otherwise by default")
 	rootCmd.AddCommand(
 		command.NewConfigCommand(),
 		command.NewRegionCommand(),
@@ -49,22 +75,75 @@ func init() {
 		command.NewTSOCommand(),
 		command.NewHotSpotCommand(),
 		command.NewClusterCommand(),
+		command.NewParseURLsCommand(),
+		command.NewContextCommand(),
+		command.NewDebugCommand(),
+		command.NewMetricsCommand(),
+		command.NewDoctorCommand(),
+		command.NewVersionCommand(),
 	)
 	cobra.EnablePrefixMatching = true
 }
 
-// Start run Command
-func Start(args []string) {
+// Start run Command, it returns the exit code of the command.
+func Start(args []string) int {
+	command.ResetExitCode()
 	rootCmd.SetArgs(args)
 	rootCmd.SilenceErrors = true
+	resetFlags(command.ContextFlags...)
 	rootCmd.ParseFlags(args)
-	err := command.InitPDClient(rootCmd)
-	if err != nil {
+	if err := command.ApplyContext(rootCmd); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	// Contexts can be managed without connecting to pd.
+	if c, _, err := rootCmd.Find(args); err != nil || !isContextCommand(c) {
+		if err := command.InitPDClient(rootCmd); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 	rootCmd.SetUsageTemplate(command.UsageTemplate)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(rootCmd.UsageString())
 	}
+	c, _, err := rootCmd.Find(args)
+	if err != nil {
+		c = rootCmd
+	}
+	resetCommandFlags(c)
+	return command.ExitCode()
+}
+
+func isContextCommand(c *cobra.Command) bool {
+	for ; c != nil; c = c.Parent() {
+		if c.Name() == "context" {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFlags restores the flags set by the last command, such as the flags
+// set by the last context, so the context is applied again when Start is
+// called in a loop.
+func resetFlags(names ...string) {
+	for _, name := range names {
+		if f := rootCmd.PersistentFlags().Lookup(name); f != nil {
+			resetFlag(f)
+		}
+	}
+}
+
+// resetCommandFlags restores all flags of the executed command and the
+// persistent flags, cobra never restores them, so the flags given in a line
+// of the interactive mode only apply to that line.
+func resetCommandFlags(c *cobra.Command) {
+	c.Flags().VisitAll(resetFlag)
+	rootCmd.PersistentFlags().VisitAll(resetFlag)
+}
+
+func resetFlag(f *pflag.Flag) {
+	f.Value.Set(f.DefValue)
+	f.Changed = false
 }
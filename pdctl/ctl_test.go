@@ -0,0 +1,62 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdctl
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+)
+
+func TestPDCtl(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testCtlSuite{})
+
+type testCtlSuite struct{}
+
+func (s *testCtlSuite) TestFlagsNotKept(c *C) {
+	cfg := server.NewTestSingleConfig()
+	svr, err := server.CreateServer(cfg, api.NewHandler)
+	c.Assert(err, IsNil)
+	c.Assert(svr.Run(), IsNil)
+	defer func() {
+		svr.Close()
+		os.RemoveAll(cfg.DataDir)
+	}()
+	for i := 0; i < 100 && !svr.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(svr.IsLeader(), IsTrue)
+
+	checkFlags := func() {
+		check, _, err := rootCmd.Find([]string{"region", "check"})
+		c.Assert(err, IsNil)
+		for _, name := range []string{"fix", "yes", "raw", "trace"} {
+			f := check.Flags().Lookup(name)
+			c.Assert(f.Changed, IsFalse, Commentf("flag %s", name))
+			c.Assert(f.Value.String(), Equals, "false", Commentf("flag %s", name))
+		}
+	}
+	// The lines of the interactive mode are run by Start one by one.
+	Start([]string{"-u", cfg.ClientUrls, "region", "check", "--fix", "--yes", "--raw", "--trace"})
+	checkFlags()
+	Start([]string{"-u", cfg.ClientUrls, "region", "check"})
+	checkFlags()
+}
@@ -14,9 +14,12 @@
 package apiutil
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 
 	"github.com/juju/errors"
 )
@@ -37,3 +40,25 @@ func ReadJSON(r io.ReadCloser, data interface{}) error {
 
 	return nil
 }
+
+// unixDial returns a dial function which connects to the unix socket at path
+// instead of the address of the request.
+func unixDial(path string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
+// NewUnixTransport returns a transport which connects to the unix socket at
+// socketPath whatever the host of the request is.
+func NewUnixTransport(socketPath string) *http.Transport {
+	return &http.Transport{DialContext: unixDial(socketPath)}
+}
+
+// NewUnixHTTPClient returns a client which sends the requests to the unix
+// socket at socketPath, the URLs are http URLs with any host, such as
+// "http://unix/pd/api/v1/status".
+func NewUnixHTTPClient(socketPath string) *http.Client {
+	return &http.Client{Transport: NewUnixTransport(socketPath)}
+}
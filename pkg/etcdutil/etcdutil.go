@@ -95,15 +95,26 @@ func RemoveEtcdMember(client *clientv3.Client, id uint64) (*clientv3.MemberRemov
 	return rmResp, errors.Trace(err)
 }
 
-// WaitEtcdStart checks etcd starts ok or not
-func WaitEtcdStart(c *clientv3.Client, endpoint string) error {
+// UpdateEtcdMember updates the peer urls of a member by the given id.
+func UpdateEtcdMember(client *clientv3.Client, id uint64, peerUrls []string) (*clientv3.MemberUpdateResponse, error) {
+	ctx, cancel := context.WithTimeout(client.Ctx(), DefaultRequestTimeout)
+	updateResp, err := client.MemberUpdate(ctx, id, peerUrls)
+	cancel()
+	return updateResp, errors.Trace(err)
+}
+
+// WaitEtcdStart checks etcd starts ok or not, it gives up once ctx is done.
+func WaitEtcdStart(ctx context.Context, c *clientv3.Client, endpoint string) error {
 	var err error
 	for i := 0; i < maxCheckEtcdRunningCount; i++ {
 		// etcd may not start ok, we should wait and check again
-		_, err = endpointStatus(c, endpoint)
+		_, err = endpointStatus(ctx, c, endpoint)
 		if err == nil {
 			return nil
 		}
+		if ctx.Err() != nil {
+			return errors.Trace(ctx.Err())
+		}
 
 		time.Sleep(checkEtcdRunningDelay)
 		continue
@@ -113,11 +124,11 @@ func WaitEtcdStart(c *clientv3.Client, endpoint string) error {
 }
 
 // endpointStatus checks whether current etcd is running.
-func endpointStatus(c *clientv3.Client, endpoint string) (*clientv3.StatusResponse, error) {
+func endpointStatus(ctx context.Context, c *clientv3.Client, endpoint string) (*clientv3.StatusResponse, error) {
 	m := clientv3.NewMaintenance(c)
 
 	start := time.Now()
-	ctx, cancel := context.WithTimeout(c.Ctx(), DefaultRequestTimeout)
+	ctx, cancel := context.WithTimeout(ctx, DefaultRequestTimeout)
 	resp, err := m.Status(ctx, endpoint)
 	cancel()
 
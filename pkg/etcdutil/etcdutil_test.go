@@ -25,6 +25,7 @@ import (
 	"github.com/coreos/etcd/pkg/types"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/pd/pkg/testutil"
+	"golang.org/x/net/context"
 )
 
 func Test(t *testing.T) {
@@ -72,7 +73,7 @@ func (s *testEtcdutilSuite) TestMemberHelpers(c *C) {
 	c.Assert(err, IsNil)
 
 	// Test WaitEtcdStart
-	err = WaitEtcdStart(client1, ep1)
+	err = WaitEtcdStart(context.Background(), client1, ep1)
 	c.Assert(err, IsNil)
 
 	// Test ListEtcdMembers
@@ -104,7 +105,7 @@ func (s *testEtcdutilSuite) TestMemberHelpers(c *C) {
 	})
 	c.Assert(err, IsNil)
 
-	err = WaitEtcdStart(client2, ep2)
+	err = WaitEtcdStart(context.Background(), client2, ep2)
 	c.Assert(err, IsNil)
 
 	listResp2, err := ListEtcdMembers(client2)
@@ -134,6 +135,15 @@ func (s *testEtcdutilSuite) TestMemberHelpers(c *C) {
 	c.Assert(len(listResp3.Members), Equals, 1)
 	c.Assert(listResp3.Members[0].ID, Equals, uint64(etcd1.Server.ID()))
 
+	// Test UpdateEtcdMember
+	peerURLs := []string{cfg1.LPUrls[0].String(), "http://127.0.0.1:12380"}
+	_, err = UpdateEtcdMember(client1, uint64(etcd1.Server.ID()), peerURLs)
+	c.Assert(err, IsNil)
+
+	listResp4, err := ListEtcdMembers(client1)
+	c.Assert(err, IsNil)
+	c.Assert(listResp4.Members[0].PeerURLs, DeepEquals, peerURLs)
+
 	etcd1.Close()
 	etcd2.Close()
 	cleanConfig(cfg1)
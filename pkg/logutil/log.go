@@ -16,6 +16,10 @@ package logutil
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log/syslog"
+	"net/url"
 	"os"
 	"path"
 	"runtime"
@@ -24,14 +28,17 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/pkg/capnslog"
 	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/typeutil"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
-	defaultLogTimeFormat = "2006/01/02 15:04:05.000"
-	defaultLogMaxSize    = 300 // MB
-	defaultLogFormat     = "text"
-	defaultLogLevel      = log.InfoLevel
+	defaultLogTimeFormat  = "2006/01/02 15:04:05.000"
+	defaultLogMaxSize     = 300 // MB
+	defaultLogFormat      = "text"
+	defaultLogLevel       = log.InfoLevel
+	defaultSyslogTag      = "pd"
+	defaultSyslogFacility = "user"
 )
 
 // FileLogConfig serializes file log related config in toml/json.
@@ -58,13 +65,109 @@ type LogConfig struct {
 	DisableTimestamp bool `toml:"disable-timestamp" json:"disable-timestamp"`
 	// File log config.
 	File FileLogConfig `toml:"file" json:"file"`
+	// Error file log config, warning and more severe logs are also written
+	// to it if the filename is set.
+	ErrorFile FileLogConfig `toml:"error-file" json:"error-file"`
+	// Syslog config, the logs are sent to syslog instead of the file if the
+	// address is set.
+	Syslog SyslogConfig `toml:"syslog" json:"syslog"`
+	// Only 1 of every SampleRate identical logs is written in each
+	// SampleWindow, and the number of the suppressed logs is reported when
+	// the window ends. All logs are written if it is 0 or 1.
+	SampleRate uint64 `toml:"sample-rate" json:"sample-rate"`
+	// Window of the log sampling, default is 10s.
+	SampleWindow typeutil.Duration `toml:"sample-window" json:"sample-window"`
+}
+
+// SyslogConfig serializes syslog related config in toml/json.
+type SyslogConfig struct {
+	// Address of the syslog server, such as "udp://10.0.1.1:514" or
+	// "unixgram:///dev/log", leave empty to disable syslog.
+	Address string `toml:"address" json:"address"`
+	// Facility of the logs, such as "daemon" or "local0", default is "user".
+	Facility string `toml:"facility" json:"facility"`
+	// Tag of the logs, default is "pd".
+	Tag string `toml:"tag" json:"tag"`
+}
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":   syslog.LOG_KERN,
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"auth":   syslog.LOG_AUTH,
+	"syslog": syslog.LOG_SYSLOG,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// Validate checks the address and facility of the syslog config.
+func (cfg *SyslogConfig) Validate() error {
+	if len(cfg.Address) == 0 {
+		return nil
+	}
+	if _, _, err := parseSyslogAddress(cfg.Address); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := cfg.priority(); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (cfg *SyslogConfig) priority() (syslog.Priority, error) {
+	facility := cfg.Facility
+	if len(facility) == 0 {
+		facility = defaultSyslogFacility
+	}
+	p, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return 0, errors.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+	return p | syslog.LOG_INFO, nil
+}
+
+// parseSyslogAddress returns the network and address to dial.
+func parseSyslogAddress(addr string) (string, string, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if len(u.Host) == 0 {
+			return "", "", errors.Errorf("invalid syslog address %q: host is missing", addr)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		if len(u.Path) == 0 {
+			return "", "", errors.Errorf("invalid syslog address %q: path is missing", addr)
+		}
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", errors.Errorf("invalid syslog address %q: scheme must be one of udp, tcp, unix, unixgram", addr)
+	}
 }
 
 // redirectFormatter will redirect etcd logs to logrus logs.
-type redirectFormatter struct{}
+type redirectFormatter struct {
+	// structured reports the package and level as fields instead of
+	// concatenating them into the message, used by the json log format.
+	structured bool
+}
 
 // Format implements capnslog.Formatter hook.
 func (rf *redirectFormatter) Format(pkg string, level capnslog.LogLevel, depth int, entries ...interface{}) {
+	if rf.structured {
+		rf.formatStructured(pkg, level, entries...)
+		return
+	}
+
 	if pkg != "" {
 		pkg = fmt.Sprint(pkg, ": ")
 	}
@@ -87,6 +190,29 @@ func (rf *redirectFormatter) Format(pkg string, level capnslog.LogLevel, depth i
 	}
 }
 
+func (rf *redirectFormatter) formatStructured(pkg string, level capnslog.LogLevel, entries ...interface{}) {
+	// logrus owns the "level" key, so the original etcd level is kept
+	// under "etcd-level".
+	entry := log.WithFields(log.Fields{
+		"pkg":        pkg,
+		"etcd-level": level.String(),
+	})
+	msg := fmt.Sprint(entries...)
+
+	switch level {
+	case capnslog.CRITICAL:
+		entry.Fatal(msg)
+	case capnslog.ERROR:
+		entry.Error(msg)
+	case capnslog.WARNING:
+		entry.Warning(msg)
+	case capnslog.NOTICE, capnslog.INFO:
+		entry.Info(msg)
+	case capnslog.DEBUG, capnslog.TRACE:
+		entry.Debug(msg)
+	}
+}
+
 // Flush only for implementing Formatter.
 func (rf *redirectFormatter) Flush() {}
 
@@ -123,22 +249,112 @@ func (hook *contextHook) Levels() []log.Level {
 	return log.AllLevels
 }
 
+// errorFileHook writes warning and more severe logs to another file.
+type errorFileHook struct {
+	formatter log.Formatter
+	out       io.Writer
+}
+
+// Fire implements logrus.Hook interface.
+func (hook *errorFileHook) Fire(entry *log.Entry) error {
+	if isSampledOut(entry) {
+		return nil
+	}
+	serialized, err := hook.formatter.Format(entry)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = hook.out.Write(serialized)
+	return errors.Trace(err)
+}
+
+// Levels implements logrus.Hook interface.
+func (hook *errorFileHook) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+// syslogHook sends logs to syslog with the severity of their levels.
+type syslogHook struct {
+	formatter log.Formatter
+	writer    *syslog.Writer
+}
+
+func newSyslogHook(cfg *SyslogConfig, formatter log.Formatter) (*syslogHook, error) {
+	network, addr, err := parseSyslogAddress(cfg.Address)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	priority, err := cfg.priority()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tag := cfg.Tag
+	if len(tag) == 0 {
+		tag = defaultSyslogTag
+	}
+	writer, err := syslog.Dial(network, addr, priority, tag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &syslogHook{formatter: formatter, writer: writer}, nil
+}
+
+// Fire implements logrus.Hook interface.
+func (hook *syslogHook) Fire(entry *log.Entry) error {
+	if isSampledOut(entry) {
+		return nil
+	}
+	serialized, err := hook.formatter.Format(entry)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	msg := string(serialized)
+	switch entry.Level {
+	case log.PanicLevel, log.FatalLevel:
+		err = hook.writer.Crit(msg)
+	case log.ErrorLevel:
+		err = hook.writer.Err(msg)
+	case log.WarnLevel:
+		err = hook.writer.Warning(msg)
+	case log.InfoLevel:
+		err = hook.writer.Info(msg)
+	default:
+		err = hook.writer.Debug(msg)
+	}
+	return errors.Trace(err)
+}
+
+// Levels implements logrus.Hook interface.
+func (hook *syslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+var logLevels = map[string]log.Level{
+	"fatal":   log.FatalLevel,
+	"error":   log.ErrorLevel,
+	"warn":    log.WarnLevel,
+	"warning": log.WarnLevel,
+	"debug":   log.DebugLevel,
+	"info":    log.InfoLevel,
+}
+
 func stringToLogLevel(level string) log.Level {
-	switch strings.ToLower(level) {
-	case "fatal":
-		return log.FatalLevel
-	case "error":
-		return log.ErrorLevel
-	case "warn", "warning":
-		return log.WarnLevel
-	case "debug":
-		return log.DebugLevel
-	case "info":
-		return log.InfoLevel
+	if l, ok := logLevels[strings.ToLower(level)]; ok {
+		return l
 	}
 	return defaultLogLevel
 }
 
+// IsValidLogLevel reports whether the level is a known log level, an empty
+// level is valid and means the default level.
+func IsValidLogLevel(level string) bool {
+	if level == "" {
+		return true
+	}
+	_, ok := logLevels[strings.ToLower(level)]
+	return ok
+}
+
 // textFormatter is for compatability with ngaut/log
 type textFormatter struct {
 	DisableTimestamp bool
@@ -192,9 +408,18 @@ func stringToLogFormatter(format string, disableTimestamp bool) log.Formatter {
 
 // InitFileLog initializes file based logging options.
 func InitFileLog(cfg *FileLogConfig) error {
+	output, err := newRotateLogger(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.SetOutput(output)
+	return nil
+}
+
+func newRotateLogger(cfg *FileLogConfig) (*lumberjack.Logger, error) {
 	if st, err := os.Stat(cfg.Filename); err == nil {
 		if st.IsDir() {
-			return errors.New("can't use directory as log file name")
+			return nil, errors.New("can't use directory as log file name")
 		}
 	}
 	if cfg.MaxSize == 0 {
@@ -202,30 +427,68 @@ func InitFileLog(cfg *FileLogConfig) error {
 	}
 
 	// use lumberjack to logrotate
-	output := &lumberjack.Logger{
+	return &lumberjack.Logger{
 		Filename:   cfg.Filename,
 		MaxSize:    cfg.MaxSize,
 		MaxBackups: cfg.MaxBackups,
 		MaxAge:     cfg.MaxDays,
 		LocalTime:  true,
-	}
-
-	log.SetOutput(output)
-	return nil
+	}, nil
 }
 
-// InitLogger initalizes PD's logger.
+// InitLogger initalizes PD's logger. The hooks of the last call are replaced,
+// so it can be called again to apply a new config.
 func InitLogger(cfg *LogConfig) error {
 	log.SetLevel(stringToLogLevel(cfg.Level))
-	log.AddHook(&contextHook{})
+	hooks := make(log.LevelHooks)
+	hooks.Add(&contextHook{})
 
 	if cfg.Format == "" {
 		cfg.Format = defaultLogFormat
 	}
-	log.SetFormatter(stringToLogFormatter(cfg.Format, cfg.DisableTimestamp))
+	log.SetFormatter(&sampledFormatter{stringToLogFormatter(cfg.Format, cfg.DisableTimestamp)})
 
 	// etcd log
-	capnslog.SetFormatter(&redirectFormatter{})
+	capnslog.SetFormatter(&redirectFormatter{
+		structured: strings.ToLower(cfg.Format) == "json",
+	})
+
+	// The sampler must be added before the hooks which write the logs, so
+	// they can skip the dropped ones.
+	if cfg.SampleRate > 1 {
+		s := newLogSampler(cfg.SampleRate, cfg.SampleWindow.Duration)
+		hooks.Add(s)
+		setLogSampler(s)
+	} else {
+		setLogSampler(nil)
+	}
+
+	// The hook must be added after contextHook to get the file and line.
+	if len(cfg.ErrorFile.Filename) != 0 {
+		output, err := newRotateLogger(&cfg.ErrorFile)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		hooks.Add(&errorFileHook{
+			formatter: stringToLogFormatter(cfg.Format, cfg.DisableTimestamp),
+			out:       output,
+		})
+	}
+
+	if len(cfg.Syslog.Address) != 0 {
+		hook, err := newSyslogHook(&cfg.Syslog, stringToLogFormatter(cfg.Format, cfg.DisableTimestamp))
+		if err != nil {
+			setHooks(hooks)
+			log.SetOutput(os.Stderr)
+			log.Warnf("failed to connect to syslog %s, logs are written to stderr: %v", cfg.Syslog.Address, err)
+			return nil
+		}
+		hooks.Add(hook)
+		setHooks(hooks)
+		log.SetOutput(ioutil.Discard)
+		return nil
+	}
+	setHooks(hooks)
 
 	if len(cfg.File.Filename) == 0 {
 		return nil
@@ -238,3 +501,8 @@ func InitLogger(cfg *LogConfig) error {
 
 	return nil
 }
+
+// setHooks replaces all hooks of the logger.
+func setHooks(hooks log.LevelHooks) {
+	log.StandardLogger().Hooks = hooks
+}
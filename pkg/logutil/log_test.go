@@ -15,8 +15,14 @@ package logutil
 
 import (
 	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
 	"strings"
 	"testing"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/pkg/capnslog"
@@ -49,6 +55,10 @@ func (s *testLogSuite) TestStringToLogLevel(c *C) {
 	c.Assert(stringToLogLevel("debug"), Equals, log.DebugLevel)
 	c.Assert(stringToLogLevel("info"), Equals, log.InfoLevel)
 	c.Assert(stringToLogLevel("whatever"), Equals, log.InfoLevel)
+
+	c.Assert(IsValidLogLevel(""), IsTrue)
+	c.Assert(IsValidLogLevel("Warning"), IsTrue)
+	c.Assert(IsValidLogLevel("whatever"), IsFalse)
 }
 
 // TestLogging assure log format and log redirection works.
@@ -76,3 +86,162 @@ func (s *testLogSuite) TestLogging(c *C) {
 	c.Assert(entry, Matches, logPattern)
 	c.Assert(strings.Contains(entry, "log_test.go"), IsTrue)
 }
+
+func (s *testLogSuite) TestStructuredLogging(c *C) {
+	conf := &LogConfig{Level: "warn", Format: "json", File: FileLogConfig{}}
+	c.Assert(InitLogger(conf), IsNil)
+	defer func() {
+		c.Assert(InitLogger(&LogConfig{Level: "warn", File: FileLogConfig{}}), IsNil)
+	}()
+
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+
+	tlog := capnslog.NewPackageLogger("github.com/pingcap/pd/pkg/logutil", "test")
+	tlog.Warningf("this message should be structured")
+
+	fields := make(map[string]interface{})
+	c.Assert(json.Unmarshal(buf.Bytes(), &fields), IsNil)
+	c.Assert(fields["pkg"], Equals, "test")
+	c.Assert(fields["etcd-level"], Equals, "WARNING")
+	c.Assert(fields["level"], Equals, "warning")
+	c.Assert(fields["msg"], Equals, "this message should be structured")
+}
+
+func (s *testLogSuite) TestErrorFile(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	errorFile := path.Join(dir, "error.log")
+	conf := &LogConfig{Level: "info", ErrorFile: FileLogConfig{Filename: errorFile}}
+	c.Assert(InitLogger(conf), IsNil)
+	log.SetOutput(s.buf)
+	defer s.buf.Reset()
+
+	tlog := capnslog.NewPackageLogger("github.com/pingcap/pd/pkg/logutil", "test")
+	log.Info("info from logrus")
+	log.Warn("warning from logrus")
+	tlog.Info("info from etcd")
+	tlog.Error("error from etcd")
+
+	data, err := ioutil.ReadFile(errorFile)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(lines[0], Matches, `.*\[warning\] warning from logrus.*`)
+	c.Assert(lines[1], Matches, `.*\[error\] .*error from etcd.*`)
+	c.Assert(strings.Contains(lines[0], "log_test.go"), IsTrue)
+
+	// All logs still go to the normal output.
+	c.Assert(strings.Count(s.buf.String(), "\n"), Equals, 4)
+}
+
+func (s *testLogSuite) TestHooksReplaced(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	defer func() {
+		c.Assert(InitLogger(&LogConfig{Level: "warn", File: FileLogConfig{}}), IsNil)
+	}()
+
+	// The hooks are not piled up by initializing the logger again.
+	conf := &LogConfig{Level: "info", SampleRate: 3, ErrorFile: FileLogConfig{Filename: path.Join(dir, "error.log")}}
+	for i := 0; i < 3; i++ {
+		c.Assert(InitLogger(conf), IsNil)
+		// contextHook, the sampler and errorFileHook.
+		c.Assert(log.StandardLogger().Hooks[log.WarnLevel], HasLen, 3)
+	}
+
+	c.Assert(InitLogger(&LogConfig{Level: "info"}), IsNil)
+	c.Assert(log.StandardLogger().Hooks[log.WarnLevel], HasLen, 1)
+}
+
+func (s *testLogSuite) TestSyslog(c *C) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer func() {
+		c.Assert(InitLogger(&LogConfig{Level: "warn", File: FileLogConfig{}}), IsNil)
+	}()
+
+	conf := &LogConfig{Level: "info", Syslog: SyslogConfig{
+		Address:  "udp://" + conn.LocalAddr().String(),
+		Facility: "local0",
+		Tag:      "pd-test",
+	}}
+	c.Assert(conf.Syslog.Validate(), IsNil)
+	c.Assert(InitLogger(conf), IsNil)
+
+	tlog := capnslog.NewPackageLogger("github.com/pingcap/pd/pkg/logutil", "test")
+	tlog.Warningf("this message should be sent to syslog")
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	c.Assert(err, IsNil)
+	// local0 is 16, warning is 4, so the priority is 16*8+4.
+	c.Assert(string(buf[:n]), Matches, `<132>.* pd-test\[\d+\]: .*this message should be sent to syslog\]\n`)
+
+	// Falls back to stderr if syslog can not be connected.
+	conf.Syslog.Address = "unixgram:///nonexistent/pd.sock"
+	c.Assert(InitLogger(conf), IsNil)
+	c.Assert(log.StandardLogger().Out, Equals, os.Stderr)
+}
+
+func (s *testLogSuite) TestSyslogConfig(c *C) {
+	c.Assert((&SyslogConfig{}).Validate(), IsNil)
+	c.Assert((&SyslogConfig{Address: "unixgram:///dev/log"}).Validate(), IsNil)
+	c.Assert((&SyslogConfig{Address: "tcp://127.0.0.1:514", Facility: "DAEMON"}).Validate(), IsNil)
+	c.Assert((&SyslogConfig{Address: "http://127.0.0.1:514"}).Validate(), ErrorMatches, ".*scheme must be one of.*")
+	c.Assert((&SyslogConfig{Address: "udp://"}).Validate(), ErrorMatches, ".*host is missing")
+	c.Assert((&SyslogConfig{Address: "udp://127.0.0.1:514", Facility: "mail2"}).Validate(), ErrorMatches, `unknown syslog facility "mail2"`)
+}
+
+func (s *testLogSuite) TestSampling(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	errorFile := path.Join(dir, "error.log")
+	conf := &LogConfig{Level: "info", SampleRate: 3, ErrorFile: FileLogConfig{Filename: errorFile}}
+	conf.SampleWindow.Duration = time.Hour
+	c.Assert(InitLogger(conf), IsNil)
+	defer func() {
+		c.Assert(InitLogger(&LogConfig{Level: "warn", File: FileLogConfig{}}), IsNil)
+		c.Assert(sampler, IsNil)
+	}()
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+
+	for i := 0; i < 7; i++ {
+		log.Warn("error storm")
+		log.WithField("i", i).Info("info storm")
+	}
+	log.Warn("another warning")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, HasLen, 7)
+	c.Assert(strings.Count(buf.String(), "error storm"), Equals, 3)
+	c.Assert(strings.Count(buf.String(), "info storm"), Equals, 3)
+	// The fields of the written logs are not changed.
+	c.Assert(strings.Contains(buf.String(), "i=3"), IsTrue)
+	c.Assert(strings.Contains(buf.String(), sampledOutField), IsFalse)
+	data, err := ioutil.ReadFile(errorFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(string(data), "\n"), Equals, 4)
+
+	buf.Reset()
+	sampler.flush()
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(buf.String(), Matches, `(?s).*\[warning\] 4 identical logs are suppressed in the last 1h0m0s: \[warning\] error storm\n.*`)
+	c.Assert(buf.String(), Matches, `(?s).*\[warning\] 4 identical logs are suppressed in the last 1h0m0s: \[info\] info storm\n.*`)
+
+	// A new window is started.
+	buf.Reset()
+	log.Warn("error storm")
+	c.Assert(strings.Count(buf.String(), "error storm"), Equals, 1)
+
+	// The fatal logs are always written.
+	for _, level := range sampler.Levels() {
+		c.Assert(level, Not(Equals), log.FatalLevel)
+	}
+}
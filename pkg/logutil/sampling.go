@@ -0,0 +1,182 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultLogSampleWindow = 10 * time.Second
+	// sampledOutField marks the entries which are dropped by the sampler, it
+	// is never written since the marked entries are not formatted.
+	sampledOutField = "log-sampled-out"
+)
+
+type sampleKey struct {
+	level log.Level
+	msg   string
+}
+
+type sampleCount struct {
+	total      uint64
+	suppressed uint64
+}
+
+// logSampler writes only 1 of every rate identical logs in each window, the
+// logs are identical if they have the same level and message. The number of
+// the suppressed logs is reported when the window ends.
+type logSampler struct {
+	rate   uint64
+	window time.Duration
+
+	mu      sync.Mutex
+	stopped bool
+	counts  map[sampleKey]*sampleCount
+	quit    chan struct{}
+}
+
+func newLogSampler(rate uint64, window time.Duration) *logSampler {
+	if window <= 0 {
+		window = defaultLogSampleWindow
+	}
+	return &logSampler{
+		rate:   rate,
+		window: window,
+		counts: make(map[sampleKey]*sampleCount),
+		quit:   make(chan struct{}),
+	}
+}
+
+var (
+	samplerMu sync.Mutex
+	sampler   *logSampler
+)
+
+// setLogSampler replaces the sampler used by the logger and stops the old one,
+// nil disables the sampling. The caller adds s to the hooks of the logger.
+func setLogSampler(s *logSampler) {
+	samplerMu.Lock()
+	defer samplerMu.Unlock()
+
+	if sampler != nil {
+		sampler.stop()
+	}
+	sampler = s
+	if s != nil {
+		go s.run()
+	}
+}
+
+func (s *logSampler) run() {
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *logSampler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		s.stopped = true
+		close(s.quit)
+	}
+}
+
+// sample returns true if the log should be written.
+func (s *logSampler) sample(level log.Level, msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return true
+	}
+	key := sampleKey{level: level, msg: msg}
+	c, ok := s.counts[key]
+	if !ok {
+		c = &sampleCount{}
+		s.counts[key] = c
+	}
+	c.total++
+	if (c.total-1)%s.rate == 0 {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+// flush starts a new window and reports the logs suppressed in the last one.
+func (s *logSampler) flush() {
+	s.mu.Lock()
+	counts := s.counts
+	s.counts = make(map[sampleKey]*sampleCount)
+	s.mu.Unlock()
+
+	for key, c := range counts {
+		if c.suppressed > 0 {
+			log.Warnf("%d identical logs are suppressed in the last %v: [%s] %s", c.suppressed, s.window, key.level, key.msg)
+		}
+	}
+}
+
+// Fire implements logrus.Hook interface.
+func (s *logSampler) Fire(entry *log.Entry) error {
+	if s.sample(entry.Level, entry.Message) {
+		return nil
+	}
+	// The fields may be shared with the other entries, so they are copied
+	// before the entry is marked.
+	data := make(log.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	data[sampledOutField] = true
+	entry.Data = data
+	return nil
+}
+
+// Levels implements logrus.Hook interface.
+func (s *logSampler) Levels() []log.Level {
+	// The fatal and panic logs are always written.
+	return []log.Level{log.ErrorLevel, log.WarnLevel, log.InfoLevel, log.DebugLevel}
+}
+
+func isSampledOut(entry *log.Entry) bool {
+	_, ok := entry.Data[sampledOutField]
+	return ok
+}
+
+// sampledFormatter formats nothing for the entries dropped by the sampler.
+type sampledFormatter struct {
+	log.Formatter
+}
+
+// Format implements logrus.Formatter.
+func (f *sampledFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if isSampledOut(entry) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
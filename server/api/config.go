@@ -15,9 +15,15 @@ package api
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
 )
@@ -38,22 +44,70 @@ func (h *confHandler) Get(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, h.svr.GetConfig())
 }
 
-func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
-	config := h.svr.GetConfig()
-	data, err := ioutil.ReadAll(r.Body)
-	r.Body.Close()
-	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
-		return
+// unknownConfigKeyError is returned by readConfigJSON if the request has a key
+// which is not in the config, so a typo is not persisted silently.
+type unknownConfigKeyError struct {
+	key   string
+	valid []string
+}
+
+func (e *unknownConfigKeyError) Error() string {
+	return fmt.Sprintf("unknown config key %q, valid keys: %s", e.key, strings.Join(e.valid, ", "))
+}
+
+// configKeys returns the sorted JSON names of the fields of the configs.
+func configKeys(cfgs ...interface{}) []string {
+	var keys []string
+	for _, cfg := range cfgs {
+		t := reflect.TypeOf(cfg).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" {
+				keys = append(keys, name)
+			}
+		}
 	}
-	err = json.Unmarshal(data, &config.Schedule)
-	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
-		return
+	sort.Strings(keys)
+	return keys
+}
+
+// readConfigJSON reads the JSON object of the request into each of the
+// configs, it fails if a key of the object is not in any of them.
+func readConfigJSON(r io.ReadCloser, cfgs ...interface{}) error {
+	var data json.RawMessage
+	if err := readJSON(r, &data); err != nil {
+		return errors.Trace(err)
+	}
+	var input map[string]json.RawMessage
+	if err := json.Unmarshal(data, &input); err != nil {
+		return errors.Trace(err)
 	}
-	err = json.Unmarshal(data, &config.Replication)
+	valid := configKeys(cfgs...)
+	for key := range input {
+		if i := sort.SearchStrings(valid, key); i == len(valid) || valid[i] != key {
+			return &unknownConfigKeyError{key: key, valid: valid}
+		}
+	}
+	for _, cfg := range cfgs {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func readConfigJSONErrorStatus(err error) int {
+	if _, ok := err.(*unknownConfigKeyError); ok {
+		return http.StatusBadRequest
+	}
+	return readJSONErrorStatus(err)
+}
+
+func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
+	config := h.svr.GetConfig()
+	err := readConfigJSON(r.Body, &config.Schedule, &config.Replication)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, readConfigJSONErrorStatus(err), err.Error())
 		return
 	}
 	h.svr.SetScheduleConfig(config.Schedule)
@@ -65,11 +119,15 @@ func (h *confHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, &h.svr.GetConfig().Schedule)
 }
 
+func (h *confHandler) GetDefault(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, server.NewDefaultScheduleConfig())
+}
+
 func (h *confHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
 	config := h.svr.GetScheduleConfig()
-	err := readJSON(r.Body, config)
+	err := readConfigJSON(r.Body, config)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, readConfigJSONErrorStatus(err), err.Error())
 		return
 	}
 
@@ -83,12 +141,45 @@ func (h *confHandler) GetReplication(w http.ResponseWriter, r *http.Request) {
 
 func (h *confHandler) SetReplication(w http.ResponseWriter, r *http.Request) {
 	config := h.svr.GetReplicationConfig()
-	err := readJSON(r.Body, config)
+	err := readConfigJSON(r.Body, config)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		h.rd.JSON(w, readConfigJSONErrorStatus(err), err.Error())
 		return
 	}
 
 	h.svr.SetReplicationConfig(*config)
 	h.rd.JSON(w, http.StatusOK, nil)
 }
+
+// resetConfigField sets the field of cfg whose JSON name is key to the value
+// of the same field of def, which are pointers to the same config struct. It
+// returns the new value, or false if cfg has no such field.
+func resetConfigField(cfg, def interface{}, key string) (interface{}, bool) {
+	v, d := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(def).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] == key {
+			v.Field(i).Set(d.Field(i))
+			return v.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// Reset sets the config key back to its compiled-in default and returns the
+// new value, such as {"region-schedule-limit": 12}.
+func (h *confHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	schedule, replication := h.svr.GetScheduleConfig(), h.svr.GetReplicationConfig()
+	if value, ok := resetConfigField(schedule, server.NewDefaultScheduleConfig(), key); ok {
+		h.svr.SetScheduleConfig(*schedule)
+		h.rd.JSON(w, http.StatusOK, map[string]interface{}{key: value})
+		return
+	}
+	if value, ok := resetConfigField(replication, server.NewDefaultReplicationConfig(), key); ok {
+		h.svr.SetReplicationConfig(*replication)
+		h.rd.JSON(w, http.StatusOK, map[string]interface{}{key: value})
+		return
+	}
+	err := &unknownConfigKeyError{key: key, valid: configKeys(schedule, replication)}
+	h.rd.JSON(w, http.StatusBadRequest, err.Error())
+}
@@ -14,6 +14,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/rand"
 	"net/http"
@@ -77,6 +78,32 @@ func (s *testConfigSuite) TestConfigAll(c *C) {
 	}
 }
 
+func (s *testConfigSuite) TestConfigUnknownKey(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	addr := cfgs[0].ClientUrls + apiPrefix + "/api/v1/config"
+	postData, err := json.Marshal(map[string]interface{}{"bogus-key": 1, "max-replicas": 5})
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(addr, "application/json", bytes.NewBuffer(postData))
+	c.Assert(err, IsNil)
+	var msg string
+	c.Assert(readJSON(resp.Body, &msg), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(msg, Equals, `unknown config key "bogus-key", valid keys: leader-schedule-limit, `+
+		`location-labels, max-replicas, max-snapshot-count, max-store-down-time, region-history-limit, region-schedule-limit, `+
+		`replica-schedule-limit`)
+
+	// Nothing is changed.
+	cfg := &server.Config{}
+	c.Assert(readJSONWithURL(addr, cfg), IsNil)
+	c.Assert(cfg.Replication.MaxReplicas, Equals, uint64(3))
+
+	// The schedule config does not accept replication keys.
+	err = postJSON(s.hc, addr+"/schedule", []byte(`{"max-replicas": 5}`))
+	c.Assert(err, NotNil)
+}
+
 func (s *testConfigSuite) TestConfigSchedule(c *C) {
 	numbers := []int{1, 3}
 	for _, num := range numbers {
@@ -137,3 +164,65 @@ func (s *testConfigSuite) TestConfigReplication(c *C) {
 		c.Assert(*rc, DeepEquals, *rc3)
 	}
 }
+
+func (s *testConfigSuite) TestConfigDefault(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	addr := cfgs[0].ClientUrls + apiPrefix + "/api/v1/config"
+	r := map[string]int{"region-schedule-limit": 20}
+	postData, err := json.Marshal(r)
+	c.Assert(err, IsNil)
+	err = postJSON(s.hc, addr, postData)
+	c.Assert(err, IsNil)
+
+	// The default config is not changed by setting the config.
+	resp, err := s.hc.Get(addr + "/default")
+	c.Assert(err, IsNil)
+	sc := &server.ScheduleConfig{}
+	err = readJSON(resp.Body, sc)
+	c.Assert(err, IsNil)
+	c.Assert(*sc, Equals, *server.NewDefaultScheduleConfig())
+	c.Assert(sc.RegionScheduleLimit, Not(Equals), uint64(20))
+}
+
+func (s *testConfigSuite) TestConfigReset(c *C) {
+	cfgs, svrs, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	addr := cfgs[0].ClientUrls + apiPrefix + "/api/v1/config"
+	postData, err := json.Marshal(map[string]interface{}{
+		"region-schedule-limit": 20,
+		"leader-schedule-limit": 32,
+		"max-replicas":          5,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.hc, addr, postData), IsNil)
+
+	resp, err := s.hc.Post(addr+"/reset/region-schedule-limit", "application/json", nil)
+	c.Assert(err, IsNil)
+	var value map[string]uint64
+	c.Assert(readJSON(resp.Body, &value), IsNil)
+	c.Assert(value, DeepEquals, map[string]uint64{"region-schedule-limit": server.NewDefaultScheduleConfig().RegionScheduleLimit})
+
+	resp, err = s.hc.Post(addr+"/reset/max-replicas", "application/json", nil)
+	c.Assert(err, IsNil)
+	value = nil
+	c.Assert(readJSON(resp.Body, &value), IsNil)
+	c.Assert(value, DeepEquals, map[string]uint64{"max-replicas": 3})
+
+	// Only the given keys are reset.
+	cfg := &server.Config{}
+	c.Assert(readJSONWithURL(addr, cfg), IsNil)
+	c.Assert(cfg.Schedule.RegionScheduleLimit, Equals, server.NewDefaultScheduleConfig().RegionScheduleLimit)
+	c.Assert(cfg.Schedule.LeaderScheduleLimit, Equals, uint64(32))
+	c.Assert(cfg.Replication.MaxReplicas, Equals, uint64(3))
+	c.Assert(svrs[0].GetScheduleConfig().RegionScheduleLimit, Equals, cfg.Schedule.RegionScheduleLimit)
+
+	resp, err = s.hc.Post(addr+"/reset/bogus-key", "application/json", nil)
+	c.Assert(err, IsNil)
+	var msg string
+	c.Assert(readJSON(resp.Body, &msg), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(msg, Matches, `unknown config key "bogus-key", valid keys: .*`)
+}
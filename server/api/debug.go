@@ -0,0 +1,59 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type debugHandler struct {
+	rd *render.Render
+}
+
+func newDebugHandler(rd *render.Render) *debugHandler {
+	return &debugHandler{
+		rd: rd,
+	}
+}
+
+// GetSlowRequests returns the latest slow etcd requests. The since parameter
+// is a duration such as "5m" to only return the requests in it, and the op
+// parameter is "kvget" or "txn" to only return the requests of the op.
+func (h *debugHandler) GetSlowRequests(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid since %q", s))
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+	op := r.URL.Query().Get("op")
+	if op != "" && op != server.SlowRequestKVGet && op != server.SlowRequestTxn {
+		h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("unknown op %q", op))
+		return
+	}
+
+	requests := server.GetSlowRequests(since, op)
+	if requests == nil {
+		requests = []*server.SlowRequest{}
+	}
+	h.rd.JSON(w, http.StatusOK, requests)
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testDebugSuite{})
+
+type testDebugSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testDebugSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1/debug", s.svr.GetAddr(), apiPrefix)
+}
+
+func (s *testDebugSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testDebugSuite) TestSlowRequests(c *C) {
+	var requests []*server.SlowRequest
+	c.Assert(readJSONWithURL(s.urlPrefix+"/slow-requests?since=5m&op=txn", &requests), IsNil)
+	for _, req := range requests {
+		c.Assert(req.Op, Equals, server.SlowRequestTxn)
+	}
+
+	for _, query := range []string{"since=5", "since=-5m", "op=put"} {
+		resp, err := http.Get(s.urlPrefix + "/slow-requests?" + query)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+}
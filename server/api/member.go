@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -24,6 +25,7 @@ import (
 	"github.com/pingcap/pd/pkg/etcdutil"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
+	"golang.org/x/net/context"
 )
 
 const defaultDialTimeout = 5 * time.Second
@@ -48,7 +50,63 @@ func (h *memberListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	ret := make(map[string][]*pdpb.Member)
+	disabled, err := h.svr.GetCampaignDisabledMembers()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ret := make(map[string][]*memberInfo)
+	for _, m := range members {
+		ret["members"] = append(ret["members"], &memberInfo{
+			Member:           m,
+			CampaignDisabled: disabled[m.GetMemberId()],
+		})
+	}
+	h.rd.JSON(w, http.StatusOK, ret)
+}
+
+type memberInfo struct {
+	*pdpb.Member
+	CampaignDisabled bool `json:"campaign_disabled,omitempty"`
+}
+
+type etcdMemberInfo struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	ClientUrls []string `json:"client_urls"`
+	PeerUrls   []string `json:"peer_urls"`
+}
+
+type etcdMemberListHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newEtcdMemberListHandler(svr *server.Server, rd *render.Render) *etcdMemberListHandler {
+	return &etcdMemberListHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *etcdMemberListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := h.svr.GetClient()
+
+	listResp, err := etcdutil.ListEtcdMembers(client)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	members := make([]*etcdMemberInfo, 0, len(listResp.Members))
+	for _, m := range listResp.Members {
+		members = append(members, &etcdMemberInfo{
+			Name:       m.Name,
+			MemberID:   m.ID,
+			ClientUrls: m.ClientURLs,
+			PeerUrls:   m.PeerURLs,
+		})
+	}
+	ret := make(map[string][]*etcdMemberInfo)
 	ret["members"] = members
 	h.rd.JSON(w, http.StatusOK, ret)
 }
@@ -114,6 +172,153 @@ func (h *memberDeleteHandler) DeleteByID(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, fmt.Sprintf("removed, pd: %v", id))
 }
 
+type memberUpdateHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newMemberUpdateHandler(svr *server.Server, rd *render.Render) *memberUpdateHandler {
+	return &memberUpdateHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// UpdateByName updates the peer urls of the member, such as
+// {"peer_urls": ["http://10.0.1.1:2380"]}.
+func (h *memberUpdateHandler) UpdateByName(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PeerUrls []string `json:"peer_urls"`
+	}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, readJSONErrorStatus(err), err.Error())
+		return
+	}
+	if len(input.PeerUrls) == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "missing peer urls")
+		return
+	}
+	if _, err := server.ParseUrls(strings.Join(input.PeerUrls, ",")); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client := h.svr.GetClient()
+	name := mux.Vars(r)["name"]
+	listResp, err := etcdutil.ListEtcdMembers(client)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var id uint64
+	for _, m := range listResp.Members {
+		if name == m.Name {
+			id = m.ID
+			break
+		}
+	}
+	if id == 0 {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+		return
+	}
+
+	if _, err = etcdutil.UpdateEtcdMember(client, id, input.PeerUrls); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, fmt.Sprintf("updated, pd: %s", name))
+}
+
+// SetCampaign allows or disallows the member to campaign leader, such as
+// {"enable": false}.
+func (h *memberUpdateHandler) SetCampaign(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Enable *bool `json:"enable"`
+	}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, readJSONErrorStatus(err), err.Error())
+		return
+	}
+	if input.Enable == nil {
+		h.rd.JSON(w, http.StatusBadRequest, "missing enable")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	listResp, err := etcdutil.ListEtcdMembers(h.svr.GetClient())
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var id uint64
+	for _, m := range listResp.Members {
+		if name == m.Name {
+			id = m.ID
+			break
+		}
+	}
+	if id == 0 {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+		return
+	}
+
+	if err = h.svr.SetMemberCampaign(id, *input.Enable); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, fmt.Sprintf("updated, pd: %s", name))
+}
+
+type etcdMemberDBInfo struct {
+	Name     string `json:"name"`
+	MemberID uint64 `json:"member_id"`
+	DBSize   int64  `json:"db_size"`
+	// Error is set if the member cannot be reached.
+	Error string `json:"error,omitempty"`
+}
+
+type etcdDBInfo struct {
+	// Quota is 0 if the backend quota is disabled.
+	Quota   int64               `json:"quota"`
+	Members []*etcdMemberDBInfo `json:"members"`
+}
+
+// GetDBSize returns the backend size of each etcd member and the backend
+// quota, etcd rejects writes after the size exceeds the quota.
+func (h *etcdMemberListHandler) GetDBSize(w http.ResponseWriter, r *http.Request) {
+	client := h.svr.GetClient()
+
+	listResp, err := etcdutil.ListEtcdMembers(client)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	info := &etcdDBInfo{
+		Quota:   h.svr.GetEtcdQuotaBytes(),
+		Members: make([]*etcdMemberDBInfo, 0, len(listResp.Members)),
+	}
+	for _, m := range listResp.Members {
+		member := &etcdMemberDBInfo{
+			Name:     m.Name,
+			MemberID: m.ID,
+		}
+		info.Members = append(info.Members, member)
+		if len(m.ClientURLs) == 0 {
+			member.Error = "no client url"
+			continue
+		}
+		ctx, cancel := context.WithTimeout(client.Ctx(), defaultDialTimeout)
+		status, err := client.Status(ctx, m.ClientURLs[0])
+		cancel()
+		if err != nil {
+			member.Error = err.Error()
+			continue
+		}
+		member.DBSize = status.DbSize
+	}
+	h.rd.JSON(w, http.StatusOK, info)
+}
+
 type leaderHandler struct {
 	svr *server.Server
 	rd  *render.Render
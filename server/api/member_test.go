@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/coreos/etcd/etcdserver"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/server"
@@ -83,6 +84,59 @@ func (s *testMemberAPISuite) TestMemberList(c *C) {
 	}
 }
 
+func (s *testMemberAPISuite) TestEtcdMemberList(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	addr := cfgs[rand.Intn(len(cfgs))].ClientUrls + apiPrefix + "/api/v1/members/etcd"
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	buf, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	checkListResponse(c, buf, cfgs)
+}
+
+func (s *testMemberAPISuite) TestEtcdDBSize(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	var info etcdDBInfo
+	addr := cfgs[rand.Intn(len(cfgs))].ClientUrls + apiPrefix + "/api/v1/members/etcd/db"
+	c.Assert(readJSONWithURL(addr, &info), IsNil)
+	c.Assert(info.Quota, Equals, etcdserver.DefaultQuotaBytes)
+	c.Assert(info.Members, HasLen, len(cfgs))
+	for _, m := range info.Members {
+		c.Assert(m.Error, Equals, "")
+		c.Assert(m.DBSize, Greater, int64(0))
+	}
+}
+
+func (s *testMemberAPISuite) TestMemberUpdate(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	prefix := cfgs[0].ClientUrls + apiPrefix + "/api/v1/members"
+	post := func(name, body string) int {
+		resp, err := s.hc.Post(prefix+"/name/"+name, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	peerURL := "http://127.0.0.1:12380"
+	c.Assert(post(cfgs[0].Name, `{"peer_urls": ["`+peerURL+`"]}`), Equals, http.StatusOK)
+	members := make(map[string][]*etcdMemberInfo)
+	c.Assert(readJSONWithURL(prefix+"/etcd", &members), IsNil)
+	c.Assert(members["members"], HasLen, 1)
+	c.Assert(members["members"][0].PeerUrls, DeepEquals, []string{peerURL})
+
+	c.Assert(post(cfgs[0].Name, `{"peer_urls": ["127.0.0.1:2380"]}`), Equals, http.StatusBadRequest)
+	c.Assert(post(cfgs[0].Name, `{}`), Equals, http.StatusBadRequest)
+	c.Assert(post("unknown", `{"peer_urls": ["`+peerURL+`"]}`), Equals, http.StatusNotFound)
+}
+
 func (s *testMemberAPISuite) TestMemberDelete(c *C) {
 	s.testMemberDelete(c, true)
 	s.testMemberDelete(c, false)
@@ -211,6 +265,71 @@ func (s *testMemberAPISuite) TestLeaderResign(c *C) {
 	s.post(c, addrs[leader2.GetMemberId()]+apiPrefix+"/api/v1/leader/transfer/"+leader1.GetName())
 	leader3 := s.waitLeaderChange(c, svrs[0], leader2)
 	c.Assert(leader3.GetMemberId(), Equals, leader1.GetMemberId())
+
+	// The leader is kept if the next leader is unknown or itself.
+	for _, name := range []string{"unknown", leader3.GetName()} {
+		res, err := http.Post(addrs[leader3.GetMemberId()]+apiPrefix+"/api/v1/leader/transfer/"+name, "", nil)
+		c.Assert(err, IsNil)
+		res.Body.Close()
+		c.Assert(res.StatusCode, Equals, http.StatusInternalServerError)
+	}
+	leader, err := svrs[0].GetLeader()
+	c.Assert(err, IsNil)
+	c.Assert(leader.GetMemberId(), Equals, leader3.GetMemberId())
+}
+
+func (s *testMemberAPISuite) TestMemberCampaign(c *C) {
+	cfgs, svrs, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	leader1, err := svrs[0].GetLeader()
+	c.Assert(err, IsNil)
+	prefix := cfgs[rand.Intn(len(cfgs))].ClientUrls + apiPrefix + "/api/v1/members"
+	post := func(name, body string) int {
+		resp, err := s.hc.Post(prefix+"/name/"+name+"/campaign", "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	campaignDisabled := func() map[string]bool {
+		var got map[string][]*memberInfo
+		c.Assert(readJSONWithURL(prefix, &got), IsNil)
+		c.Assert(got["members"], HasLen, len(cfgs))
+		ret := make(map[string]bool)
+		for _, m := range got["members"] {
+			ret[m.GetName()] = m.CampaignDisabled
+		}
+		return ret
+	}
+
+	// The disabled leader resigns and never comes back.
+	c.Assert(post(leader1.GetName(), `{"enable": false}`), Equals, http.StatusOK)
+	leader2 := s.waitLeaderChange(c, svrs[0], leader1)
+	mustWaitLeader(c, svrs)
+	c.Assert(campaignDisabled()[leader1.GetName()], IsTrue)
+	c.Assert(campaignDisabled()[leader2.GetName()], IsFalse)
+
+	// The last member which can campaign cannot be disabled.
+	var others []string
+	for _, cfg := range cfgs {
+		if cfg.Name != leader1.GetName() && cfg.Name != leader2.GetName() {
+			others = append(others, cfg.Name)
+		}
+	}
+	c.Assert(post(others[0], `{"enable": false}`), Equals, http.StatusOK)
+	c.Assert(post(leader2.GetName(), `{"enable": false}`), Equals, http.StatusInternalServerError)
+	leader, err := svrs[0].GetLeader()
+	c.Assert(err, IsNil)
+	c.Assert(leader.GetMemberId(), Equals, leader2.GetMemberId())
+
+	c.Assert(post(leader1.GetName(), `{"enable": true}`), Equals, http.StatusOK)
+	c.Assert(post(others[0], `{"enable": true}`), Equals, http.StatusOK)
+	for _, disabled := range campaignDisabled() {
+		c.Assert(disabled, IsFalse)
+	}
+
+	c.Assert(post(leader1.GetName(), `{}`), Equals, http.StatusBadRequest)
+	c.Assert(post("unknown", `{"enable": false}`), Equals, http.StatusNotFound)
 }
 
 func (s *testMemberAPISuite) post(c *C, url string) {
@@ -14,6 +14,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -52,6 +53,35 @@ func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, op)
 }
 
+const (
+	defaultBalancePreviewLimit = 10
+	maxBalancePreviewLimit     = 100
+)
+
+// GetBalancePreview returns the operators the balance schedulers would
+// generate, they are not added.
+func (h *operatorHandler) GetBalancePreview(w http.ResponseWriter, r *http.Request) {
+	limit := defaultBalancePreviewLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+			h.r.JSON(w, http.StatusBadRequest, "invalid limit "+limitStr)
+			return
+		}
+		if limit > maxBalancePreviewLimit {
+			h.r.JSON(w, http.StatusBadRequest, fmt.Sprintf("limit %d is greater than %d", limit, maxBalancePreviewLimit))
+			return
+		}
+	}
+
+	ops, err := h.Handler.GetBalancePreview(limit)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, ops)
+}
+
 func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 	var (
 		results []server.Operator
@@ -90,13 +120,13 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := readJSON(r.Body, &input); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, readJSONErrorStatus(err), err.Error())
 		return
 	}
 
 	name, ok := input["name"].(string)
 	if !ok {
-		h.r.JSON(w, http.StatusInternalServerError, "missing operator name")
+		h.r.JSON(w, http.StatusBadRequest, "missing operator name")
 		return
 	}
 
@@ -113,7 +143,7 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "transfer-region":
@@ -132,7 +162,7 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferRegionOperator(uint64(regionID), storeIDs); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	case "transfer-peer":
@@ -152,7 +182,37 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+	case "add-peer":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		storeID, ok := input["store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to add peer to")
+			return
+		}
+		if err := h.AddAddPeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
+			return
+		}
+	case "remove-peer":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		storeID, ok := input["store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to remove peer from")
+			return
+		}
+		if err := h.AddRemovePeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+			h.r.JSON(w, errorStatus(err), err.Error())
 			return
 		}
 	default:
@@ -0,0 +1,133 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testOperatorSuite{})
+
+type testOperatorSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testOperatorSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	mustPutStore(c, s.svr, &metapb.Store{Id: 2, Address: "localhost:2"})
+}
+
+func (s *testOperatorSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorSuite) TestAddRemovePeer(c *C) {
+	url := fmt.Sprintf("%s/operators", s.urlPrefix)
+	regionURL := fmt.Sprintf("%s/%d", url, region.GetId())
+
+	post := func(body string) int {
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The region already has a peer in store 1.
+	c.Assert(post(fmt.Sprintf(`{"name":"add-peer","region_id":%d,"store_id":1}`, region.GetId())), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"name":"add-peer","region_id":1000,"store_id":2}`), Equals, http.StatusNotFound)
+	c.Assert(post(fmt.Sprintf(`{"name":"add-peer","region_id":%d,"store_id":1000}`, region.GetId())), Equals, http.StatusNotFound)
+
+	err := postJSON(&http.Client{}, url, []byte(fmt.Sprintf(`{"name":"add-peer","region_id":%d,"store_id":2}`, region.GetId())))
+	c.Assert(err, IsNil)
+	op := make(map[string]interface{})
+	err = readJSONWithURL(regionURL, &op)
+	c.Assert(err, IsNil)
+	c.Assert(op["name"], Equals, "admin_operator")
+
+	// The region has no peer in store 3.
+	c.Assert(post(fmt.Sprintf(`{"name":"remove-peer","region_id":%d,"store_id":3}`, region.GetId())), Equals, http.StatusBadRequest)
+
+	err = postJSON(&http.Client{}, url, []byte(fmt.Sprintf(`{"name":"remove-peer","region_id":%d,"store_id":1}`, region.GetId())))
+	c.Assert(err, IsNil)
+}
+
+func (s *testOperatorSuite) TestTransferStoreLeaders(c *C) {
+	resp, err := http.Post(fmt.Sprintf("%s/store/1/transfer-leaders", s.urlPrefix), "", nil)
+	c.Assert(err, IsNil)
+	var info map[string]interface{}
+	c.Assert(readJSON(resp.Body, &info), IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	// The region led by store 1 has no follower.
+	c.Assert(info["count"], Equals, float64(0))
+
+	err = postJSON(&http.Client{}, fmt.Sprintf("%s/store/100/transfer-leaders", s.urlPrefix), nil)
+	c.Assert(err, ErrorMatches, "(?s).*not found.*")
+}
+
+func (s *testOperatorSuite) TestFixStorePlacement(c *C) {
+	url := fmt.Sprintf("%s/store/1/fix-placement?dry_run=true", s.urlPrefix)
+	resp, err := http.Post(url, "", nil)
+	c.Assert(err, IsNil)
+	var ops []map[string]interface{}
+	c.Assert(readJSON(resp.Body, &ops), IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	// No location labels are set, so all peers are placed well.
+	c.Assert(ops, HasLen, 0)
+
+	err = postJSON(&http.Client{}, fmt.Sprintf("%s/store/100/fix-placement", s.urlPrefix), nil)
+	c.Assert(err, ErrorMatches, "(?s).*not found.*")
+}
+
+func (s *testOperatorSuite) TestBalancePreview(c *C) {
+	url := fmt.Sprintf("%s/operators/balance-preview", s.urlPrefix)
+	var ops []map[string]interface{}
+	err := readJSONWithURL(url+"?limit=5", &ops)
+	c.Assert(err, IsNil)
+	c.Assert(len(ops) <= 10, IsTrue)
+
+	for _, limit := range []string{"0", "x", "101"} {
+		resp, err := http.Get(url + "?limit=" + limit)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+}
+
+func (s *testOperatorSuite) TestSchedulerDecisions(c *C) {
+	// The leaders are in store 1 already, so the scheduler does nothing.
+	err := postJSON(&http.Client{}, fmt.Sprintf("%s/schedulers", s.urlPrefix), []byte(`{"name":"grant-leader-scheduler","store_id":1}`))
+	c.Assert(err, IsNil)
+	defer s.svr.GetHandler().RemoveScheduler("grant-leader-scheduler-1")
+
+	var decisions []*server.SchedulerDecision
+	err = readJSONWithURL(fmt.Sprintf("%s/schedulers?explain=true", s.urlPrefix), &decisions)
+	c.Assert(err, IsNil)
+	c.Assert(decisions, DeepEquals, []*server.SchedulerDecision{{Name: "grant-leader-scheduler-1"}})
+}
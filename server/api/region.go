@@ -14,10 +14,13 @@
 package api
 
 import (
+	"encoding/hex"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
@@ -33,6 +36,32 @@ type regionsInfo struct {
 	Regions []*metapb.Region `json:"regions"`
 }
 
+type regionsCountInfo struct {
+	Count int `json:"count"`
+}
+
+type scatterRegionsInfo struct {
+	OperatorCount int `json:"operator_count"`
+}
+
+type regionSiblingsInfo struct {
+	Prev *server.RegionInfo `json:"prev"`
+	Next *server.RegionInfo `json:"next"`
+}
+
+type mergeCandidatesInfo struct {
+	Count      int                      `json:"count"`
+	Candidates []*server.MergeCandidate `json:"candidates"`
+}
+
+type leaderDistributionInfo struct {
+	RegionCount int                        `json:"region_count"`
+	Stores      []*server.StoreLeaderCount `json:"stores"`
+}
+
+// defaultMergeCandidatesLimit is the number of sample candidates returned.
+const defaultMergeCandidatesLimit = 10
+
 type regionHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -64,6 +93,54 @@ func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, regionInfo)
 }
 
+// GetRegionHistory returns the recent events of a region from the oldest to
+// the latest. It is empty if the region is not changed since the pd leader is
+// elected.
+func (h *regionHandler) GetRegionHistory(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := cluster.GetRegionHistory(regionID)
+	if errors.Cause(err) == server.ErrRegionHistoryDisabled {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if events == nil {
+		events = []*server.RegionEvent{}
+	}
+	h.rd.JSON(w, http.StatusOK, events)
+}
+
+// FixRegion adds an operator to repair the down peer or the missing peer of
+// the region and returns it, it returns null if the region has no anomaly.
+func (h *regionHandler) FixRegion(w http.ResponseWriter, r *http.Request) {
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	op, err := h.svr.GetHandler().FixRegion(regionID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, op)
+}
+
 func (h *regionHandler) GetRegionByKey(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
@@ -96,9 +173,186 @@ func (h *regionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	regions := cluster.GetRegions()
+	// Regions are sorted to make the ETag stable.
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].GetId() < regions[j].GetId()
+	})
 	regionsInfo := &regionsInfo{
 		Count:   len(regions),
 		Regions: regions,
 	}
-	h.rd.JSON(w, http.StatusOK, regionsInfo)
+	writeJSONWithETag(h.rd, w, r, regionsInfo)
+}
+
+func (h *regionsHandler) GetRegionCount(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, &regionsCountInfo{Count: cluster.GetRegionCount()})
+}
+
+func (h *regionsHandler) GetSiblings(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	regionIDStr := vars["id"]
+	regionID, err := strconv.ParseUint(regionIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	prev, next, err := cluster.GetAdjacentRegions(regionID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &regionSiblingsInfo{Prev: prev, Next: next})
+}
+
+func (h *regionsHandler) GetMergeCandidates(w http.ResponseWriter, r *http.Request) {
+	limit := defaultMergeCandidatesLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid limit "+limitStr)
+			return
+		}
+	}
+
+	candidates, err := h.svr.GetHandler().GetMergeCandidates()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	info := &mergeCandidatesInfo{Count: len(candidates)}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	info.Candidates = candidates
+	h.rd.JSON(w, http.StatusOK, info)
+}
+
+// CheckRegions returns the down peers and the missing peers of the regions.
+func (h *regionsHandler) CheckRegions(w http.ResponseWriter, r *http.Request) {
+	anomalies, err := h.svr.GetHandler().CheckRegions()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if anomalies == nil {
+		anomalies = []*server.RegionAnomaly{}
+	}
+	h.rd.JSON(w, http.StatusOK, anomalies)
+}
+
+// ScatterRegions adds operators to move the peers of a region, or of the
+// regions in a key range, to the stores with less regions.
+func (h *regionsHandler) ScatterRegions(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, readJSONErrorStatus(err), err.Error())
+		return
+	}
+
+	var (
+		count int
+		err   error
+	)
+	if id, ok := input["region_id"]; ok {
+		regionID, ok := id.(float64)
+		if !ok || regionID < 0 || regionID != float64(uint64(regionID)) {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid region id")
+			return
+		}
+		count, err = h.svr.GetHandler().AddScatterRegionOperator(uint64(regionID))
+	} else {
+		startKey, ok := parseHexKey(input["start_key"])
+		if !ok {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid start key")
+			return
+		}
+		endKey, ok := parseHexKey(input["end_key"])
+		if !ok {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid end key")
+			return
+		}
+		count, err = h.svr.GetHandler().AddScatterRangeOperators(startKey, endKey)
+	}
+	if err != nil {
+		h.rd.JSON(w, errorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &scatterRegionsInfo{OperatorCount: count})
+}
+
+// AccelerateSchedule makes the peers of the regions in the key range moved
+// first by the schedulers for a while, it returns the number of the regions.
+func (h *regionsHandler) AccelerateSchedule(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, readJSONErrorStatus(err), err.Error())
+		return
+	}
+	startKey, ok := parseHexKey(input["start_key"])
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid start key")
+		return
+	}
+	endKey, ok := parseHexKey(input["end_key"])
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid end key")
+		return
+	}
+	count, err := h.svr.GetHandler().AccelerateRangeSchedule(startKey, endKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &regionsCountInfo{Count: count})
+}
+
+// GetLeaderDistribution returns the leader count on each store of the regions
+// overlapping the key range given by the "start_key" and "end_key" queries in
+// hex.
+func (h *regionsHandler) GetLeaderDistribution(w http.ResponseWriter, r *http.Request) {
+	startKey, err := hex.DecodeString(r.URL.Query().Get("start_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid start key")
+		return
+	}
+	endKey, err := hex.DecodeString(r.URL.Query().Get("end_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid end key")
+		return
+	}
+	count, stores, err := h.svr.GetHandler().GetRangeLeaderDistribution(startKey, endKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &leaderDistributionInfo{RegionCount: count, Stores: stores})
+}
+
+// parseHexKey decodes a hex encoded key, a missing key is empty.
+func parseHexKey(v interface{}) ([]byte, bool) {
+	if v == nil {
+		return nil, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return key, true
 }
@@ -15,6 +15,8 @@ package api
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -87,3 +89,294 @@ func (s *testRegionSuite) TestRegion(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(r2, DeepEquals, r)
 }
+
+func (s *testRegionSuite) TestRegionHistory(c *C) {
+	url := fmt.Sprintf("%s/region/id/%d/history", s.urlPrefix, 51)
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	cfg := *s.svr.GetScheduleConfig()
+	origin := cfg.RegionHistoryLimit
+	cfg.RegionHistoryLimit = 2
+	s.svr.SetScheduleConfig(cfg)
+	defer func() {
+		cfg.RegionHistoryLimit = origin
+		s.svr.SetScheduleConfig(cfg)
+	}()
+
+	var events []*server.RegionEvent
+	c.Assert(readJSONWithURL(url, &events), IsNil)
+	c.Assert(events, HasLen, 0)
+
+	r := newTestRegionInfo(51, 1, []byte("h"), []byte("i"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+	c.Assert(readJSONWithURL(url, &events), IsNil)
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[0].Type, Equals, server.RegionEventCreate)
+	c.Assert(events[1].Type, Equals, server.RegionEventLeaderChange)
+
+	// Only the latest events are kept.
+	r.Peers = append(r.Peers, &metapb.Peer{Id: 52, StoreId: 2})
+	r.RegionEpoch = &metapb.RegionEpoch{ConfVer: 1}
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+	c.Assert(readJSONWithURL(url, &events), IsNil)
+	c.Assert(events, HasLen, 2)
+	c.Assert(events[1].Type, Equals, server.RegionEventAddPeer)
+	c.Assert(events[1].Detail, Equals, "peer 52(store 2)")
+
+	resp, err = http.Get(fmt.Sprintf("%s/region/id/%s/history", s.urlPrefix, "x"))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testRegionSuite) TestCheckAndFixRegion(c *C) {
+	r := newTestRegionInfo(60, 1, []byte("v"), []byte("w"))
+	r.Peers = append(r.Peers, &metapb.Peer{Id: 61, StoreId: 2})
+	r.DownPeers = []*pdpb.PeerStats{{Peer: r.Peers[1], DownSeconds: 60}}
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+	full := newTestRegionInfo(62, 1, []byte("w"), []byte("x"))
+	full.Peers = append(full.Peers, &metapb.Peer{Id: 63, StoreId: 2}, &metapb.Peer{Id: 64, StoreId: 3})
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), full)
+
+	var anomalies []*server.RegionAnomaly
+	c.Assert(readJSONWithURL(s.urlPrefix+"/regions/check", &anomalies), IsNil)
+	var found []*server.RegionAnomaly
+	for _, a := range anomalies {
+		c.Assert(a.RegionID, Not(Equals), uint64(62))
+		if a.RegionID == 60 {
+			found = append(found, a)
+		}
+	}
+	c.Assert(found, DeepEquals, []*server.RegionAnomaly{
+		{RegionID: 60, Type: server.RegionAnomalyDownPeer, Detail: "peer 61 on store 2 is down for 60s"},
+		{RegionID: 60, Type: server.RegionAnomalyMissPeer, Detail: "region has 2 replicas, 3 expected"},
+	})
+
+	fix := func(id string) *http.Response {
+		resp, err := http.Post(fmt.Sprintf("%s/region/id/%s/fix", s.urlPrefix, id), "application/json", nil)
+		c.Assert(err, IsNil)
+		return resp
+	}
+	// The region has no anomaly.
+	resp := fix("62")
+	var op map[string]interface{}
+	c.Assert(readJSON(resp.Body, &op), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(op, IsNil)
+
+	// There is no other store to replace the down peer.
+	resp = fix("60")
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+	resp = fix("1000")
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+	resp = fix("x")
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testRegionSuite) TestRegionCount(c *C) {
+	r := newTestRegionInfo(3, 1, []byte("b"), []byte("c"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+
+	url := fmt.Sprintf("%s/regions", s.urlPrefix)
+	regions := &regionsInfo{}
+	err := readJSONWithURL(url, regions)
+	c.Assert(err, IsNil)
+
+	url = fmt.Sprintf("%s/regions/count", s.urlPrefix)
+	count := &regionsCountInfo{}
+	err = readJSONWithURL(url, count)
+	c.Assert(err, IsNil)
+	c.Assert(count.Count, Equals, regions.Count)
+}
+
+func (s *testRegionSuite) TestRegionSibling(c *C) {
+	r1 := newTestRegionInfo(5, 1, []byte("x"), []byte("y"))
+	r2 := newTestRegionInfo(6, 1, []byte("y"), []byte("z"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r1)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r2)
+
+	url := fmt.Sprintf("%s/regions/sibling/%d", s.urlPrefix, r2.GetId())
+	siblings := &regionSiblingsInfo{}
+	err := readJSONWithURL(url, siblings)
+	c.Assert(err, IsNil)
+	c.Assert(siblings.Prev, DeepEquals, r1)
+	c.Assert(siblings.Next, IsNil)
+
+	url = fmt.Sprintf("%s/regions/sibling/%d", s.urlPrefix, 100)
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+}
+
+func (s *testRegionSuite) TestStorePendingPeerRegions(c *C) {
+	r1 := newTestRegionInfo(10, 1, []byte("m"), []byte("n"))
+	r1.PendingPeers = []*metapb.Peer{r1.Leader}
+	r2 := newTestRegionInfo(11, 1, []byte("n"), []byte("o"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r1)
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r2)
+
+	getRegionIDs := func(url string) map[uint64]struct{} {
+		regions := &regionsInfo{}
+		err := readJSONWithURL(url, regions)
+		c.Assert(err, IsNil)
+		c.Assert(regions.Regions, HasLen, regions.Count)
+		ids := make(map[uint64]struct{})
+		for _, region := range regions.Regions {
+			ids[region.GetId()] = struct{}{}
+		}
+		return ids
+	}
+
+	ids := getRegionIDs(fmt.Sprintf("%s/store/%d/regions?check=pending-peer", s.urlPrefix, 1))
+	c.Assert(ids, HasKey, r1.GetId())
+	_, ok := ids[r2.GetId()]
+	c.Assert(ok, IsFalse)
+
+	ids = getRegionIDs(fmt.Sprintf("%s/store/%d/regions", s.urlPrefix, 1))
+	c.Assert(ids, HasKey, r1.GetId())
+	c.Assert(ids, HasKey, r2.GetId())
+
+	url := fmt.Sprintf("%s/store/%d/regions?check=unknown", s.urlPrefix, 1)
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	url = fmt.Sprintf("%s/store/%d/regions?check=pending-peer", s.urlPrefix, 100)
+	resp, err = http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testRegionSuite) TestMergeCandidates(c *C) {
+	r1 := newTestRegionInfo(30, 1, []byte("p"), []byte("q"))
+	r2 := newTestRegionInfo(31, 1, []byte("q"), []byte("r"))
+	r3 := newTestRegionInfo(32, 1, []byte("r"), []byte("s"))
+	r3.PendingPeers = []*metapb.Peer{r3.Leader}
+	for _, r := range []*server.RegionInfo{r1, r2, r3} {
+		mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+	}
+
+	url := fmt.Sprintf("%s/regions/merge-candidates?limit=100", s.urlPrefix)
+	info := &mergeCandidatesInfo{}
+	err := readJSONWithURL(url, info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Candidates, HasLen, info.Count)
+	pairs := make(map[server.MergeCandidate]struct{})
+	for _, candidate := range info.Candidates {
+		pairs[*candidate] = struct{}{}
+	}
+	c.Assert(pairs, HasKey, server.MergeCandidate{SourceID: 30, TargetID: 31})
+	// Region 32 has a pending peer.
+	_, ok := pairs[server.MergeCandidate{SourceID: 31, TargetID: 32}]
+	c.Assert(ok, IsFalse)
+
+	url = fmt.Sprintf("%s/regions/merge-candidates?limit=0", s.urlPrefix)
+	info = &mergeCandidatesInfo{}
+	err = readJSONWithURL(url, info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Count, Greater, 0)
+	c.Assert(info.Candidates, HasLen, 0)
+
+	url = fmt.Sprintf("%s/regions/merge-candidates?limit=x", s.urlPrefix)
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testRegionSuite) TestAccelerateSchedule(c *C) {
+	r := newTestRegionInfo(41, 1, []byte("v"), []byte("w"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+
+	url := fmt.Sprintf("%s/regions/accelerate-schedule", s.urlPrefix)
+	post := func(body string) *http.Response {
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		return resp
+	}
+
+	resp := post(`{"start_key": "76", "end_key": "7600"}`)
+	info := &regionsCountInfo{}
+	c.Assert(readJSON(resp.Body, info), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	// The bootstrapped region has no keys, so it is in both ranges.
+	resp = post(`{"start_key": "7700", "end_key": "78"}`)
+	other := &regionsCountInfo{}
+	c.Assert(readJSON(resp.Body, other), IsNil)
+	c.Assert(info.Count-other.Count, Equals, 1)
+
+	for _, body := range []string{`{"start_key": "xx"}`, `{"end_key": 1}`} {
+		resp = post(body)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+}
+
+func (s *testRegionSuite) TestLeaderDistribution(c *C) {
+	r := newTestRegionInfo(42, 1, []byte("g"), []byte("h"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+
+	url := fmt.Sprintf("%s/regions/leader-distribution", s.urlPrefix)
+	info := &leaderDistributionInfo{}
+	err := readJSONWithURL(url+"?start_key=67&end_key=6701", info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Stores, HasLen, 1)
+	c.Assert(info.Stores[0].StoreID, Equals, uint64(1))
+
+	// The bootstrapped region has no keys, so it is in both ranges.
+	other := &leaderDistributionInfo{}
+	err = readJSONWithURL(url+"?start_key=6900&end_key=6a", other)
+	c.Assert(err, IsNil)
+	c.Assert(info.RegionCount-other.RegionCount, Equals, 1)
+	c.Assert(info.Stores[0].LeaderCount-other.Stores[0].LeaderCount, Equals, 1)
+
+	resp, err := http.Get(url + "?start_key=xx")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testRegionSuite) TestScatterRegions(c *C) {
+	r := newTestRegionInfo(40, 1, []byte("t"), []byte("u"))
+	mustRegionHeartBeat(c, s.regionHeartbeat, s.svr.ClusterID(), r)
+
+	url := fmt.Sprintf("%s/regions/scatter", s.urlPrefix)
+	post := func(body string) *http.Response {
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		return resp
+	}
+
+	// The only peer is the leader, which is not moved.
+	resp := post(`{"region_id": 40}`)
+	info := &scatterRegionsInfo{}
+	c.Assert(readJSON(resp.Body, info), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(info.OperatorCount, Equals, 0)
+
+	resp = post(`{"start_key": "74", "end_key": "75"}`)
+	info = &scatterRegionsInfo{}
+	c.Assert(readJSON(resp.Body, info), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(info.OperatorCount, Equals, 0)
+
+	for _, body := range []string{`{"region_id": "x"}`, `{"region_id": -1}`, `{"start_key": "xx"}`, `{"end_key": 1}`} {
+		resp = post(body)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+
+	resp = post(`{"region_id": 1000}`)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
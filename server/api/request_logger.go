@@ -0,0 +1,53 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pingcap/pd/pkg/etcdutil"
+	"github.com/urfave/negroni"
+)
+
+// requestLogger logs the method, path, status, response size and cost of
+// each request at the debug level, since the API is polled by the clients. A
+// request slower than the slow request time of the etcd requests is logged as
+// a warning.
+type requestLogger struct {
+	slowRequestTime time.Duration
+}
+
+func newRequestLogger() *requestLogger {
+	return &requestLogger{slowRequestTime: etcdutil.DefaultSlowRequestTime}
+}
+
+func (l *requestLogger) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	next(w, r)
+	cost := time.Since(start)
+
+	// negroni wraps the writer, so the status and size are known. net/http
+	// replies 200 if nothing is written.
+	status, size := http.StatusOK, 0
+	if rw, ok := w.(negroni.ResponseWriter); ok && rw.Written() {
+		status, size = rw.Status(), rw.Size()
+	}
+	if cost > l.slowRequestTime {
+		log.Warnf("[http] slow request %s %s, status: %d, size: %d, cost: %v", r.Method, r.URL.Path, status, size, cost)
+		return
+	}
+	log.Debugf("[http] %s %s, status: %d, size: %d, cost: %v", r.Method, r.URL.Path, status, size, cost)
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/pingcap/check"
+	"github.com/urfave/negroni"
+)
+
+var _ = Suite(&testRequestLoggerSuite{})
+
+type testRequestLoggerSuite struct{}
+
+func (s *testRequestLoggerSuite) TestRequestLogger(c *C) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	mux.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprint(w, "short")
+	})
+	engine := negroni.New(&requestLogger{slowRequestTime: 50 * time.Millisecond}, negroni.Wrap(mux))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	// The requests are logged at the debug level, which is below the default.
+	resp, err := http.Get(server.URL + "/teapot?limit=1")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(buf.String(), Equals, "")
+
+	resp, err = http.Post(server.URL+"/slow", "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(buf.String(), Matches, `(?s).*level=warning msg="\[http\] slow request POST /slow, status: 200, size: 0, cost: .*".*`)
+}
@@ -36,12 +36,14 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	operatorHandler := newOperatorHandler(handler, rd)
 	router.HandleFunc("/api/v1/operators", operatorHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/operators", operatorHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/operators/balance-preview", operatorHandler.GetBalancePreview).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
 
 	schedulerHandler := newSchedulerHandler(handler, rd)
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/evict-slow-store-scheduler", schedulerHandler.GetSlowStore).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
 
 	router.Handle("/api/v1/cluster", newClusterHandler(svr, rd)).Methods("GET")
@@ -50,16 +52,35 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	confHandler := newConfHandler(svr, rd)
 	router.HandleFunc("/api/v1/config", confHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/config", confHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/config/default", confHandler.GetDefault).Methods("GET")
+	router.HandleFunc("/api/v1/config/reset/{key}", confHandler.Reset).Methods("POST")
 	router.HandleFunc("/api/v1/config/schedule", confHandler.SetSchedule).Methods("POST")
 	router.HandleFunc("/api/v1/config/schedule", confHandler.GetSchedule).Methods("GET")
 	router.HandleFunc("/api/v1/config/replicate", confHandler.SetReplication).Methods("POST")
 	router.HandleFunc("/api/v1/config/replicate", confHandler.GetReplication).Methods("GET")
 
-	storeHandler := newStoreHandler(svr, rd)
+	storeHandler := newStoreHandler(svr)
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}", storeHandler.SetAddress).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/regions", storeHandler.GetRegions).Methods("GET")
 	router.HandleFunc("/api/v1/store/{id}/label", storeHandler.SetLabels).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/label/{key}", storeHandler.DeleteLabel).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}/grpc-status", storeHandler.Probe).Methods("GET")
+	router.HandleFunc("/api/v1/store/{id}/snapshots", storeHandler.GetSnapshots).Methods("GET")
+	router.HandleFunc("/api/v1/store/{id}/note", storeHandler.SetNote).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/preferred-leader", storeHandler.SetPreferredLeader).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/relocate", storeHandler.Relocate).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/transfer-leaders", storeHandler.TransferLeaders).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/fix-placement", storeHandler.FixPlacement).Methods("POST")
+	if svr.GetConfig().EnableTestAPI {
+		router.HandleFunc("/api/v1/store/{id}/status", storeHandler.SetStatus).Methods("POST")
+		router.HandleFunc("/api/v1/store/{id}/touch", storeHandler.Touch).Methods("POST")
+	}
 	router.Handle("/api/v1/stores", newStoresHandler(svr, rd)).Methods("GET")
+	router.HandleFunc("/api/v1/stores/heartbeat", newStoresHandler(svr, rd).GetHeartbeatAges).Methods("GET")
+	router.HandleFunc("/api/v1/stores/snapshots", newStoresHandler(svr, rd).GetSnapshots).Methods("GET")
 
 	labelsHandler := newLabelsHandler(svr, rd)
 	router.HandleFunc("/api/v1/labels", labelsHandler.Get).Methods("GET")
@@ -73,15 +94,30 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	regionHandler := newRegionHandler(svr, rd)
 	router.HandleFunc("/api/v1/region/id/{id}", regionHandler.GetRegionByID).Methods("GET")
+	router.HandleFunc("/api/v1/region/id/{id}/history", regionHandler.GetRegionHistory).Methods("GET")
+	router.HandleFunc("/api/v1/region/id/{id}/fix", regionHandler.FixRegion).Methods("POST")
 	router.HandleFunc("/api/v1/region/key/{key}", regionHandler.GetRegionByKey).Methods("GET")
 
 	router.Handle("/api/v1/regions", newRegionsHandler(svr, rd)).Methods("GET")
+	router.HandleFunc("/api/v1/regions/count", newRegionsHandler(svr, rd).GetRegionCount).Methods("GET")
+	router.HandleFunc("/api/v1/regions/sibling/{id}", newRegionsHandler(svr, rd).GetSiblings).Methods("GET")
+	router.HandleFunc("/api/v1/regions/scatter", newRegionsHandler(svr, rd).ScatterRegions).Methods("POST")
+	router.HandleFunc("/api/v1/regions/accelerate-schedule", newRegionsHandler(svr, rd).AccelerateSchedule).Methods("POST")
+	router.HandleFunc("/api/v1/regions/merge-candidates", newRegionsHandler(svr, rd).GetMergeCandidates).Methods("GET")
+	router.HandleFunc("/api/v1/regions/check", newRegionsHandler(svr, rd).CheckRegions).Methods("GET")
+	router.HandleFunc("/api/v1/regions/leader-distribution", newRegionsHandler(svr, rd).GetLeaderDistribution).Methods("GET")
 	router.Handle("/api/v1/version", newVersionHandler(rd)).Methods("GET")
 	router.Handle("/api/v1/status", newStatusHandler(rd)).Methods("GET")
+	router.HandleFunc("/api/v1/debug/slow-requests", newDebugHandler(rd).GetSlowRequests).Methods("GET")
+	router.HandleFunc("/api/v1/tso/status", newTSOHandler(svr, rd).GetStatus).Methods("GET")
 
 	router.Handle("/api/v1/members", newMemberListHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/members/etcd", newEtcdMemberListHandler(svr, rd)).Methods("GET")
+	router.HandleFunc("/api/v1/members/etcd/db", newEtcdMemberListHandler(svr, rd).GetDBSize).Methods("GET")
 	memberDeleteHandler := newMemberDeleteHandler(svr, rd)
 	router.HandleFunc("/api/v1/members/name/{name}", memberDeleteHandler.DeleteByName).Methods("DELETE")
+	router.HandleFunc("/api/v1/members/name/{name}", newMemberUpdateHandler(svr, rd).UpdateByName).Methods("POST")
+	router.HandleFunc("/api/v1/members/name/{name}/campaign", newMemberUpdateHandler(svr, rd).SetCampaign).Methods("POST")
 	router.HandleFunc("/api/v1/members/id/{id}", memberDeleteHandler.DeleteByID).Methods("DELETE")
 
 	leaderHandler := newLeaderHandler(svr, rd)
@@ -33,7 +33,18 @@ func newSchedulerHandler(handler *server.Handler, r *render.Render) *schedulerHa
 	}
 }
 
+// List returns the names of the schedulers, or the last decision of each
+// scheduler if explain is true.
 func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("explain") == "true" {
+		decisions, err := h.GetSchedulerDecisions()
+		if err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusOK, decisions)
+		return
+	}
 	schedulers, err := h.GetSchedulers()
 	if err != nil {
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -45,7 +56,7 @@ func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := readJSON(r.Body, &input); err != nil {
-		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		h.r.JSON(w, readJSONErrorStatus(err), err.Error())
 		return
 	}
 
@@ -81,6 +92,11 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	case "evict-slow-store-scheduler":
+		if err := h.AddEvictSlowStoreScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	case "shuffle-leader-scheduler":
 		if err := h.AddShuffleLeaderScheduler(); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -99,6 +115,20 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, nil)
 }
 
+type slowStoreInfo struct {
+	// StoreID is 0 if no store is being evicted.
+	StoreID uint64 `json:"store_id"`
+}
+
+func (h *schedulerHandler) GetSlowStore(w http.ResponseWriter, r *http.Request) {
+	storeID, err := h.Handler.GetSlowStore()
+	if err != nil {
+		h.r.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, &slowStoreInfo{StoreID: storeID})
+}
+
 func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	name := mux.Vars(r)["name"]
 
@@ -29,6 +29,8 @@ func NewHandler(svr *server.Server) http.Handler {
 
 	recovery := negroni.NewRecovery()
 	engine.Use(recovery)
+	// The redirected requests are logged with the time taken by the leader.
+	engine.Use(newRequestLogger())
 
 	router := mux.NewRouter()
 	router.PathPrefix(apiPrefix).Handler(negroni.New(
@@ -14,12 +14,14 @@
 package api
 
 import (
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -157,11 +159,24 @@ func mustPutStore(c *C, s *server.Server, store *metapb.Store) {
 	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_OK)
 }
 
+func mustStoreHeartBeat(c *C, s *server.Server, stats *pdpb.StoreStats) {
+	grpcPDClient := mustNewGrpcClient(c, s.GetAddr())
+	req := &pdpb.StoreHeartbeatRequest{
+		Header: newRequestHeader(s.ClusterID()),
+		Stats:  stats,
+	}
+	resp, err := grpcPDClient.StoreHeartbeat(context.Background(), req)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_OK)
+}
+
 func mustRegionHeartBeat(c *C, client pdpb.PD_RegionHeartbeatClient, clusterID uint64, region *server.RegionInfo) {
 	req := &pdpb.RegionHeartbeatRequest{
-		Header: newRequestHeader(clusterID),
-		Region: region.Region,
-		Leader: region.Leader,
+		Header:       newRequestHeader(clusterID),
+		Region:       region.Region,
+		Leader:       region.Leader,
+		DownPeers:    region.DownPeers,
+		PendingPeers: region.PendingPeers,
 	}
 
 	err := client.Send(req)
@@ -177,5 +192,9 @@ func readJSONWithURL(url string, data interface{}) error {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("[%d] %s", resp.StatusCode, b)
+	}
 	return readJSON(resp.Body, data)
 }
@@ -17,10 +17,16 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/apiutil"
 	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
 )
 
 var _ = Suite(&testStatusAPISuite{})
@@ -61,3 +67,41 @@ func (s *testStatusAPISuite) TestStatus(c *C) {
 		s.testStatusInternal(c, num)
 	}
 }
+
+func (s *testStatusAPISuite) TestVersion(c *C) {
+	origin := server.PDBuildTS
+	defer func() { server.PDBuildTS = origin }()
+	server.PDBuildTS = "2017-09-01 15:04:05"
+
+	w := httptest.NewRecorder()
+	newVersionHandler(render.New(render.Options{})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	got := version{}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &got), IsNil)
+	c.Assert(got, Equals, version{Version: server.PDReleaseVersion, GitHash: server.PDGitHash, BuildTS: "2017-09-01T15:04:05Z"})
+}
+
+func (s *testStatusAPISuite) TestUnixSocket(c *C) {
+	svr, clean := mustNewServer(c)
+	defer clean()
+
+	dir, err := ioutil.TempDir("", "pd_api_unix")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pd.sock")
+	l, err := net.Listen("unix", path)
+	c.Assert(err, IsNil)
+	httpServer := httptest.NewUnstartedServer(NewHandler(svr))
+	httpServer.Listener.Close()
+	httpServer.Listener = l
+	httpServer.Start()
+	defer httpServer.Close()
+
+	resp, err := apiutil.NewUnixHTTPClient(path).Get("http://unix" + apiPrefix + "/api/v1/status")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	buf, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	checkStatusResponse(c, buf, nil)
+}
@@ -14,8 +14,10 @@
 package api
 
 import (
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"time"
 
@@ -51,6 +53,15 @@ type storeStatus struct {
 type storeInfo struct {
 	Store  *metaStore   `json:"store"`
 	Status *storeStatus `json:"status"`
+	// Note is set by operators, such as why the store is offline.
+	Note string `json:"note,omitempty"`
+	// PreferredLeader is set by operators for the stores which should host
+	// more leaders.
+	PreferredLeader bool `json:"preferred_leader,omitempty"`
+	// LeaderWeight and RegionWeight are set by operators, the leaders and
+	// regions are balanced in proportion to them.
+	LeaderWeight float64 `json:"leader_weight"`
+	RegionWeight float64 `json:"region_weight"`
 }
 
 const downStateName = "Down"
@@ -89,20 +100,18 @@ type storesInfo struct {
 
 type storeHandler struct {
 	svr *server.Server
-	rd  *render.Render
 }
 
-func newStoreHandler(svr *server.Server, rd *render.Render) *storeHandler {
+func newStoreHandler(svr *server.Server) *storeHandler {
 	return &storeHandler{
 		svr: svr,
-		rd:  rd,
 	}
 }
 
 func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
 		return
 	}
 
@@ -110,24 +119,144 @@ func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	storeIDStr := vars["id"]
 	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	store, status, err := cluster.GetStore(storeID)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	storeInfo := newStoreInfo(store, status)
+	if storeInfo.Note, err = cluster.GetStoreNote(storeID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	storeInfo.PreferredLeader = cluster.IsStorePreferredLeader(storeID)
+	storeInfo.LeaderWeight, storeInfo.RegionWeight = cluster.GetStoreWeight(storeID)
+	writeJSON(w, http.StatusOK, storeInfo)
+}
+
+// SetAddress changes the address of the store and returns the updated store.
+// The address should be in the form of host:port.
+func (h *storeHandler) SetAddress(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var input map[string]string
+	if err = readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+	address, ok := input["address"]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("missing address"))
+		return
+	}
+	if err = validateStoreAddress(address); err != nil {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	if _, err = cluster.UpdateStoreAddress(storeID, address); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	store, status, err := cluster.GetStore(storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
 	storeInfo := newStoreInfo(store, status)
-	h.rd.JSON(w, http.StatusOK, storeInfo)
+	if storeInfo.Note, err = cluster.GetStoreNote(storeID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	storeInfo.PreferredLeader = cluster.IsStorePreferredLeader(storeID)
+	storeInfo.LeaderWeight, storeInfo.RegionWeight = cluster.GetStoreWeight(storeID)
+	writeJSON(w, http.StatusOK, storeInfo)
+}
+
+func validateStoreAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Errorf("invalid address %q: %s", address, err)
+	}
+	if host == "" {
+		return errors.Errorf("invalid address %q: missing host", address)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return errors.Errorf("invalid address %q: invalid port", address)
+	}
+	return nil
+}
+
+// GetRegions returns the regions which have a peer on the store. If check is
+// "pending-peer", only the regions whose peer on the store is pending are
+// returned.
+func (h *storeHandler) GetRegions(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	check := r.URL.Query().Get("check")
+	if check != "" && check != "pending-peer" {
+		writeError(w, http.StatusBadRequest, errors.Errorf("unknown check %q", check))
+		return
+	}
+
+	regions, err := cluster.GetStoreRegions(storeID)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	metaRegions := make([]*metapb.Region, 0, len(regions))
+	for _, region := range regions {
+		if check == "pending-peer" {
+			peer := region.GetStorePeer(storeID)
+			if peer == nil || region.GetPendingPeer(peer.GetId()) == nil {
+				continue
+			}
+		}
+		metaRegions = append(metaRegions, region.Region)
+	}
+	sort.Slice(metaRegions, func(i, j int) bool {
+		return metaRegions[i].GetId() < metaRegions[j].GetId()
+	})
+	writeJSON(w, http.StatusOK, &regionsInfo{
+		Count:   len(metaRegions),
+		Regions: metaRegions,
+	})
 }
 
 func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
 		return
 	}
 
@@ -135,7 +264,7 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	storeIDStr := vars["id"]
 	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -147,17 +276,17 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.rd.JSON(w, http.StatusOK, nil)
+	writeJSON(w, http.StatusOK, nil)
 }
 
 func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
 		return
 	}
 
@@ -165,29 +294,429 @@ func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
 	storeIDStr := vars["id"]
 	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	var input map[string]string
+	// A null value is decoded as nil, so it is told from an absent key.
+	var input map[string]*string
 	if err := readJSON(r.Body, &input); err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, readJSONErrorStatus(err), err)
 		return
 	}
 	var labels []*metapb.StoreLabel
 	for k, v := range input {
-		labels = append(labels, &metapb.StoreLabel{
-			Key:   k,
-			Value: v,
-		})
+		label := &metapb.StoreLabel{Key: k}
+		if v != nil {
+			label.Value = *v
+		}
+		labels = append(labels, label)
+	}
+
+	// Labels are merged into the existing ones unless replace is set, and a
+	// null or empty value removes the label, such as {"zone": "cn", "rack":
+	// null}. The labels which are absent are not changed.
+	replace := r.URL.Query().Get("replace") == "true"
+	if err := cluster.UpdateStoreLabels(storeID, labels, replace); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// storeProbeTimeout bounds the dial of a store probe.
+const storeProbeTimeout = 3 * time.Second
+
+type storeProbeInfo struct {
+	StoreID   uint64            `json:"store_id"`
+	Address   string            `json:"address"`
+	Reachable bool              `json:"reachable"`
+	Latency   typeutil.Duration `json:"latency"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// probeStore dials the store address to check if it is reachable, the latency
+// is the time to establish the connection.
+func probeStore(store *metapb.Store, timeout time.Duration) *storeProbeInfo {
+	info := &storeProbeInfo{
+		StoreID: store.GetId(),
+		Address: store.GetAddress(),
+	}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", store.GetAddress(), timeout)
+	info.Latency = typeutil.NewDuration(time.Since(start))
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	conn.Close()
+	info.Reachable = true
+	return info
+}
+
+// Probe checks if the grpc address of the store can be connected by pd.
+func (h *storeHandler) Probe(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	store, _, err := cluster.GetStore(storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, probeStore(store, storeProbeTimeout))
+}
+
+type storeSnapshotInfo struct {
+	StoreID            uint64 `json:"store_id"`
+	SendingSnapCount   uint32 `json:"sending_snap_count"`
+	ReceivingSnapCount uint32 `json:"receiving_snap_count"`
+	ApplyingSnapCount  uint32 `json:"applying_snap_count"`
+}
+
+func newStoreSnapshotInfo(storeID uint64, status *server.StoreStatus) *storeSnapshotInfo {
+	return &storeSnapshotInfo{
+		StoreID:            storeID,
+		SendingSnapCount:   status.GetSendingSnapCount(),
+		ReceivingSnapCount: status.GetReceivingSnapCount(),
+		ApplyingSnapCount:  status.GetApplyingSnapCount(),
+	}
+}
+
+// GetSnapshots returns the snapshot counts in the last heartbeat of the store.
+func (h *storeHandler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
 	}
 
-	if err := cluster.UpdateStoreLabels(storeID, labels); err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	_, status, err := cluster.GetStore(storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.rd.JSON(w, http.StatusOK, nil)
+	writeJSON(w, http.StatusOK, newStoreSnapshotInfo(storeID, status))
+}
+
+// SetNote sets the note of the store, an empty note clears it.
+func (h *storeHandler) SetNote(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var input map[string]string
+	if err := readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+	note, ok := input["note"]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("missing note"))
+		return
+	}
+	if err := cluster.SetStoreNote(storeID, note); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// SetPreferredLeader marks the store as preferred to host leaders or not.
+func (h *storeHandler) SetPreferredLeader(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var input map[string]bool
+	if err := readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+	preferred, ok := input["preferred"]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("missing preferred"))
+		return
+	}
+	if err := cluster.SetStorePreferredLeader(storeID, preferred); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// SetWeight sets the leader and region weight of the store, both of them
+// should be greater than 0.
+func (h *storeHandler) SetWeight(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var input map[string]float64
+	if err := readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+	leader, ok := input["leader"]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("missing leader weight"))
+		return
+	}
+	region, ok := input["region"]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("missing region weight"))
+		return
+	}
+	if leader <= 0 || region <= 0 {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid weight %v, %v, should be greater than 0", leader, region))
+		return
+	}
+	if err := cluster.SetStoreWeight(storeID, leader, region); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// DeleteLabel removes a label from the store, it does nothing if the store
+// has no such label.
+func (h *storeHandler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// A label with an empty value is removed.
+	labels := []*metapb.StoreLabel{{Key: vars["key"]}}
+	if err := cluster.UpdateStoreLabels(storeID, labels, false); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+func (h *storeHandler) Relocate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var input map[string]interface{}
+	if err = readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+	ids, ok := parseStoreIDs(input["to_store_ids"])
+	if !ok || len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid store ids to relocate to"))
+		return
+	}
+	targetIDs := make([]uint64, 0, len(ids))
+	for id := range ids {
+		targetIDs = append(targetIDs, id)
+	}
+
+	count, err := h.svr.GetHandler().AddRelocateStoreOperators(storeID, targetIDs)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &regionsCountInfo{Count: count})
+}
+
+// TransferLeaders transfers the leaders of all regions led by the store to its
+// healthy followers, it returns the number of scheduled regions.
+func (h *storeHandler) TransferLeaders(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	count, err := h.svr.GetHandler().AddTransferLeadersOperators(storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &regionsCountInfo{Count: count})
+}
+
+// FixPlacement moves the peers of the store which break the location labels,
+// the operators are only returned if "dry_run" is true.
+func (h *storeHandler) FixPlacement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	ops, err := h.svr.GetHandler().FixStorePlacement(storeID, dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if ops == nil {
+		ops = []server.Operator{}
+	}
+	writeJSON(w, http.StatusOK, ops)
+}
+
+type storeStatusInput struct {
+	Capacity    *uint64 `json:"capacity"`
+	Available   *uint64 `json:"available"`
+	RegionCount *int    `json:"region_count"`
+}
+
+// SetStatus overrides the reported status of a store, it is a test-only API.
+func (h *storeHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var input storeStatusInput
+	if err = readJSON(r.Body, &input); err != nil {
+		writeError(w, readJSONErrorStatus(err), err)
+		return
+	}
+
+	err = cluster.SetStoreStatus(storeID, func(status *server.StoreStatus) {
+		if input.Capacity != nil {
+			status.Capacity = *input.Capacity
+		}
+		if input.Available != nil {
+			status.Available = *input.Available
+		}
+		if input.RegionCount != nil {
+			status.RegionCount = *input.RegionCount
+		}
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// Touch sets the last heartbeat time of the store to now as if it just sent a
+// heartbeat, so a store which is down because of a transient problem is up
+// again. It is only used by tests.
+func (h *storeHandler) Touch(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	err = cluster.SetStoreStatus(storeID, func(status *server.StoreStatus) {
+		status.LastHeartbeatTS = now
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	_, status, err := cluster.GetStore(storeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	seconds := int64(time.Since(status.LastHeartbeatTS).Seconds())
+	writeJSON(w, http.StatusOK, &storeHeartbeatAge{StoreID: storeID, HeartbeatAge: &seconds})
 }
 
 type storesHandler struct {
@@ -205,7 +734,7 @@ func newStoresHandler(svr *server.Server, rd *render.Render) *storesHandler {
 func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cluster := h.svr.GetRaftCluster()
 	if cluster == nil {
-		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
 		return
 	}
 
@@ -216,7 +745,13 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	urlFilter, err := newStoreStateFilter(r.URL)
 	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	notes, err := cluster.GetStoreNotes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -224,16 +759,121 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	for _, s := range stores {
 		store, status, err := cluster.GetStore(s.GetId())
 		if err != nil {
-			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
 
 		storeInfo := newStoreInfo(store, status)
+		storeInfo.Note = notes[store.GetId()]
+		storeInfo.PreferredLeader = cluster.IsStorePreferredLeader(store.GetId())
+		storeInfo.LeaderWeight, storeInfo.RegionWeight = cluster.GetStoreWeight(store.GetId())
 		storesInfo.Stores = append(storesInfo.Stores, storeInfo)
 	}
 	storesInfo.Count = len(storesInfo.Stores)
 
-	h.rd.JSON(w, http.StatusOK, storesInfo)
+	// Stores are sorted to make the ETag stable.
+	sort.Slice(storesInfo.Stores, func(i, j int) bool {
+		return storesInfo.Stores[i].Store.GetId() < storesInfo.Stores[j].Store.GetId()
+	})
+	writeJSONWithETag(h.rd, w, r, storesInfo)
+}
+
+type storeHeartbeatAge struct {
+	StoreID uint64 `json:"store_id"`
+	// Seconds since the last heartbeat, null if the store never heartbeats.
+	HeartbeatAge *int64 `json:"heartbeat_age_seconds"`
+}
+
+type storesHeartbeatAgeInfo struct {
+	Count  int                  `json:"count"`
+	Stores []*storeHeartbeatAge `json:"stores"`
+}
+
+// GetHeartbeatAges returns how long ago each store sent its last heartbeat,
+// computed with the clock of pd.
+func (h *storesHandler) GetHeartbeatAges(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	urlFilter, err := newStoreStateFilter(r.URL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stores := urlFilter.filter(cluster.GetStores())
+	info := &storesHeartbeatAgeInfo{
+		Stores: make([]*storeHeartbeatAge, 0, len(stores)),
+	}
+	now := time.Now()
+	for _, s := range stores {
+		_, status, err := cluster.GetStore(s.GetId())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		age := &storeHeartbeatAge{StoreID: s.GetId()}
+		if !status.LastHeartbeatTS.IsZero() {
+			seconds := int64(now.Sub(status.LastHeartbeatTS).Seconds())
+			age.HeartbeatAge = &seconds
+		}
+		info.Stores = append(info.Stores, age)
+	}
+	info.Count = len(info.Stores)
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+type storesSnapshotInfo struct {
+	Count  int                  `json:"count"`
+	Stores []*storeSnapshotInfo `json:"stores"`
+	// Total is the sum of the counts of the stores, its store id is 0.
+	Total *storeSnapshotInfo `json:"total"`
+}
+
+// GetSnapshots returns the snapshot counts in the last heartbeats of the
+// stores, sorted by store id.
+func (h *storesHandler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		writeError(w, http.StatusInternalServerError, server.ErrNotBootstrapped)
+		return
+	}
+
+	urlFilter, err := newStoreStateFilter(r.URL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stores := urlFilter.filter(cluster.GetStores())
+	info := &storesSnapshotInfo{
+		Stores: make([]*storeSnapshotInfo, 0, len(stores)),
+		Total:  &storeSnapshotInfo{},
+	}
+	for _, s := range stores {
+		_, status, err := cluster.GetStore(s.GetId())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		snapshot := newStoreSnapshotInfo(s.GetId(), status)
+		info.Total.SendingSnapCount += snapshot.SendingSnapCount
+		info.Total.ReceivingSnapCount += snapshot.ReceivingSnapCount
+		info.Total.ApplyingSnapCount += snapshot.ApplyingSnapCount
+		info.Stores = append(info.Stores, snapshot)
+	}
+	info.Count = len(info.Stores)
+	sort.Slice(info.Stores, func(i, j int) bool {
+		return info.Stores[i].StoreID < info.Stores[j].StoreID
+	})
+
+	writeJSON(w, http.StatusOK, info)
 }
 
 type storeStateFilter struct {
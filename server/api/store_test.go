@@ -17,8 +17,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -113,6 +115,54 @@ func (s *testStoreSuite) TestStoresList(c *C) {
 
 }
 
+func (s *testStoreSuite) TestStoresETag(c *C) {
+	url := fmt.Sprintf("%s/stores", s.urlPrefix)
+	resp, err := http.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	etag := resp.Header.Get("ETag")
+	c.Assert(etag, Not(Equals), "")
+
+	for _, t := range []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		c.Assert(err, IsNil)
+		req.Header.Set("If-None-Match", t.ifNoneMatch)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, t.status)
+		c.Assert(resp.Header.Get("ETag"), Equals, etag)
+	}
+}
+
+func (s *testStoreSuite) TestStoresHeartbeatAge(c *C) {
+	mustStoreHeartBeat(c, s.svr, &pdpb.StoreStats{StoreId: 1, Capacity: 100, Available: 50})
+
+	url := fmt.Sprintf("%s/stores/heartbeat", s.urlPrefix)
+	info := &storesHeartbeatAgeInfo{}
+	err := readJSONWithURL(url, info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Count, Equals, 3)
+
+	ages := make(map[uint64]*int64)
+	for _, age := range info.Stores {
+		ages[age.StoreID] = age.HeartbeatAge
+	}
+	c.Assert(ages[1], NotNil)
+	c.Assert(*ages[1] >= 0 && *ages[1] < 60, IsTrue)
+	// Store 6 has never sent a heartbeat.
+	c.Assert(ages[6], IsNil)
+}
+
 func (s *testStoreSuite) TestStoreGet(c *C) {
 	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
 	info := new(storeInfo)
@@ -157,9 +207,296 @@ func (s *testStoreSuite) TestStoreLabel(c *C) {
 		c.Assert(expectLabel[l.Key], Equals, l.Value)
 	}
 
+	// Test delete by an empty value.
+	labels = map[string]string{"zack": ""}
+	b, err = json.Marshal(labels)
+	c.Assert(err, IsNil)
+	err = postJSON(&http.Client{}, url+"/label", b)
+	c.Assert(err, IsNil)
+
+	expectLabel = map[string]string{"zone": "cn", "host": "host1"}
+	err = readJSONWithURL(url, &info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Store.Labels, HasLen, len(expectLabel))
+	for _, l := range info.Store.Labels {
+		c.Assert(expectLabel[l.Key], Equals, l.Value)
+	}
+
+	// Test set and delete by a null value at once.
+	err = postJSON(&http.Client{}, url+"/label", []byte(`{"host": null, "zack": "zack2"}`))
+	c.Assert(err, IsNil)
+
+	expectLabel = map[string]string{"zone": "cn", "zack": "zack2"}
+	err = readJSONWithURL(url, &info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Store.Labels, HasLen, len(expectLabel))
+	for _, l := range info.Store.Labels {
+		c.Assert(expectLabel[l.Key], Equals, l.Value)
+	}
+
+	// Test replace.
+	labels = map[string]string{"zone": "us", "rack": "r1"}
+	b, err = json.Marshal(labels)
+	c.Assert(err, IsNil)
+	err = postJSON(&http.Client{}, url+"/label?replace=true", b)
+	c.Assert(err, IsNil)
+
+	err = readJSONWithURL(url, &info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Store.Labels, HasLen, len(labels))
+	for _, l := range info.Store.Labels {
+		c.Assert(labels[l.Key], Equals, l.Value)
+	}
+
+	// Test delete, deleting an absent label does nothing.
+	for _, key := range []string{"rack", "absent"} {
+		req, err := http.NewRequest(http.MethodDelete, url+"/label/"+key, nil)
+		c.Assert(err, IsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	}
+
+	err = readJSONWithURL(url, &info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Store.Labels, HasLen, 1)
+	c.Assert(info.Store.Labels[0].Key, Equals, "zone")
+	c.Assert(info.Store.Labels[0].Value, Equals, "us")
+
+	// The error is responded as a JSON object with the status code.
+	resp, err := http.Post(fmt.Sprintf("%s/store/100/label", s.urlPrefix), "application/json", strings.NewReader(`{"zone": "cn"}`))
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+	c.Assert(resp.Header.Get("Content-Type"), Equals, "application/json; charset=UTF-8")
+	var errResp errorResponse
+	c.Assert(readJSON(resp.Body, &errResp), IsNil)
+	c.Assert(errResp.Code, Equals, http.StatusInternalServerError)
+	c.Assert(errResp.Error, Equals, "invalid store ID 100, not found")
+
 	s.stores[0].Labels = info.Store.Labels
 }
 
+func (s *testStoreSuite) TestStoreNote(c *C) {
+	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
+	var info storeInfo
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.Note, Equals, "")
+
+	b, err := json.Marshal(map[string]string{"note": "disk replacement"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(&http.Client{}, url+"/note", b), IsNil)
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.Note, Equals, "disk replacement")
+
+	stores := &storesInfo{}
+	c.Assert(readJSONWithURL(s.urlPrefix+"/stores", stores), IsNil)
+	for _, store := range stores.Stores {
+		if store.Store.GetId() == 1 {
+			c.Assert(store.Note, Equals, "disk replacement")
+		} else {
+			c.Assert(store.Note, Equals, "")
+		}
+	}
+
+	// An empty note clears it.
+	b, err = json.Marshal(map[string]string{"note": ""})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(&http.Client{}, url+"/note", b), IsNil)
+	info = storeInfo{}
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.Note, Equals, "")
+
+	c.Assert(postJSON(&http.Client{}, url+"/note", []byte("{}")), NotNil)
+	c.Assert(postJSON(&http.Client{}, s.urlPrefix+"/store/100/note", b), NotNil)
+}
+
+func (s *testStoreSuite) TestStorePreferredLeader(c *C) {
+	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
+	c.Assert(postJSON(&http.Client{}, url+"/preferred-leader", []byte(`{"preferred":true}`)), IsNil)
+	var info storeInfo
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.PreferredLeader, IsTrue)
+
+	stores := &storesInfo{}
+	c.Assert(readJSONWithURL(s.urlPrefix+"/stores", stores), IsNil)
+	for _, store := range stores.Stores {
+		c.Assert(store.PreferredLeader, Equals, store.Store.GetId() == 1)
+	}
+
+	c.Assert(postJSON(&http.Client{}, url+"/preferred-leader", []byte(`{"preferred":false}`)), IsNil)
+	info = storeInfo{}
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.PreferredLeader, IsFalse)
+
+	c.Assert(postJSON(&http.Client{}, url+"/preferred-leader", []byte("{}")), NotNil)
+	c.Assert(postJSON(&http.Client{}, s.urlPrefix+"/store/100/preferred-leader", []byte(`{"preferred":true}`)), NotNil)
+}
+
+func (s *testStoreSuite) TestStoreWeight(c *C) {
+	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
+	var info storeInfo
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.LeaderWeight, Equals, 1.0)
+	c.Assert(info.RegionWeight, Equals, 1.0)
+
+	c.Assert(postJSON(&http.Client{}, url+"/weight", []byte(`{"leader":2,"region":0.5}`)), IsNil)
+	info = storeInfo{}
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.LeaderWeight, Equals, 2.0)
+	c.Assert(info.RegionWeight, Equals, 0.5)
+
+	stores := &storesInfo{}
+	c.Assert(readJSONWithURL(s.urlPrefix+"/stores", stores), IsNil)
+	for _, store := range stores.Stores {
+		if store.Store.GetId() == 1 {
+			c.Assert(store.LeaderWeight, Equals, 2.0)
+		} else {
+			c.Assert(store.LeaderWeight, Equals, 1.0)
+		}
+	}
+
+	c.Assert(postJSON(&http.Client{}, url+"/weight", []byte(`{"leader":1,"region":1}`)), IsNil)
+	c.Assert(postJSON(&http.Client{}, url+"/weight", []byte(`{"leader":1}`)), NotNil)
+	c.Assert(postJSON(&http.Client{}, url+"/weight", []byte(`{"leader":0,"region":1}`)), NotNil)
+	c.Assert(postJSON(&http.Client{}, s.urlPrefix+"/store/100/weight", []byte(`{"leader":1,"region":1}`)), NotNil)
+}
+
+func (s *testStoreSuite) TestStoreSetAddress(c *C) {
+	url := fmt.Sprintf("%s/store/4", s.urlPrefix)
+	setAddress := func(address string) error {
+		b, err := json.Marshal(map[string]string{"address": address})
+		c.Assert(err, IsNil)
+		return postJSON(&http.Client{}, url, b)
+	}
+	defer func() {
+		c.Assert(setAddress("localhost:4"), IsNil)
+	}()
+
+	c.Assert(setAddress("tikv-4.example.com:20160"), IsNil)
+	var info storeInfo
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.Store.GetAddress(), Equals, "tikv-4.example.com:20160")
+
+	// The address of a tombstone store can be reused.
+	c.Assert(setAddress("localhost:7"), IsNil)
+
+	for _, address := range []string{"localhost:1", "localhost", ":20160", "localhost:port", "localhost:0", ""} {
+		c.Assert(setAddress(address), NotNil)
+	}
+	c.Assert(readJSONWithURL(url, &info), IsNil)
+	c.Assert(info.Store.GetAddress(), Equals, "localhost:7")
+
+	c.Assert(postJSON(&http.Client{}, url, []byte("{}")), NotNil)
+	b, err := json.Marshal(map[string]string{"address": "localhost:100"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(&http.Client{}, s.urlPrefix+"/store/100", b), NotNil)
+}
+
+func (s *testStoreSuite) TestStoreProbe(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	info := probeStore(&metapb.Store{Id: 100, Address: l.Addr().String()}, time.Second)
+	c.Assert(info.Reachable, IsTrue)
+	c.Assert(info.Error, Equals, "")
+
+	// Nothing listens on the address of store 1.
+	info = &storeProbeInfo{}
+	c.Assert(readJSONWithURL(fmt.Sprintf("%s/store/1/grpc-status", s.urlPrefix), info), IsNil)
+	c.Assert(info.StoreID, Equals, uint64(1))
+	c.Assert(info.Address, Equals, "localhost:1")
+	c.Assert(info.Reachable, IsFalse)
+	c.Assert(info.Error, Not(Equals), "")
+
+	err = readJSONWithURL(fmt.Sprintf("%s/store/100/grpc-status", s.urlPrefix), info)
+	c.Assert(err, NotNil)
+}
+
+func (s *testStoreSuite) TestStoreSetStatus(c *C) {
+	url := fmt.Sprintf("%s/store/4", s.urlPrefix)
+	input := map[string]interface{}{
+		"capacity":     100 * 1024 * 1024,
+		"available":    10 * 1024 * 1024,
+		"region_count": 3,
+	}
+	b, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	err = postJSON(&http.Client{}, url+"/status", b)
+	c.Assert(err, IsNil)
+
+	info := new(storeInfo)
+	err = readJSONWithURL(url, info)
+	c.Assert(err, IsNil)
+	c.Assert(uint64(info.Status.Capacity), Equals, uint64(100*1024*1024))
+	c.Assert(uint64(info.Status.Available), Equals, uint64(10*1024*1024))
+	c.Assert(info.Status.RegionCount, Equals, 3)
+
+	// Fields not given are left unchanged.
+	b, err = json.Marshal(map[string]interface{}{"available": 0})
+	c.Assert(err, IsNil)
+	err = postJSON(&http.Client{}, url+"/status", b)
+	c.Assert(err, IsNil)
+
+	err = readJSONWithURL(url, info)
+	c.Assert(err, IsNil)
+	c.Assert(uint64(info.Status.Capacity), Equals, uint64(100*1024*1024))
+	c.Assert(uint64(info.Status.Available), Equals, uint64(0))
+	c.Assert(info.Status.RegionCount, Equals, 3)
+}
+
+func (s *testStoreSuite) TestStoreTouch(c *C) {
+	_, status, err := s.svr.GetRaftCluster().GetStore(1)
+	c.Assert(err, IsNil)
+	before := status.LastHeartbeatTS
+
+	resp, err := http.Post(fmt.Sprintf("%s/store/1/touch", s.urlPrefix), "", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	age := &storeHeartbeatAge{}
+	c.Assert(readJSON(resp.Body, age), IsNil)
+	c.Assert(age.StoreID, Equals, uint64(1))
+	c.Assert(*age.HeartbeatAge, Equals, int64(0))
+
+	_, status, err = s.svr.GetRaftCluster().GetStore(1)
+	c.Assert(err, IsNil)
+	c.Assert(status.LastHeartbeatTS.After(before), IsTrue)
+
+	resp, err = http.Post(fmt.Sprintf("%s/store/100/touch", s.urlPrefix), "", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+}
+
+func (s *testStoreSuite) TestStoreSnapshots(c *C) {
+	mustStoreHeartBeat(c, s.svr, &pdpb.StoreStats{
+		StoreId:            1,
+		Capacity:           100,
+		Available:          50,
+		SendingSnapCount:   3,
+		ReceivingSnapCount: 2,
+		ApplyingSnapCount:  1,
+	})
+	want := &storeSnapshotInfo{StoreID: 1, SendingSnapCount: 3, ReceivingSnapCount: 2, ApplyingSnapCount: 1}
+
+	info := &storeSnapshotInfo{}
+	err := readJSONWithURL(fmt.Sprintf("%s/store/1/snapshots", s.urlPrefix), info)
+	c.Assert(err, IsNil)
+	c.Assert(info, DeepEquals, want)
+
+	stores := &storesSnapshotInfo{}
+	err = readJSONWithURL(fmt.Sprintf("%s/stores/snapshots", s.urlPrefix), stores)
+	c.Assert(err, IsNil)
+	c.Assert(stores.Count, Equals, 3)
+	c.Assert(stores.Stores, DeepEquals, []*storeSnapshotInfo{want, {StoreID: 4}, {StoreID: 6}})
+	c.Assert(stores.Total, DeepEquals, &storeSnapshotInfo{SendingSnapCount: 3, ReceivingSnapCount: 2, ApplyingSnapCount: 1})
+
+	resp, err := http.Get(fmt.Sprintf("%s/store/100/snapshots", s.urlPrefix))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+}
+
 func (s *testStoreSuite) TestStoreDelete(c *C) {
 	table := []struct {
 		id     int
@@ -186,6 +523,43 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 	}
 }
 
+func (s *testStoreSuite) TestStoreRelocate(c *C) {
+	post := func(id string, body string) int {
+		url := fmt.Sprintf("%s/store/%s/relocate", s.urlPrefix, id)
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Store 4 has not sent any heartbeat yet.
+	c.Assert(post("1", `{"to_store_ids":[4]}`), Equals, http.StatusBadRequest)
+
+	mustStoreHeartBeat(c, s.svr, &pdpb.StoreStats{StoreId: 4, Capacity: 100, Available: 50})
+	c.Assert(post("x", `{"to_store_ids":[4]}`), Equals, http.StatusBadRequest)
+	c.Assert(post("1", `{"to_store_ids":[1]}`), Equals, http.StatusBadRequest)
+	c.Assert(post("1", `{"to_store_ids":[]}`), Equals, http.StatusBadRequest)
+	c.Assert(post("1", `{"to_store_ids":[100]}`), Equals, http.StatusNotFound)
+	c.Assert(post("100", `{"to_store_ids":[4]}`), Equals, http.StatusNotFound)
+	relocate := func() int {
+		resp, err := http.Post(fmt.Sprintf("%s/store/1/relocate", s.urlPrefix), "application/json", strings.NewReader(`{"to_store_ids":[4]}`))
+		c.Assert(err, IsNil)
+		info := &regionsCountInfo{}
+		c.Assert(readJSON(resp.Body, info), IsNil)
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+		return info.Count
+	}
+	c.Assert(relocate(), Equals, 1)
+
+	op := make(map[string]interface{})
+	err := readJSONWithURL(fmt.Sprintf("%s/operators/%d", s.urlPrefix, region.GetId()), &op)
+	c.Assert(err, IsNil)
+	c.Assert(op["ops"], HasLen, 2)
+
+	// The region which has the operator is not scheduled again.
+	c.Assert(relocate(), Equals, 0)
+}
+
 func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	table := []struct {
 		u    string
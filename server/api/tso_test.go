@@ -0,0 +1,46 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testTSOSuite{})
+
+type testTSOSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testTSOSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1/tso", s.svr.GetAddr(), apiPrefix)
+}
+
+func (s *testTSOSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testTSOSuite) TestStatus(c *C) {
+	status := &server.TSOStatus{}
+	c.Assert(readJSONWithURL(s.urlPrefix+"/status", status), IsNil)
+	c.Assert(status.Physical.IsZero(), IsFalse)
+	c.Assert(status.SavedTime.After(status.Physical), IsTrue)
+}
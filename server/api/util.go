@@ -15,18 +15,31 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/unrolled/render"
 )
 
+// errRequestBodyTruncated is returned by readJSON if the body is shorter than
+// its Content-Length, which usually means the client disconnected while
+// uploading it.
+var errRequestBodyTruncated = errors.New("request body truncated, please send the request again")
+
 func readJSON(r io.ReadCloser, data interface{}) error {
 	defer r.Close()
 
 	b, err := ioutil.ReadAll(r)
+	if err == io.ErrUnexpectedEOF {
+		return errors.Trace(errRequestBodyTruncated)
+	}
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -38,6 +51,59 @@ func readJSON(r io.ReadCloser, data interface{}) error {
 	return nil
 }
 
+// readJSONErrorStatus returns the status code of the error of readJSON, a
+// truncated body is a bad request.
+func readJSONErrorStatus(err error) int {
+	if errors.Cause(err) == errRequestBodyTruncated {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// errorStatus returns the status code of an error of the handler, it is 404
+// if a store or region is not found, and 400 if the request is not valid.
+func errorStatus(err error) int {
+	switch {
+	case errors.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.IsNotValid(err):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponse is the body of an error response written by writeError.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// writeError responds the error as {"error": "...", "code": status}, so the
+// clients can parse the errors of the handlers in the same way.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorResponse{Error: err.Error(), Code: status})
+}
+
+// writeJSON responds data as indented JSON with the code, the same as the
+// renderer of the router. If data cannot be encoded, it responds the error
+// instead. The errors are logged since the handlers have nothing else to do
+// with them.
+func writeJSON(w http.ResponseWriter, code int, data interface{}) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Errorf("failed to encode the response: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return errors.Trace(err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if _, err = w.Write(append(b, '\n')); err != nil {
+		log.Errorf("failed to write the response: %v", err)
+		return errors.Trace(err)
+	}
+	return nil
+}
+
 func postJSON(cli *http.Client, url string, data []byte) error {
 	resp, err := cli.Post(url, "application/json", bytes.NewBuffer(data))
 	if err != nil {
@@ -53,3 +119,31 @@ func postJSON(cli *http.Client, url string, data []byte) error {
 	}
 	return nil
 }
+
+// writeJSONWithETag renders data as JSON with an ETag of its content. If the
+// ETag matches the If-None-Match header of the request, it responds 304
+// without the body.
+func writeJSONWithETag(rd *render.Render, w http.ResponseWriter, r *http.Request, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(b))
+	w.Header().Set("ETag", etag)
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	rd.JSON(w, http.StatusOK, data)
+}
+
+func matchETag(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/unrolled/render"
+)
+
+var _ = Suite(&testUtilSuite{})
+
+type testUtilSuite struct{}
+
+// newTestRequest parses a raw request, so the body can be shorter than the
+// Content-Length.
+func newTestRequest(c *C, contentLength int, body string) *http.Request {
+	raw := fmt.Sprintf("POST / HTTP/1.1\r\nHost: pd\r\nContent-Length: %d\r\n\r\n%s", contentLength, body)
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	c.Assert(err, IsNil)
+	return req
+}
+
+func (s *testUtilSuite) TestReadJSON(c *C) {
+	var data map[string]interface{}
+	body := `{"name":"balance-leader-scheduler"}`
+
+	req := newTestRequest(c, len(body), body)
+	c.Assert(readJSON(req.Body, &data), IsNil)
+	c.Assert(data["name"], Equals, "balance-leader-scheduler")
+
+	// The body is shorter than the Content-Length.
+	req = newTestRequest(c, len(body), body[:10])
+	err := readJSON(req.Body, &data)
+	c.Assert(errors.Cause(err), Equals, errRequestBodyTruncated)
+	c.Assert(readJSONErrorStatus(err), Equals, http.StatusBadRequest)
+
+	// A complete body with bad JSON is not a truncated body.
+	req = newTestRequest(c, 10, body[:10])
+	err = readJSON(req.Body, &data)
+	c.Assert(err, NotNil)
+	c.Assert(errors.Cause(err), Not(Equals), errRequestBodyTruncated)
+	c.Assert(readJSONErrorStatus(err), Equals, http.StatusInternalServerError)
+}
+
+func (s *testUtilSuite) TestWriteJSON(c *C) {
+	data := map[string]interface{}{"count": 1, "stores": []int{1}}
+	w := httptest.NewRecorder()
+	c.Assert(writeJSON(w, http.StatusCreated, data), IsNil)
+	c.Assert(w.Code, Equals, http.StatusCreated)
+	c.Assert(w.Header().Get("Content-Type"), Equals, "application/json; charset=UTF-8")
+
+	// The response is the same as the one of the renderer of the router.
+	expect := httptest.NewRecorder()
+	render.New(render.Options{IndentJSON: true}).JSON(expect, http.StatusCreated, data)
+	c.Assert(w.Body.String(), Equals, expect.Body.String())
+
+	// The error is responded if the data cannot be encoded.
+	w = httptest.NewRecorder()
+	c.Assert(writeJSON(w, http.StatusOK, make(chan int)), NotNil)
+	c.Assert(w.Code, Equals, http.StatusInternalServerError)
+	var errResp errorResponse
+	c.Assert(readJSON(ioutil.NopCloser(w.Body), &errResp), IsNil)
+	c.Assert(errResp.Code, Equals, http.StatusInternalServerError)
+	c.Assert(errResp.Error, Matches, ".*unsupported type.*")
+}
@@ -3,11 +3,14 @@ package api
 import (
 	"net/http"
 
+	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
 )
 
 type version struct {
 	Version string `json:"version"`
+	GitHash string `json:"git_hash"`
+	BuildTS string `json:"build_ts"`
 }
 
 type versionHandler struct {
@@ -22,7 +25,9 @@ func newVersionHandler(rd *render.Render) *versionHandler {
 
 func (h *versionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	version := &version{
-		Version: "1.0.0",
+		Version: server.PDReleaseVersion,
+		GitHash: server.PDGitHash,
+		BuildTS: server.GetBuildTime(),
 	}
 	h.rd.JSON(w, http.StatusOK, version)
 }
@@ -14,14 +14,17 @@
 package server
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/montanaflynn/stats"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -62,6 +65,21 @@ func shouldBalance(source, target *storeInfo, kind ResourceKind) bool {
 	return diffCount >= minBalanceDiff(sourceCount)
 }
 
+// explainBalance compares the scores of the source and the target store, such
+// as "store 1 has 30 leaders (score 30.00), 200% higher than store 4 with 10
+// (score 10.00)".
+func explainBalance(source, target *storeInfo, kind ResourceKind) string {
+	sourceScore := source.resourceScore(kind)
+	targetScore := target.resourceScore(kind)
+	higher := "higher"
+	if targetScore > 0 {
+		higher = fmt.Sprintf("%.0f%% higher", (sourceScore/targetScore-1)*100)
+	}
+	return fmt.Sprintf("store %d has %d %ss (score %.2f), %s than store %d with %d (score %.2f)",
+		source.GetId(), source.resourceCount(kind), kind, sourceScore, higher,
+		target.GetId(), target.resourceCount(kind), targetScore)
+}
+
 func adjustBalanceLimit(cluster *clusterInfo, kind ResourceKind) uint64 {
 	stores := cluster.getStores()
 	counts := make([]float64, 0, len(stores))
@@ -75,9 +93,11 @@ func adjustBalanceLimit(cluster *clusterInfo, kind ResourceKind) uint64 {
 }
 
 type balanceLeaderScheduler struct {
+	decisionRecorder
 	opt      *scheduleOption
 	limit    uint64
 	selector Selector
+	counter  *prometheus.CounterVec
 }
 
 func newBalanceLeaderScheduler(opt *scheduleOption) *balanceLeaderScheduler {
@@ -90,6 +110,7 @@ func newBalanceLeaderScheduler(opt *scheduleOption) *balanceLeaderScheduler {
 		opt:      opt,
 		limit:    1,
 		selector: newBalanceSelector(LeaderKind, filters),
+		counter:  schedulerCounter,
 	}
 }
 
@@ -110,29 +131,38 @@ func (l *balanceLeaderScheduler) Prepare(cluster *clusterInfo) error { return ni
 func (l *balanceLeaderScheduler) Cleanup(cluster *clusterInfo) {}
 
 func (l *balanceLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
-	schedulerCounter.WithLabelValues(l.GetName(), "schedule").Inc()
-	region, newLeader := scheduleTransferLeader(cluster, l.GetName(), l.selector)
+	l.counter.WithLabelValues(l.GetName(), "schedule").Inc()
+	region, newLeader := scheduleTransferLeader(cluster, l.counter, l.GetName(), l.selector)
 	if region == nil {
+		l.record("no leader to transfer between the stores")
 		return nil
 	}
 
 	source := cluster.getStore(region.Leader.GetStoreId())
 	target := cluster.getStore(newLeader.GetStoreId())
+	reason := explainBalance(source, target, l.GetResourceKind())
 	if !shouldBalance(source, target, l.GetResourceKind()) {
-		schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
+		l.counter.WithLabelValues(l.GetName(), "skip").Inc()
+		l.record("skipped, the difference is too small: %s", reason)
 		return nil
 	}
 	l.limit = adjustBalanceLimit(cluster, l.GetResourceKind())
-	schedulerCounter.WithLabelValues(l.GetName(), "new_opeartor").Inc()
-	return newTransferLeader(region, newLeader)
+	l.counter.WithLabelValues(l.GetName(), "new_opeartor").Inc()
+	l.record("transfer the leader of region %d: %s", region.GetId(), reason)
+	return explainOperator(newTransferLeader(region, newLeader), "%s", reason)
 }
 
 type balanceRegionScheduler struct {
+	decisionRecorder
 	opt      *scheduleOption
 	rep      *Replication
 	cache    *idCache
 	limit    uint64
 	selector Selector
+	counter  *prometheus.CounterVec
+	// preview makes the new peers have no ids, so no id is allocated for the
+	// operators which are only shown.
+	preview bool
 }
 
 func newBalanceRegionScheduler(opt *scheduleOption) *balanceRegionScheduler {
@@ -151,6 +181,7 @@ func newBalanceRegionScheduler(opt *scheduleOption) *balanceRegionScheduler {
 		cache:    cache,
 		limit:    1,
 		selector: newBalanceSelector(RegionKind, filters),
+		counter:  schedulerCounter,
 	}
 }
 
@@ -171,16 +202,18 @@ func (s *balanceRegionScheduler) Prepare(cluster *clusterInfo) error { return ni
 func (s *balanceRegionScheduler) Cleanup(cluster *clusterInfo) {}
 
 func (s *balanceRegionScheduler) Schedule(cluster *clusterInfo) Operator {
-	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	s.counter.WithLabelValues(s.GetName(), "schedule").Inc()
 	// Select a peer from the store with most regions.
-	region, oldPeer := scheduleRemovePeer(cluster, s.GetName(), s.selector)
+	region, oldPeer := scheduleRemovePeer(cluster, s.counter, s.GetName(), s.selector)
 	if region == nil {
+		s.record("no region to move between the stores")
 		return nil
 	}
 
 	// We don't schedule region with abnormal number of replicas.
 	if len(region.GetPeers()) != s.rep.GetMaxReplicas() {
-		schedulerCounter.WithLabelValues(s.GetName(), "abnormal_replica").Inc()
+		s.counter.WithLabelValues(s.GetName(), "abnormal_replica").Inc()
+		s.record("skipped, region %d has %d replicas, %d expected", region.GetId(), len(region.GetPeers()), s.rep.GetMaxReplicas())
 		return nil
 	}
 
@@ -190,7 +223,7 @@ func (s *balanceRegionScheduler) Schedule(cluster *clusterInfo) Operator {
 		// and skip it for a while.
 		s.cache.set(oldPeer.GetStoreId())
 	}
-	schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
+	s.counter.WithLabelValues(s.GetName(), "new_operator").Inc()
 	return op
 }
 
@@ -201,20 +234,31 @@ func (s *balanceRegionScheduler) transferPeer(cluster *clusterInfo, region *Regi
 	scoreGuard := newDistinctScoreFilter(s.rep, stores, source)
 
 	checker := newReplicaChecker(s.opt, cluster)
-	newPeer := checker.SelectBestPeerToAddReplica(region, scoreGuard)
+	var newPeer *metapb.Peer
+	if s.preview {
+		if storeID, _ := checker.SelectBestStoreToAddReplica(region, scoreGuard); storeID != 0 {
+			newPeer = &metapb.Peer{StoreId: storeID}
+		}
+	} else {
+		newPeer = checker.SelectBestPeerToAddReplica(region, scoreGuard)
+	}
 	if newPeer == nil {
-		schedulerCounter.WithLabelValues(s.GetName(), "no_peer").Inc()
+		s.counter.WithLabelValues(s.GetName(), "no_peer").Inc()
+		s.record("skipped, no store can take region %d from store %d", region.GetId(), source.GetId())
 		return nil
 	}
 
 	target := cluster.getStore(newPeer.GetStoreId())
+	reason := explainBalance(source, target, s.GetResourceKind())
 	if !shouldBalance(source, target, s.GetResourceKind()) {
-		schedulerCounter.WithLabelValues(s.GetName(), "skip").Inc()
+		s.counter.WithLabelValues(s.GetName(), "skip").Inc()
+		s.record("skipped, the difference is too small: %s", reason)
 		return nil
 	}
 	s.limit = adjustBalanceLimit(cluster, s.GetResourceKind())
 
-	return newTransferPeer(region, RegionKind, oldPeer, newPeer)
+	s.record("move region %d: %s", region.GetId(), reason)
+	return explainOperator(newTransferPeer(region, RegionKind, oldPeer, newPeer), "%s", reason)
 }
 
 // replicaChecker ensures region has the best replicas.
@@ -251,7 +295,7 @@ func (r *replicaChecker) Check(region *RegionInfo) Operator {
 		if newPeer == nil {
 			return nil
 		}
-		return newAddPeer(region, newPeer)
+		return explainOperator(newAddPeer(region, newPeer), "region has %d replicas, %d expected", len(region.GetPeers()), r.rep.GetMaxReplicas())
 	}
 
 	if len(region.GetPeers()) > r.rep.GetMaxReplicas() {
@@ -259,7 +303,7 @@ func (r *replicaChecker) Check(region *RegionInfo) Operator {
 		if oldPeer == nil {
 			return nil
 		}
-		return newRemovePeer(region, oldPeer)
+		return explainOperator(newRemovePeer(region, oldPeer), "region has %d replicas, %d expected", len(region.GetPeers()), r.rep.GetMaxReplicas())
 	}
 
 	return r.checkBestReplacement(region)
@@ -366,7 +410,7 @@ func (r *replicaChecker) checkDownPeer(region *RegionInfo) Operator {
 		if stats.GetDownSeconds() < uint64(r.opt.GetMaxStoreDownTime().Seconds()) {
 			continue
 		}
-		return newRemovePeer(region, peer)
+		return explainOperator(newRemovePeer(region, peer), "peer %d on store %d is down for %ds", peer.GetId(), peer.GetStoreId(), stats.GetDownSeconds())
 	}
 	return nil
 }
@@ -384,14 +428,14 @@ func (r *replicaChecker) checkOfflinePeer(region *RegionInfo) Operator {
 
 		// check the number of replicas firstly
 		if len(region.GetPeers()) > r.opt.GetMaxReplicas() {
-			return newRemovePeer(region, peer)
+			return explainOperator(newRemovePeer(region, peer), "store %d is %s", store.GetId(), strings.ToLower(store.GetState().String()))
 		}
 
 		newPeer := r.SelectBestPeerToAddReplica(region)
 		if newPeer == nil {
 			return nil
 		}
-		return newTransferPeer(region, RegionKind, peer, newPeer)
+		return explainOperator(newTransferPeer(region, RegionKind, peer, newPeer), "store %d is %s", store.GetId(), strings.ToLower(store.GetState().String()))
 	}
 
 	return nil
@@ -414,7 +458,25 @@ func (r *replicaChecker) checkBestReplacement(region *RegionInfo) Operator {
 	if err != nil {
 		return nil
 	}
-	return newTransferPeer(region, RegionKind, oldPeer, newPeer)
+	return explainOperator(newTransferPeer(region, RegionKind, oldPeer, newPeer),
+		"store %d is more distinct from the other replicas than store %d (score %.2f > %.2f)", storeID, oldPeer.GetStoreId(), newScore, oldScore)
+}
+
+// selectBetterStore returns the store to move the peer of the region on the
+// store to, whose location is more distinct from the other peers. It returns
+// 0 if the peer is already placed well by the location labels.
+func (r *replicaChecker) selectBetterStore(region *RegionInfo, storeID uint64) uint64 {
+	peer := region.GetStorePeer(storeID)
+	store := r.cluster.getStore(storeID)
+	if peer == nil || store == nil {
+		return 0
+	}
+	oldScore := r.rep.GetDistinctScore(r.cluster.getRegionStores(region), store)
+	newStoreID, newScore := r.selectBestReplacement(region, peer)
+	if newStoreID == 0 || newScore <= oldScore {
+		return 0
+	}
+	return newStoreID
 }
 
 // RegionStat records each hot region's statistics
@@ -268,7 +268,10 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceLimit(c *C) {
 	// Leaders:    2    0    0    0
 	// Region1:    L    F    F    F
 	s.tc.updateLeaderCount(1, 2)
-	c.Check(s.schedule(), NotNil)
+	op := s.schedule()
+	c.Check(op, NotNil)
+	c.Check(op.(*regionOperator).Reason, Matches, "store 1 has 2 leaders .* than store [234] with 0 .*")
+	c.Check(s.lb.GetLastDecision(), Matches, "transfer the leader of region 1: store 1 has 2 leaders .*")
 
 	// Stores:     1    2    3    4
 	// Leaders:    7    8    9   10
@@ -280,6 +283,7 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceLimit(c *C) {
 	s.tc.addLeaderRegion(1, 4, 1, 2, 3)
 	// Min balance diff is 4. Now is 10-7=3.
 	c.Check(s.schedule(), IsNil)
+	c.Check(s.lb.GetLastDecision(), Matches, "skipped, the difference is too small: store 4 has 10 leaders .*")
 
 	// Stores:     1    2    3    4
 	// Leaders:    7    8    9   16
@@ -289,6 +293,47 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceLimit(c *C) {
 	c.Check(s.schedule(), NotNil)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestPreferredLeader(c *C) {
+	// Stores:     1    2
+	// Leaders:   10   10
+	// Region1:    L    F
+	s.tc.addLeaderStore(1, 10)
+	s.tc.addLeaderStore(2, 10)
+	s.tc.addLeaderRegion(1, 1, 2)
+	c.Check(s.schedule(), IsNil)
+
+	// The leader score of store 2 is 5 if it is preferred.
+	c.Assert(s.cluster.setStorePreferredLeader(2, true), IsNil)
+	checkTransferLeader(c, s.schedule(), 1, 2)
+
+	// Stores:     1    2
+	// Leaders:    7   13
+	// Region1:    F    L
+	s.tc.updateLeaderCount(1, 7)
+	s.tc.updateLeaderCount(2, 13)
+	s.tc.addLeaderRegion(1, 2, 1)
+	c.Check(s.schedule(), IsNil)
+
+	c.Assert(s.cluster.setStorePreferredLeader(2, false), IsNil)
+	checkTransferLeader(c, s.schedule(), 2, 1)
+	c.Assert(s.cluster.setStorePreferredLeader(3, true), NotNil)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestStoreWeight(c *C) {
+	// Stores:     1    2
+	// Leaders:   10   16
+	// Region1:    F    L
+	s.tc.addLeaderStore(1, 10)
+	s.tc.addLeaderStore(2, 16)
+	s.tc.addLeaderRegion(1, 2, 1)
+	checkTransferLeader(c, s.schedule(), 2, 1)
+
+	// The leader score of store 2 is 8 if its leader weight is 2.
+	c.Assert(s.cluster.setStoreWeight(2, 2, 1), IsNil)
+	c.Check(s.schedule(), IsNil)
+	c.Assert(s.cluster.setStoreWeight(3, 2, 1), NotNil)
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestBalanceFilter(c *C) {
 	// Stores:     1    2    3    4
 	// Leaders:    1    2    3   10
@@ -377,6 +422,43 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance(c *C) {
 	c.Assert(sb.Schedule(cluster), NotNil)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestStoreWeightByCapacity(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	sb := newBalanceRegionScheduler(opt)
+
+	opt.SetMaxReplicas(1)
+
+	// Store 2 has 3 times the capacity of store 1, and the weights are set as
+	// `store auto-weight --by capacity` does, the region weight is left at 1.
+	tc.addRegionStore(1, 30)
+	tc.addRegionStore(2, 30)
+	store := cluster.getStore(2)
+	store.status.Capacity = 3 * 1024
+	store.status.Available = store.status.Capacity
+	cluster.putStore(store)
+	c.Assert(cluster.setStoreWeight(1, 0.5, 1), IsNil)
+	c.Assert(cluster.setStoreWeight(2, 1.5, 1), IsNil)
+	tc.addLeaderRegion(1, 1)
+	tc.addLeaderRegion(2, 2)
+
+	for i := 0; i < 60; i++ {
+		op := sb.Schedule(cluster)
+		if op == nil {
+			break
+		}
+		checkTransferPeer(c, op, 1, 2)
+		tc.updateRegionCount(1, int(cluster.getStore(1).regionCount())-1)
+		tc.updateRegionCount(2, int(cluster.getStore(2).regionCount())+1)
+	}
+	// The regions are balanced in proportion to the capacity, that is about
+	// 15 and 45, not to the capacity squared.
+	c.Assert(cluster.getStore(1).regionCount(), Equals, uint64(18))
+	c.Assert(cluster.getStore(2).regionCount(), Equals, uint64(42))
+}
+
 func (s *testBalanceRegionSchedulerSuite) TestReplicas3(c *C) {
 	cluster := newClusterInfo(newMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
@@ -501,7 +583,9 @@ func (s *testReplicaCheckerSuite) TestBasic(c *C) {
 
 	// Region has 2 peers, we need to add a new peer.
 	region := cluster.getRegion(1)
-	checkAddPeer(c, rc.Check(region), 4)
+	op := rc.Check(region)
+	checkAddPeer(c, op, 4)
+	c.Assert(op.(*regionOperator).Reason, Equals, "region has 2 replicas, 3 expected")
 
 	// Test healthFilter.
 	// If store 4 is down, we add to store 3.
@@ -546,7 +630,9 @@ func (s *testReplicaCheckerSuite) TestBasic(c *C) {
 		DownSeconds: 24 * 60 * 60,
 	}
 	region.DownPeers = append(region.DownPeers, downPeer)
-	checkRemovePeer(c, rc.Check(region), 2)
+	op = rc.Check(region)
+	checkRemovePeer(c, op, 2)
+	c.Assert(op.(*regionOperator).Reason, Matches, "peer [0-9]+ on store 2 is down for 86400s")
 	region.DownPeers = nil
 	c.Assert(rc.Check(region), IsNil)
 
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -27,13 +28,19 @@ import (
 
 var (
 	errStoreNotFound = func(storeID uint64) error {
-		return errors.Errorf("store %v not found", storeID)
+		return errors.NotFoundf("store %v", storeID)
 	}
 	errStoreIsBlocked = func(storeID uint64) error {
 		return errors.Errorf("store %v is blocked", storeID)
 	}
 	errRegionNotFound = func(regionID uint64) error {
-		return errors.Errorf("region %v not found", regionID)
+		return errors.NotFoundf("region %v", regionID)
+	}
+	errRegionHasNoPeer = func(storeID uint64) error {
+		return errors.NewNotValid(nil, fmt.Sprintf("region has no peer in store %v", storeID))
+	}
+	errRegionHasPeer = func(storeID uint64) error {
+		return errors.NewNotValid(nil, fmt.Sprintf("region already has peer in store %v", storeID))
 	}
 	errRegionIsStale = func(region *metapb.Region, origin *metapb.Region) error {
 		return errors.Errorf("region is stale: region %v origin %v", region, origin)
@@ -275,6 +282,18 @@ func (r *regionsInfo) searchRegion(regionKey []byte) *RegionInfo {
 	return r.getRegion(region.GetId())
 }
 
+func (r *regionsInfo) getAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo) {
+	var prevRegion, nextRegion *RegionInfo
+	prev, next := r.tree.getAdjacentRegions(region.Region)
+	if prev != nil {
+		prevRegion = r.getRegion(prev.GetId())
+	}
+	if next != nil {
+		nextRegion = r.getRegion(next.GetId())
+	}
+	return prevRegion, nextRegion
+}
+
 func (r *regionsInfo) getRegions() []*RegionInfo {
 	regions := make([]*RegionInfo, 0, r.regions.Len())
 	for _, region := range r.regions.m {
@@ -283,6 +302,20 @@ func (r *regionsInfo) getRegions() []*RegionInfo {
 	return regions
 }
 
+// getStoreRegions returns the regions which have a peer on the store.
+func (r *regionsInfo) getStoreRegions(storeID uint64) []*RegionInfo {
+	regions := make([]*RegionInfo, 0, r.getStoreRegionCount(storeID))
+	for _, rm := range []*regionMap{r.leaders[storeID], r.followers[storeID]} {
+		if rm == nil {
+			continue
+		}
+		for _, region := range rm.m {
+			regions = append(regions, region.clone())
+		}
+	}
+	return regions
+}
+
 func (r *regionsInfo) getMetaRegions() []*metapb.Region {
 	regions := make([]*metapb.Region, 0, r.regions.Len())
 	for _, region := range r.regions.m {
@@ -345,6 +378,16 @@ type clusterInfo struct {
 
 	activeRegions   int
 	writeStatistics *lruCache
+
+	// accelerated are the regions whose peers are moved first by the
+	// schedulers which move peers, such as balance-region, until the expire
+	// time.
+	accelerated map[uint64]time.Time
+
+	// opt is used to get the region history limit, the history is not kept
+	// if it is nil.
+	opt     *scheduleOption
+	history *regionHistory
 }
 
 func newClusterInfo(id IDAllocator) *clusterInfo {
@@ -353,6 +396,8 @@ func newClusterInfo(id IDAllocator) *clusterInfo {
 		stores:          newStoresInfo(),
 		regions:         newRegionsInfo(),
 		writeStatistics: newLRUCache(writeStatLRUMaxLen),
+		accelerated:     make(map[uint64]time.Time),
+		history:         newRegionHistory(),
 	}
 }
 
@@ -376,6 +421,26 @@ func loadClusterInfo(id IDAllocator, kv *kv) (*clusterInfo, error) {
 	}
 	log.Infof("load %v stores cost %v", c.stores.getStoreCount(), time.Since(start))
 
+	preferred, err := kv.loadPreferredLeaderStores()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, storeID := range preferred {
+		if store, ok := c.stores.stores[storeID]; ok {
+			store.preferredLeader = true
+		}
+	}
+
+	weights, err := kv.loadStoreWeights()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for storeID, weight := range weights {
+		if store, ok := c.stores.stores[storeID]; ok {
+			store.leaderWeight, store.regionWeight = weight.Leader, weight.Region
+		}
+	}
+
 	start = time.Now()
 	if err := kv.loadRegions(c.regions, kvRangeLimit); err != nil {
 		return nil, errors.Trace(err)
@@ -452,6 +517,44 @@ func (c *clusterInfo) putStoreLocked(store *storeInfo) error {
 	return nil
 }
 
+// setStorePreferredLeader saves whether the store is preferred to host leaders.
+func (c *clusterInfo) setStorePreferredLeader(storeID uint64, preferred bool) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.stores.getStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	if c.kv != nil {
+		if err := c.kv.saveStorePreferredLeader(storeID, preferred); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	store.preferredLeader = preferred
+	c.stores.setStore(store)
+	return nil
+}
+
+// setStoreWeight saves the leader and region weight of the store.
+func (c *clusterInfo) setStoreWeight(storeID uint64, leader, region float64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.stores.getStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	if c.kv != nil {
+		if err := c.kv.saveStoreWeight(storeID, &storeWeight{Leader: leader, Region: region}); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	store.leaderWeight, store.regionWeight = leader, region
+	c.stores.setStore(store)
+	return nil
+}
+
 func (c *clusterInfo) blockStore(storeID uint64) error {
 	c.Lock()
 	defer c.Unlock()
@@ -539,6 +642,12 @@ func (c *clusterInfo) searchRegion(regionKey []byte) *RegionInfo {
 	return c.regions.searchRegion(regionKey)
 }
 
+func (c *clusterInfo) getAdjacentRegions(region *RegionInfo) (*RegionInfo, *RegionInfo) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.getAdjacentRegions(region)
+}
+
 func (c *clusterInfo) putRegion(region *RegionInfo) error {
 	c.Lock()
 	defer c.Unlock()
@@ -591,6 +700,12 @@ func (c *clusterInfo) getStoreLeaderCount(storeID uint64) int {
 	return c.regions.getStoreLeaderCount(storeID)
 }
 
+func (c *clusterInfo) getStoreRegions(storeID uint64) []*RegionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.getStoreRegions(storeID)
+}
+
 func (c *clusterInfo) randLeaderRegion(storeID uint64) *RegionInfo {
 	c.RLock()
 	defer c.RUnlock()
@@ -603,6 +718,43 @@ func (c *clusterInfo) randFollowerRegion(storeID uint64) *RegionInfo {
 	return c.regions.randFollowerRegion(storeID)
 }
 
+// accelerateRegions makes the peers of the regions moved before the ones of
+// the other regions for ttl.
+func (c *clusterInfo) accelerateRegions(regionIDs []uint64, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	expire := time.Now().Add(ttl)
+	for _, id := range regionIDs {
+		c.accelerated[id] = expire
+	}
+}
+
+// randAcceleratedRegion returns a random accelerated region which has a peer
+// on the store, the expired and removed regions are cleaned up.
+func (c *clusterInfo) randAcceleratedRegion(storeID uint64) *RegionInfo {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.accelerated) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var candidates []*RegionInfo
+	for id, expire := range c.accelerated {
+		region := c.regions.getRegion(id)
+		if region == nil || expire.Before(now) {
+			delete(c.accelerated, id)
+			continue
+		}
+		if region.GetStorePeer(storeID) != nil && len(region.DownPeers) == 0 && len(region.PendingPeers) == 0 {
+			candidates = append(candidates, region)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
 func (c *clusterInfo) getRegionStores(region *RegionInfo) []*storeInfo {
 	c.RLock()
 	defer c.RUnlock()
@@ -657,6 +809,21 @@ func (c *clusterInfo) handleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	return nil
 }
 
+// setStoreStatus overrides the store status with update.
+func (c *clusterInfo) setStoreStatus(storeID uint64, update func(*StoreStatus)) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.stores.getStore(storeID)
+	if store == nil {
+		return errors.Trace(errStoreNotFound(storeID))
+	}
+	update(store.status)
+
+	c.stores.setStore(store)
+	return nil
+}
+
 func (c *clusterInfo) updateStoreStatus(id uint64) {
 	c.stores.setLeaderCount(id, c.regions.getStoreLeaderCount(id))
 	c.stores.setRegionCount(id, c.regions.getStoreRegionCount(id))
@@ -712,6 +879,10 @@ func (c *clusterInfo) handleRegionHeartbeat(region *RegionInfo) error {
 		}
 	}
 
+	if limit := c.getRegionHistoryLimit(); limit > 0 {
+		c.history.add(region.GetId(), int(limit), diffRegionEvents(origin, region, time.Now()))
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
@@ -738,6 +909,19 @@ func (c *clusterInfo) handleRegionHeartbeat(region *RegionInfo) error {
 	return nil
 }
 
+func (c *clusterInfo) getRegionHistoryLimit() uint64 {
+	if c.opt == nil {
+		return 0
+	}
+	return c.opt.GetRegionHistoryLimit()
+}
+
+// getRegionHistory returns the recent events of a region from the oldest to
+// the latest.
+func (c *clusterInfo) getRegionHistory(regionID uint64) []*RegionEvent {
+	return c.history.get(regionID)
+}
+
 func (c *clusterInfo) updateWriteStatus(region *RegionInfo) {
 	var WrittenBytesPerSec uint64
 	v, isExist := c.writeStatistics.peek(region.GetId())
@@ -213,6 +213,7 @@ func checkRegions(c *C, cache *regionsInfo, regions []*RegionInfo) {
 	c.Assert(cache.getRegionCount(), Equals, len(regions))
 	for id, count := range regionCount {
 		c.Assert(cache.getStoreRegionCount(id), Equals, count)
+		c.Assert(cache.getStoreRegions(id), HasLen, count)
 	}
 	for id, count := range leaderCount {
 		c.Assert(cache.getStoreLeaderCount(id), Equals, count)
@@ -276,6 +277,10 @@ func (s *testClusterInfoSuite) TestLoadClusterInfo(c *C) {
 	c.Assert(kv.saveMeta(meta), IsNil)
 	stores := mustSaveStores(c, kv, n)
 	regions := mustSaveRegions(c, kv, n)
+	// The preference of a store which does not exist is ignored.
+	c.Assert(kv.saveStorePreferredLeader(2, true), IsNil)
+	c.Assert(kv.saveStorePreferredLeader(uint64(n)+1, true), IsNil)
+	c.Assert(kv.saveStoreWeight(3, &storeWeight{Leader: 2, Region: 0.5}), IsNil)
 
 	cluster, err = loadClusterInfo(server.idAlloc, kv)
 	c.Assert(err, IsNil)
@@ -291,6 +296,16 @@ func (s *testClusterInfoSuite) TestLoadClusterInfo(c *C) {
 	for _, region := range cluster.getMetaRegions() {
 		c.Assert(region, DeepEquals, regions[region.GetId()])
 	}
+	for _, store := range cluster.getStores() {
+		c.Assert(store.preferredLeader, Equals, store.GetId() == 2)
+		if store.GetId() == 3 {
+			c.Assert(store.getLeaderWeight(), Equals, 2.0)
+			c.Assert(store.getRegionWeight(), Equals, 0.5)
+		} else {
+			c.Assert(store.getLeaderWeight(), Equals, defaultStoreWeight)
+			c.Assert(store.getRegionWeight(), Equals, defaultStoreWeight)
+		}
+	}
 }
 
 func (s *testClusterInfoSuite) testStoreHeartbeat(c *C, cache *clusterInfo) {
@@ -0,0 +1,124 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// kvMissLimit triggers cleaning up the expired missing keys.
+const kvMissLimit = 1024
+
+// cachedKV wraps getValue with a negative cache, so reading a key which does
+// not exist yet won't hit etcd again until ttl passes. Existing values are
+// never cached, and a key must be invalidated after it is written. It is only
+// used for the keys which are written by this server, since the other members
+// cannot invalidate it.
+type cachedKV struct {
+	sync.Mutex
+	s   *Server
+	ttl time.Duration
+	// missing maps keys to the time they expire from the cache.
+	missing map[string]time.Time
+	// gen is increased by each invalidation, a missing key is not cached if
+	// it is invalidated after it is read.
+	gen uint64
+}
+
+// newCachedKV creates a cachedKV, the cache is disabled if ttl is 0.
+func newCachedKV(s *Server, ttl time.Duration) *cachedKV {
+	return &cachedKV{
+		s:       s,
+		ttl:     ttl,
+		missing: make(map[string]time.Time),
+	}
+}
+
+func (kv *cachedKV) getValue(key string) ([]byte, error) {
+	if kv.isMissing(key) {
+		return nil, nil
+	}
+
+	gen := kv.generation()
+	value, err := getValue(kv.s.ctx, kv.s.client, key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value == nil {
+		kv.setMissing(key, gen)
+	}
+	return value, nil
+}
+
+// invalidate removes the keys from the cache, it must be called after the
+// keys are written.
+func (kv *cachedKV) invalidate(keys ...string) {
+	kv.Lock()
+	defer kv.Unlock()
+
+	for _, key := range keys {
+		delete(kv.missing, key)
+	}
+	kv.gen++
+}
+
+func (kv *cachedKV) generation() uint64 {
+	kv.Lock()
+	defer kv.Unlock()
+
+	return kv.gen
+}
+
+func (kv *cachedKV) isMissing(key string) bool {
+	kv.Lock()
+	defer kv.Unlock()
+
+	expire, ok := kv.missing[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expire) {
+		delete(kv.missing, key)
+		return false
+	}
+	return true
+}
+
+// setMissing caches the key which is not found by the read started at the
+// generation gen, unless a write is invalidated since then.
+func (kv *cachedKV) setMissing(key string, gen uint64) {
+	if kv.ttl == 0 {
+		return
+	}
+
+	kv.Lock()
+	defer kv.Unlock()
+
+	if kv.gen != gen {
+		return
+	}
+
+	now := time.Now()
+	if len(kv.missing) >= kvMissLimit {
+		for k, expire := range kv.missing {
+			if now.After(expire) {
+				delete(kv.missing, k)
+			}
+		}
+	}
+	kv.missing[key] = now.Add(kv.ttl)
+}
@@ -33,7 +33,8 @@ const (
 
 // Error instances
 var (
-	ErrNotBootstrapped = errors.New("TiKV cluster is not bootstrapped, please start TiKV first")
+	ErrNotBootstrapped       = errors.New("TiKV cluster is not bootstrapped, please start TiKV first")
+	ErrRegionHistoryDisabled = errors.New("region history is disabled, set region-history-limit to enable it")
 )
 
 // RaftCluster is used for cluster config management.
@@ -105,6 +106,7 @@ func (c *RaftCluster) start() error {
 	if cluster == nil {
 		return nil
 	}
+	cluster.opt = c.s.scheduleOpt
 	c.cachedCluster = cluster
 	c.coordinator = newCoordinator(c.cachedCluster, c.s.scheduleOpt)
 	c.quit = make(chan struct{})
@@ -296,14 +298,14 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	}
 
 	// Set cluster meta
-	clusterValue, err := clusterMeta.Marshal()
+	clusterRootPath := s.getClusterRootPath()
+	clusterOp, err := opPutProtoMsg(clusterRootPath, &clusterMeta, true)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	clusterRootPath := s.getClusterRootPath()
 
 	var ops []clientv3.Op
-	ops = append(ops, clientv3.OpPut(clusterRootPath, string(clusterValue)))
+	ops = append(ops, clusterOp)
 
 	// Set bootstrap time
 	bootstrapKey := makeBootstrapTimeKey(clusterRootPath)
@@ -315,20 +317,19 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	// Set store meta
 	storeMeta := req.GetStore()
 	storePath := makeStoreKey(clusterRootPath, storeMeta.GetId())
-	storeValue, err := storeMeta.Marshal()
+	storeOp, err := opPutProtoMsg(storePath, storeMeta, true)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	ops = append(ops, clientv3.OpPut(storePath, string(storeValue)))
+	ops = append(ops, storeOp)
 
-	regionValue, err := req.GetRegion().Marshal()
+	// Set region meta with region id.
+	regionPath := makeRegionKey(clusterRootPath, req.GetRegion().GetId())
+	regionOp, err := opPutProtoMsg(regionPath, req.GetRegion(), true)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-
-	// Set region meta with region id.
-	regionPath := makeRegionKey(clusterRootPath, req.GetRegion().GetId())
-	ops = append(ops, clientv3.OpPut(regionPath, string(regionValue)))
+	ops = append(ops, regionOp)
 
 	// TODO: we must figure out a better way to handle bootstrap failed, maybe intervene manually.
 	bootstrapCmp := clientv3.Compare(clientv3.CreateRevision(clusterRootPath), "=", 0)
@@ -340,6 +341,7 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 		log.Warnf("cluster %d already bootstrapped", clusterID)
 		return nil, errors.Errorf("cluster %d already bootstrapped", clusterID)
 	}
+	s.kv.cache.invalidate(clusterRootPath, bootstrapKey, storePath, regionPath)
 
 	log.Infof("bootstrap cluster %d ok", clusterID)
 
@@ -378,11 +380,44 @@ func (c *RaftCluster) GetRegionInfoByID(regionID uint64) *RegionInfo {
 	return c.cachedCluster.getRegion(regionID)
 }
 
+// GetRegionHistory returns the recent events of a region from the oldest to
+// the latest.
+func (c *RaftCluster) GetRegionHistory(regionID uint64) ([]*RegionEvent, error) {
+	if c.cachedCluster.getRegionHistoryLimit() == 0 {
+		return nil, errors.Trace(ErrRegionHistoryDisabled)
+	}
+	return c.cachedCluster.getRegionHistory(regionID), nil
+}
+
+// GetAdjacentRegions returns the previous and next regions of the region by
+// key order, they are nil if there is no such region.
+func (c *RaftCluster) GetAdjacentRegions(regionID uint64) (*RegionInfo, *RegionInfo, error) {
+	region := c.cachedCluster.getRegion(regionID)
+	if region == nil {
+		return nil, nil, errors.Trace(errRegionNotFound(regionID))
+	}
+	prev, next := c.cachedCluster.getAdjacentRegions(region)
+	return prev, next, nil
+}
+
 // GetRegions gets regions from cluster.
 func (c *RaftCluster) GetRegions() []*metapb.Region {
 	return c.cachedCluster.getMetaRegions()
 }
 
+// GetStoreRegions gets the regions which have a peer on the store.
+func (c *RaftCluster) GetStoreRegions(storeID uint64) ([]*RegionInfo, error) {
+	if c.cachedCluster.getStore(storeID) == nil {
+		return nil, errors.Trace(errStoreNotFound(storeID))
+	}
+	return c.cachedCluster.getStoreRegions(storeID), nil
+}
+
+// GetRegionCount returns the number of regions in cluster.
+func (c *RaftCluster) GetRegionCount() int {
+	return c.cachedCluster.getRegionCount()
+}
+
 // GetStores gets stores from cluster.
 func (c *RaftCluster) GetStores() []*metapb.Store {
 	return c.cachedCluster.getMetaStores()
@@ -402,17 +437,117 @@ func (c *RaftCluster) GetStore(storeID uint64) (*metapb.Store, *StoreStatus, err
 	return store.Store, store.status, nil
 }
 
-// UpdateStoreLabels updates a store's location labels.
-func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLabel) error {
+// GetStoreNote returns the note of a store, it is empty if not set.
+func (c *RaftCluster) GetStoreNote(storeID uint64) (string, error) {
+	note, err := c.s.kv.loadStoreNote(storeID)
+	return note, errors.Trace(err)
+}
+
+// GetStoreNotes returns the notes of all stores which are set, keyed by the
+// store IDs, so listing the stores reads them from etcd once.
+func (c *RaftCluster) GetStoreNotes() (map[uint64]string, error) {
+	notes, err := c.s.kv.loadStoreNotes()
+	return notes, errors.Trace(err)
+}
+
+// SetStoreNote records a free-text note of a store for operators, such as why
+// it is offline. An empty note clears it.
+func (c *RaftCluster) SetStoreNote(storeID uint64, note string) error {
+	if c.cachedCluster.getStore(storeID) == nil {
+		return errors.Errorf("invalid store ID %d, not found", storeID)
+	}
+	return errors.Trace(c.s.kv.saveStoreNote(storeID, note))
+}
+
+// IsStorePreferredLeader returns whether the store is preferred to host leaders.
+func (c *RaftCluster) IsStorePreferredLeader(storeID uint64) bool {
+	store := c.cachedCluster.getStore(storeID)
+	return store != nil && store.preferredLeader
+}
+
+// SetStorePreferredLeader marks the store as preferred to host leaders or not,
+// the balance-leader scheduler moves more leaders to the preferred stores.
+func (c *RaftCluster) SetStorePreferredLeader(storeID uint64, preferred bool) error {
+	return errors.Trace(c.cachedCluster.setStorePreferredLeader(storeID, preferred))
+}
+
+// GetStoreWeight returns the leader and region weight of the store.
+func (c *RaftCluster) GetStoreWeight(storeID uint64) (float64, float64) {
+	store := c.cachedCluster.getStore(storeID)
+	if store == nil {
+		return defaultStoreWeight, defaultStoreWeight
+	}
+	return store.getLeaderWeight(), store.getRegionWeight()
+}
+
+// SetStoreWeight sets the leader and region weight of the store, the
+// balance schedulers move the leaders and regions in proportion to them.
+func (c *RaftCluster) SetStoreWeight(storeID uint64, leader, region float64) error {
+	if leader <= 0 || region <= 0 {
+		return errors.Errorf("invalid weight %v, %v, should be greater than 0", leader, region)
+	}
+	return errors.Trace(c.cachedCluster.setStoreWeight(storeID, leader, region))
+}
+
+// UpdateStoreLabels updates a store's location labels. The given labels are
+// merged into the existing ones and a label with an empty value is removed.
+// If replace is true, all existing labels are cleared first.
+func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLabel, replace bool) error {
+	c.Lock()
+	defer c.Unlock()
+
 	store := c.cachedCluster.getStore(storeID)
 	if store == nil {
 		return errors.Errorf("invalid store ID %d, not found", storeID)
 	}
-	storeMeta := store.Store
-	storeMeta.Labels = labels
-	// putStore will perform label merge.
-	err := c.putStore(storeMeta)
-	return errors.Trace(err)
+	if replace {
+		store.Labels = nil
+	}
+	store.mergeLabels(labels)
+
+	newLabels := store.Labels[:0]
+	for _, label := range store.Labels {
+		if label.GetValue() != "" {
+			newLabels = append(newLabels, label)
+		}
+	}
+	store.Labels = newLabels
+
+	c.checkLocationLabels(store)
+	return errors.Trace(c.cachedCluster.putStore(store))
+}
+
+// UpdateStoreAddress changes the address of a store, the address can not be
+// used by another store unless that store is tombstone.
+func (c *RaftCluster) UpdateStoreAddress(storeID uint64, address string) (*metapb.Store, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	cluster := c.cachedCluster
+	store := cluster.getStore(storeID)
+	if store == nil {
+		return nil, errors.Errorf("invalid store ID %d, not found", storeID)
+	}
+	for _, s := range cluster.getStores() {
+		if s.isTombstone() {
+			continue
+		}
+		if s.GetId() != storeID && s.GetAddress() == address {
+			return nil, errors.Errorf("duplicated store address: %s, already registered by store %d", address, s.GetId())
+		}
+	}
+
+	store.Address = address
+	if err := cluster.putStore(store); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return store.Store, nil
+}
+
+// SetStoreStatus overrides the reported status of a store, it is only used by
+// tests to simulate stores. The override lasts until the next heartbeat.
+func (c *RaftCluster) SetStoreStatus(storeID uint64, update func(*StoreStatus)) error {
+	return errors.Trace(c.cachedCluster.setStoreStatus(storeID, update))
 }
 
 func (c *RaftCluster) putStore(store *metapb.Store) error {
@@ -446,14 +581,16 @@ func (c *RaftCluster) putStore(store *metapb.Store) error {
 		s.mergeLabels(store.Labels)
 	}
 
-	// Check location labels.
+	c.checkLocationLabels(s)
+	return cluster.putStore(s)
+}
+
+func (c *RaftCluster) checkLocationLabels(s *storeInfo) {
 	for _, k := range c.s.cfg.Replication.LocationLabels {
 		if v := s.getLabelValue(k); len(v) == 0 {
 			log.Warnf("missing location label %q in store %v", k, s)
 		}
 	}
-
-	return cluster.putStore(s)
 }
 
 // RemoveStore marks a store as offline in cluster.
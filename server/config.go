@@ -16,7 +16,10 @@ package server
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -78,6 +81,22 @@ type Config struct {
 	// the default retention is 1 hour
 	AutoCompactionRetention int `toml:"auto-compaction-retention" json:"auto-compaction-retention"`
 
+	// EnableTestAPI enables the APIs which are only used by integration tests,
+	// such as overriding a store's reported status. Never enable it in production.
+	EnableTestAPI bool `toml:"enable-test-api" json:"enable-test-api"`
+
+	// DisableSlowLog disables timing the etcd gets and txns, so the slow ones
+	// are neither logged nor recorded for 'debug slow-requests'. It saves the
+	// cost of the warnings on an extremely busy PD, the txn duration metrics
+	// are not observed either.
+	DisableSlowLog bool `toml:"disable-slow-log" json:"disable-slow-log"`
+
+	// KVMissCacheTTL is how long the cluster meta and the bootstrap time are
+	// remembered if they are not found in etcd, so reading them again before
+	// the cluster is bootstrapped does not hit etcd until then. 0 disables
+	// the cache.
+	KVMissCacheTTL typeutil.Duration `toml:"kv-miss-cache-ttl" json:"kv-miss-cache-ttl"`
+
 	tickMs     uint64
 	electionMs uint64
 
@@ -113,8 +132,11 @@ func NewConfig() *Config {
 
 	fs.StringVar(&cfg.Log.Level, "L", "", "log level: debug, info, warn, error, fatal (default 'info')")
 	fs.StringVar(&cfg.Log.File.Filename, "log-file", "", "log file path")
+	fs.StringVar(&cfg.Log.ErrorFile.Filename, "log-error-file", "", "log file path for warning and more severe logs")
 	fs.BoolVar(&cfg.Log.File.LogRotate, "log-rotate", true, "rotate log")
 
+	fs.BoolVar(&cfg.EnableTestAPI, "enable-test-api", false, "enable test-only APIs, never use it in production")
+
 	return cfg
 }
 
@@ -216,6 +238,71 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// Validate checks the adjusted config, it returns an error describing all the
+// invalid items so they can be fixed at once.
+func (c *Config) Validate() error {
+	var msgs []string
+	if !logutil.IsValidLogLevel(c.Log.Level) {
+		msgs = append(msgs, fmt.Sprintf("log level %q is unknown, it should be one of debug, info, warn, error, fatal", c.Log.Level))
+	}
+	for _, file := range []string{c.Log.File.Filename, c.Log.ErrorFile.Filename} {
+		if file == "" {
+			continue
+		}
+		if err := checkLogDir(file); err != nil {
+			msgs = append(msgs, fmt.Sprintf("log file %q can not be written: %s", file, err))
+		}
+	}
+	if err := c.Log.Syslog.Validate(); err != nil {
+		msgs = append(msgs, err.Error())
+	}
+	if c.LeaderLease <= 0 {
+		msgs = append(msgs, fmt.Sprintf("lease %d should be positive", c.LeaderLease))
+	}
+	durations := []struct {
+		name string
+		v    typeutil.Duration
+	}{
+		{"tso-save-interval", c.TsoSaveInterval},
+		{"schedule.max-store-down-time", c.Schedule.MaxStoreDownTime},
+	}
+	for _, d := range durations {
+		if d.v.Duration <= 0 {
+			msgs = append(msgs, fmt.Sprintf("%s %s should be positive", d.name, d.v.Duration))
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.Errorf("invalid config: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// checkLogDir checks that the directory of the log file can be written. The
+// missing directories are created by the logger, so the nearest existing one
+// is checked.
+func checkLogDir(file string) error {
+	dir := filepath.Dir(file)
+	for {
+		st, err := os.Stat(dir)
+		if err == nil {
+			if !st.IsDir() {
+				return errors.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) || filepath.Dir(dir) == dir {
+			return errors.Trace(err)
+		}
+		dir = filepath.Dir(dir)
+	}
+	f, err := ioutil.TempFile(dir, ".pd-log-check")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	f.Close()
+	return errors.Trace(os.Remove(f.Name()))
+}
+
 func (c *Config) adjust() error {
 	if err := c.validate(); err != nil {
 		return errors.Trace(err)
@@ -297,6 +384,10 @@ type ScheduleConfig struct {
 	RegionScheduleLimit uint64 `toml:"region-schedule-limit,omitempty" json:"region-schedule-limit"`
 	// ReplicaScheduleLimit is the max coexist replica schedules.
 	ReplicaScheduleLimit uint64 `toml:"replica-schedule-limit,omitempty" json:"replica-schedule-limit"`
+	// RegionHistoryLimit is the max number of the recent events kept for
+	// each region, such as splits and leader changes. The history is not
+	// kept if it is 0.
+	RegionHistoryLimit uint64 `toml:"region-history-limit,omitempty" json:"region-history-limit"`
 }
 
 const (
@@ -308,6 +399,14 @@ const (
 	defaultReplicaScheduleLimit = 16
 )
 
+// NewDefaultScheduleConfig returns the compiled-in default schedule
+// configuration.
+func NewDefaultScheduleConfig() *ScheduleConfig {
+	c := &ScheduleConfig{}
+	c.adjust()
+	return c
+}
+
 func (c *ScheduleConfig) adjust() {
 	adjustUint64(&c.MaxSnapshotCount, defaultMaxSnapshotCount)
 	adjustDuration(&c.MaxStoreDownTime, defaultMaxStoreDownTime)
@@ -328,6 +427,14 @@ type ReplicationConfig struct {
 	LocationLabels typeutil.StringSlice `toml:"location-labels,omitempty" json:"location-labels"`
 }
 
+// NewDefaultReplicationConfig returns the compiled-in default replication
+// configuration.
+func NewDefaultReplicationConfig() *ReplicationConfig {
+	c := &ReplicationConfig{}
+	c.adjust()
+	return c
+}
+
 func (c *ReplicationConfig) clone() *ReplicationConfig {
 	locationLabels := make(typeutil.StringSlice, 0, len(c.LocationLabels))
 	copy(locationLabels, c.LocationLabels)
@@ -394,6 +501,10 @@ func (o *scheduleOption) GetReplicaScheduleLimit() uint64 {
 	return o.load().ReplicaScheduleLimit
 }
 
+func (o *scheduleOption) GetRegionHistoryLimit() uint64 {
+	return o.load().RegionHistoryLimit
+}
+
 func (o *scheduleOption) persist(kv *kv) error {
 	return kv.saveScheduleOption(o)
 }
@@ -408,6 +519,14 @@ func ParseUrls(s string) ([]url.URL, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		switch u.Scheme {
+		case "http", "https", "unix", "unixs":
+		default:
+			return nil, errors.Errorf("invalid url %q: scheme must be one of http, https, unix, unixs", item)
+		}
+		if u.Host == "" {
+			return nil, errors.Errorf("invalid url %q: missing host", item)
+		}
 
 		urls = append(urls, *u)
 	}
@@ -0,0 +1,72 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestParseUrls(c *C) {
+	urls, err := ParseUrls("http://127.0.0.1:2379,https://pd1:2379")
+	c.Assert(err, IsNil)
+	c.Assert(urls, HasLen, 2)
+	c.Assert(urls[0].Scheme, Equals, "http")
+	c.Assert(urls[0].Host, Equals, "127.0.0.1:2379")
+	c.Assert(urls[1].Scheme, Equals, "https")
+	c.Assert(urls[1].Host, Equals, "pd1:2379")
+
+	for _, s := range []string{
+		"127.0.0.1:2379",
+		"http://",
+		"ftp://127.0.0.1:2379",
+		"http://127.0.0.1:2379,",
+		"http://127.0.0.1:2379,http//127.0.0.1:2380",
+	} {
+		_, err = ParseUrls(s)
+		c.Assert(err, NotNil, Commentf("%s", s))
+	}
+}
+
+func (s *testConfigSuite) TestValidate(c *C) {
+	dir, err := ioutil.TempDir("", "pd_config_test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	c.Assert(cfg.Parse(nil), IsNil)
+	cfg.Log.Level = "WARNING"
+	// The missing directories are created by the logger.
+	cfg.Log.File.Filename = filepath.Join(dir, "log", "pd.log")
+	c.Assert(cfg.Validate(), IsNil)
+
+	file := filepath.Join(dir, "file")
+	c.Assert(ioutil.WriteFile(file, nil, 0644), IsNil)
+	cfg.Log.Level = "verbose"
+	cfg.Log.ErrorFile.Filename = filepath.Join(file, "pd.log")
+	cfg.Log.Syslog.Address = "udp://127.0.0.1:514"
+	cfg.Log.Syslog.Facility = "mail2"
+	cfg.TsoSaveInterval.Duration = -time.Second
+	err = cfg.Validate()
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, `invalid config: log level "verbose" is unknown.*; log file ".*" can not be written: .* is not a directory; unknown syslog facility "mail2"; tso-save-interval -1s should be positive`)
+}
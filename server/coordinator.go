@@ -15,6 +15,7 @@ package server
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,10 +31,12 @@ const (
 	historiesCacheSize        = 1000
 	eventsCacheSize           = 1000
 	maxScheduleRetries        = 10
+	balancePreviewAttempts    = 4
 	maxScheduleInterval       = time.Minute
 	minScheduleInterval       = time.Millisecond * 10
 	minSlowScheduleInterval   = time.Second * 3
 	scheduleIntervalFactor    = 1.3
+	accelerateScheduleTTL     = 10 * time.Minute
 
 	writeStatLRUMaxLen            = 1000
 	storeHotRegionsDefaultLen     = 100
@@ -151,6 +154,18 @@ func (c *coordinator) getHotWriteRegions() *StoreHotRegionInfos {
 	return s.Scheduler.(*balanceHotRegionScheduler).GetStatus()
 }
 
+// getSlowStore returns the store evicted by the evict-slow-store-scheduler, 0
+// means no slow store.
+func (c *coordinator) getSlowStore() (uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	s, ok := c.schedulers[evictSlowStoreName]
+	if !ok {
+		return 0, false
+	}
+	return s.Scheduler.(*evictSlowStoreScheduler).getSlowStore(), true
+}
+
 func (c *coordinator) getSchedulers() []string {
 	c.RLock()
 	defer c.RUnlock()
@@ -162,6 +177,29 @@ func (c *coordinator) getSchedulers() []string {
 	return names
 }
 
+// SchedulerDecision explains the last decision of a scheduler, the decision is
+// empty if the scheduler does not explain it or has not run yet.
+type SchedulerDecision struct {
+	Name     string `json:"name"`
+	Decision string `json:"decision"`
+}
+
+func (c *coordinator) getSchedulerDecisions() []*SchedulerDecision {
+	c.RLock()
+	defer c.RUnlock()
+
+	decisions := make([]*SchedulerDecision, 0, len(c.schedulers))
+	for name, s := range c.schedulers {
+		d := &SchedulerDecision{Name: name}
+		if e, ok := s.Scheduler.(decisionExplainer); ok {
+			d.Decision = e.GetLastDecision()
+		}
+		decisions = append(decisions, d)
+	}
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].Name < decisions[j].Name })
+	return decisions
+}
+
 func (c *coordinator) collectSchedulerMetrics() {
 	c.RLock()
 	defer c.RUnlock()
@@ -339,6 +377,39 @@ func (c *coordinator) getOperators() []Operator {
 	return operators
 }
 
+// previewBalance runs new balance schedulers, which have no state shared with
+// the running ones, and returns up to limit operators of each without adding
+// them. The regions which already have operators are skipped. The schedulers
+// count their events by a counter which is not registered, and the new peers
+// of their operators have no ids, so the preview has no side effect.
+func (c *coordinator) previewBalance(limit int) []Operator {
+	counter := newSchedulerCounter()
+	leader := newBalanceLeaderScheduler(c.opt)
+	leader.counter = counter
+	region := newBalanceRegionScheduler(c.opt)
+	region.counter, region.preview = counter, true
+	schedulers := []Scheduler{leader, region}
+	var ops []Operator
+	for _, s := range schedulers {
+		// The schedulers select regions randomly, so they are run more times
+		// to find different regions.
+		regions := make(map[uint64]struct{})
+		for i := 0; i < limit*balancePreviewAttempts && len(regions) < limit; i++ {
+			op := s.Schedule(c.cluster)
+			if op == nil {
+				continue
+			}
+			regionID := op.GetRegionID()
+			if _, ok := regions[regionID]; ok || c.getOperator(regionID) != nil {
+				continue
+			}
+			regions[regionID] = struct{}{}
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
 func (c *coordinator) getHistories() []Operator {
 	c.RLock()
 	defer c.RUnlock()
@@ -19,6 +19,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	dto "github.com/prometheus/client_model/go"
 )
 
 type testOperator struct {
@@ -348,6 +349,72 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	c.Assert(resp, IsNil)
 }
 
+func (s *testCoordinatorSuite) TestBalancePreview(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	// Stores:     1    2    3    4
+	// Leaders:    1    2    3   10
+	// Region1:    F    F    F    L
+	// Region2:    F    F    F    L
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 2)
+	tc.addLeaderStore(3, 3)
+	tc.addLeaderStore(4, 10)
+	tc.addLeaderRegion(1, 4, 1, 2, 3)
+	tc.addLeaderRegion(2, 4, 1, 2, 3)
+
+	ops := co.previewBalance(10)
+	c.Assert(ops, HasLen, 2)
+	regions := make(map[uint64]struct{})
+	for _, op := range ops {
+		checkTransferLeader(c, op, 4, 1)
+		regions[op.GetRegionID()] = struct{}{}
+	}
+	c.Assert(regions, HasLen, 2)
+	// The operators are not added.
+	c.Assert(co.getOperators(), HasLen, 0)
+
+	c.Assert(co.previewBalance(1), HasLen, 1)
+
+	// Region 2 has an operator already.
+	c.Assert(co.addOperator(newTestOperator(2, LeaderKind)), IsTrue)
+	ops = co.previewBalance(10)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].GetRegionID(), Equals, uint64(1))
+}
+
+func (s *testCoordinatorSuite) TestBalanceRegionPreview(c *C) {
+	alloc := newMockIDAllocator()
+	cluster := newClusterInfo(alloc)
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	opt.SetMaxReplicas(1)
+	co := newCoordinator(cluster, opt)
+
+	tc.addRegionStore(1, 10)
+	tc.addRegionStore(2, 0)
+	tc.addLeaderRegion(1, 1)
+	base := alloc.base
+	scheduled := getSchedulerCount(c, "balance-region-scheduler", "schedule")
+
+	ops := co.previewBalance(10)
+	c.Assert(ops, HasLen, 1)
+	checkTransferPeer(c, ops[0], 1, 2)
+	// The new peer has no id, and the preview is not counted.
+	c.Assert(ops[0].(*regionOperator).Ops[0].(*changePeerOperator).ChangePeer.GetPeer().GetId(), Equals, uint64(0))
+	c.Assert(alloc.base, Equals, base)
+	c.Assert(getSchedulerCount(c, "balance-region-scheduler", "schedule"), Equals, scheduled)
+}
+
+func getSchedulerCount(c *C, name, event string) float64 {
+	m := &dto.Metric{}
+	c.Assert(schedulerCounter.WithLabelValues(name, event).Write(m), IsNil)
+	return m.GetCounter().GetValue()
+}
+
 func waitOperator(c *C, co *coordinator, regionID uint64) {
 	for i := 0; i < 20; i++ {
 		if co.getOperator(regionID) != nil {
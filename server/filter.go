@@ -203,3 +203,22 @@ func (f *distinctScoreFilter) FilterSource(store *storeInfo) bool {
 func (f *distinctScoreFilter) FilterTarget(store *storeInfo) bool {
 	return f.rep.GetDistinctScore(f.stores, store) < f.safeScore
 }
+
+// regionCountFilter ensures that the target store has at most max regions in
+// counts, which are the region counts updated with the scheduled peers.
+type regionCountFilter struct {
+	counts map[uint64]int
+	max    int
+}
+
+func newRegionCountFilter(counts map[uint64]int, max int) *regionCountFilter {
+	return &regionCountFilter{counts: counts, max: max}
+}
+
+func (f *regionCountFilter) FilterSource(store *storeInfo) bool {
+	return false
+}
+
+func (f *regionCountFilter) FilterTarget(store *storeInfo) bool {
+	return f.counts[store.GetId()] > f.max
+}
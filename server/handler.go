@@ -13,7 +13,14 @@
 
 package server
 
-import "github.com/juju/errors"
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
 
 var (
 	errNotBootstrapped  = errors.New("TiKV cluster not bootstrapped, please start TiKV first")
@@ -47,6 +54,16 @@ func (h *Handler) GetSchedulers() ([]string, error) {
 	return c.getSchedulers(), nil
 }
 
+// GetSchedulerDecisions returns the last decision of each scheduler, sorted by
+// the names of the schedulers.
+func (h *Handler) GetSchedulerDecisions() ([]*SchedulerDecision, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getSchedulerDecisions(), nil
+}
+
 // GetHotWriteRegions gets all hot regions status
 func (h *Handler) GetHotWriteRegions() *StoreHotRegionInfos {
 	c, err := h.getCoordinator()
@@ -94,6 +111,25 @@ func (h *Handler) AddEvictLeaderScheduler(storeID uint64) error {
 	return h.AddScheduler(newEvictLeaderScheduler(h.opt, storeID))
 }
 
+// AddEvictSlowStoreScheduler adds an evict-slow-store-scheduler.
+func (h *Handler) AddEvictSlowStoreScheduler() error {
+	return h.AddScheduler(newEvictSlowStoreScheduler(h.opt))
+}
+
+// GetSlowStore returns the store evicted by the evict-slow-store-scheduler,
+// 0 means no store is evicted.
+func (h *Handler) GetSlowStore() (uint64, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	storeID, ok := c.getSlowStore()
+	if !ok {
+		return 0, errors.Errorf("%s is not added", evictSlowStoreName)
+	}
+	return storeID, nil
+}
+
 // AddShuffleLeaderScheduler adds a shuffle-leader-scheduler.
 func (h *Handler) AddShuffleLeaderScheduler() error {
 	return h.AddScheduler(newShuffleLeaderScheduler(h.opt))
@@ -144,6 +180,16 @@ func (h *Handler) GetOperators() ([]Operator, error) {
 	return c.getOperators(), nil
 }
 
+// GetBalancePreview returns the operators the balance schedulers would
+// generate now, up to limit operators of each, without adding them.
+func (h *Handler) GetBalancePreview(limit int) ([]Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.previewBalance(limit), nil
+}
+
 // GetAdminOperators returns the running admin operators.
 func (h *Handler) GetAdminOperators() ([]Operator, error) {
 	return h.GetOperatorsOfKind(AdminKind)
@@ -205,7 +251,7 @@ func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) err
 	}
 	newLeader := region.GetStorePeer(storeID)
 	if newLeader == nil {
-		return errors.Errorf("region has no peer in store %v", storeID)
+		return errRegionHasNoPeer(storeID)
 	}
 
 	op := newTransferLeaderOperator(regionID, region.Leader, newLeader)
@@ -268,7 +314,7 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 
 	oldPeer := region.GetStorePeer(fromStoreID)
 	if oldPeer == nil {
-		return errors.Errorf("region has no peer in store %v", fromStoreID)
+		return errRegionHasNoPeer(fromStoreID)
 	}
 
 	if c.cluster.getStore(toStoreID) == nil {
@@ -284,3 +330,573 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 	c.addOperator(newAdminOperator(region, addPeer, removePeer))
 	return nil
 }
+
+// AddAddPeerOperator adds an operator to add peer.
+func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	region := c.cluster.getRegion(regionID)
+	if region == nil {
+		return errRegionNotFound(regionID)
+	}
+
+	if region.GetStorePeer(toStoreID) != nil {
+		return errRegionHasPeer(toStoreID)
+	}
+
+	if c.cluster.getStore(toStoreID) == nil {
+		return errStoreNotFound(toStoreID)
+	}
+	newPeer, err := c.cluster.allocPeer(toStoreID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	c.addOperator(newAdminOperator(region, newAddPeerOperator(regionID, newPeer)))
+	return nil
+}
+
+// AddRemovePeerOperator adds an operator to remove peer.
+func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	region := c.cluster.getRegion(regionID)
+	if region == nil {
+		return errRegionNotFound(regionID)
+	}
+
+	oldPeer := region.GetStorePeer(fromStoreID)
+	if oldPeer == nil {
+		return errRegionHasNoPeer(fromStoreID)
+	}
+
+	c.addOperator(newAdminOperator(region, newRemovePeerOperator(regionID, oldPeer)))
+	return nil
+}
+
+func checkMergeRegions(source, target *RegionInfo) error {
+	if source.GetId() == target.GetId() {
+		return errors.Errorf("cannot merge region %v into itself", source.GetId())
+	}
+
+	isAdjacent := func(left, right *RegionInfo) bool {
+		return len(left.GetEndKey()) > 0 && bytes.Equal(left.GetEndKey(), right.GetStartKey())
+	}
+	if !isAdjacent(source, target) && !isAdjacent(target, source) {
+		return errors.Errorf("region %v and region %v are not adjacent", source.GetId(), target.GetId())
+	}
+
+	sourceStores, targetStores := source.GetStoreIds(), target.GetStoreIds()
+	if len(sourceStores) != len(targetStores) {
+		return errors.Errorf("region %v and region %v have peers on different stores", source.GetId(), target.GetId())
+	}
+	for storeID := range sourceStores {
+		if _, ok := targetStores[storeID]; !ok {
+			return errors.Errorf("region %v and region %v have peers on different stores", source.GetId(), target.GetId())
+		}
+	}
+
+	for _, region := range []*RegionInfo{source, target} {
+		if len(region.DownPeers) > 0 || len(region.PendingPeers) > 0 {
+			return errors.Errorf("region %v has down or pending peers", region.GetId())
+		}
+	}
+	return nil
+}
+
+// MergeCandidate is a pair of adjacent regions which can be merged.
+type MergeCandidate struct {
+	SourceID uint64 `json:"source_region_id"`
+	TargetID uint64 `json:"target_region_id"`
+}
+
+// GetMergeCandidates returns the pairs of adjacent regions which pass the
+// merge checks, in the order of keys. Region sizes are not reported by the
+// heartbeats, so they are not checked.
+// TODO: report the empty regions and merge them once the heartbeats carry the
+// approximate size and keys of regions and merge operators are supported.
+func (h *Handler) GetMergeCandidates() ([]*MergeCandidate, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	regions := c.cluster.getRegions()
+	sort.Slice(regions, func(i, j int) bool {
+		return bytes.Compare(regions[i].GetStartKey(), regions[j].GetStartKey()) < 0
+	})
+	var candidates []*MergeCandidate
+	for i := 0; i+1 < len(regions); i++ {
+		source, target := regions[i], regions[i+1]
+		if checkMergeRegions(source, target) == nil {
+			candidates = append(candidates, &MergeCandidate{
+				SourceID: source.GetId(),
+				TargetID: target.GetId(),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// AddRelocateStoreOperators adds operators to move all peers of the store to
+// the target stores, it returns the number of scheduled regions. The regions
+// which have admin operators are skipped.
+func (h *Handler) AddRelocateStoreOperators(storeID uint64, targetIDs []uint64) (int, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	if c.cluster.getStore(storeID) == nil {
+		return 0, errStoreNotFound(storeID)
+	}
+	for _, id := range targetIDs {
+		target := c.cluster.getStore(id)
+		if target == nil {
+			return 0, errStoreNotFound(id)
+		}
+		if id == storeID {
+			return 0, errors.NewNotValid(nil, fmt.Sprintf("store %v can not be relocated to itself", id))
+		}
+		if !target.isUp() || target.downTime() > c.opt.GetMaxStoreDownTime() {
+			return 0, errors.NewNotValid(nil, fmt.Sprintf("target store %v is not up", id))
+		}
+		if target.status.GetAvailable() == 0 {
+			return 0, errors.NewNotValid(nil, fmt.Sprintf("target store %v has no available space", id))
+		}
+	}
+
+	count := 0
+	for _, region := range c.cluster.getRegions() {
+		oldPeer := region.GetStorePeer(storeID)
+		if oldPeer == nil {
+			continue
+		}
+		if op := c.getOperator(region.GetId()); op != nil && op.GetResourceKind() == AdminKind {
+			continue
+		}
+		// Pick targets in turn to spread regions among them.
+		for i := range targetIDs {
+			toStoreID := targetIDs[(count+i)%len(targetIDs)]
+			if region.GetStorePeer(toStoreID) != nil {
+				continue
+			}
+			newPeer, err := c.cluster.allocPeer(toStoreID)
+			if err != nil {
+				return count, errors.Trace(err)
+			}
+			addPeer := newAddPeerOperator(region.GetId(), newPeer)
+			removePeer := newRemovePeerOperator(region.GetId(), oldPeer)
+			if c.addOperator(newAdminOperator(region, addPeer, removePeer)) {
+				count++
+			}
+			break
+		}
+	}
+	return count, nil
+}
+
+// AddTransferLeadersOperators adds operators to transfer the leaders of all
+// regions led by the store to healthy followers, it returns the number of
+// added operators. The regions without a healthy follower are skipped.
+func (h *Handler) AddTransferLeadersOperators(storeID uint64) (int, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if c.cluster.getStore(storeID) == nil {
+		return 0, errStoreNotFound(storeID)
+	}
+	return transferStoreLeaders(c, storeID), nil
+}
+
+// transferStoreLeaders transfers the leaders of the store to followers picked
+// like the evict-leader scheduler does, so the leaders are spread among them.
+// The operators of the schedulers are replaced, but the regions which have
+// admin operators are skipped, so running it again does not restart them.
+func transferStoreLeaders(c *coordinator, storeID uint64) int {
+	selector := newRandomSelector([]Filter{newStateFilter(c.opt), newHealthFilter(c.opt)})
+	regions := c.cluster.getStoreRegions(storeID)
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetId() < regions[j].GetId() })
+	count := 0
+	for _, region := range regions {
+		if region.Leader.GetStoreId() != storeID {
+			continue
+		}
+		if op := c.getOperator(region.GetId()); op != nil && op.GetResourceKind() == AdminKind {
+			continue
+		}
+		target := selector.SelectTarget(c.cluster.getFollowerStores(region))
+		if target == nil {
+			continue
+		}
+		op := newTransferLeaderOperator(region.GetId(), region.Leader, region.GetStorePeer(target.GetId()))
+		if c.addOperator(newAdminOperator(region, op)) {
+			count++
+		}
+	}
+	return count
+}
+
+// FixStorePlacement adds operators to move the peers of the store which break
+// the location labels to better placed stores, it returns the operators. The
+// operators are only returned if dryRun is set, and their new peers have no
+// ids.
+func (h *Handler) FixStorePlacement(storeID uint64, dryRun bool) ([]Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if c.cluster.getStore(storeID) == nil {
+		return nil, errStoreNotFound(storeID)
+	}
+	return fixStorePlacement(c, storeID, dryRun)
+}
+
+// fixStorePlacement moves each peer of the store to the store selected by the
+// replica checker, if it is more distinct from the other peers of the region.
+// The regions which have operators are skipped.
+func fixStorePlacement(c *coordinator, storeID uint64, dryRun bool) ([]Operator, error) {
+	regions := c.cluster.getStoreRegions(storeID)
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetId() < regions[j].GetId() })
+	var ops []Operator
+	for _, region := range regions {
+		if c.getOperator(region.GetId()) != nil {
+			continue
+		}
+		toStoreID := c.checker.selectBetterStore(region, storeID)
+		if toStoreID == 0 {
+			continue
+		}
+		// No id is allocated for the operators which are never added.
+		newPeer := &metapb.Peer{StoreId: toStoreID}
+		if !dryRun {
+			var err error
+			if newPeer, err = c.cluster.allocPeer(toStoreID); err != nil {
+				return ops, errors.Trace(err)
+			}
+		}
+		addPeer := newAddPeerOperator(region.GetId(), newPeer)
+		removePeer := newRemovePeerOperator(region.GetId(), region.GetStorePeer(storeID))
+		op := newAdminOperator(region, addPeer, removePeer)
+		if !dryRun && !c.addOperator(op) {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// The types of the region anomalies found by CheckRegions.
+const (
+	RegionAnomalyMissPeer = "miss-peer"
+	RegionAnomalyDownPeer = "down-peer"
+)
+
+// RegionAnomaly is a problem of the replicas of a region.
+type RegionAnomaly struct {
+	RegionID uint64 `json:"region_id"`
+	Type     string `json:"type"`
+	Detail   string `json:"detail"`
+}
+
+// CheckRegions returns the anomalies of the regions which can be repaired by
+// FixRegion, sorted by the region IDs.
+func (h *Handler) CheckRegions() ([]*RegionAnomaly, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return checkRegionAnomalies(c), nil
+}
+
+func checkRegionAnomalies(c *coordinator) []*RegionAnomaly {
+	regions := c.cluster.getRegions()
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetId() < regions[j].GetId() })
+	var anomalies []*RegionAnomaly
+	for _, region := range regions {
+		for _, stats := range region.DownPeers {
+			anomalies = append(anomalies, &RegionAnomaly{
+				RegionID: region.GetId(),
+				Type:     RegionAnomalyDownPeer,
+				Detail:   fmt.Sprintf("peer %d on store %d is down for %ds", stats.GetPeer().GetId(), stats.GetPeer().GetStoreId(), stats.GetDownSeconds()),
+			})
+		}
+		if n, expected := len(region.GetPeers()), c.opt.GetMaxReplicas(); n < expected {
+			anomalies = append(anomalies, &RegionAnomaly{
+				RegionID: region.GetId(),
+				Type:     RegionAnomalyMissPeer,
+				Detail:   fmt.Sprintf("region has %d replicas, %d expected", n, expected),
+			})
+		}
+	}
+	return anomalies
+}
+
+// FixRegion adds an operator to repair the region and returns it, it returns
+// nil if the region has no anomaly. A down peer is replaced first, so the
+// region is fixed by one operator at a time.
+func (h *Handler) FixRegion(regionID uint64) (Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	region := c.cluster.getRegion(regionID)
+	if region == nil {
+		return nil, errRegionNotFound(regionID)
+	}
+	return fixRegion(c, region)
+}
+
+// fixRegion replaces a down peer by a new peer on the store selected by the
+// replica checker, or only removes it if the region has more replicas than
+// expected. A region without down peers gets a new peer if it has fewer
+// replicas than expected.
+func fixRegion(c *coordinator, region *RegionInfo) (Operator, error) {
+	if c.getOperator(region.GetId()) != nil {
+		return nil, errors.Errorf("region %d already has an operator", region.GetId())
+	}
+
+	var ops []Operator
+	if len(region.DownPeers) > 0 {
+		downPeer := region.DownPeers[0].GetPeer()
+		removePeer := newRemovePeerOperator(region.GetId(), downPeer)
+		if len(region.GetPeers()) > c.opt.GetMaxReplicas() {
+			ops = append(ops, removePeer)
+		} else {
+			storeID, _ := c.checker.selectBestReplacement(region, downPeer)
+			if storeID == 0 {
+				return nil, errors.Errorf("no store to replace the down peer %d of region %d", downPeer.GetId(), region.GetId())
+			}
+			newPeer, err := c.cluster.allocPeer(storeID)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			ops = append(ops, newAddPeerOperator(region.GetId(), newPeer), removePeer)
+		}
+	} else if len(region.GetPeers()) < c.opt.GetMaxReplicas() {
+		storeID, _ := c.checker.SelectBestStoreToAddReplica(region, c.checker.filters...)
+		if storeID == 0 {
+			return nil, errors.Errorf("no store to add a replica of region %d", region.GetId())
+		}
+		newPeer, err := c.cluster.allocPeer(storeID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ops = append(ops, newAddPeerOperator(region.GetId(), newPeer))
+	} else {
+		return nil, nil
+	}
+
+	op := newAdminOperator(region, ops...)
+	if !c.addOperator(op) {
+		return nil, errors.Errorf("failed to add the operator of region %d", region.GetId())
+	}
+	return op, nil
+}
+
+// AddScatterRegionOperator adds an operator to move the followers of the
+// region to the stores with fewer regions, it returns the number of added
+// operators.
+func (h *Handler) AddScatterRegionOperator(regionID uint64) (int, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	region := c.cluster.getRegion(regionID)
+	if region == nil {
+		return 0, errRegionNotFound(regionID)
+	}
+	return scatterRegions(c, []*RegionInfo{region})
+}
+
+// AddScatterRangeOperators is like AddScatterRegionOperator, but scatters the
+// regions overlapping [startKey, endKey), an empty endKey means no end.
+func (h *Handler) AddScatterRangeOperators(startKey, endKey []byte) (int, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	return scatterRegions(c, getRangeRegions(c.cluster, startKey, endKey))
+}
+
+// AccelerateRangeSchedule makes the peers of the regions overlapping
+// [startKey, endKey) moved before the other regions by the schedulers for
+// accelerateScheduleTTL, it returns the number of the regions.
+func (h *Handler) AccelerateRangeSchedule(startKey, endKey []byte) (int, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+
+	regions := getRangeRegions(c.cluster, startKey, endKey)
+	ids := make([]uint64, 0, len(regions))
+	for _, region := range regions {
+		ids = append(ids, region.GetId())
+	}
+	c.cluster.accelerateRegions(ids, accelerateScheduleTTL)
+	return len(ids), nil
+}
+
+// StoreLeaderCount is the number of leaders on a store.
+type StoreLeaderCount struct {
+	StoreID     uint64 `json:"store_id"`
+	Address     string `json:"address"`
+	LeaderCount int    `json:"leader_count"`
+}
+
+// GetRangeLeaderDistribution returns the number of the regions overlapping
+// [startKey, endKey), and the number of their leaders on each store.
+func (h *Handler) GetRangeLeaderDistribution(startKey, endKey []byte) (int, []*StoreLeaderCount, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+
+	regions := getRangeRegions(c.cluster, startKey, endKey)
+	return len(regions), countStoreLeaders(c.cluster, regions), nil
+}
+
+// countStoreLeaders returns the leader counts of the regions on each store
+// sorted by the store ids. The stores which are not tombstone are included
+// even if they have no leader, so a store missing the leaders is shown. A
+// region without a leader is not counted.
+func countStoreLeaders(cluster *clusterInfo, regions []*RegionInfo) []*StoreLeaderCount {
+	counts := make(map[uint64]*StoreLeaderCount)
+	for _, store := range cluster.getStores() {
+		if !store.isTombstone() {
+			counts[store.GetId()] = &StoreLeaderCount{StoreID: store.GetId(), Address: store.GetAddress()}
+		}
+	}
+	for _, region := range regions {
+		storeID := region.Leader.GetStoreId()
+		if storeID == 0 {
+			continue
+		}
+		count, ok := counts[storeID]
+		if !ok {
+			count = &StoreLeaderCount{StoreID: storeID}
+			if store := cluster.getStore(storeID); store != nil {
+				count.Address = store.GetAddress()
+			}
+			counts[storeID] = count
+		}
+		count.LeaderCount++
+	}
+
+	res := make([]*StoreLeaderCount, 0, len(counts))
+	for _, count := range counts {
+		res = append(res, count)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StoreID < res[j].StoreID })
+	return res
+}
+
+// getRangeRegions returns the regions overlapping [startKey, endKey) sorted
+// by the start keys, an empty endKey means no end.
+func getRangeRegions(cluster *clusterInfo, startKey, endKey []byte) []*RegionInfo {
+	var regions []*RegionInfo
+	for _, region := range cluster.getRegions() {
+		if len(endKey) > 0 && bytes.Compare(region.GetStartKey(), endKey) >= 0 {
+			continue
+		}
+		if len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), startKey) <= 0 {
+			continue
+		}
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return bytes.Compare(regions[i].GetStartKey(), regions[j].GetStartKey()) < 0
+	})
+	return regions
+}
+
+// scatterRegions moves each follower of the regions to the store selected by
+// the replica checker with its filters, so the location labels are kept and
+// the blocked, busy or almost full stores are skipped. The stores with fewer
+// regions are tried first, and a target should have at least 2 regions fewer
+// than the follower's store. The region counts are updated with the moved
+// peers, so the regions of a range are spread instead of moved to the same
+// store. Leaders are not moved to avoid transferring leaders.
+func scatterRegions(c *coordinator, regions []*RegionInfo) (int, error) {
+	counts := make(map[uint64]int)
+	for _, store := range c.cluster.getStores() {
+		counts[store.GetId()] = c.cluster.getStoreRegionCount(store.GetId())
+	}
+
+	count := 0
+	for _, region := range regions {
+		// The region with the new peers, so they are placed apart.
+		placed := region.clone()
+		var ops []Operator
+		for _, peer := range region.GetPeers() {
+			if peer.GetId() == region.Leader.GetId() {
+				continue
+			}
+			source := c.cluster.getStore(peer.GetStoreId())
+			if source == nil {
+				continue
+			}
+			storeID := selectScatterStore(c, placed, source, counts)
+			if storeID == 0 {
+				continue
+			}
+			newPeer, err := c.cluster.allocPeer(storeID)
+			if err != nil {
+				return count, errors.Trace(err)
+			}
+			ops = append(ops, newAddPeerOperator(region.GetId(), newPeer), newRemovePeerOperator(region.GetId(), peer))
+			placed.RemoveStorePeer(source.GetId())
+			placed.Peers = append(placed.Peers, newPeer)
+			counts[source.GetId()]--
+			counts[storeID]++
+		}
+		if len(ops) > 0 && c.addOperator(newAdminOperator(region, ops...)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// selectScatterStore returns the store to move the peer of the region on the
+// source store to, or 0 if there is none. The stores are tried by their
+// region counts in counts from the fewest, and the replica checker selects
+// the best one of them which is not less distinct than the source store.
+func selectScatterStore(c *coordinator, region *RegionInfo, source *storeInfo, counts map[uint64]int) uint64 {
+	newRegion := region.clone()
+	newRegion.RemoveStorePeer(source.GetId())
+	filters := []Filter{
+		newExcludedFilter(nil, region.GetStoreIds()),
+		newBlockFilter(),
+		newDistinctScoreFilter(c.checker.rep, c.cluster.getRegionStores(region), source),
+	}
+	filters = append(filters, c.checker.filters...)
+
+	levels := make([]int, 0, len(counts))
+	for _, n := range counts {
+		levels = append(levels, n)
+	}
+	sort.Ints(levels)
+	for i, level := range levels {
+		if level+1 >= counts[source.GetId()] {
+			break
+		}
+		if i > 0 && level == levels[i-1] {
+			continue
+		}
+		countFilter := newRegionCountFilter(counts, level)
+		if storeID, _ := c.checker.SelectBestStoreToAddReplica(newRegion, append(filters, countFilter)...); storeID != 0 {
+			return storeID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,297 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testHandlerSuite{})
+
+type testHandlerSuite struct{}
+
+func checkChangePeer(c *C, op Operator, changeType pdpb.ConfChangeType, storeID uint64) {
+	cp := op.(*changePeerOperator).ChangePeer
+	c.Assert(cp.GetChangeType(), Equals, changeType)
+	c.Assert(cp.GetPeer().GetStoreId(), Equals, storeID)
+}
+
+func (s *testHandlerSuite) TestScatterRegions(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	for id := uint64(1); id <= 6; id++ {
+		tc.addRegionStore(id, 0)
+	}
+	// All regions are on stores 1, 2 and 3 with leaders on store 1.
+	var regions []*RegionInfo
+	for id := uint64(1); id <= 4; id++ {
+		tc.addLeaderRegion(id, 1, 2, 3)
+		regions = append(regions, cluster.getRegion(id))
+	}
+	// Store 6 is down.
+	tc.setStoreDown(6)
+
+	count, err := scatterRegions(co, regions)
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 2)
+
+	// The followers are moved to the stores with fewest regions in turn, the
+	// order of stores 4 and 5 is not fixed since they have the same score.
+	for _, regionID := range []uint64{1, 2} {
+		ops := co.getOperator(regionID).(*adminOperator).Ops
+		c.Assert(ops, HasLen, 4)
+		checkChangePeer(c, ops[1], pdpb.ConfChangeType_RemoveNode, 2)
+		checkChangePeer(c, ops[3], pdpb.ConfChangeType_RemoveNode, 3)
+		targets := make(map[uint64]struct{})
+		for _, op := range []Operator{ops[0], ops[2]} {
+			cp := op.(*changePeerOperator).ChangePeer
+			c.Assert(cp.GetChangeType(), Equals, pdpb.ConfChangeType_AddNode)
+			targets[cp.GetPeer().GetStoreId()] = struct{}{}
+		}
+		c.Assert(targets, DeepEquals, map[uint64]struct{}{4: {}, 5: {}})
+	}
+	// Stores 2, 3, 4 and 5 have 2 regions each, so no more moves are needed.
+	c.Assert(co.getOperator(3), IsNil)
+	c.Assert(co.getOperator(4), IsNil)
+}
+
+func (s *testHandlerSuite) TestScatterRegionsFilter(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3, "zone")
+	co := newCoordinator(cluster, opt)
+
+	tc.addLabelsStore(1, 3, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(2, 3, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(3, 3, map[string]string{"zone": "z3"})
+	// Store 4 is in the zone of the leader, store 5 is blocked and store 6
+	// is almost full.
+	tc.addLabelsStore(4, 0, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(5, 0, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(6, 0, map[string]string{"zone": "z3"})
+	c.Assert(cluster.blockStore(5), IsNil)
+	tc.updateStorageRatio(6, 0.9, 0.1)
+	for id := uint64(1); id <= 3; id++ {
+		tc.addLeaderRegion(id, 1, 2, 3)
+	}
+	regions := []*RegionInfo{cluster.getRegion(1)}
+
+	count, err := scatterRegions(co, regions)
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 0)
+
+	cluster.unblockStore(5)
+	count, err = scatterRegions(co, regions)
+	c.Assert(err, IsNil)
+	c.Assert(count, Equals, 1)
+	ops := co.getOperator(1).(*adminOperator).Ops
+	c.Assert(ops, HasLen, 2)
+	checkChangePeer(c, ops[0], pdpb.ConfChangeType_AddNode, 5)
+	checkChangePeer(c, ops[1], pdpb.ConfChangeType_RemoveNode, 2)
+}
+
+func (s *testHandlerSuite) TestFixStorePlacement(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	opt.rep = newTestReplication(3, "zone")
+	co := newCoordinator(cluster, opt)
+
+	tc.addLabelsStore(1, 2, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(2, 2, map[string]string{"zone": "z1"})
+	tc.addLabelsStore(3, 2, map[string]string{"zone": "z2"})
+	tc.addLabelsStore(4, 1, map[string]string{"zone": "z3"})
+	// The peers on store 1 and 2 of region 1 are in the same zone.
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 2, 3, 4)
+
+	ops, err := fixStorePlacement(co, 2, true)
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].GetRegionID(), Equals, uint64(1))
+	steps := ops[0].(*adminOperator).Ops
+	checkChangePeer(c, steps[0], pdpb.ConfChangeType_AddNode, 4)
+	checkChangePeer(c, steps[1], pdpb.ConfChangeType_RemoveNode, 2)
+	c.Assert(co.getOperator(1), IsNil)
+	// No peer id is allocated for a dry run.
+	c.Assert(steps[0].(*changePeerOperator).ChangePeer.GetPeer().GetId(), Equals, uint64(0))
+
+	ops, err = fixStorePlacement(co, 2, false)
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(co.getOperator(1), Equals, ops[0])
+	c.Assert(ops[0].(*adminOperator).Ops[0].(*changePeerOperator).ChangePeer.GetPeer().GetId(), Not(Equals), uint64(0))
+
+	// The region which has an operator is skipped.
+	ops, err = fixStorePlacement(co, 2, false)
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 0)
+}
+
+func (s *testHandlerSuite) TestFixRegion(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	for id := uint64(1); id <= 4; id++ {
+		tc.addRegionStore(id, 1)
+	}
+	// The peer on store 3 of region 1 is down, region 2 misses a peer.
+	tc.addLeaderRegion(1, 1, 2, 3)
+	region := cluster.getRegion(1)
+	region.DownPeers = []*pdpb.PeerStats{{Peer: region.GetStorePeer(3), DownSeconds: 3600}}
+	tc.putRegion(region)
+	tc.setStoreDown(3)
+	tc.addLeaderRegion(2, 1, 2)
+	tc.addLeaderRegion(3, 1, 2, 4)
+
+	anomalies := checkRegionAnomalies(co)
+	c.Assert(anomalies, DeepEquals, []*RegionAnomaly{
+		{RegionID: 1, Type: RegionAnomalyDownPeer, Detail: fmt.Sprintf("peer %d on store 3 is down for 3600s", region.GetStorePeer(3).GetId())},
+		{RegionID: 2, Type: RegionAnomalyMissPeer, Detail: "region has 2 replicas, 3 expected"},
+	})
+
+	op, err := fixRegion(co, cluster.getRegion(1))
+	c.Assert(err, IsNil)
+	steps := op.(*adminOperator).Ops
+	c.Assert(steps, HasLen, 2)
+	checkChangePeer(c, steps[0], pdpb.ConfChangeType_AddNode, 4)
+	checkChangePeer(c, steps[1], pdpb.ConfChangeType_RemoveNode, 3)
+	c.Assert(co.getOperator(1), Equals, op)
+	// The region which has an operator is not fixed again.
+	_, err = fixRegion(co, cluster.getRegion(1))
+	c.Assert(err, NotNil)
+
+	// The down store is not selected.
+	op, err = fixRegion(co, cluster.getRegion(2))
+	c.Assert(err, IsNil)
+	steps = op.(*adminOperator).Ops
+	c.Assert(steps, HasLen, 1)
+	checkChangePeer(c, steps[0], pdpb.ConfChangeType_AddNode, 4)
+
+	op, err = fixRegion(co, cluster.getRegion(3))
+	c.Assert(err, IsNil)
+	c.Assert(op, IsNil)
+}
+
+func (s *testHandlerSuite) TestTransferStoreLeaders(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	for id := uint64(1); id <= 4; id++ {
+		tc.addRegionStore(id, 0)
+	}
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 2, 1, 3)
+	tc.addLeaderRegion(3, 1, 4)
+	tc.addLeaderRegion(4, 1, 2)
+	// Store 4 is down, so region 3 has no healthy follower.
+	tc.setStoreDown(4)
+
+	c.Assert(transferStoreLeaders(co, 1), Equals, 2)
+	for _, id := range []uint64{1, 4} {
+		ops := co.getOperator(id).(*adminOperator).Ops
+		c.Assert(ops, HasLen, 1)
+		op := ops[0].(*transferLeaderOperator)
+		c.Assert(op.OldLeader.GetStoreId(), Equals, uint64(1))
+		c.Assert(op.NewLeader.GetStoreId(), Not(Equals), uint64(1))
+	}
+	c.Assert(co.getOperator(4).(*adminOperator).Ops[0].(*transferLeaderOperator).NewLeader.GetStoreId(), Equals, uint64(2))
+	c.Assert(co.getOperator(2), IsNil)
+	c.Assert(co.getOperator(3), IsNil)
+
+	// The regions which have admin operators are skipped.
+	c.Assert(transferStoreLeaders(co, 1), Equals, 0)
+}
+
+func (s *testHandlerSuite) TestCountStoreLeaders(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	for id := uint64(1); id <= 4; id++ {
+		tc.addRegionStore(id, 0)
+	}
+	store := cluster.getStore(4)
+	store.State = metapb.StoreState_Tombstone
+	cluster.putStore(store)
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 3)
+	tc.addLeaderRegion(3, 2, 1)
+	tc.putRegion(newRegionInfo(&metapb.Region{Id: 4}, nil))
+
+	counts := countStoreLeaders(cluster, cluster.getRegions())
+	// Store 3 has no leader, and the tombstone store 4 is not shown.
+	c.Assert(counts, HasLen, 3)
+	for i, n := range []int{2, 1, 0} {
+		c.Assert(counts[i].StoreID, Equals, uint64(i+1))
+		c.Assert(counts[i].LeaderCount, Equals, n)
+	}
+
+	counts = countStoreLeaders(cluster, []*RegionInfo{cluster.getRegion(3)})
+	c.Assert(counts[0].LeaderCount, Equals, 0)
+	c.Assert(counts[1].LeaderCount, Equals, 1)
+}
+
+func (s *testHandlerSuite) TestAccelerateRegions(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	for id := uint64(1); id <= 3; id++ {
+		tc.addRegionStore(id, 3)
+	}
+	tc.addRegionStore(4, 0)
+	keys := []string{"", "b", "d", ""}
+	for id := uint64(1); id <= 3; id++ {
+		region := &metapb.Region{Id: id, StartKey: []byte(keys[id-1]), EndKey: []byte(keys[id])}
+		for storeID := uint64(1); storeID <= 3; storeID++ {
+			peer, _ := tc.allocPeer(storeID)
+			region.Peers = append(region.Peers, peer)
+		}
+		tc.putRegion(newRegionInfo(region, region.Peers[0]))
+	}
+
+	regions := getRangeRegions(cluster, []byte("c"), []byte("e"))
+	c.Assert(regions, HasLen, 2)
+	c.Assert(regions[0].GetId(), Equals, uint64(2))
+	c.Assert(regions[1].GetId(), Equals, uint64(3))
+	c.Assert(getRangeRegions(cluster, []byte("a"), nil), HasLen, 3)
+	c.Assert(getRangeRegions(cluster, []byte("b"), []byte("d")), HasLen, 1)
+
+	c.Assert(cluster.randAcceleratedRegion(1), IsNil)
+	cluster.accelerateRegions([]uint64{2, 3}, time.Minute)
+	cluster.accelerateRegions([]uint64{1}, -time.Second)
+	for i := 0; i < 10; i++ {
+		region := cluster.randAcceleratedRegion(1)
+		c.Assert(region.GetId(), Not(Equals), uint64(1))
+	}
+	// No accelerated region has a peer on store 4.
+	c.Assert(cluster.randAcceleratedRegion(4), IsNil)
+	// The expired region is cleaned up.
+	c.Assert(cluster.accelerated, HasLen, 2)
+
+	// The accelerated regions are moved first.
+	region, peer := scheduleRemovePeer(cluster, schedulerCounter, "test", newBalanceSelector(RegionKind, nil))
+	c.Assert(region.GetId(), Not(Equals), uint64(1))
+	c.Assert(peer, NotNil)
+}
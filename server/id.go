@@ -20,6 +20,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/etcd/clientv3"
 	"github.com/juju/errors"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -59,42 +60,71 @@ func (alloc *idAllocator) Alloc() (uint64, error) {
 }
 
 func (alloc *idAllocator) generate() (uint64, error) {
-	key := alloc.s.getAllocIDPath()
-	value, err := getValue(alloc.s.client, key)
+	start, err := allocIDs(alloc.s.ctx, alloc.s.client, alloc.s.getAllocIDPath(), allocStep, alloc.s.leaderCmp())
+	if errors.Cause(err) == errAllocIDsCmpFailed {
+		return 0, errors.New("generate id failed, we may not leader")
+	}
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
 
-	var (
-		cmp clientv3.Cmp
-		end uint64
-	)
-
-	if value == nil {
-		// create the key
-		cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
-	} else {
-		// update the key
-		end, err = bytesToUint64(value)
+	end := start + allocStep
+	log.Infof("idAllocator allocates a new id: %d", end)
+	return end, nil
+}
+
+// errAllocIDsCmpFailed is returned by allocIDs if the other comparisons fail.
+var errAllocIDsCmpFailed = errors.New("alloc ids failed, the comparisons do not hold")
+
+// allocIDs reserves count IDs with the key, which stores the last reserved ID,
+// and returns the start of the range, the reserved IDs are start+1 to
+// start+count. The key is written only if it is not modified since it is
+// read, so concurrent allocators retry instead of reserving the same IDs. The
+// other comparisons cs, such as the leader comparison, must hold too.
+func allocIDs(ctx context.Context, c *clientv3.Client, key string, count uint64, cs ...clientv3.Cmp) (uint64, error) {
+	lastRev := int64(-1)
+	for {
+		resp, err := kvGet(ctx, c, key)
 		if err != nil {
 			return 0, errors.Trace(err)
 		}
 
-		cmp = clientv3.Compare(clientv3.Value(key), "=", string(value))
-	}
+		var (
+			start uint64
+			rev   int64
+		)
+		if n := len(resp.Kvs); n > 1 {
+			return 0, errors.Errorf("invalid get value resp %v, must only one", resp.Kvs)
+		} else if n == 1 {
+			if start, err = bytesToUint64(resp.Kvs[0].Value); err != nil {
+				return 0, errors.Trace(err)
+			}
+			rev = resp.Kvs[0].ModRevision
+		}
+		// The key is not modified since the last txn, so the other comparisons
+		// fail.
+		if rev == lastRev {
+			return 0, errors.Trace(errAllocIDsCmpFailed)
+		}
 
-	end += allocStep
-	value = uint64ToBytes(end)
-	resp, err := alloc.s.leaderTxn(cmp).Then(clientv3.OpPut(key, string(value))).Commit()
-	if err != nil {
-		return 0, errors.Trace(err)
-	}
-	if !resp.Succeeded {
-		return 0, errors.New("generate id failed, we may not leader")
-	}
+		// The mod revision of a key which does not exist is 0.
+		cmp := clientv3.Compare(clientv3.ModRevision(key), "=", rev)
+		txnResp, err := newSlowLogTxn(ctx, c).If(append([]clientv3.Cmp{cmp}, cs...)...).Then(clientv3.OpPut(key, string(uint64ToBytes(start+count)))).Commit()
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		if txnResp.Succeeded {
+			return start, nil
+		}
+		lastRev = rev
+		log.Debugf("alloc ids of %s conflicts at revision %d, retry", key, rev)
 
-	log.Infof("idAllocator allocates a new id: %d", end)
-	return end, nil
+		select {
+		case <-ctx.Done():
+			return 0, errors.Trace(ctx.Err())
+		default:
+		}
+	}
 }
 
 func (s *Server) getAllocIDPath() string {
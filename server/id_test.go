@@ -17,6 +17,7 @@ import (
 	"sync"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"golang.org/x/net/context"
@@ -95,3 +96,53 @@ func (s *testAllocIDSuite) TestCommand(c *C) {
 		last = resp.GetId()
 	}
 }
+
+func (s *testAllocIDSuite) TestAllocIDs(c *C) {
+	key := "/test/alloc_ids"
+	start, err := allocIDs(s.svr.ctx, s.client, key, 10)
+	c.Assert(err, IsNil)
+	c.Assert(start, Equals, uint64(0))
+	start, err = allocIDs(s.svr.ctx, s.client, key, 5)
+	c.Assert(err, IsNil)
+	c.Assert(start, Equals, uint64(10))
+
+	// Concurrent allocators never reserve overlapping ranges.
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		starts = make(map[uint64]struct{})
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				start, err := allocIDs(s.svr.ctx, s.client, key, 5)
+				c.Assert(err, IsNil)
+				c.Assert(start%5, Equals, uint64(0))
+				m.Lock()
+				_, ok := starts[start]
+				starts[start] = struct{}{}
+				m.Unlock()
+				c.Assert(ok, IsFalse)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := getValue(s.svr.ctx, s.client, key)
+	c.Assert(err, IsNil)
+	end, err := bytesToUint64(value)
+	c.Assert(err, IsNil)
+	c.Assert(end, Equals, uint64(15+10*20*5))
+
+	// Nothing is reserved if the other comparisons fail.
+	cmp := clientv3.Compare(clientv3.Value(key), "=", "x")
+	_, err = allocIDs(s.svr.ctx, s.client, key, 5, cmp)
+	c.Assert(errors.Cause(err), Equals, errAllocIDsCmpFailed)
+	value, err = getValue(s.svr.ctx, s.client, key)
+	c.Assert(err, IsNil)
+	end, err = bytesToUint64(value)
+	c.Assert(err, IsNil)
+	c.Assert(end, Equals, uint64(15+10*20*5))
+}
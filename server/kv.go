@@ -18,10 +18,12 @@ import (
 	"fmt"
 	"math"
 	"path"
+	"strconv"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/gogo/protobuf/proto"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -42,6 +44,7 @@ var (
 type kv struct {
 	s           *Server
 	client      *clientv3.Client
+	cache       *cachedKV
 	clusterPath string
 	configPath  string
 }
@@ -50,6 +53,7 @@ func newKV(s *Server) *kv {
 	return &kv{
 		s:           s,
 		client:      s.client,
+		cache:       newCachedKV(s, s.cfg.KVMissCacheTTL.Duration),
 		clusterPath: path.Join(s.rootPath, "raft"),
 		configPath:  path.Join(s.rootPath, "config"),
 	}
@@ -65,12 +69,24 @@ func (kv *kv) regionPath(regionID uint64) string {
 	return path.Join(kv.clusterPath, "r", fmt.Sprintf("%020d", regionID))
 }
 
+func (kv *kv) storeNotePath(storeID uint64) string {
+	return path.Join(kv.clusterPath, "store_note", fmt.Sprintf("%020d", storeID))
+}
+
+func (kv *kv) storePreferredLeaderPath(storeID uint64) string {
+	return path.Join(kv.clusterPath, "store_preferred_leader", fmt.Sprintf("%020d", storeID))
+}
+
+func (kv *kv) storeWeightPath(storeID uint64) string {
+	return path.Join(kv.clusterPath, "store_weight", fmt.Sprintf("%020d", storeID))
+}
+
 func (kv *kv) clusterStatePath(option string) string {
 	return path.Join(kv.clusterPath, "status", option)
 }
 
 func (kv *kv) getRaftClusterBootstrapTime() (time.Time, error) {
-	data, err := kv.load(kv.clusterStatePath("raft_bootstrap_time"))
+	data, err := kv.cache.getValue(kv.clusterStatePath("raft_bootstrap_time"))
 	if err != nil {
 		return zeroTime, errors.Trace(err)
 	}
@@ -80,8 +96,17 @@ func (kv *kv) getRaftClusterBootstrapTime() (time.Time, error) {
 	return parseTimestamp(data)
 }
 
+// loadMeta loads the cluster meta through the miss cache, since it is read
+// repeatedly until the cluster is bootstrapped.
 func (kv *kv) loadMeta(meta *metapb.Cluster) (bool, error) {
-	return kv.loadProto(kv.clusterPath, meta)
+	value, err := kv.cache.getValue(kv.clusterPath)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if value == nil {
+		return false, nil
+	}
+	return true, proto.Unmarshal(value, meta)
 }
 
 func (kv *kv) saveMeta(meta *metapb.Cluster) error {
@@ -96,6 +121,94 @@ func (kv *kv) saveStore(store *metapb.Store) error {
 	return kv.saveProto(kv.storePath(store.GetId()), store)
 }
 
+func (kv *kv) loadStoreNote(storeID uint64) (string, error) {
+	value, err := kv.load(kv.storeNotePath(storeID))
+	return string(value), errors.Trace(err)
+}
+
+// loadStoreNotes returns the notes of the stores which are set, keyed by the
+// store IDs.
+func (kv *kv) loadStoreNotes() (map[uint64]string, error) {
+	notes := make(map[uint64]string)
+	start, end := kv.storeNotePath(0), kv.storeNotePath(math.MaxUint64)
+	err := kvGetPaged(kv.s.ctx, kv.client, start, end, kvRangeLimit, clientv3.SortAscend, func(item *mvccpb.KeyValue) (bool, error) {
+		storeID, err := strconv.ParseUint(path.Base(string(item.Key)), 10, 64)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		notes[storeID] = string(item.Value)
+		return true, nil
+	})
+	return notes, errors.Trace(err)
+}
+
+// saveStoreNote saves the note of a store, an empty note is removed.
+func (kv *kv) saveStoreNote(storeID uint64, note string) error {
+	if note != "" {
+		return kv.save(kv.storeNotePath(storeID), note)
+	}
+	return kv.remove(kv.storeNotePath(storeID))
+}
+
+// loadPreferredLeaderStores returns the IDs of the stores which are preferred
+// to host leaders.
+func (kv *kv) loadPreferredLeaderStores() ([]uint64, error) {
+	var storeIDs []uint64
+	start, end := kv.storePreferredLeaderPath(0), kv.storePreferredLeaderPath(math.MaxUint64)
+	err := kvGetPaged(kv.s.ctx, kv.client, start, end, kvRangeLimit, clientv3.SortAscend, func(item *mvccpb.KeyValue) (bool, error) {
+		storeID, err := strconv.ParseUint(path.Base(string(item.Key)), 10, 64)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		storeIDs = append(storeIDs, storeID)
+		return true, nil
+	})
+	return storeIDs, errors.Trace(err)
+}
+
+// saveStorePreferredLeader saves whether a store is preferred to host leaders,
+// only the preferred stores have the key.
+func (kv *kv) saveStorePreferredLeader(storeID uint64, preferred bool) error {
+	if preferred {
+		return kv.save(kv.storePreferredLeaderPath(storeID), "true")
+	}
+	return kv.remove(kv.storePreferredLeaderPath(storeID))
+}
+
+// storeWeight is the leader and region weight of a store saved in kv.
+type storeWeight struct {
+	Leader float64 `json:"leader"`
+	Region float64 `json:"region"`
+}
+
+// loadStoreWeights returns the weights of the stores which are set, keyed by
+// the store IDs.
+func (kv *kv) loadStoreWeights() (map[uint64]*storeWeight, error) {
+	weights := make(map[uint64]*storeWeight)
+	start, end := kv.storeWeightPath(0), kv.storeWeightPath(math.MaxUint64)
+	err := kvGetPaged(kv.s.ctx, kv.client, start, end, kvRangeLimit, clientv3.SortAscend, func(item *mvccpb.KeyValue) (bool, error) {
+		storeID, err := strconv.ParseUint(path.Base(string(item.Key)), 10, 64)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		weight := &storeWeight{}
+		if err = json.Unmarshal(item.Value, weight); err != nil {
+			return false, errors.Trace(err)
+		}
+		weights[storeID] = weight
+		return true, nil
+	})
+	return weights, errors.Trace(err)
+}
+
+func (kv *kv) saveStoreWeight(storeID uint64, weight *storeWeight) error {
+	value, err := json.Marshal(weight)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.storeWeightPath(storeID), string(value))
+}
+
 func (kv *kv) loadRegion(regionID uint64, region *metapb.Region) (bool, error) {
 	return kv.loadProto(kv.regionPath(regionID), region)
 }
@@ -151,61 +264,29 @@ func (kv *kv) loadConfig(cfg *Config) (bool, error) {
 }
 
 func (kv *kv) loadStores(stores *storesInfo, rangeLimit int64) error {
-	nextID := uint64(0)
-	endStore := kv.storePath(math.MaxUint64)
-	withRange := clientv3.WithRange(endStore)
-	withLimit := clientv3.WithLimit(rangeLimit)
-
-	for {
-		key := kv.storePath(nextID)
-		resp, err := kvGet(kv.client, key, withRange, withLimit)
-		if err != nil {
-			return errors.Trace(err)
-		}
-
-		for _, item := range resp.Kvs {
-			store := &metapb.Store{}
-			if err := store.Unmarshal(item.Value); err != nil {
-				return errors.Trace(err)
-			}
-
-			nextID = store.GetId() + 1
-			stores.setStore(newStoreInfo(store))
-		}
-
-		if len(resp.Kvs) < int(rangeLimit) {
-			return nil
+	start, end := kv.storePath(0), kv.storePath(math.MaxUint64)
+	err := kvGetPaged(kv.s.ctx, kv.client, start, end, rangeLimit, clientv3.SortAscend, func(item *mvccpb.KeyValue) (bool, error) {
+		store := &metapb.Store{}
+		if err := store.Unmarshal(item.Value); err != nil {
+			return false, errors.Trace(err)
 		}
-	}
+		stores.setStore(newStoreInfo(store))
+		return true, nil
+	})
+	return errors.Trace(err)
 }
 
 func (kv *kv) loadRegions(regions *regionsInfo, rangeLimit int64) error {
-	nextID := uint64(0)
-	endRegion := kv.regionPath(math.MaxUint64)
-	withRange := clientv3.WithRange(endRegion)
-	withLimit := clientv3.WithLimit(rangeLimit)
-
-	for {
-		key := kv.regionPath(nextID)
-		resp, err := kvGet(kv.client, key, withRange, withLimit)
-		if err != nil {
-			return errors.Trace(err)
+	start, end := kv.regionPath(0), kv.regionPath(math.MaxUint64)
+	err := kvGetPaged(kv.s.ctx, kv.client, start, end, rangeLimit, clientv3.SortAscend, func(item *mvccpb.KeyValue) (bool, error) {
+		region := &metapb.Region{}
+		if err := region.Unmarshal(item.Value); err != nil {
+			return false, errors.Trace(err)
 		}
-
-		for _, item := range resp.Kvs {
-			region := &metapb.Region{}
-			if err := region.Unmarshal(item.Value); err != nil {
-				return errors.Trace(err)
-			}
-
-			nextID = region.GetId() + 1
-			regions.setRegion(newRegionInfo(region, nil))
-		}
-
-		if len(resp.Kvs) < int(rangeLimit) {
-			return nil
-		}
-	}
+		regions.setRegion(newRegionInfo(region, nil))
+		return true, nil
+	})
+	return errors.Trace(err)
 }
 
 func (kv *kv) loadProto(key string, msg proto.Message) (bool, error) {
@@ -220,46 +301,101 @@ func (kv *kv) loadProto(key string, msg proto.Message) (bool, error) {
 }
 
 func (kv *kv) saveProto(key string, msg proto.Message) error {
-	value, err := proto.Marshal(msg)
+	txn, err := putProtoMsg(kv.txn(), key, msg, false)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return kv.save(key, string(value))
+	return kv.commit(key, txn)
 }
 
 func (kv *kv) load(key string) ([]byte, error) {
-	resp, err := kvGet(kv.client, key)
+	value, err := getValue(kv.s.ctx, kv.client, key)
+	return value, errors.Trace(err)
+}
+
+func (kv *kv) save(key, value string) error {
+	return kv.commit(key, kv.txn().Then(clientv3.OpPut(key, value)))
+}
+
+// commit commits the txn which writes the key, the cached value of the key is
+// invalidated after the txn succeeds.
+func (kv *kv) commit(key string, txn clientv3.Txn) error {
+	resp, err := txn.Commit()
 	if err != nil {
-		return nil, errors.Trace(err)
+		return errors.Trace(err)
 	}
-	if n := len(resp.Kvs); n == 0 {
-		return nil, nil
-	} else if n > 1 {
-		return nil, errors.Errorf("load more than one kvs: key %v kvs %v", key, n)
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
 	}
-	return resp.Kvs[0].Value, nil
+	kv.cache.invalidate(key)
+	return nil
 }
 
-func (kv *kv) save(key, value string) error {
-	resp, err := kv.txn().Then(clientv3.OpPut(key, value)).Commit()
+func (kv *kv) remove(key string) error {
+	resp, err := kv.txn().Then(clientv3.OpDelete(key)).Commit()
 	if err != nil {
 		return errors.Trace(err)
 	}
 	if !resp.Succeeded {
 		return errors.Trace(errTxnFailed)
 	}
+	kv.cache.invalidate(key)
 	return nil
 }
 
-func kvGet(c *clientv3.Client, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
-	ctx, cancel := context.WithTimeout(c.Ctx(), kvRequestTimeout)
+func kvGet(ctx context.Context, c *clientv3.Client, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, kvRequestTimeout)
 	defer cancel()
 
+	if isSlowLogDisabled() {
+		resp, err := clientv3.NewKV(c).Get(ctx, key, opts...)
+		return resp, errors.Trace(wrapTimeoutError(err, key, 0))
+	}
+
 	start := time.Now()
 	resp, err := clientv3.NewKV(c).Get(ctx, key, opts...)
-	if cost := time.Since(start); cost > kvSlowRequestTime {
+	cost := time.Since(start)
+	if cost > kvSlowRequestTime {
 		log.Warnf("kv gets too slow: key %v cost %v err %v", key, cost, err)
+		slowRequests.add(SlowRequestKVGet, start, cost, fmt.Sprintf("key: %v, err: %v", key, err))
 	}
 
-	return resp, errors.Trace(err)
+	return resp, errors.Trace(wrapTimeoutError(err, key, cost))
+}
+
+// kvGetPaged calls fn with the keys in [start, end) in the order of keys, up to
+// limit keys are got in each request. The keys are walked backward from end if
+// order is clientv3.SortDescend. The walk stops if fn returns false or an
+// error.
+// Note that etcd reads all the rest of the range to sort it in each request
+// of a descending walk, so a long one should stop early.
+func kvGetPaged(ctx context.Context, c *clientv3.Client, start, end string, limit int64, order clientv3.SortOrder,
+	fn func(item *mvccpb.KeyValue) (bool, error)) error {
+	withLimit := clientv3.WithLimit(limit)
+	withSort := clientv3.WithSort(clientv3.SortByKey, order)
+	for start < end {
+		resp, err := kvGet(ctx, c, start, clientv3.WithRange(end), withLimit, withSort)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		for _, item := range resp.Kvs {
+			ok, err := fn(item)
+			if err != nil || !ok {
+				return errors.Trace(err)
+			}
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			return nil
+		}
+		last := string(resp.Kvs[len(resp.Kvs)-1].Key)
+		if order == clientv3.SortDescend {
+			// The range end is exclusive.
+			end = last
+		} else {
+			start = last + "\x00"
+		}
+	}
+	return nil
 }
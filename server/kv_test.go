@@ -15,9 +15,16 @@ package server
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/testutil"
+	"golang.org/x/net/context"
 )
 
 var _ = Suite(&testKVSuite{})
@@ -133,3 +140,119 @@ func (s *testKVSuite) TestLoadRegions(c *C) {
 		c.Assert(region, DeepEquals, regions[region.GetId()])
 	}
 }
+
+func (s *testKVSuite) TestGetPaged(c *C) {
+	prefix := "/test/paged/"
+	for i := 0; i < 10; i++ {
+		_, err := clientv3.NewKV(s.server.client).Put(context.Background(), fmt.Sprintf("%s%d", prefix, i), "")
+		c.Assert(err, IsNil)
+	}
+	walk := func(start, end string, order clientv3.SortOrder, max int) []string {
+		var keys []string
+		err := kvGetPaged(s.server.ctx, s.server.client, start, end, 3, order, func(item *mvccpb.KeyValue) (bool, error) {
+			keys = append(keys, strings.TrimPrefix(string(item.Key), prefix))
+			return len(keys) < max, nil
+		})
+		c.Assert(err, IsNil)
+		return keys
+	}
+
+	c.Assert(walk(prefix, prefix+"9", clientv3.SortAscend, 100), DeepEquals, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8"})
+	c.Assert(walk(prefix+"2", prefix+"~", clientv3.SortDescend, 100), DeepEquals, []string{"9", "8", "7", "6", "5", "4", "3", "2"})
+	// Walk backward from a key and stop early.
+	c.Assert(walk(prefix, prefix+"5", clientv3.SortDescend, 4), DeepEquals, []string{"4", "3", "2", "1"})
+	c.Assert(walk(prefix+"5", prefix+"5", clientv3.SortAscend, 100), HasLen, 0)
+
+	err := kvGetPaged(s.server.ctx, s.server.client, prefix, prefix+"~", 3, clientv3.SortAscend, func(*mvccpb.KeyValue) (bool, error) {
+		return false, errors.New("stop")
+	})
+	c.Assert(err, ErrorMatches, "stop")
+}
+
+func (s *testKVSuite) TestCancelRootContext(c *C) {
+	// Nothing listens on this url, so requests block until ctx is done.
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints: []string{testutil.AllocTestURL()},
+	})
+	c.Assert(err, IsNil)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err = kvGet(ctx, client, "key")
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, kvRequestTimeout/2)
+
+	start = time.Now()
+	_, err = newSlowLogTxn(ctx, client).Then(clientv3.OpPut("key", "value")).Commit()
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, requestTimeout/2)
+}
+
+func (s *testKVSuite) TestCachedKV(c *C) {
+	key := fmt.Sprintf("/pd/%v/test/cached", s.server.clusterID)
+	put := func(value string) {
+		_, err := clientv3.NewKV(s.server.client).Put(context.Background(), key, value)
+		c.Assert(err, IsNil)
+	}
+
+	kv := newCachedKV(s.server, time.Hour)
+	value, err := kv.getValue(key)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	// The missing key is remembered until it is invalidated.
+	put("v1")
+	value, err = kv.getValue(key)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+	kv.invalidate(key)
+	value, err = kv.getValue(key)
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "v1")
+
+	// Existing values are not cached.
+	put("v2")
+	value, err = kv.getValue(key)
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "v2")
+
+	// The missing key expires after ttl.
+	missingKey := key + "/missing"
+	kv = newCachedKV(s.server, 100*time.Millisecond)
+	value, err = kv.getValue(missingKey)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+	c.Assert(kv.isMissing(missingKey), IsTrue)
+	time.Sleep(200 * time.Millisecond)
+	c.Assert(kv.isMissing(missingKey), IsFalse)
+
+	// The cache is disabled with zero ttl.
+	kv = newCachedKV(s.server, 0)
+	value, err = kv.getValue(missingKey)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+	c.Assert(kv.isMissing(missingKey), IsFalse)
+
+	// A missing key is not cached if it is written after it is read.
+	kv = newCachedKV(s.server, time.Hour)
+	gen := kv.generation()
+	kv.invalidate(missingKey)
+	kv.setMissing(missingKey, gen)
+	c.Assert(kv.isMissing(missingKey), IsFalse)
+
+	// The ttl of the kv of the server is from the config, and only the cluster
+	// meta and the bootstrap time are cached.
+	serverKV := newKV(s.server)
+	c.Assert(serverKV.cache.ttl, Equals, s.server.cfg.KVMissCacheTTL.Duration)
+	serverKV.cache.ttl = time.Hour
+	value, err = serverKV.load(missingKey)
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+	c.Assert(serverKV.cache.isMissing(missingKey), IsFalse)
+}
@@ -57,6 +57,10 @@ func (s *Server) getNextLeaderPath() string {
 	return path.Join(s.rootPath, "next_leader")
 }
 
+func (s *Server) getCampaignDisabledPath() string {
+	return path.Join(s.rootPath, "campaign_disabled")
+}
+
 func (s *Server) leaderLoop() {
 	defer s.wg.Done()
 
@@ -66,7 +70,7 @@ func (s *Server) leaderLoop() {
 			return
 		}
 
-		leader, err := getLeader(s.client, s.getLeaderPath())
+		leader, err := getLeader(s.ctx, s.client, s.getLeaderPath())
 		if err != nil {
 			log.Errorf("get leader err %v", err)
 			time.Sleep(200 * time.Millisecond)
@@ -90,7 +94,7 @@ func (s *Server) leaderLoop() {
 		}
 
 		// Check if current pd is expected to be next leader.
-		nextLeaders, err := getNextLeaders(s.client, s.getNextLeaderPath())
+		nextLeaders, err := getNextLeaders(s.ctx, s.client, s.getNextLeaderPath())
 		if err != nil {
 			log.Errorf("check next leader failed: %v", err)
 			time.Sleep(200 * time.Millisecond)
@@ -102,6 +106,17 @@ func (s *Server) leaderLoop() {
 				continue
 			}
 		}
+		// Check if current pd is not allowed to campaign.
+		disabled, err := s.GetCampaignDisabledMembers()
+		if err != nil {
+			log.Errorf("check campaign disabled failed: %v", err)
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+		if disabled[s.id] {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
 
 		if err = s.campaignLeader(); err != nil {
 			log.Errorf("campaign leader err %s", errors.ErrorStack(err))
@@ -114,9 +129,9 @@ func getLeaderAddr(leader *pdpb.Member) string {
 }
 
 // getLeader gets server leader from etcd.
-func getLeader(c *clientv3.Client, leaderPath string) (*pdpb.Member, error) {
+func getLeader(ctx context.Context, c *clientv3.Client, leaderPath string) (*pdpb.Member, error) {
 	leader := &pdpb.Member{}
-	ok, err := getProtoMsg(c, leaderPath, leader)
+	ok, err := getProtoMsg(ctx, c, leaderPath, leader)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -127,8 +142,8 @@ func getLeader(c *clientv3.Client, leaderPath string) (*pdpb.Member, error) {
 	return leader, nil
 }
 
-func getNextLeaders(c *clientv3.Client, path string) (map[uint64]struct{}, error) {
-	val, err := getValue(c, path)
+func getNextLeaders(ctx context.Context, c *clientv3.Client, path string) (map[uint64]struct{}, error) {
+	val, err := getValue(ctx, c, path)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -151,7 +166,7 @@ func (s *Server) GetLeader() (*pdpb.Member, error) {
 	if s.isClosed() {
 		return nil, errors.New("server is closed")
 	}
-	leader, err := getLeader(s.client, s.getLeaderPath())
+	leader, err := getLeader(s.ctx, s.client, s.getLeaderPath())
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -317,6 +332,13 @@ func (s *Server) ResignLeader(nextLeader string) error {
 			leaderIDs = append(leaderIDs, strconv.FormatUint(member.ID, 10))
 		}
 	}
+	// All the others would campaign without the next leader directive.
+	if nextLeader != "" && len(leaderIDs) == 0 {
+		return errors.Errorf("member %s not found", nextLeader)
+	}
+	if nextLeader == s.Name() {
+		return errors.Errorf("%s is already the leader", nextLeader)
+	}
 	nextLeaderValue := strings.Join(leaderIDs, ",")
 
 	// Save expect leader(s) to etcd.
@@ -349,6 +371,66 @@ func (s *Server) ResignLeader(nextLeader string) error {
 	}
 }
 
+// GetCampaignDisabledMembers returns the ids of the members which are not
+// allowed to campaign leader.
+func (s *Server) GetCampaignDisabledMembers() (map[uint64]bool, error) {
+	prefix := s.getCampaignDisabledPath() + "/"
+	resp, err := kvGet(s.ctx, s.client, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ids := make(map[uint64]bool, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		id, err := strconv.ParseUint(strings.TrimPrefix(string(item.Key), prefix), 10, 64)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// SetMemberCampaign allows or disallows the member to campaign leader. The
+// last member which can campaign cannot be disallowed, and the current leader
+// resigns if it is disallowed.
+func (s *Server) SetMemberCampaign(id uint64, enable bool) error {
+	key := path.Join(s.getCampaignDisabledPath(), strconv.FormatUint(id, 10))
+	op := clientv3.OpDelete(key)
+	if !enable {
+		res, err := etcdutil.ListEtcdMembers(s.client)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		disabled, err := s.GetCampaignDisabledMembers()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		var others int
+		for _, member := range res.Members {
+			if member.ID != id && !disabled[member.ID] {
+				others++
+			}
+		}
+		if others == 0 {
+			return errors.Errorf("member %d is the last one which can campaign", id)
+		}
+		op = clientv3.OpPut(key, "")
+	}
+	resp, err := s.leaderTxn().Then(op).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("save campaign flag failed, maybe lost leadership")
+	}
+	log.Infof("%s sets campaign of member %d to %v", s.Name(), id, enable)
+
+	if !enable && id == s.id {
+		return errors.Trace(s.ResignLeader(""))
+	}
+	return nil
+}
+
 func (s *Server) deleteLeaderKey() error {
 	// delete leader itself and let others start a new election again.
 	leaderKey := s.getLeaderPath()
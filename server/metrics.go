@@ -65,13 +65,7 @@ var (
 			Help:      "Status of the scheduler.",
 		}, []string{"kind", "type"})
 
-	schedulerCounter = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Namespace: "pd",
-			Subsystem: "scheduler",
-			Name:      "event_count",
-			Help:      "Counter of scheduler events.",
-		}, []string{"type", "name"})
+	schedulerCounter = newSchedulerCounter()
 
 	regionHeartbeatCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -110,3 +104,15 @@ func init() {
 	prometheus.MustRegister(hotSpotStatusGauge)
 	prometheus.MustRegister(tsoCounter)
 }
+
+// newSchedulerCounter returns a counter of scheduler events, it is only
+// exported if it is registered.
+func newSchedulerCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "event_count",
+			Help:      "Counter of scheduler events.",
+		}, []string{"type", "name"})
+}
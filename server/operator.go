@@ -14,6 +14,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/typeutil"
 )
 
 const maxOperatorWaitTime = 5 * time.Minute
@@ -153,12 +155,38 @@ type Operator interface {
 	Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse, bool)
 }
 
+// operatorProgress estimates how far an operator goes, it is shown with the
+// operator in the API.
+type operatorProgress struct {
+	// Progress is the ratio of the finished steps, from 0 to 1.
+	Progress float64 `json:"progress"`
+	// EstimatedRemaining is extrapolated from the time taken by the finished
+	// steps, it is null if no step is finished yet.
+	EstimatedRemaining *typeutil.Duration `json:"estimated_remaining"`
+}
+
+func newOperatorProgress(start time.Time, finished, total int, now time.Time) operatorProgress {
+	if total == 0 || finished >= total {
+		remaining := typeutil.NewDuration(0)
+		return operatorProgress{Progress: 1, EstimatedRemaining: &remaining}
+	}
+	p := operatorProgress{Progress: float64(finished) / float64(total)}
+	if finished > 0 {
+		perStep := now.Sub(start) / time.Duration(finished)
+		remaining := typeutil.NewDuration(perStep * time.Duration(total-finished))
+		p.EstimatedRemaining = &remaining
+	}
+	return p
+}
+
 type adminOperator struct {
-	Name   string        `json:"name"`
-	Region *RegionInfo   `json:"region"`
-	Start  time.Time     `json:"start"`
-	Ops    []Operator    `json:"ops"`
-	State  OperatorState `json:"state"`
+	Name   string      `json:"name"`
+	Region *RegionInfo `json:"region"`
+	Start  time.Time   `json:"start"`
+	// Index is the number of the finished ops.
+	Index int           `json:"index"`
+	Ops   []Operator    `json:"ops"`
+	State OperatorState `json:"state"`
 }
 
 func newAdminOperator(region *RegionInfo, ops ...Operator) *adminOperator {
@@ -206,16 +234,27 @@ func (op *adminOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse,
 	// Do all operators in order.
 	for i := 0; i < len(op.Ops); i++ {
 		if res, finished := op.Ops[i].Do(region); !finished {
+			op.Index = i
 			op.State = OperatorRunning
 			return res, false
 		}
 	}
 
 	// Admin operator never ends, remove it from the API.
+	op.Index = len(op.Ops)
 	op.State = OperatorFinished
 	return nil, false
 }
 
+// MarshalJSON returns the operator with its progress.
+func (op *adminOperator) MarshalJSON() ([]byte, error) {
+	type operator adminOperator
+	return json.Marshal(struct {
+		*operator
+		operatorProgress
+	}{(*operator)(op), newOperatorProgress(op.Start, op.Index, len(op.Ops), time.Now())})
+}
+
 type regionOperator struct {
 	Name   string        `json:"name"`
 	Region *RegionInfo   `json:"region"`
@@ -225,6 +264,9 @@ type regionOperator struct {
 	Ops    []Operator    `json:"ops"`
 	Kind   ResourceKind  `json:"kind"`
 	State  OperatorState `json:"state"`
+	// Reason explains why the operator is created, such as the scores of the
+	// stores which a balance scheduler compares.
+	Reason string `json:"reason,omitempty"`
 }
 
 func newRegionOperator(region *RegionInfo, kind ResourceKind, ops ...Operator) *regionOperator {
@@ -243,6 +285,15 @@ func newRegionOperator(region *RegionInfo, kind ResourceKind, ops ...Operator) *
 	}
 }
 
+// explainOperator sets the reason of op if it is a region operator, it
+// returns op so it can wrap the constructors which may return nil.
+func explainOperator(op Operator, format string, args ...interface{}) Operator {
+	if regionOp, ok := op.(*regionOperator); ok && regionOp != nil {
+		regionOp.Reason = fmt.Sprintf(format, args...)
+	}
+	return op
+}
+
 func (op *regionOperator) String() string {
 	return fmt.Sprintf("%+v", *op)
 }
@@ -296,6 +347,15 @@ func (op *regionOperator) Do(region *RegionInfo) (*pdpb.RegionHeartbeatResponse,
 	return nil, true
 }
 
+// MarshalJSON returns the operator with its progress.
+func (op *regionOperator) MarshalJSON() ([]byte, error) {
+	type operator regionOperator
+	return json.Marshal(struct {
+		*operator
+		operatorProgress
+	}{(*operator)(op), newOperatorProgress(op.Start, op.Index, len(op.Ops), time.Now())})
+}
+
 type changePeerOperator struct {
 	Name       string           `json:"name"`
 	RegionID   uint64           `json:"region_id"`
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
@@ -199,3 +200,31 @@ func (o *testOperatorSuite) TestOperatorState(c *C) {
 	c.Assert(op.GetState(), Equals, OperatorTimeOut)
 
 }
+
+func (o *testOperatorSuite) TestOperatorProgress(c *C) {
+	start := time.Now()
+	p := newOperatorProgress(start, 0, 4, start.Add(time.Minute))
+	c.Assert(p.Progress, Equals, 0.0)
+	c.Assert(p.EstimatedRemaining, IsNil)
+
+	p = newOperatorProgress(start, 1, 4, start.Add(time.Minute))
+	c.Assert(p.Progress, Equals, 0.25)
+	c.Assert(p.EstimatedRemaining.Duration, Equals, 3*time.Minute)
+
+	p = newOperatorProgress(start, 4, 4, start.Add(time.Minute))
+	c.Assert(p.Progress, Equals, 1.0)
+	c.Assert(p.EstimatedRemaining.Duration, Equals, time.Duration(0))
+
+	region := newRegionInfo(&metapb.Region{Id: 1}, nil)
+	op := newRegionOperator(region, RegionKind, newRemovePeerOperator(1, &metapb.Peer{Id: 2, StoreId: 2}),
+		newRemovePeerOperator(1, &metapb.Peer{Id: 3, StoreId: 3}))
+	op.Index = 1
+	data, err := json.Marshal(op)
+	c.Assert(err, IsNil)
+	var res map[string]interface{}
+	c.Assert(json.Unmarshal(data, &res), IsNil)
+	c.Assert(res["name"], Equals, "region_operator")
+	c.Assert(res["index"], Equals, 1.0)
+	c.Assert(res["progress"], Equals, 0.5)
+	c.Assert(res["estimated_remaining"], NotNil)
+}
@@ -225,6 +225,33 @@ func (t *regionTree) search(regionKey []byte) *metapb.Region {
 	return result.region
 }
 
+// getAdjacentRegions returns the previous and next regions of a region by key
+// order, they are nil if there is no such region.
+func (t *regionTree) getAdjacentRegions(region *metapb.Region) (*metapb.Region, *metapb.Region) {
+	item := &regionItem{region: &metapb.Region{StartKey: region.GetStartKey()}}
+
+	// Regions are sorted by start key reversely, so ascending gets the
+	// regions before the region.
+	var prev, next *metapb.Region
+	t.tree.AscendGreaterOrEqual(item, func(i btree.Item) bool {
+		r := i.(*regionItem).region
+		if bytes.Equal(r.GetStartKey(), region.GetStartKey()) {
+			return true
+		}
+		prev = r
+		return false
+	})
+	t.tree.DescendLessOrEqual(item, func(i btree.Item) bool {
+		r := i.(*regionItem).region
+		if bytes.Equal(r.GetStartKey(), region.GetStartKey()) {
+			return true
+		}
+		next = r
+		return false
+	})
+	return prev, next
+}
+
 // This is a helper function to find an item.
 func (t *regionTree) find(region *metapb.Region) *regionItem {
 	item := &regionItem{region: region}
@@ -0,0 +1,169 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// regionHistoryLRUMaxLen is the max number of regions whose history is kept,
+// the history of the least recently changed regions is dropped first.
+const regionHistoryLRUMaxLen = 10000
+
+// The types of the region events.
+const (
+	RegionEventCreate       = "create"
+	RegionEventSplit        = "split"
+	RegionEventMerge        = "merge"
+	RegionEventRangeChange  = "range-change"
+	RegionEventAddPeer      = "add-peer"
+	RegionEventRemovePeer   = "remove-peer"
+	RegionEventLeaderChange = "leader-change"
+)
+
+// RegionEvent is a change of a region found by its heartbeats.
+type RegionEvent struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Detail string    `json:"detail"`
+}
+
+// regionHistory keeps the recent events of each region.
+type regionHistory struct {
+	// mu makes appending the events of a region atomic, the events of a
+	// region are never changed after they are put into the cache.
+	mu     sync.Mutex
+	events *lruCache
+}
+
+func newRegionHistory() *regionHistory {
+	return &regionHistory{
+		events: newLRUCache(regionHistoryLRUMaxLen),
+	}
+}
+
+// add appends the events of a region, only the latest limit events are kept.
+func (h *regionHistory) add(regionID uint64, limit int, events []*RegionEvent) {
+	if len(events) == 0 || limit <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var history []*RegionEvent
+	if v, ok := h.events.peek(regionID); ok {
+		history = v.([]*RegionEvent)
+	}
+	history = append(append([]*RegionEvent(nil), history...), events...)
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	h.events.add(regionID, history)
+}
+
+// get returns the events of a region from the oldest to the latest.
+func (h *regionHistory) get(regionID uint64) []*RegionEvent {
+	v, ok := h.events.peek(regionID)
+	if !ok {
+		return nil
+	}
+	return v.([]*RegionEvent)
+}
+
+// diffRegionEvents returns the events which change origin to region, origin
+// is nil if the region is new.
+func diffRegionEvents(origin, region *RegionInfo, now time.Time) []*RegionEvent {
+	var events []*RegionEvent
+	add := func(typ string, format string, args ...interface{}) {
+		events = append(events, &RegionEvent{Time: now, Type: typ, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if origin == nil {
+		add(RegionEventCreate, "range %s, peers %s", formatRegionRange(region.Region), formatPeers(region.GetPeers()))
+		if region.Leader.GetId() != 0 {
+			add(RegionEventLeaderChange, "leader %s", formatPeer(region.Leader))
+		}
+		return events
+	}
+
+	if region.GetRegionEpoch().GetVersion() > origin.GetRegionEpoch().GetVersion() {
+		from, to := formatRegionRange(origin.Region), formatRegionRange(region.Region)
+		switch {
+		case containsRange(origin.Region, region.Region):
+			add(RegionEventSplit, "range %s -> %s", from, to)
+		case containsRange(region.Region, origin.Region):
+			add(RegionEventMerge, "range %s -> %s", from, to)
+		default:
+			add(RegionEventRangeChange, "range %s -> %s", from, to)
+		}
+	}
+	if region.GetRegionEpoch().GetConfVer() > origin.GetRegionEpoch().GetConfVer() {
+		for _, p := range region.GetPeers() {
+			if origin.GetPeer(p.GetId()) == nil {
+				add(RegionEventAddPeer, "peer %s", formatPeer(p))
+			}
+		}
+		for _, p := range origin.GetPeers() {
+			if region.GetPeer(p.GetId()) == nil {
+				add(RegionEventRemovePeer, "peer %s", formatPeer(p))
+			}
+		}
+	}
+	if region.Leader.GetId() != origin.Leader.GetId() {
+		if origin.Leader.GetId() == 0 {
+			add(RegionEventLeaderChange, "leader %s", formatPeer(region.Leader))
+		} else {
+			add(RegionEventLeaderChange, "leader %s -> %s", formatPeer(origin.Leader), formatPeer(region.Leader))
+		}
+	}
+	return events
+}
+
+// containsRange returns true if the range of outer contains the range of inner.
+func containsRange(outer, inner *metapb.Region) bool {
+	if bytes.Compare(inner.GetStartKey(), outer.GetStartKey()) < 0 {
+		return false
+	}
+	if len(outer.GetEndKey()) == 0 {
+		return true
+	}
+	return len(inner.GetEndKey()) != 0 && bytes.Compare(inner.GetEndKey(), outer.GetEndKey()) <= 0
+}
+
+func formatRegionRange(region *metapb.Region) string {
+	return fmt.Sprintf("[%x, %x)", region.GetStartKey(), region.GetEndKey())
+}
+
+func formatPeer(peer *metapb.Peer) string {
+	return fmt.Sprintf("%d(store %d)", peer.GetId(), peer.GetStoreId())
+}
+
+func formatPeers(peers []*metapb.Peer) string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, p := range peers {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(formatPeer(p))
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testRegionHistorySuite{})
+
+type testRegionHistorySuite struct{}
+
+func newHistoryTestRegion(start, end string, version, confVer uint64, peers ...*metapb.Peer) *RegionInfo {
+	region := &metapb.Region{
+		Id:          1,
+		StartKey:    []byte(start),
+		EndKey:      []byte(end),
+		RegionEpoch: &metapb.RegionEpoch{Version: version, ConfVer: confVer},
+		Peers:       peers,
+	}
+	return newRegionInfo(region, peers[0])
+}
+
+func getEventTypes(events []*RegionEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func (s *testRegionHistorySuite) TestDiffRegionEvents(c *C) {
+	now := time.Now()
+	p1, p2, p3 := &metapb.Peer{Id: 11, StoreId: 1}, &metapb.Peer{Id: 12, StoreId: 2}, &metapb.Peer{Id: 13, StoreId: 3}
+
+	origin := newHistoryTestRegion("a", "", 1, 1, p1, p2)
+	events := diffRegionEvents(nil, origin, now)
+	c.Assert(getEventTypes(events), DeepEquals, []string{RegionEventCreate, RegionEventLeaderChange})
+	c.Assert(events[0].Detail, Equals, "range [61, ), peers [11(store 1), 12(store 2)]")
+	c.Assert(events[0].Time, Equals, now)
+
+	split := newHistoryTestRegion("a", "c", 2, 1, p1, p2)
+	events = diffRegionEvents(origin, split, now)
+	c.Assert(getEventTypes(events), DeepEquals, []string{RegionEventSplit})
+	c.Assert(events[0].Detail, Equals, "range [61, ) -> [61, 63)")
+
+	merge := newHistoryTestRegion("", "c", 3, 1, p1, p2)
+	c.Assert(getEventTypes(diffRegionEvents(split, merge, now)), DeepEquals, []string{RegionEventMerge})
+	moved := newHistoryTestRegion("b", "d", 4, 1, p1, p2)
+	c.Assert(getEventTypes(diffRegionEvents(split, moved, now)), DeepEquals, []string{RegionEventRangeChange})
+
+	// A peer is moved and the leader is transferred to the new peer.
+	changed := newHistoryTestRegion("a", "c", 2, 3, p1, p3)
+	changed.Leader = p3
+	events = diffRegionEvents(split, changed, now)
+	c.Assert(getEventTypes(events), DeepEquals, []string{RegionEventAddPeer, RegionEventRemovePeer, RegionEventLeaderChange})
+	c.Assert(events[0].Detail, Equals, "peer 13(store 3)")
+	c.Assert(events[1].Detail, Equals, "peer 12(store 2)")
+	c.Assert(events[2].Detail, Equals, "leader 11(store 1) -> 13(store 3)")
+
+	c.Assert(diffRegionEvents(split, split.clone(), now), HasLen, 0)
+}
+
+func (s *testRegionHistorySuite) TestRegionHistory(c *C) {
+	h := newRegionHistory()
+	c.Assert(h.get(1), IsNil)
+
+	newEvents := func(types ...string) []*RegionEvent {
+		events := make([]*RegionEvent, 0, len(types))
+		for _, t := range types {
+			events = append(events, &RegionEvent{Type: t})
+		}
+		return events
+	}
+	h.add(1, 3, newEvents(RegionEventCreate, RegionEventLeaderChange))
+	h.add(1, 3, nil)
+	c.Assert(getEventTypes(h.get(1)), DeepEquals, []string{RegionEventCreate, RegionEventLeaderChange})
+
+	// The returned events are not changed by the later events.
+	events := h.get(1)
+	h.add(1, 3, newEvents(RegionEventSplit, RegionEventMerge))
+	c.Assert(getEventTypes(events), DeepEquals, []string{RegionEventCreate, RegionEventLeaderChange})
+	c.Assert(getEventTypes(h.get(1)), DeepEquals, []string{RegionEventLeaderChange, RegionEventSplit, RegionEventMerge})
+
+	h.add(2, 0, newEvents(RegionEventCreate))
+	c.Assert(h.get(2), IsNil)
+}
+
+func (s *testRegionHistorySuite) TestHeartbeatHistory(c *C) {
+	_, opt := newTestScheduleConfig()
+	cluster := newClusterInfo(newMockIDAllocator())
+	p1 := &metapb.Peer{Id: 11, StoreId: 1}
+	region := newHistoryTestRegion("", "", 1, 1, p1)
+
+	// The history is not kept by default.
+	cluster.opt = opt
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	c.Assert(cluster.getRegionHistory(1), IsNil)
+
+	opt.load().RegionHistoryLimit = 10
+	region = newHistoryTestRegion("", "b", 2, 1, p1)
+	c.Assert(cluster.handleRegionHeartbeat(region), IsNil)
+	// The stale heartbeats are not recorded.
+	c.Assert(cluster.handleRegionHeartbeat(newHistoryTestRegion("", "", 1, 1, p1)), NotNil)
+	c.Assert(getEventTypes(cluster.getRegionHistory(1)), DeepEquals, []string{RegionEventSplit})
+}
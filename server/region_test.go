@@ -167,6 +167,30 @@ func (s *testRegionSuite) TestRegionTree(c *C) {
 	c.Assert(tree.search([]byte("e")), Equals, regionE)
 }
 
+func (s *testRegionSuite) TestRegionTreeAdjacentRegions(c *C) {
+	tree := newRegionTree()
+
+	regionA := newRegion([]byte{}, []byte("b"))
+	regionB := newRegion([]byte("b"), []byte("c"))
+	regionD := newRegion([]byte("d"), []byte{})
+	tree.update(regionA)
+	tree.update(regionB)
+	tree.update(regionD)
+
+	prev, next := tree.getAdjacentRegions(regionA)
+	c.Assert(prev, IsNil)
+	c.Assert(next, Equals, regionB)
+
+	prev, next = tree.getAdjacentRegions(regionB)
+	c.Assert(prev, Equals, regionA)
+	c.Assert(next, Equals, regionD)
+
+	// There is a gap between b and d.
+	prev, next = tree.getAdjacentRegions(regionD)
+	c.Assert(prev, Equals, regionB)
+	c.Assert(next, IsNil)
+}
+
 func splitRegions(regions []*metapb.Region) []*metapb.Region {
 	results := make([]*metapb.Region, 0, len(regions)*2)
 	for _, region := range regions {
@@ -16,10 +16,13 @@ package server
 import (
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Scheduler is an interface to schedule resources.
@@ -32,6 +35,31 @@ type Scheduler interface {
 	Schedule(cluster *clusterInfo) Operator
 }
 
+// decisionExplainer is implemented by the schedulers which explain their last
+// decision, no matter whether an operator is created.
+type decisionExplainer interface {
+	GetLastDecision() string
+}
+
+// decisionRecorder records the last decision of a scheduler, it is read by
+// the API while the scheduler is running.
+type decisionRecorder struct {
+	sync.RWMutex
+	decision string
+}
+
+func (r *decisionRecorder) record(format string, args ...interface{}) {
+	r.Lock()
+	defer r.Unlock()
+	r.decision = fmt.Sprintf(format, args...)
+}
+
+func (r *decisionRecorder) GetLastDecision() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.decision
+}
+
 // grantLeaderScheduler transfers all leaders to peers in the store.
 type grantLeaderScheduler struct {
 	opt     *scheduleOption
@@ -135,6 +163,111 @@ func (s *evictLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
 }
 
+const evictSlowStoreName = "evict-slow-store-scheduler"
+
+// evictSlowStoreScheduler evicts leaders from a store which reports it is
+// busy, until the store recovers. Only one store is evicted at a time.
+type evictSlowStoreScheduler struct {
+	sync.RWMutex
+	opt      *scheduleOption
+	selector Selector
+	storeID  uint64
+}
+
+func newEvictSlowStoreScheduler(opt *scheduleOption) *evictSlowStoreScheduler {
+	filters := []Filter{
+		newStateFilter(opt),
+		newHealthFilter(opt),
+	}
+
+	return &evictSlowStoreScheduler{
+		opt:      opt,
+		selector: newRandomSelector(filters),
+	}
+}
+
+func (s *evictSlowStoreScheduler) GetName() string {
+	return evictSlowStoreName
+}
+
+func (s *evictSlowStoreScheduler) GetResourceKind() ResourceKind {
+	return LeaderKind
+}
+
+func (s *evictSlowStoreScheduler) GetResourceLimit() uint64 {
+	return s.opt.GetLeaderScheduleLimit()
+}
+
+func (s *evictSlowStoreScheduler) Prepare(cluster *clusterInfo) error { return nil }
+
+func (s *evictSlowStoreScheduler) Cleanup(cluster *clusterInfo) {
+	if storeID := s.getSlowStore(); storeID != 0 {
+		cluster.unblockStore(storeID)
+		s.setSlowStore(0)
+	}
+}
+
+// getSlowStore returns the store being evicted, 0 means no slow store.
+func (s *evictSlowStoreScheduler) getSlowStore() uint64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.storeID
+}
+
+func (s *evictSlowStoreScheduler) setSlowStore(storeID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.storeID = storeID
+}
+
+func (s *evictSlowStoreScheduler) isSlow(store *storeInfo) bool {
+	return store != nil && store.isUp() && store.downTime() < s.opt.GetMaxStoreDownTime() && store.status.GetIsBusy()
+}
+
+// selectSlowStore returns the first slow store which can be blocked.
+func (s *evictSlowStoreScheduler) selectSlowStore(cluster *clusterInfo) uint64 {
+	stores := cluster.getStores()
+	sort.Slice(stores, func(i, j int) bool { return stores[i].GetId() < stores[j].GetId() })
+	for _, store := range stores {
+		if s.isSlow(store) && cluster.blockStore(store.GetId()) == nil {
+			log.Infof("[%s] start to evict leaders from slow store %d", s.GetName(), store.GetId())
+			return store.GetId()
+		}
+	}
+	return 0
+}
+
+func (s *evictSlowStoreScheduler) Schedule(cluster *clusterInfo) Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	storeID := s.getSlowStore()
+	if storeID != 0 && !s.isSlow(cluster.getStore(storeID)) {
+		log.Infof("[%s] store %d is not slow any more", s.GetName(), storeID)
+		cluster.unblockStore(storeID)
+		storeID = 0
+	}
+	if storeID == 0 {
+		storeID = s.selectSlowStore(cluster)
+	}
+	s.setSlowStore(storeID)
+	if storeID == 0 {
+		schedulerCounter.WithLabelValues(s.GetName(), "no_slow_store").Inc()
+		return nil
+	}
+
+	region := cluster.randLeaderRegion(storeID)
+	if region == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no_leader").Inc()
+		return nil
+	}
+	target := s.selector.SelectTarget(cluster.getFollowerStores(region))
+	if target == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no_target_store").Inc()
+		return nil
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
+	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
+}
+
 type shuffleLeaderScheduler struct {
 	opt      *scheduleOption
 	selector Selector
@@ -179,7 +312,7 @@ func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
 	// Select a store and transfer a leader from it.
 	if s.selected == nil {
-		region, newLeader := scheduleTransferLeader(cluster, s.GetName(), s.selector)
+		region, newLeader := scheduleTransferLeader(cluster, schedulerCounter, s.GetName(), s.selector)
 		if region == nil {
 			return nil
 		}
@@ -238,7 +371,7 @@ func (s *shuffleRegionScheduler) Cleanup(cluster *clusterInfo) {}
 
 func (s *shuffleRegionScheduler) Schedule(cluster *clusterInfo) Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
-	region, oldPeer := scheduleRemovePeer(cluster, s.GetName(), s.selector)
+	region, oldPeer := scheduleRemovePeer(cluster, schedulerCounter, s.GetName(), s.selector)
 	if region == nil {
 		schedulerCounter.WithLabelValues(s.GetName(), "no_region").Inc()
 		return nil
@@ -315,21 +448,25 @@ func scheduleAddPeer(cluster *clusterInfo, s Selector, filters ...Filter) *metap
 }
 
 // scheduleRemovePeer schedules a region to remove the peer.
-func scheduleRemovePeer(cluster *clusterInfo, schedulerName string, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
+func scheduleRemovePeer(cluster *clusterInfo, counter *prometheus.CounterVec, schedulerName string, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
 	stores := cluster.getStores()
 
 	source := s.SelectSource(stores, filters...)
 	if source == nil {
-		schedulerCounter.WithLabelValues(schedulerName, "no_store").Inc()
+		counter.WithLabelValues(schedulerName, "no_store").Inc()
 		return nil, nil
 	}
 
-	region := cluster.randFollowerRegion(source.GetId())
+	// The accelerated regions are moved first.
+	region := cluster.randAcceleratedRegion(source.GetId())
+	if region == nil {
+		region = cluster.randFollowerRegion(source.GetId())
+	}
 	if region == nil {
 		region = cluster.randLeaderRegion(source.GetId())
 	}
 	if region == nil {
-		schedulerCounter.WithLabelValues(schedulerName, "no_region").Inc()
+		counter.WithLabelValues(schedulerName, "no_region").Inc()
 		return nil, nil
 	}
 
@@ -337,10 +474,10 @@ func scheduleRemovePeer(cluster *clusterInfo, schedulerName string, s Selector,
 }
 
 // scheduleTransferLeader schedules a region to transfer leader to the peer.
-func scheduleTransferLeader(cluster *clusterInfo, schedulerName string, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
+func scheduleTransferLeader(cluster *clusterInfo, counter *prometheus.CounterVec, schedulerName string, s Selector, filters ...Filter) (*RegionInfo, *metapb.Peer) {
 	stores := cluster.getStores()
 	if len(stores) == 0 {
-		schedulerCounter.WithLabelValues(schedulerName, "no_store").Inc()
+		counter.WithLabelValues(schedulerName, "no_store").Inc()
 		return nil, nil
 	}
 
@@ -360,7 +497,7 @@ func scheduleTransferLeader(cluster *clusterInfo, schedulerName string, s Select
 		leastLeaderDistance = math.Abs(leastLeaderStore.leaderScore() - averageLeader)
 	}
 	if mostLeaderDistance == 0 && leastLeaderDistance == 0 {
-		schedulerCounter.WithLabelValues(schedulerName, "already_balanced").Inc()
+		counter.WithLabelValues(schedulerName, "already_balanced").Inc()
 		return nil, nil
 	}
 
@@ -368,13 +505,13 @@ func scheduleTransferLeader(cluster *clusterInfo, schedulerName string, s Select
 		// Transfer a leader out of mostLeaderStore.
 		region := cluster.randLeaderRegion(mostLeaderStore.GetId())
 		if region == nil {
-			schedulerCounter.WithLabelValues(schedulerName, "no_leader_region").Inc()
+			counter.WithLabelValues(schedulerName, "no_leader_region").Inc()
 			return nil, nil
 		}
 		targetStores := cluster.getFollowerStores(region)
 		target := s.SelectTarget(targetStores)
 		if target == nil {
-			schedulerCounter.WithLabelValues(schedulerName, "no_target_store").Inc()
+			counter.WithLabelValues(schedulerName, "no_target_store").Inc()
 			return nil, nil
 		}
 
@@ -384,7 +521,7 @@ func scheduleTransferLeader(cluster *clusterInfo, schedulerName string, s Select
 	// Transfer a leader into leastLeaderStore.
 	region := cluster.randFollowerRegion(leastLeaderStore.GetId())
 	if region == nil {
-		schedulerCounter.WithLabelValues(schedulerName, "no_target_peer").Inc()
+		counter.WithLabelValues(schedulerName, "no_target_peer").Inc()
 		return nil, nil
 	}
 	return region, region.GetStorePeer(leastLeaderStore.GetId())
@@ -53,3 +53,47 @@ func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
 		c.Assert(op.NewLeader.GetStoreId(), Equals, sourceID)
 	}
 }
+
+var _ = Suite(&testEvictSlowStoreSuite{})
+
+type testEvictSlowStoreSuite struct{}
+
+func (s *testEvictSlowStoreSuite) TestEvictSlowStore(c *C) {
+	cluster := newClusterInfo(newMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	sl := newEvictSlowStoreScheduler(opt)
+	c.Assert(sl.Schedule(cluster), IsNil)
+
+	// Add stores 1,2,3
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 0)
+	tc.addLeaderStore(3, 0)
+	// Add region 1 with leader in store 1
+	tc.addLeaderRegion(1, 1, 2, 3)
+
+	// No store is slow.
+	c.Assert(sl.Schedule(cluster), IsNil)
+	c.Assert(sl.getSlowStore(), Equals, uint64(0))
+
+	// Store 1 is slow, evict the leader.
+	tc.setStoreBusy(1, true)
+	checkTransferLeaderFrom(c, sl.Schedule(cluster), 1)
+	c.Assert(sl.getSlowStore(), Equals, uint64(1))
+	c.Assert(cluster.getStore(1).isBlocked(), IsTrue)
+
+	// Store 1 recovers.
+	tc.setStoreBusy(1, false)
+	c.Assert(sl.Schedule(cluster), IsNil)
+	c.Assert(sl.getSlowStore(), Equals, uint64(0))
+	c.Assert(cluster.getStore(1).isBlocked(), IsFalse)
+
+	// Store 2 is slow and is unblocked by cleanup.
+	tc.setStoreBusy(2, true)
+	c.Assert(sl.Schedule(cluster), IsNil)
+	c.Assert(sl.getSlowStore(), Equals, uint64(2))
+	sl.Cleanup(cluster)
+	c.Assert(sl.getSlowStore(), Equals, uint64(0))
+	c.Assert(cluster.getStore(2).isBlocked(), IsFalse)
+}
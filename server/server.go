@@ -26,11 +26,13 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/embed"
+	"github.com/coreos/etcd/etcdserver"
 	"github.com/coreos/etcd/pkg/types"
 	"github.com/juju/errors"
 	"github.com/ngaut/systimemon"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/pkg/etcdutil"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
@@ -56,6 +58,10 @@ type Server struct {
 
 	wg sync.WaitGroup
 
+	// Root context for all etcd requests, cancelled when the server closes.
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	// Etcd and cluster informations.
 	etcd        *embed.Etcd
 	client      *clientv3.Client
@@ -84,12 +90,14 @@ type Server struct {
 func CreateServer(cfg *Config, apiRegister func(*Server) http.Handler) (*Server, error) {
 	log.Infof("PD config - %v", cfg)
 	rand.Seed(time.Now().UnixNano())
+	setSlowLogDisabled(cfg.DisableSlowLog)
 
 	s := &Server{
 		cfg:         cfg,
 		scheduleOpt: newScheduleOption(cfg),
 		resignCh:    make(chan struct{}),
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.handler = newHandler(s)
 
 	// Adjust etcd config.
@@ -133,7 +141,7 @@ func (s *Server) startEtcd() error {
 		return errors.Trace(err)
 	}
 
-	if err = etcdutil.WaitEtcdStart(client, endpoints[0]); err != nil {
+	if err = etcdutil.WaitEtcdStart(s.ctx, client, endpoints[0]); err != nil {
 		// See https://github.com/coreos/etcd/issues/6067
 		// Here may return "not capable" error because we don't start
 		// all etcds in initial_cluster at same time, so here just log
@@ -185,7 +193,7 @@ func (s *Server) startServer() error {
 
 func (s *Server) initClusterID() error {
 	// Get any cluster key to parse the cluster ID.
-	resp, err := kvGet(s.client, pdRootPath, clientv3.WithFirstCreate()...)
+	resp, err := kvGet(s.ctx, s.client, pdRootPath, clientv3.WithFirstCreate()...)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -224,6 +232,9 @@ func (s *Server) Close() {
 
 	log.Info("closing server")
 
+	// Abort all in-flight etcd requests.
+	s.cancel()
+
 	s.enableLeader(false)
 
 	if s.client != nil {
@@ -288,6 +299,20 @@ func (s *Server) GetClient() *clientv3.Client {
 	return s.client
 }
 
+// GetEtcdQuotaBytes returns the backend quota of the embedded etcd, writes are
+// rejected after the backend size exceeds it. It returns 0 if the quota is
+// disabled.
+func (s *Server) GetEtcdQuotaBytes() int64 {
+	quota := s.etcdCfg.QuotaBackendBytes
+	if quota < 0 {
+		return 0
+	}
+	if quota == 0 {
+		return etcdserver.DefaultQuotaBytes
+	}
+	return quota
+}
+
 // ID returns the unique etcd ID for this server in etcd cluster.
 func (s *Server) ID() uint64 {
 	return s.id
@@ -306,7 +331,7 @@ func (s *Server) ClusterID() uint64 {
 // txn returns an etcd client transaction wrapper.
 // The wrapper will set a request timeout to the context and log slow transactions.
 func (s *Server) txn() clientv3.Txn {
-	return newSlowLogTxn(s.client)
+	return newSlowLogTxn(s.ctx, s.client)
 }
 
 // leaderTxn returns txn() with a leader comparison to guarantee that
@@ -22,6 +22,7 @@ import (
 	"github.com/coreos/etcd/clientv3"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/pd/pkg/testutil"
+	"golang.org/x/net/context"
 )
 
 func TestServer(t *testing.T) {
@@ -160,7 +161,7 @@ func (s *testLeaderServerSuite) TestLeader(c *C) {
 
 	// wait leader changes
 	for i := 0; i < 50; i++ {
-		leader, _ := getLeader(client, s.leaderPath)
+		leader, _ := getLeader(context.Background(), client, s.leaderPath)
 		if leader != nil && getLeaderAddr(leader) != getLeaderAddr(leader1) {
 			break
 		}
@@ -0,0 +1,93 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+// The operations of slow requests.
+const (
+	SlowRequestKVGet = "kvget"
+	SlowRequestTxn   = "txn"
+)
+
+const maxSlowRequests = 256
+
+// SlowRequest is an etcd request which is slower than the slow request time.
+type SlowRequest struct {
+	Op     string            `json:"op"`
+	Time   time.Time         `json:"time"`
+	Cost   typeutil.Duration `json:"cost"`
+	Detail string            `json:"detail"`
+}
+
+// slowRequestLog keeps the latest slow requests in a ring buffer.
+type slowRequestLog struct {
+	sync.Mutex
+	requests []*SlowRequest
+	next     int
+}
+
+func newSlowRequestLog(size int) *slowRequestLog {
+	return &slowRequestLog{
+		requests: make([]*SlowRequest, 0, size),
+	}
+}
+
+func (l *slowRequestLog) add(op string, start time.Time, cost time.Duration, detail string) {
+	l.Lock()
+	defer l.Unlock()
+
+	req := &SlowRequest{
+		Op:     op,
+		Time:   start,
+		Cost:   typeutil.NewDuration(cost),
+		Detail: detail,
+	}
+	if len(l.requests) < cap(l.requests) {
+		l.requests = append(l.requests, req)
+		return
+	}
+	l.requests[l.next] = req
+	l.next = (l.next + 1) % len(l.requests)
+}
+
+// get returns the requests from the oldest one which start after since, and
+// are of the op if it is not empty.
+func (l *slowRequestLog) get(since time.Time, op string) []*SlowRequest {
+	l.Lock()
+	defer l.Unlock()
+
+	var res []*SlowRequest
+	for i := range l.requests {
+		req := l.requests[(l.next+i)%len(l.requests)]
+		if req.Time.Before(since) || (op != "" && req.Op != op) {
+			continue
+		}
+		res = append(res, req)
+	}
+	return res
+}
+
+var slowRequests = newSlowRequestLog(maxSlowRequests)
+
+// GetSlowRequests returns the latest slow etcd requests which start after
+// since. If op is not empty, only the requests of the op are returned.
+func GetSlowRequests(since time.Time, op string) []*SlowRequest {
+	return slowRequests.get(since, op)
+}
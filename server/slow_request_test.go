@@ -0,0 +1,55 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSlowRequestSuite{})
+
+type testSlowRequestSuite struct{}
+
+func (s *testSlowRequestSuite) TestSlowRequestLog(c *C) {
+	l := newSlowRequestLog(3)
+	c.Assert(l.get(time.Time{}, ""), HasLen, 0)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		op := SlowRequestKVGet
+		if i%2 == 1 {
+			op = SlowRequestTxn
+		}
+		l.add(op, start.Add(time.Duration(i)*time.Minute), time.Second, "")
+	}
+
+	// The oldest one is dropped.
+	reqs := l.get(time.Time{}, "")
+	c.Assert(reqs, HasLen, 3)
+	for i, req := range reqs {
+		c.Assert(req.Time, Equals, start.Add(time.Duration(i+1)*time.Minute))
+	}
+
+	reqs = l.get(start.Add(2*time.Minute), "")
+	c.Assert(reqs, HasLen, 2)
+	c.Assert(reqs[0].Time, Equals, start.Add(2*time.Minute))
+
+	reqs = l.get(time.Time{}, SlowRequestTxn)
+	c.Assert(reqs, HasLen, 2)
+	for _, req := range reqs {
+		c.Assert(req.Op, Equals, SlowRequestTxn)
+	}
+}
@@ -21,11 +21,26 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
+// preferredLeaderScoreRatio scales the leader score of a preferred leader
+// store.
+const preferredLeaderScoreRatio = 0.5
+
+// defaultStoreWeight is the leader and region weight of a store which are not
+// set.
+const defaultStoreWeight = 1.0
+
 // storeInfo contains information about a store.
 // TODO: Export this to API directly.
 type storeInfo struct {
 	*metapb.Store
 	status *StoreStatus
+	// preferredLeader is set by operators for the stores which should host
+	// more leaders, such as the stores in the primary data center.
+	preferredLeader bool
+	// leaderWeight and regionWeight are set by operators, the resources are
+	// balanced in proportion to them. 0 means defaultStoreWeight.
+	leaderWeight float64
+	regionWeight float64
 }
 
 func newStoreInfo(store *metapb.Store) *storeInfo {
@@ -37,8 +52,11 @@ func newStoreInfo(store *metapb.Store) *storeInfo {
 
 func (s *storeInfo) clone() *storeInfo {
 	return &storeInfo{
-		Store:  proto.Clone(s.Store).(*metapb.Store),
-		status: s.status.clone(),
+		Store:           proto.Clone(s.Store).(*metapb.Store),
+		status:          s.status.clone(),
+		preferredLeader: s.preferredLeader,
+		leaderWeight:    s.leaderWeight,
+		regionWeight:    s.regionWeight,
 	}
 }
 
@@ -74,8 +92,29 @@ func (s *storeInfo) leaderCount() uint64 {
 	return uint64(s.status.LeaderCount)
 }
 
+func (s *storeInfo) getLeaderWeight() float64 {
+	if s.leaderWeight <= 0 {
+		return defaultStoreWeight
+	}
+	return s.leaderWeight
+}
+
+func (s *storeInfo) getRegionWeight() float64 {
+	if s.regionWeight <= 0 {
+		return defaultStoreWeight
+	}
+	return s.regionWeight
+}
+
+// leaderScore is divided by the leader weight. The score of a preferred leader
+// store is also scaled down, so the leaders are balanced to it until it has
+// 1/preferredLeaderScoreRatio times the leaders of the other stores.
 func (s *storeInfo) leaderScore() float64 {
-	return float64(s.status.LeaderCount)
+	score := float64(s.status.LeaderCount) / s.getLeaderWeight()
+	if s.preferredLeader {
+		return score * preferredLeaderScoreRatio
+	}
+	return score
 }
 
 func (s *storeInfo) regionCount() uint64 {
@@ -86,7 +125,7 @@ func (s *storeInfo) regionScore() float64 {
 	if s.status.GetCapacity() == 0 {
 		return 0
 	}
-	return float64(s.status.RegionCount) / float64(s.status.GetCapacity())
+	return float64(s.status.RegionCount) / float64(s.status.GetCapacity()) / s.getRegionWeight()
 }
 
 func (s *storeInfo) storageSize() uint64 {
@@ -60,6 +60,7 @@ func NewTestSingleConfig() *Config {
 
 		LeaderLease:     1,
 		TsoSaveInterval: typeutil.NewDuration(200 * time.Millisecond),
+		EnableTestAPI:   true,
 	}
 
 	cfg.AdvertiseClientUrls = cfg.ClientUrls
@@ -38,6 +38,35 @@ var (
 type atomicObject struct {
 	physical time.Time
 	logical  int64
+	// saved is the upper bound of the physical time saved in etcd when the
+	// object is stored.
+	saved time.Time
+}
+
+// TSOStatus is the in-memory state of the TSO allocator.
+type TSOStatus struct {
+	// Physical is the physical time of the allocated timestamps.
+	Physical time.Time `json:"physical"`
+	// Logical is the number of timestamps allocated with the physical time.
+	Logical int64 `json:"logical"`
+	// SavedTime is saved in etcd as the upper bound of the physical time, the
+	// physical time can pass it only after it is saved again, and the next
+	// leader starts after it.
+	SavedTime time.Time `json:"saved_time"`
+}
+
+// GetTSOStatus returns the state of the TSO allocator, it fails if the
+// timestamp is not synced, such as the server is not leader.
+func (s *Server) GetTSOStatus() (*TSOStatus, error) {
+	current, ok := s.ts.Load().(*atomicObject)
+	if !ok || current.physical == zeroTime {
+		return nil, errors.New("timestamp is not synced, the server may not be leader")
+	}
+	return &TSOStatus{
+		Physical:  current.physical,
+		Logical:   atomic.LoadInt64(&current.logical),
+		SavedTime: current.saved,
+	}, nil
 }
 
 func (s *Server) getTimestampPath() string {
@@ -45,7 +74,7 @@ func (s *Server) getTimestampPath() string {
 }
 
 func (s *Server) loadTimestamp() (time.Time, error) {
-	data, err := getValue(s.client, s.getTimestampPath())
+	data, err := getValue(s.ctx, s.client, s.getTimestampPath())
 	if err != nil {
 		return zeroTime, errors.Trace(err)
 	}
@@ -105,6 +134,7 @@ func (s *Server) syncTimestamp() error {
 
 	current := &atomicObject{
 		physical: now,
+		saved:    save,
 	}
 	s.ts.Store(current)
 
@@ -142,6 +172,7 @@ func (s *Server) updateTimestamp() error {
 
 	current := &atomicObject{
 		physical: now,
+		saved:    s.lastSavedTime,
 	}
 	s.ts.Store(current)
 
@@ -65,7 +65,7 @@ func (s *testTsoSuite) testGetTimestamp(c *C, n int) *pdpb.Timestamp {
 
 func mustGetLeader(c *C, client *clientv3.Client, leaderPath string) *pdpb.Member {
 	for i := 0; i < 20; i++ {
-		leader, err := getLeader(client, leaderPath)
+		leader, err := getLeader(context.Background(), client, leaderPath)
 		c.Assert(err, IsNil)
 		if leader != nil {
 			return leader
@@ -103,3 +103,14 @@ func (s *testTsoSuite) TestTso(c *C) {
 
 	wg.Wait()
 }
+
+func (s *testTsoSuite) TestTSOStatus(c *C) {
+	ts := s.testGetTimestamp(c, 10)
+	status, err := s.svr.GetTSOStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.Physical.UnixNano()/int64(time.Millisecond), Not(Less), ts.GetPhysical())
+	c.Assert(status.SavedTime.After(status.Physical), IsTrue)
+
+	_, err = (&Server{}).GetTSOStatus()
+	c.Assert(err, NotNil)
+}
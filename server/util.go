@@ -20,6 +20,7 @@ import (
 	"math/rand"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -47,15 +48,30 @@ const (
 
 // Version information.
 var (
-	PDBuildTS   = "None"
-	PDGitHash   = "None"
-	PDGitBranch = "None"
+	PDReleaseVersion = "1.0.0"
+	PDBuildTS        = "None"
+	PDGitHash        = "None"
+	PDGitBranch      = "None"
 )
 
+// buildTSLayout is the layout of PDBuildTS set by the Makefile, in UTC.
+const buildTSLayout = "2006-01-02 15:04:05"
+
+// GetBuildTime returns PDBuildTS in RFC3339, or PDBuildTS itself if it is not
+// set by the Makefile.
+func GetBuildTime() string {
+	t, err := time.Parse(buildTSLayout, PDBuildTS)
+	if err != nil {
+		return PDBuildTS
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 // LogPDInfo prints the PD version information.
 func LogPDInfo() {
 	log.Infof("Welcome to Placement Driver (PD).")
 	log.Infof("Version:")
+	log.Infof("Release Version: %s", PDReleaseVersion)
 	log.Infof("Git Commit Hash: %s", PDGitHash)
 	log.Infof("Git Branch: %s", PDGitBranch)
 	log.Infof("UTC Build Time:  %s", PDBuildTS)
@@ -63,15 +79,17 @@ func LogPDInfo() {
 
 // PrintPDInfo prints the PD version information without log info.
 func PrintPDInfo() {
+	fmt.Println("Release Version:", PDReleaseVersion)
 	fmt.Println("Git Commit Hash:", PDGitHash)
 	fmt.Println("Git Branch:", PDGitBranch)
 	fmt.Println("UTC Build Time: ", PDBuildTS)
 }
 
-// A helper function to get value with key from etcd.
+// A helper function to get value with key from etcd, a timeout is reported
+// with the key by kvGet.
 // TODO: return the value revision for outer use.
-func getValue(c *clientv3.Client, key string, opts ...clientv3.OpOption) ([]byte, error) {
-	resp, err := kvGet(c, key, opts...)
+func getValue(ctx context.Context, c *clientv3.Client, key string, opts ...clientv3.OpOption) ([]byte, error) {
+	resp, err := kvGet(ctx, c, key, opts...)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -87,8 +105,8 @@ func getValue(c *clientv3.Client, key string, opts ...clientv3.OpOption) ([]byte
 
 // Return boolean to indicate whether the key exists or not.
 // TODO: return the value revision for outer use.
-func getProtoMsg(c *clientv3.Client, key string, msg proto.Message, opts ...clientv3.OpOption) (bool, error) {
-	value, err := getValue(c, key, opts...)
+func getProtoMsg(ctx context.Context, c *clientv3.Client, key string, msg proto.Message, opts ...clientv3.OpOption) (bool, error) {
+	value, err := getValue(ctx, c, key, opts...)
 	if err != nil {
 		return false, errors.Trace(err)
 	}
@@ -103,6 +121,39 @@ func getProtoMsg(c *clientv3.Client, key string, msg proto.Message, opts ...clie
 	return true, nil
 }
 
+// putProtoMsg appends the put of the marshaled message to the txn, the txn
+// must not have a Then yet. See opPutProtoMsg for verify.
+func putProtoMsg(txn clientv3.Txn, key string, msg proto.Message, verify bool) (clientv3.Txn, error) {
+	op, err := opPutProtoMsg(key, msg, verify)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return txn.Then(op), nil
+}
+
+// opPutProtoMsg returns the put op of the marshaled message. If verify is
+// set, the value is unmarshaled again and compared with the message, so a
+// message which does not round trip is not persisted and fails the loading
+// later. The verification uses reflection, so it is only for the rare
+// writes, such as the bootstrap.
+func opPutProtoMsg(key string, msg proto.Message, verify bool) (clientv3.Op, error) {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return clientv3.Op{}, errors.Annotatef(err, "marshal %s", key)
+	}
+	if !verify {
+		return clientv3.OpPut(key, string(value)), nil
+	}
+	check := reflect.New(reflect.TypeOf(msg).Elem()).Interface().(proto.Message)
+	if err = proto.Unmarshal(value, check); err != nil {
+		return clientv3.Op{}, errors.Annotatef(err, "verify %s", key)
+	}
+	if !proto.Equal(msg, check) {
+		return clientv3.Op{}, errors.Errorf("verify %s: %v is changed to %v after marshal", key, msg, check)
+	}
+	return clientv3.OpPut(key, string(value)), nil
+}
+
 func initOrGetClusterID(c *clientv3.Client, key string) (uint64, error) {
 	ctx, cancel := context.WithTimeout(c.Ctx(), requestTimeout)
 	defer cancel()
@@ -156,14 +207,46 @@ func uint64ToBytes(v uint64) []byte {
 	return b
 }
 
+// wrapTimeoutError annotates an etcd error caused by an exceeded deadline
+// with the key and the elapsed time, a bare "context deadline exceeded"
+// does not tell which request is slow. A zero cost means the request is not
+// timed.
+func wrapTimeoutError(err error, key string, cost time.Duration) error {
+	if errors.Cause(err) != context.DeadlineExceeded {
+		return err
+	}
+	if cost == 0 {
+		return errors.Annotatef(err, "etcd request timed out (key=%s)", key)
+	}
+	return errors.Annotatef(err, "etcd request timed out after %s (key=%s)", cost, key)
+}
+
+// slowLogDisabled is set by Config.DisableSlowLog, it is 1 if kvGet and
+// slowLogTxn do not time the requests.
+var slowLogDisabled int32
+
+func setSlowLogDisabled(disabled bool) {
+	var v int32
+	if disabled {
+		v = 1
+	}
+	atomic.StoreInt32(&slowLogDisabled, v)
+}
+
+func isSlowLogDisabled() bool {
+	return atomic.LoadInt32(&slowLogDisabled) == 1
+}
+
 // slowLogTxn wraps etcd transaction and log slow one.
 type slowLogTxn struct {
 	clientv3.Txn
 	cancel context.CancelFunc
+	// keys are written by the txn, they are reported if it times out.
+	keys []string
 }
 
-func newSlowLogTxn(client *clientv3.Client) clientv3.Txn {
-	ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+func newSlowLogTxn(ctx context.Context, client *clientv3.Client) clientv3.Txn {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	return &slowLogTxn{
 		Txn:    client.Txn(ctx),
 		cancel: cancel,
@@ -174,18 +257,32 @@ func (t *slowLogTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
 	return &slowLogTxn{
 		Txn:    t.Txn.If(cs...),
 		cancel: t.cancel,
+		keys:   t.keys,
 	}
 }
 
 func (t *slowLogTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	keys := append([]string(nil), t.keys...)
+	for _, op := range ops {
+		keys = append(keys, string(op.KeyBytes()))
+	}
 	return &slowLogTxn{
 		Txn:    t.Txn.Then(ops...),
 		cancel: t.cancel,
+		keys:   keys,
 	}
 }
 
 // Commit implements Txn Commit interface.
 func (t *slowLogTxn) Commit() (*clientv3.TxnResponse, error) {
+	if isSlowLogDisabled() {
+		resp, err := t.Txn.Commit()
+		t.cancel()
+		txnCounter.WithLabelValues(txnResultLabel(resp, err)).Inc()
+		err = wrapTimeoutError(err, strings.Join(t.keys, ","), 0)
+		return resp, errors.Trace(err)
+	}
+
 	start := time.Now()
 	resp, err := t.Txn.Commit()
 	t.cancel()
@@ -193,17 +290,71 @@ func (t *slowLogTxn) Commit() (*clientv3.TxnResponse, error) {
 	cost := time.Now().Sub(start)
 	if cost > slowRequestTime {
 		log.Warnf("txn runs too slow, resp: %v, err: %v, cost: %s", resp, err, cost)
+		slowRequests.add(SlowRequestTxn, start, cost, fmt.Sprintf("err: %v", err))
 	}
-	label := "success"
-	if err != nil {
-		label = "failed"
-	}
+	label := txnResultLabel(resp, err)
 	txnCounter.WithLabelValues(label).Inc()
 	txnDuration.WithLabelValues(label).Observe(cost.Seconds())
 
+	err = wrapTimeoutError(err, strings.Join(t.keys, ","), cost)
 	return resp, errors.Trace(err)
 }
 
+// txnResultLabel returns the metrics label of a txn result. A txn whose
+// comparisons fail is a conflict rather than an error, such as a lost
+// leadership or a concurrent update.
+func txnResultLabel(resp *clientv3.TxnResponse, err error) string {
+	if err != nil {
+		return "failed"
+	}
+	if !resp.Succeeded {
+		return "conflict"
+	}
+	return "success"
+}
+
+// slowLogRead reads etcd with a chosen consistency, slow reads are logged and
+// recorded the same as kvGet.
+type slowLogRead struct {
+	ctx    context.Context
+	client *clientv3.Client
+	opts   []clientv3.OpOption
+}
+
+// newSerializableRead returns a reader served by the local etcd member without
+// a round trip through the raft leader, so it is cheaper but may miss the
+// latest writes, even the ones made by this PD just before. It is safe when a
+// stale value is only a delay, such as for a cache which is refreshed or
+// invalidated later, or for values which only move forward. Reads which decide
+// a write, such as the leader key, the allocated IDs or the TSO, must use
+// newLinearizableRead or a txn.
+func newSerializableRead(ctx context.Context, client *clientv3.Client) *slowLogRead {
+	return &slowLogRead{
+		ctx:    ctx,
+		client: client,
+		opts:   []clientv3.OpOption{clientv3.WithSerializable()},
+	}
+}
+
+// newLinearizableRead returns a reader which sees all writes committed before
+// the read, it is the same as kvGet.
+func newLinearizableRead(ctx context.Context, client *clientv3.Client) *slowLogRead {
+	return &slowLogRead{
+		ctx:    ctx,
+		client: client,
+	}
+}
+
+func (r *slowLogRead) get(key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kvGet(r.ctx, r.client, key, append(opts, r.opts...)...)
+	return resp, errors.Trace(err)
+}
+
+func (r *slowLogRead) getValue(key string, opts ...clientv3.OpOption) ([]byte, error) {
+	value, err := getValue(r.ctx, r.client, key, append(opts, r.opts...)...)
+	return value, errors.Trace(err)
+}
+
 func sliceClone(strs []string) []string {
 	data := make([]string, 0, len(strs))
 	for _, str := range strs {
@@ -17,7 +17,12 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
 )
 
 var _ = Suite(&testMinMaxSuite{})
@@ -69,3 +74,139 @@ func (s *testUtilSuite) TestSubTimeByWallClock(c *C) {
 		c.Assert(duration, Equals, time.Second*time.Duration(r))
 	}
 }
+
+func (s *testUtilSuite) TestGetBuildTime(c *C) {
+	origin := PDBuildTS
+	defer func() { PDBuildTS = origin }()
+
+	PDBuildTS = "2017-09-01 15:04:05"
+	c.Assert(GetBuildTime(), Equals, "2017-09-01T15:04:05Z")
+	// It is returned as is if it is not set by the Makefile.
+	PDBuildTS = "None"
+	c.Assert(GetBuildTime(), Equals, "None")
+}
+
+func getTxnCount(c *C, label string) float64 {
+	m := &dto.Metric{}
+	c.Assert(txnCounter.WithLabelValues(label).Write(m), IsNil)
+	return m.GetCounter().GetValue()
+}
+
+func (s *testUtilSuite) TestSlowLogTxnConflict(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	success, conflict := getTxnCount(c, "success"), getTxnCount(c, "conflict")
+	resp, err := svr.txn().Then(clientv3.OpPut("/test/txn", "1")).Commit()
+	c.Assert(err, IsNil)
+	c.Assert(resp.Succeeded, IsTrue)
+	// The server may commit other txns at the same time.
+	c.Assert(getTxnCount(c, "success") >= success+1, IsTrue)
+
+	cmp := clientv3.Compare(clientv3.Value("/test/txn"), "=", "2")
+	resp, err = svr.txn().If(cmp).Then(clientv3.OpPut("/test/txn", "3")).Commit()
+	c.Assert(err, IsNil)
+	c.Assert(resp.Succeeded, IsFalse)
+	c.Assert(getTxnCount(c, "conflict") >= conflict+1, IsTrue)
+}
+
+func (s *testUtilSuite) TestEtcdTimeoutError(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	_, err := getValue(ctx, svr.client, "/test/timeout")
+	c.Assert(err, ErrorMatches, `etcd request timed out after .* \(key=/test/timeout\): context deadline exceeded`)
+	c.Assert(errors.Cause(err), Equals, context.DeadlineExceeded)
+
+	_, err = newSlowLogTxn(ctx, svr.client).Then(clientv3.OpPut("/test/a", "1"), clientv3.OpPut("/test/b", "2")).Commit()
+	c.Assert(err, ErrorMatches, `etcd request timed out after .* \(key=/test/a,/test/b\): context deadline exceeded`)
+
+	// Other errors are not changed.
+	c.Assert(wrapTimeoutError(context.Canceled, "/test", time.Second), Equals, context.Canceled)
+}
+
+func (s *testUtilSuite) TestDisableSlowLog(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	setSlowLogDisabled(true)
+	defer setSlowLogDisabled(false)
+
+	success := getTxnCount(c, "success")
+	_, err := newSlowLogTxn(svr.ctx, svr.client).Then(clientv3.OpPut("/test/slow-log", "1")).Commit()
+	c.Assert(err, IsNil)
+	c.Assert(getTxnCount(c, "success") >= success+1, IsTrue)
+	value, err := getValue(svr.ctx, svr.client, "/test/slow-log")
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "1")
+
+	// The keys of the timed out requests are still reported.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = getValue(ctx, svr.client, "/test/timeout")
+	c.Assert(err, ErrorMatches, `etcd request timed out \(key=/test/timeout\): context deadline exceeded`)
+	_, err = newSlowLogTxn(ctx, svr.client).Then(clientv3.OpPut("/test/a", "1")).Commit()
+	c.Assert(err, ErrorMatches, `etcd request timed out \(key=/test/a\): context deadline exceeded`)
+}
+
+func (s *testUtilSuite) TestSlowLogRead(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	_, err := newSlowLogTxn(svr.ctx, svr.client).Then(clientv3.OpPut("/test/read/a", "1"), clientv3.OpPut("/test/read/b", "2")).Commit()
+	c.Assert(err, IsNil)
+
+	// There is only one member, so a serializable read sees the writes too.
+	for _, r := range []*slowLogRead{newSerializableRead(svr.ctx, svr.client), newLinearizableRead(svr.ctx, svr.client)} {
+		value, err := r.getValue("/test/read/a")
+		c.Assert(err, IsNil)
+		c.Assert(string(value), Equals, "1")
+		value, err = r.getValue("/test/read/c")
+		c.Assert(err, IsNil)
+		c.Assert(value, IsNil)
+		resp, err := r.get("/test/read/", clientv3.WithPrefix())
+		c.Assert(err, IsNil)
+		c.Assert(resp.Kvs, HasLen, 2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = newSerializableRead(ctx, svr.client).getValue("/test/read/a")
+	c.Assert(err, ErrorMatches, `etcd request timed out after .* \(key=/test/read/a\): context deadline exceeded`)
+}
+
+// unstableStore is marshaled to a store with another id.
+type unstableStore struct {
+	metapb.Store
+}
+
+func (m *unstableStore) Marshal() ([]byte, error) {
+	return (&metapb.Store{Id: m.Id + 1}).Marshal()
+}
+
+func (s *testUtilSuite) TestPutProtoMsg(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	store := &metapb.Store{Id: 1, Address: "127.0.0.1:20160"}
+	txn, err := putProtoMsg(svr.txn(), "/test/proto", store, true)
+	c.Assert(err, IsNil)
+	_, err = txn.Commit()
+	c.Assert(err, IsNil)
+	got := &metapb.Store{}
+	ok, err := getProtoMsg(svr.ctx, svr.client, "/test/proto", got)
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	c.Assert(got, DeepEquals, store)
+
+	_, err = putProtoMsg(svr.txn(), "/test/proto", &unstableStore{Store: metapb.Store{Id: 2}}, true)
+	c.Assert(err, ErrorMatches, "verify /test/proto: .* is changed to .* after marshal")
+	// The message is only marshaled without verify.
+	_, err = putProtoMsg(svr.txn(), "/test/proto", &unstableStore{Store: metapb.Store{Id: 2}}, false)
+	c.Assert(err, IsNil)
+}